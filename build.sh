@@ -15,9 +15,12 @@ if [ ! -d "internal/contracts" ] || [ -z "$(ls -A internal/contracts)" ]; then
     ./generate.sh
 fi
 
-# Build the binary
-echo "Compiling Go binary..."
-go build -o wallet-exporter ./cmd/exporter
+# Build the binary, stamping it with the version shown by ./wallet-exporter version
+VERSION=${VERSION:-$(git describe --tags --always --dirty 2>/dev/null || echo dev)}
+COMMIT=${COMMIT:-$(git rev-parse HEAD 2>/dev/null || true)}
+BUILD_DATE=${BUILD_DATE:-$(date -u +%Y-%m-%dT%H:%M:%SZ)}
+echo "Compiling Go binary ($VERSION)..."
+go build -ldflags "-X main.version=$VERSION -X main.commit=$COMMIT -X main.buildDate=$BUILD_DATE" -o wallet-exporter ./cmd/exporter
 
 echo "✅ Build complete! Binary: ./wallet-exporter"
 echo ""
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"gopkg.in/yaml.v3"
+)
+
+// ruleFile is a Prometheus rule file
+type ruleFile struct {
+	Groups []ruleGroup `yaml:"groups"`
+}
+
+type ruleGroup struct {
+	Name  string      `yaml:"name"`
+	Rules []alertRule `yaml:"rules"`
+}
+
+type alertRule struct {
+	Alert       string            `yaml:"alert"`
+	Expr        string            `yaml:"expr"`
+	For         string            `yaml:"for,omitempty"`
+	Labels      map[string]string `yaml:"labels"`
+	Annotations map[string]string `yaml:"annotations"`
+}
+
+// alertRuleOptions are the thresholds the recommended rules are rendered with
+type alertRuleOptions struct {
+	Prefix         string
+	Network        string
+	Job            string
+	StaleAfter     time.Duration
+	PingWindow     time.Duration
+	MinFIL         float64 // Absolute FIL floor for every wallet (0 = only the configured thresholds)
+	MinUSDFC       float64 // Absolute USDFC floor for every wallet (0 = only the configured thresholds)
+	FundedWarnDays float64 // 0 = no warning rule
+	FundedCritDays float64 // 0 = no critical rule
+	PingSelector   string  // Extra matchers for the ping rule, e.g. region="eu"
+}
+
+// promDuration formats d the way Prometheus writes durations (1h30m, 5m)
+func promDuration(d time.Duration) string {
+	return model.Duration(d).String()
+}
+
+// generateAlertRules renders the recommended alerting rules for the exporter's metrics
+func generateAlertRules(opts alertRuleOptions) ruleFile {
+	p := opts.Prefix
+	pings := ""
+	if opts.PingSelector != "" {
+		pings = "{" + opts.PingSelector + "}"
+	}
+
+	rules := []alertRule{
+		{
+			Alert:  "WalletExporterDown",
+			Expr:   fmt.Sprintf(`up{job=%q} == 0`, opts.Job),
+			For:    "5m",
+			Labels: map[string]string{"severity": "critical"},
+			Annotations: map[string]string{
+				"summary":     "Wallet exporter {{ $labels.instance }} is down",
+				"description": "Prometheus has not been able to scrape the wallet exporter for 5 minutes, so no balance alerts can fire.",
+			},
+		},
+		{
+			Alert:  "WalletExporterScrapeStale",
+			Expr:   fmt.Sprintf(`time() - %s_last_successful_scrape_timestamp_seconds > %d`, p, int64(opts.StaleAfter.Seconds())),
+			For:    "5m",
+			Labels: map[string]string{"severity": "warning"},
+			Annotations: map[string]string{
+				"summary":     "Wallet exporter {{ $labels.instance }} has stale data",
+				"description": fmt.Sprintf("The last successful scrape finished {{ $value | humanizeDuration }} ago (more than %s); balances are out of date.", promDuration(opts.StaleAfter)),
+			},
+		},
+		{
+			Alert:  "WalletExporterRPCCircuitOpen",
+			Expr:   fmt.Sprintf(`%s_rpc_circuit_open == 1`, p),
+			For:    "10m",
+			Labels: map[string]string{"severity": "warning"},
+			Annotations: map[string]string{
+				"summary":     "Wallet exporter {{ $labels.instance }} skips scrapes",
+				"description": "The RPC endpoint keeps failing, so scrapes are skipped until it recovers.",
+			},
+		},
+		{
+			Alert:  "WalletBelowThreshold",
+			Expr:   fmt.Sprintf(`%s_wallet_below_threshold == 1`, p),
+			For:    "10m",
+			Labels: map[string]string{"severity": "warning"},
+			Annotations: map[string]string{
+				"summary":     "{{ $labels.name }} is low on {{ $labels.token }}",
+				"description": "The {{ $labels.token }} balance of {{ $labels.name }} ({{ $labels.address }}) is below its configured minimum.",
+			},
+		},
+	}
+
+	if opts.MinFIL > 0 {
+		rules = append(rules, alertRule{
+			Alert:  "WalletFILBalanceLow",
+			Expr:   fmt.Sprintf(`%s_wallet_fil_balance < %g`, p, opts.MinFIL),
+			For:    "10m",
+			Labels: map[string]string{"severity": "warning"},
+			Annotations: map[string]string{
+				"summary":     fmt.Sprintf("{{ $labels.name }} has less than %g FIL", opts.MinFIL),
+				"description": "{{ $labels.name }} ({{ $labels.address }}) holds {{ $value | humanize }} FIL.",
+			},
+		})
+	}
+	if opts.MinUSDFC > 0 {
+		rules = append(rules, alertRule{
+			Alert:  "WalletUSDFCBalanceLow",
+			Expr:   fmt.Sprintf(`%s_wallet_usdfc_balance < %g`, p, opts.MinUSDFC),
+			For:    "10m",
+			Labels: map[string]string{"severity": "warning"},
+			Annotations: map[string]string{
+				"summary":     fmt.Sprintf("{{ $labels.name }} has less than %g USDFC", opts.MinUSDFC),
+				"description": "{{ $labels.name }} ({{ $labels.address }}) holds {{ $value | humanize }} USDFC.",
+			},
+		})
+	}
+
+	rules = append(rules, alertRule{
+		Alert:  "ProviderPingFailing",
+		Expr:   fmt.Sprintf(`max_over_time(%s_provider_ping_success%s[%s]) == 0`, p, pings, promDuration(opts.PingWindow)),
+		Labels: map[string]string{"severity": "warning"},
+		Annotations: map[string]string{
+			"summary":     "Provider {{ $labels.name }} is not answering pings",
+			"description": fmt.Sprintf("Every ping of product {{ $labels.product_type }} at {{ $labels.service_url }} failed for %s.", promDuration(opts.PingWindow)),
+		},
+	})
+
+	// Only accounts with a lockup rate run out; the others keep their funded-until epoch
+	funded := func(days float64) string {
+		return fmt.Sprintf(`%s < %g and %s_wallet_payments_lockup_rate > 0`, fundedDaysExpr(p, opts.Network, ""), days, p)
+	}
+	if opts.FundedWarnDays > 0 {
+		rules = append(rules, alertRule{
+			Alert:  "PaymentsFundingLow",
+			Expr:   funded(opts.FundedWarnDays),
+			For:    "30m",
+			Labels: map[string]string{"severity": "warning"},
+			Annotations: map[string]string{
+				"summary":     "{{ $labels.name }} Payments account runs out in {{ $value | humanize }} days",
+				"description": fmt.Sprintf("The {{ $labels.token }} Payments account of {{ $labels.name }} ({{ $labels.address }}) is funded for less than %g days at its current lockup rate.", opts.FundedWarnDays),
+			},
+		})
+	}
+	if opts.FundedCritDays > 0 {
+		rules = append(rules, alertRule{
+			Alert:  "PaymentsFundingCritical",
+			Expr:   funded(opts.FundedCritDays),
+			For:    "10m",
+			Labels: map[string]string{"severity": "critical"},
+			Annotations: map[string]string{
+				"summary":     "{{ $labels.name }} Payments account runs out in {{ $value | humanize }} days",
+				"description": fmt.Sprintf("The {{ $labels.token }} Payments account of {{ $labels.name }} ({{ $labels.address }}) is funded for less than %g days; top it up before rails terminate.", opts.FundedCritDays),
+			},
+		})
+	}
+
+	return ruleFile{Groups: []ruleGroup{{Name: "wallet-exporter", Rules: rules}}}
+}
+
+func runGenerateRules(args []string) int {
+	flags := flag.NewFlagSet("generate-rules", flag.ExitOnError)
+	out := flags.String("out", "", "write the rules to this file instead of stdout")
+	prefix := flags.String("prefix", "", "metrics prefix (default METRICS_PREFIX)")
+	network := flags.String("network", "", "network the funded-until epochs are converted for (default NETWORK)")
+	job := flags.String("job", "wallet-exporter", "Prometheus job that scrapes the exporter")
+	staleAfter := flags.Duration("stale-after", 0, "alert when the last successful scrape is older than this (default READY_MAX_STALENESS)")
+	pingWindow := flags.Duration("ping-window", 15*time.Minute, "alert when every ping of a provider failed for this long")
+	minFIL := flags.Float64("min-fil", 0, "also alert on every wallet below this many FIL (0 = only the configured thresholds)")
+	minUSDFC := flags.Float64("min-usdfc", 0, "also alert on every wallet below this many USDFC (0 = only the configured thresholds)")
+	warnDays := flags.Float64("funded-warning-days", -1, "warn when a Payments account is funded for less than this many days (default ALERT_RUNWAY_WARNING_DAYS, 0 = off)")
+	critDays := flags.Float64("funded-critical-days", -1, "critical when a Payments account is funded for less than this many days (default ALERT_RUNWAY_CRITICAL_DAYS, 0 = off)")
+	pingSelector := flags.String("ping-selector", "", `extra matchers for the ping rule, e.g. region="eu"`)
+	flags.Parse(args)
+
+	cfg, _, err := loadCommandConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+
+	opts := alertRuleOptions{
+		Prefix:         cfg.MetricsPrefix,
+		Network:        cfg.Network,
+		Job:            *job,
+		StaleAfter:     cfg.ReadyMaxStaleness,
+		PingWindow:     *pingWindow,
+		MinFIL:         *minFIL,
+		MinUSDFC:       *minUSDFC,
+		FundedWarnDays: cfg.AlertRunwayWarningDays,
+		FundedCritDays: cfg.AlertRunwayCriticalDays,
+		PingSelector:   *pingSelector,
+	}
+	if *prefix != "" {
+		opts.Prefix = *prefix
+	}
+	if *network != "" {
+		opts.Network = *network
+	}
+	if *staleAfter > 0 {
+		opts.StaleAfter = *staleAfter
+	}
+	if *warnDays >= 0 {
+		opts.FundedWarnDays = *warnDays
+	}
+	if *critDays >= 0 {
+		opts.FundedCritDays = *critDays
+	}
+	if _, ok := networkGenesis[opts.Network]; !ok {
+		fmt.Fprintf(os.Stderr, "❌ Unknown network %q, use calibration or mainnet\n", opts.Network)
+		return 2
+	}
+	if opts.StaleAfter <= 0 || opts.PingWindow <= 0 {
+		fmt.Fprintf(os.Stderr, "❌ -stale-after and -ping-window must be positive\n")
+		return 2
+	}
+
+	rules := generateAlertRules(opts)
+	var buf bytes.Buffer
+	buf.WriteString("# Generated by wallet-exporter generate-rules\n")
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(rules); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to encode rules: %v\n", err)
+		return 1
+	}
+	data := buf.Bytes()
+
+	if *out == "" {
+		os.Stdout.Write(data)
+		return 0
+	}
+	if err := os.WriteFile(*out, data, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to write rules: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "✓ Wrote %d alerting rules to %s\n", len(rules.Groups[0].Rules), *out)
+	return 0
+}
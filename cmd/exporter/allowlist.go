@@ -0,0 +1,52 @@
+package main
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+)
+
+// allowlist refuses requests from clients outside HTTP_ALLOWED_CIDRS. The client is the
+// connection's peer; X-Forwarded-For is not trusted, as there is no proxy to vouch for it.
+type allowlist struct {
+	networks []netip.Prefix
+	exp      requestRejecter
+	logger   *slog.Logger
+}
+
+func newAllowlist(networks []netip.Prefix, exp requestRejecter, logger *slog.Logger) *allowlist {
+	return &allowlist{networks: networks, exp: exp, logger: logger}
+}
+
+// allows reports whether a request from remoteAddr (host:port) may be served
+func (a *allowlist) allows(remoteAddr string) bool {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, network := range a.networks {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *allowlist) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.allows(r.RemoteAddr) {
+			a.logger.Warn("Rejected request from address outside HTTP_ALLOWED_CIDRS", "remote_addr", r.RemoteAddr, "path", r.URL.Path)
+			a.exp.RequestRejected("allowlist")
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
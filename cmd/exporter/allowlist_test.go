@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestAllowlist(t *testing.T) {
+	networks := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("2001:db8::/32"),
+	}
+
+	tests := []struct {
+		name          string
+		remoteAddr    string
+		forwardedFor  string
+		expectAllowed bool
+	}{
+		{"allowed network", "10.1.2.3:1000", "", true},
+		{"allowed IPv6 network", "[2001:db8::1]:1000", "", true},
+		{"IPv4-mapped address", "[::ffff:10.1.2.3]:1000", "", true},
+		{"denied address", "192.168.1.1:1000", "", false},
+		{"unparsable address", "example.com:1000", "", false},
+		// X-Forwarded-For is not trusted, only the connection's peer counts
+		{"forwarded from allowed network", "192.168.1.1:1000", "10.1.2.3", false},
+		{"forwarded from denied network", "10.1.2.3:1000", "192.168.1.1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rejected := &rejectCounter{}
+			handler := newAllowlist(networks, rejected, slog.New(slog.NewTextHandler(io.Discard, nil))).
+				wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			r := httptest.NewRequest(http.MethodGet, "/status", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if tt.expectAllowed {
+				if w.Code != http.StatusOK {
+					t.Errorf("Expected request from %s to be served, got status %d", tt.remoteAddr, w.Code)
+				}
+				if len(rejected.reasons) != 0 {
+					t.Errorf("Expected no rejection counted, got %v", rejected.reasons)
+				}
+				return
+			}
+			if w.Code != http.StatusForbidden {
+				t.Errorf("Expected request from %s to be forbidden, got status %d", tt.remoteAddr, w.Code)
+			}
+			if len(rejected.reasons) != 1 || rejected.reasons[0] != "allowlist" {
+				t.Errorf("Expected one allowlist rejection counted, got %v", rejected.reasons)
+			}
+		})
+	}
+}
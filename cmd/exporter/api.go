@@ -0,0 +1,183 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"wallet-exporter/internal/config"
+	"wallet-exporter/internal/exporter"
+	"wallet-exporter/internal/filaddr"
+)
+
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+)
+
+// walletPage is a page of the wallet list
+type walletPage struct {
+	Total   int            `json:"total"` // Wallets matching the filters
+	Offset  int            `json:"offset"`
+	Limit   int            `json:"limit"`
+	Wallets []walletDetail `json:"wallets"`
+}
+
+// walletDetail is a wallet with the ping results of its products
+type walletDetail struct {
+	walletStatus
+	Pings []pingStatus `json:"pings,omitempty"`
+}
+
+type pingStatus struct {
+	ProductType     uint8   `json:"product_type"`
+	ServiceURL      string  `json:"service_url"`
+	Success         bool    `json:"success"`
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+// walletAPI serves the wallets of the last scrape under /api/v1
+type walletAPI struct {
+	cfg    *config.Config
+	exp    *exporter.WalletExporter
+	logger *slog.Logger
+}
+
+func (a *walletAPI) detail(wallet exporter.WalletInfo, pings map[uint64][]exporter.PingResult) walletDetail {
+	detail := walletDetail{walletStatus: newWalletStatus(wallet, a.cfg.ExportDataSets)}
+	if wallet.Type == "provider" {
+		for _, ping := range pings[wallet.ProviderID] {
+			detail.Pings = append(detail.Pings, pingStatus{
+				ProductType:     ping.ProductType,
+				ServiceURL:      ping.ServiceURL,
+				Success:         ping.Success,
+				DurationSeconds: ping.Duration.Seconds(),
+			})
+		}
+	}
+	return detail
+}
+
+// listWallets serves GET /api/v1/wallets. Wallets can be filtered by a comma-separated list
+// of types (?type=provider,client) and paged with ?offset= and ?limit=.
+func (a *walletAPI) listWallets(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	offset, err := queryInt(query.Get("offset"), 0)
+	if err != nil || offset < 0 {
+		http.Error(w, "offset must be a non-negative integer", http.StatusBadRequest)
+		return
+	}
+	limit, err := queryInt(query.Get("limit"), defaultPageLimit)
+	if err != nil || limit <= 0 || limit > maxPageLimit {
+		http.Error(w, "limit must be between 1 and "+strconv.Itoa(maxPageLimit), http.StatusBadRequest)
+		return
+	}
+	var types []string
+	if value := query.Get("type"); value != "" {
+		types = strings.Split(value, ",")
+	}
+
+	pings := a.exp.GetPingResults()
+	var matching []exporter.WalletInfo
+	for _, wallet := range a.exp.GetWallets() {
+		if len(types) == 0 || slices.Contains(types, wallet.Type) {
+			matching = append(matching, wallet)
+		}
+	}
+
+	page := walletPage{Total: len(matching), Offset: offset, Limit: limit, Wallets: []walletDetail{}}
+	for _, wallet := range matching[min(offset, len(matching)):min(offset+limit, len(matching))] {
+		page.Wallets = append(page.Wallets, a.detail(wallet, pings))
+	}
+	a.writeJSON(w, page)
+}
+
+// getWallet serves GET /api/v1/wallets/{address}. The address can be given in 0x or f410/t410
+// form; a wallet monitored under several types is returned as a list.
+func (a *walletAPI) getWallet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	address, ok := parseWalletAddress(r.PathValue("address"))
+	if !ok {
+		http.Error(w, "invalid address", http.StatusBadRequest)
+		return
+	}
+
+	pings := a.exp.GetPingResults()
+	var found []walletDetail
+	for _, wallet := range a.exp.GetWallets() {
+		if wallet.Address == address {
+			found = append(found, a.detail(wallet, pings))
+		}
+	}
+	if len(found) == 0 {
+		http.Error(w, "wallet not found", http.StatusNotFound)
+		return
+	}
+	a.writeJSON(w, found)
+}
+
+// getProvider serves GET /api/v1/providers/{id}
+func (a *walletAPI) getProvider(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid provider ID", http.StatusBadRequest)
+		return
+	}
+
+	for _, wallet := range a.exp.GetWallets() {
+		if wallet.Type == "provider" && wallet.ProviderID == id {
+			a.writeJSON(w, a.detail(wallet, a.exp.GetPingResults()))
+			return
+		}
+	}
+	http.Error(w, "provider not found", http.StatusNotFound)
+}
+
+func (a *walletAPI) writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		a.logger.Error("Failed to encode API response", "error", err)
+	}
+}
+
+// parseWalletAddress parses a wallet address given in 0x or f410/t410 form
+func parseWalletAddress(raw string) (common.Address, bool) {
+	if filaddr.IsDelegated(raw) {
+		converted, err := filaddr.ToEth(raw)
+		if err != nil {
+			return common.Address{}, false
+		}
+		raw = converted
+	}
+	if !common.IsHexAddress(raw) {
+		return common.Address{}, false
+	}
+	return common.HexToAddress(raw), true
+}
+
+// queryInt parses an integer query parameter, returning fallback if it is empty
+func queryInt(value string, fallback int) (int, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(value)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"wallet-exporter/internal/config"
+)
+
+// apiAuth checks bearer tokens against the configured API tokens and their scopes
+type apiAuth struct {
+	tokens []config.APIToken
+	logger *slog.Logger
+}
+
+// lookup returns the API token matching the request's bearer token
+func (a *apiAuth) lookup(r *http.Request) (config.APIToken, bool) {
+	secret, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || secret == "" {
+		return config.APIToken{}, false
+	}
+
+	for _, token := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(secret), []byte(token.Token)) == 1 {
+			return token, true
+		}
+	}
+	return config.APIToken{}, false
+}
+
+// require wraps next so that it only runs for tokens granted scope. Without any configured
+// tokens the read scope stays open for backward compatibility, while every other scope is
+// refused, so admin endpoints are disabled until tokens are set up.
+func (a *apiAuth) require(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(a.tokens) == 0 && scope == config.ScopeRead {
+			next(w, r)
+			return
+		}
+
+		token, ok := a.lookup(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="wallet-exporter"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if !token.HasScope(scope) {
+			a.logger.Warn("API token lacks scope", "token", token.Name, "scope", scope, "path", r.URL.Path)
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		next(w, r)
+	}
+}
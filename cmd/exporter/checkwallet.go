@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"wallet-exporter/internal/exporter"
+	"wallet-exporter/internal/filaddr"
+)
+
+// exitBelowThreshold is the exit code of check-wallet when a balance is below its threshold,
+// kept apart from 1 so scripts can tell a low balance from a failed check
+const exitBelowThreshold = 3
+
+func runCheckWallet(args []string) int {
+	flags := flag.NewFlagSet("check-wallet", flag.ExitOnError)
+	minFIL := flags.Float64("min-fil", 0, "exit 3 if the FIL balance is below this (0 = no threshold)")
+	minUSDFC := flags.Float64("min-usdfc", 0, "exit 3 if the USDFC balance is below this (0 = no threshold)")
+	minAvailable := flags.Float64("min-available", 0, "exit 3 if the available USDFC in the Payments contract is below this (0 = no threshold)")
+	timeout := flags.Duration("timeout", time.Minute, "timeout for the balance lookups")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s check-wallet [flags] <address>\n\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	address, ok := parseWalletAddress(flags.Arg(0))
+	if !ok {
+		fmt.Fprintf(os.Stderr, "❌ %q is not a 0x or f410/t410 address\n", flags.Arg(0))
+		return 2
+	}
+
+	cfg, exp, err := newCommandExporter()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	defer exp.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	wallet, block, err := exp.CheckWallet(ctx, address)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to check wallet: %v\n", err)
+		return 1
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Address:\t%s\n", wallet.Address.Hex())
+	fmt.Fprintf(w, "Filecoin address:\t%s\n", filaddr.FromEth(wallet.Address, cfg.Network))
+	fmt.Fprintf(w, "Network:\t%s\n", cfg.Network)
+	fmt.Fprintf(w, "Block:\t%d\n", block)
+	fmt.Fprintln(w)
+
+	below, unreadable := false, false
+	check := func(label string, amount *big.Int, decimals int, min float64) {
+		status := ""
+		if min > 0 {
+			if belowThreshold(amount, decimals, min) {
+				status = fmt.Sprintf("❌ below %g", min)
+				below = true
+			} else {
+				status = fmt.Sprintf("✓ min %g", min)
+			}
+		}
+		fmt.Fprintf(w, "%s:\t%s\t%s\n", label, exporter.FormatUnits(amount, decimals), status)
+	}
+	check("FIL", wallet.FILBalance, 18, *minFIL)
+	check("USDFC", wallet.USDFCBalance, 18, *minUSDFC)
+	fmt.Fprintln(w)
+
+	fmt.Fprintln(w, "Payments\tFunds\tAvailable\tLocked\tFunded until epoch\tLockup rate per epoch")
+	for _, account := range wallet.PaymentsAccounts {
+		if account.PaymentsInfo == nil {
+			fmt.Fprintf(w, "%s\t❌ lookup failed\t\t\t\t\n", account.Token)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", account.Token,
+			exporter.FormatUnits(account.Funds, account.Decimals),
+			exporter.FormatUnits(account.Available, account.Decimals),
+			exporter.FormatUnits(account.Locked, account.Decimals),
+			intString(account.FundedUntilEpoch),
+			exporter.FormatUnits(account.LockupRate, account.Decimals))
+	}
+	if *minAvailable > 0 {
+		// The USDFC account always comes first
+		usdfc := wallet.PaymentsAccounts[0]
+		if usdfc.PaymentsInfo == nil {
+			fmt.Fprintf(w, "\n❌ Available %s could not be read\n", usdfc.Token)
+			unreadable = true
+		} else if belowThreshold(usdfc.Available, usdfc.Decimals, *minAvailable) {
+			fmt.Fprintf(w, "\n❌ Available %s below %g\n", usdfc.Token, *minAvailable)
+			below = true
+		} else {
+			fmt.Fprintf(w, "\n✓ Available %s at least %g\n", usdfc.Token, *minAvailable)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to write report: %v\n", err)
+		return 1
+	}
+
+	if unreadable {
+		return 1
+	}
+	if below {
+		return exitBelowThreshold
+	}
+	return 0
+}
+
+// belowThreshold reports whether a raw token amount is below min whole tokens. A missing
+// amount counts as zero.
+func belowThreshold(amount *big.Int, decimals int, min float64) bool {
+	value := new(big.Float)
+	if amount != nil {
+		value.SetInt(amount)
+	}
+	scale := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil))
+	return value.Quo(value, scale).Cmp(big.NewFloat(min)) < 0
+}
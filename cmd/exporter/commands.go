@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+
+	"wallet-exporter/internal/config"
+	"wallet-exporter/internal/exporter"
+)
+
+// command is a CLI subcommand; it returns the process exit code
+type command struct {
+	description string
+	run         func(args []string) int
+}
+
+var commands = map[string]command{
+	"gen-targets":        {"Write Prometheus file_sd targets for the exporter and provider service URLs", runGenTargets},
+	"export-csv":         {"Scrape once and write every wallet with raw balances as CSV", runExportCSV},
+	"textfile":           {"Scrape once and write the metrics for the node_exporter textfile collector", runTextfile},
+	"list-providers":     {"Print every registry provider with its flags and service URL", runListProviders},
+	"check-wallet":       {"Print the FIL, USDFC and Payments balances of one address, failing below thresholds", runCheckWallet},
+	"version":            {"Print the version, commit, build date and default contract addresses", runVersion},
+	"generate-dashboard": {"Write a Grafana dashboard for the configured metrics prefix", runGenerateDashboard},
+	"generate-rules":     {"Write recommended Prometheus alerting rules for the configured metrics prefix", runGenerateRules},
+	"healthcheck":        {"Probe the local /-/ready endpoint and exit 0 if the exporter is ready", runHealthcheck},
+	"snapshot":           {"Scrape once and write every wallet's balances as JSON for diff", runSnapshot},
+	"diff":               {"Print the balance changes of every wallet between two snapshots", runDiff},
+	"doctor":             {"Check the RPC endpoint and contract addresses and print hints for what fails", runDoctor},
+}
+
+func runCommand(name string, args []string) int {
+	if name == "help" {
+		printUsage()
+		return 0
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		printUsage()
+		return 2
+	}
+	return cmd.run(args)
+}
+
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [command] [flags]\n\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "Without a command the exporter is started.\n\nCommands:\n")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-20s %s\n", name, commands[name].description)
+	}
+
+	fmt.Fprintf(os.Stderr, "\nFlags:\n")
+	fmt.Fprintf(os.Stderr, "  %-20s %s\n", "--dry-run", "Connect, scrape once and log what would be exported, without serving or pushing")
+	fmt.Fprintf(os.Stderr, "  %-20s %s\n", "--version", "Same as the version command")
+}
+
+// loadCommandConfig loads the configuration for a subcommand. Logs go to stderr so
+// that stdout only carries the command's output.
+func loadCommandConfig() (*config.Config, *slog.Logger, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	return cfg, newLogger(cfg.LogLevel, os.Stderr), nil
+}
+
+// newCommandExporter loads the configuration and connects an exporter for a subcommand
+func newCommandExporter() (*config.Config, *exporter.WalletExporter, error) {
+	cfg, logger, err := loadCommandConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	exp, err := commandExporter(cfg, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg, exp, nil
+}
+
+// commandExporter connects an exporter for a subcommand. It changes cfg to leave the files of
+// the running exporter alone.
+func commandExporter(cfg *config.Config, logger *slog.Logger) (*exporter.WalletExporter, error) {
+	// The running exporter holds the lock on the history database, and owns the state file.
+	// Commands write their output themselves instead of pushing it to the sinks.
+	cfg.HistoryPath = ""
+	cfg.StateFile = ""
+	cfg.TextfilePath = ""
+	// Commands bound their scrape with their own -timeout flag
+	cfg.ScrapeTimeout = 0
+
+	exp, err := exporter.New(cfg, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create exporter: %w", err)
+	}
+	return exp, nil
+}
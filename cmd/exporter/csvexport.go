@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"wallet-exporter/internal/exporter"
+	"wallet-exporter/internal/filaddr"
+)
+
+// csvPaymentsColumns are the columns written for every Payments token, prefixed with the token
+var csvPaymentsColumns = []string{"funds", "funds_raw", "available", "available_raw", "locked", "locked_raw", "funded_until_epoch", "lockup_rate_raw"}
+
+// writeWalletsCSV writes one row per wallet. Balances are written both in whole tokens and as
+// raw integers, so nothing is lost to floating point in spreadsheets.
+func writeWalletsCSV(out io.Writer, wallets []exporter.WalletInfo, network string, scrapedAt time.Time) error {
+	// Tokens in order of first appearance, USDFC first as every wallet lists it first
+	var tokens []string
+	seen := make(map[string]bool)
+	for _, wallet := range wallets {
+		for _, account := range wallet.PaymentsAccounts {
+			if !seen[account.Token] {
+				seen[account.Token] = true
+				tokens = append(tokens, account.Token)
+			}
+		}
+	}
+
+	header := []string{
+		"scraped_at", "address", "fil_address", "name", "type", "provider_id", "is_active", "is_approved",
+		"fil_balance", "fil_balance_raw", "usdfc_balance", "usdfc_balance_raw",
+		"payee", "payee_fil_balance_raw", "payee_usdfc_balance_raw",
+	}
+	for _, token := range tokens {
+		for _, column := range csvPaymentsColumns {
+			header = append(header, "payments_"+token+"_"+column)
+		}
+	}
+
+	w := csv.NewWriter(out)
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	for _, wallet := range wallets {
+		providerID, isActive, isApproved, payee := "", "", "", ""
+		if wallet.Type == "provider" {
+			providerID = strconv.FormatUint(wallet.ProviderID, 10)
+			isActive = strconv.FormatBool(wallet.IsActive)
+			isApproved = strconv.FormatBool(wallet.IsApproved)
+			payee = wallet.Payee.Hex()
+		}
+		payeeFIL, payeeUSDFC := "", ""
+		if wallet.PayeeFILBalance != nil {
+			payeeFIL = wallet.PayeeFILBalance.String()
+		}
+		if wallet.PayeeUSDFCBalance != nil {
+			payeeUSDFC = wallet.PayeeUSDFCBalance.String()
+		}
+
+		row := []string{
+			scrapedAt.UTC().Format(time.RFC3339),
+			wallet.Address.Hex(),
+			filaddr.FromEth(wallet.Address, network),
+			wallet.Name,
+			wallet.Type,
+			providerID,
+			isActive,
+			isApproved,
+			exporter.FormatUnits(wallet.FILBalance, 18),
+			intString(wallet.FILBalance),
+			exporter.FormatUnits(wallet.USDFCBalance, 18),
+			intString(wallet.USDFCBalance),
+			payee,
+			payeeFIL,
+			payeeUSDFC,
+		}
+
+		accounts := make(map[string]exporter.PaymentsAccount)
+		for _, account := range wallet.PaymentsAccounts {
+			if account.PaymentsInfo != nil {
+				accounts[account.Token] = account
+			}
+		}
+		for _, token := range tokens {
+			account, ok := accounts[token]
+			if !ok {
+				row = append(row, make([]string, len(csvPaymentsColumns))...)
+				continue
+			}
+			row = append(row,
+				exporter.FormatUnits(account.Funds, account.Decimals), intString(account.Funds),
+				exporter.FormatUnits(account.Available, account.Decimals), intString(account.Available),
+				exporter.FormatUnits(account.Locked, account.Decimals), intString(account.Locked),
+				intString(account.FundedUntilEpoch), intString(account.LockupRate),
+			)
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// exportCSV serves GET /api/v1/export.csv with the wallets of the last scrape
+func (a *walletAPI) exportCSV(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	scrapedAt := a.exp.GetLastScrape()
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="wallets-%s.csv"`, scrapedAt.UTC().Format("2006-01-02")))
+	if err := writeWalletsCSV(w, a.exp.GetWallets(), a.cfg.Network, scrapedAt); err != nil {
+		a.logger.Error("Failed to write CSV export", "error", err)
+	}
+}
+
+func runExportCSV(args []string) int {
+	flags := flag.NewFlagSet("export-csv", flag.ExitOnError)
+	out := flags.String("out", "", "write the CSV to this file instead of stdout")
+	timeout := flags.Duration("timeout", 5*time.Minute, "timeout for the scrape")
+	flags.Parse(args)
+
+	cfg, exp, err := newCommandExporter()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	defer exp.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	status, err := exp.ScrapeOnce(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Scrape failed: %v\n", err)
+		return 1
+	}
+	wallets := exp.GetWallets()
+	if status.LastErrors > 0 {
+		fmt.Fprintf(os.Stderr, "⚠️  Scrape had %d errors, some balances may be missing\n", status.LastErrors)
+	}
+
+	if *out == "" {
+		if err := writeWalletsCSV(os.Stdout, wallets, cfg.Network, status.LastScrape); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Failed to write CSV: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	file, err := os.Create(*out)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to create %s: %v\n", *out, err)
+		return 1
+	}
+	if err := writeWalletsCSV(file, wallets, cfg.Network, status.LastScrape); err != nil {
+		file.Close()
+		fmt.Fprintf(os.Stderr, "❌ Failed to write CSV: %v\n", err)
+		return 1
+	}
+	if err := file.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to write CSV: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(os.Stderr, "✓ Wrote %d wallets to %s\n", len(wallets), *out)
+	return 0
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"wallet-exporter/internal/config"
+	"wallet-exporter/internal/exporter"
+)
+
+// dashboardWallet is a wallet with the ping results of its provider
+type dashboardWallet struct {
+	exporter.WalletInfo
+	Pings []exporter.PingResult
+}
+
+// dashboardColumn renders one wallet table column of the status page
+type dashboardColumn struct {
+	header string
+	value  func(w dashboardWallet) string
+}
+
+// dashboardRow is a rendered wallet table row
+type dashboardRow struct {
+	Link  string // Wallet detail page
+	Type  string
+	Low   bool // Below its FIL or USDFC minimum
+	Cells []string
+}
+
+var dashboardColumns = map[string]dashboardColumn{
+	"name":    {"Name", func(w dashboardWallet) string { return w.Name }},
+	"address": {"Address", func(w dashboardWallet) string { return w.Address.Hex() }},
+	"type":    {"Type", func(w dashboardWallet) string { return w.Type }},
+	"provider_id": {"Provider ID", func(w dashboardWallet) string {
+		if w.Type != "provider" {
+			return ""
+		}
+		return fmt.Sprintf("%d", w.ProviderID)
+	}},
+	"active": {"Active", func(w dashboardWallet) string {
+		if w.Type != "provider" {
+			return ""
+		}
+		return fmt.Sprintf("%t", w.IsActive)
+	}},
+	"approved": {"Approved", func(w dashboardWallet) string {
+		if w.Type != "provider" {
+			return ""
+		}
+		return fmt.Sprintf("%t", w.IsApproved)
+	}},
+	"fil":   {"FIL", func(w dashboardWallet) string { return fmt.Sprintf("%.6f", toFloat(w.FILBalance)) }},
+	"usdfc": {"USDFC", func(w dashboardWallet) string { return fmt.Sprintf("%.6f", toFloat(w.USDFCBalance)) }},
+	"payments": {"Payments (USDFC)", func(w dashboardWallet) string {
+		if w.PaymentsFunds == nil {
+			return ""
+		}
+		return fmt.Sprintf("%.6f", toFloat(w.PaymentsFunds))
+	}},
+	"funded_until": {"Funded Until (epoch)", func(w dashboardWallet) string {
+		if w.PaymentsFundedUntil == nil {
+			return ""
+		}
+		return w.PaymentsFundedUntil.String()
+	}},
+	"ping": {"Ping", func(w dashboardWallet) string {
+		if len(w.Pings) == 0 {
+			return ""
+		}
+		failed := 0
+		for _, ping := range w.Pings {
+			if !ping.Success {
+				failed++
+			}
+		}
+		if failed == 0 {
+			return "ok"
+		}
+		return fmt.Sprintf("%d/%d failing", failed, len(w.Pings))
+	}},
+}
+
+// belowMinimum reports whether a wallet holds less FIL or USDFC than its configured minimum
+func belowMinimum(w exporter.WalletInfo) bool {
+	return (w.MinFIL > 0 && toFloat(w.FILBalance) < w.MinFIL) ||
+		(w.MinUSDFC > 0 && toFloat(w.USDFCBalance) < w.MinUSDFC)
+}
+
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html>
+<head>
+    <title>{{.Title}}</title>
+    {{- if .Refresh}}
+    <meta http-equiv="refresh" content="{{.Refresh}}">
+    {{- end}}
+    <style>
+        body { font-family: Arial, sans-serif; margin: 40px; }
+        body.light { background: #fff; color: #333; }
+        body.dark { background: #1e1e1e; color: #ddd; }
+        h1 { display: flex; align-items: center; gap: 16px; }
+        h1 img { max-height: 48px; }
+        a { color: #0066cc; text-decoration: none; margin-right: 20px; }
+        body.dark a { color: #66aaff; }
+        a:hover { text-decoration: underline; }
+        table { border-collapse: collapse; margin-top: 16px; }
+        th, td { padding: 4px 12px; text-align: left; border-bottom: 1px solid #8884; }
+        th { cursor: pointer; user-select: none; }
+        th.asc::after { content: " \25B2"; }
+        th.desc::after { content: " \25BC"; }
+        tr.low { background: #f8d7da; }
+        body.dark tr.low { background: #5c2b2f; }
+    </style>
+</head>
+<body class="{{.Theme}}">
+    <h1>{{if .LogoURL}}<img src="{{.LogoURL}}" alt="">{{end}}{{.Title}}</h1>
+    <p>Prometheus exporter for Synapse storage provider wallet balances</p>
+    <div>
+        <a href="/metrics">Metrics</a>
+        <a href="/status">Status</a>
+        <a href="/health">Health</a>
+    </div>
+    <p>Network: {{.Network}} &middot; Wallets monitored: {{len .Rows}} &middot; Last scrape: {{.LastScrape}}{{if .Restored}} (restored from the state file, refreshing){{end}}</p>
+    <label>Type:
+        <select id="type-filter">
+            <option value="">All</option>
+            {{- range .Types}}
+            <option value="{{.}}">{{.}}</option>
+            {{- end}}
+        </select>
+    </label>
+    <table id="wallets">
+        <thead><tr>{{range .Headers}}<th>{{.}}</th>{{end}}</tr></thead>
+        <tbody>
+        {{- range .Rows}}
+        <tr data-type="{{.Type}}"{{if .Low}} class="low"{{end}}>{{$link := .Link}}{{range $i, $cell := .Cells}}<td>{{if eq $i 0}}<a href="{{$link}}">{{$cell}}</a>{{else}}{{$cell}}{{end}}</td>{{end}}</tr>
+        {{- end}}
+        </tbody>
+    </table>
+    <script>
+        const table = document.getElementById("wallets");
+        const body = table.tBodies[0];
+
+        document.getElementById("type-filter").addEventListener("change", (event) => {
+            for (const row of body.rows) {
+                row.hidden = event.target.value !== "" && row.dataset.type !== event.target.value;
+            }
+        });
+
+        table.tHead.rows[0].querySelectorAll("th").forEach((th, column) => {
+            th.addEventListener("click", () => {
+                const ascending = !th.classList.contains("asc");
+                table.tHead.querySelectorAll("th").forEach((other) => other.classList.remove("asc", "desc"));
+                th.classList.add(ascending ? "asc" : "desc");
+
+                const value = (row) => row.cells[column].textContent;
+                const rows = Array.from(body.rows).sort((a, b) => {
+                    const x = value(a), y = value(b);
+                    const order = (x !== "" && y !== "" && !isNaN(x) && !isNaN(y))
+                        ? Number(x) - Number(y)
+                        : x.localeCompare(y);
+                    return ascending ? order : -order;
+                });
+                body.append(...rows);
+            });
+        });
+    </script>
+</body>
+</html>
+`))
+
+// dashboardHandler serves a read-only HTML wallet overview, branded and laid out per STATUS_* settings
+func dashboardHandler(cfg *config.Config, exp *exporter.WalletExporter, logger *slog.Logger) http.HandlerFunc {
+	columns := make([]dashboardColumn, 0, len(cfg.StatusColumns))
+	headers := make([]string, 0, len(cfg.StatusColumns))
+	for _, name := range cfg.StatusColumns {
+		columns = append(columns, dashboardColumns[name])
+		headers = append(headers, dashboardColumns[name].header)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		wallets := exp.GetWallets()
+		pings := exp.GetPingResults()
+
+		rows := make([]dashboardRow, 0, len(wallets))
+		var types []string
+		for _, wallet := range wallets {
+			dw := dashboardWallet{WalletInfo: wallet}
+			if wallet.Type == "provider" {
+				dw.Pings = pings[wallet.ProviderID]
+			}
+
+			row := dashboardRow{Link: "/wallets/" + wallet.Address.Hex(), Type: wallet.Type, Low: belowMinimum(wallet), Cells: make([]string, 0, len(columns))}
+			for _, column := range columns {
+				row.Cells = append(row.Cells, column.value(dw))
+			}
+			rows = append(rows, row)
+
+			if !slices.Contains(types, wallet.Type) {
+				types = append(types, wallet.Type)
+			}
+		}
+
+		lastScrape := "never"
+		if t := exp.GetLastScrape(); !t.IsZero() {
+			lastScrape = t.Format(time.RFC3339)
+		}
+
+		w.Header().Set("Content-Type", "text/html")
+		err := dashboardTemplate.Execute(w, map[string]any{
+			"Title":      cfg.StatusTitle,
+			"LogoURL":    cfg.StatusLogoURL,
+			"Refresh":    cfg.StatusRefreshSeconds,
+			"Theme":      cfg.StatusTheme,
+			"Network":    cfg.Network,
+			"LastScrape": lastScrape,
+			"Restored":   exp.GetScrapeStatus().Restored,
+			"Types":      types,
+			"Headers":    headers,
+			"Rows":       rows,
+		})
+		if err != nil {
+			logger.Error("Failed to render status page", "error", err)
+		}
+	}
+}
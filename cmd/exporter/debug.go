@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"time"
+)
+
+// newDebugServer serves the runtime profiles of net/http/pprof on DEBUG_PORT. They live on
+// their own port so they can be kept off the network the metrics are scraped from.
+func newDebugServer(port int) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	return &http.Server{
+		Addr:        fmt.Sprintf(":%d", port),
+		Handler:     mux,
+		ReadTimeout: 10 * time.Second,
+		// No write timeout: CPU profiles and traces stream for as long as requested
+	}
+}
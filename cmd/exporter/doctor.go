@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"wallet-exporter/internal/config"
+	"wallet-exporter/internal/exporter"
+)
+
+func runDoctor(args []string) int {
+	flags := flag.NewFlagSet("doctor", flag.ExitOnError)
+	timeout := flags.Duration("timeout", 2*time.Minute, "timeout for all checks")
+	flags.Parse(args)
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Configuration: %v\n", err)
+		fmt.Fprintf(os.Stderr, "   → Fix the variable named above; the Configuration section of the README lists them all\n")
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "✓ Configuration: network %s, RPC %s\n", cfg.Network, cfg.RPCURL)
+
+	// Warnings of failed pings would repeat the check results; keep them for LOG_LEVEL=debug
+	level := "error"
+	if cfg.LogLevel == "debug" {
+		level = "debug"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	failed := 0
+	for _, check := range exporter.Diagnose(ctx, cfg, newLogger(level, os.Stderr)) {
+		switch {
+		case check.Skipped:
+			fmt.Fprintf(os.Stderr, "- %s: skipped\n", check.Name)
+		case check.OK:
+			fmt.Fprintf(os.Stderr, "✓ %s: %s (%s)\n", check.Name, check.Detail, check.Duration.Round(time.Millisecond))
+		default:
+			failed++
+			fmt.Fprintf(os.Stderr, "❌ %s: %s\n", check.Name, check.Detail)
+			if check.Hint != "" {
+				fmt.Fprintf(os.Stderr, "   → %s\n", check.Hint)
+			}
+		}
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "\n%d checks failed\n", failed)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "\nAll checks passed\n")
+	return 0
+}
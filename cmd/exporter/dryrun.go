@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"wallet-exporter/internal/config"
+)
+
+// runDryRun connects to the RPC endpoint, resolves the contracts and scrapes once without
+// serving or pushing anything, then logs what would have been exported. It exits non-zero if
+// the scrape failed or had errors, to vet new contract addresses or RPC endpoints.
+func runDryRun(args []string) int {
+	flags := flag.NewFlagSet("dry-run", flag.ExitOnError)
+	timeout := flags.Duration("timeout", 5*time.Minute, "timeout for the scrape")
+	flags.Parse(args)
+
+	cfg, logger, err := loadCommandConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	outputs := configuredOutputs(cfg)
+
+	exp, err := commandExporter(cfg, logger)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	defer exp.Close()
+
+	logger.Info("Dry run: connected", "network", cfg.Network, "rpc_url", cfg.RPCURL)
+	for _, contract := range exp.Contracts() {
+		logger.Info("Dry run: contract resolved", "contract", contract.Name, "address", contract.Address.Hex())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	status, err := exp.ScrapeOnce(ctx)
+	if err != nil {
+		logger.Error("Dry run: scrape failed", "error", err)
+		return 1
+	}
+
+	wallets := exp.GetWallets()
+	types := make(map[string]int)
+	providers, active, approved := 0, 0, 0
+	for _, wallet := range wallets {
+		types[wallet.Type]++
+		if wallet.Type == "provider" {
+			providers++
+			if wallet.IsActive {
+				active++
+			}
+			if wallet.IsApproved {
+				approved++
+			}
+		}
+	}
+	pingsOK, pingsFailed := 0, 0
+	for _, results := range exp.GetPingResults() {
+		for _, result := range results {
+			if result.Success {
+				pingsOK++
+			} else {
+				pingsFailed++
+			}
+		}
+	}
+	logger.Info("Dry run: scrape completed",
+		"duration", status.LastDuration.Round(time.Millisecond),
+		"errors", status.LastErrors,
+		"wallets", len(wallets),
+		"by_type", formatCounts(types),
+		"providers", providers,
+		"active", active,
+		"approved", approved,
+		"pings_ok", pingsOK,
+		"pings_failed", pingsFailed,
+	)
+
+	families, err := exp.GetRegistry().Gather()
+	if err != nil {
+		logger.Error("Dry run: failed to gather metrics", "error", err)
+		return 1
+	}
+	series := 0
+	for _, family := range families {
+		series += len(family.GetMetric())
+	}
+	logger.Info("Dry run: metrics that would be exported", "families", len(families), "series", series)
+	for _, output := range outputs {
+		logger.Info("Dry run: would export to", "output", output)
+	}
+
+	if status.LastErrors > 0 {
+		logger.Error("Dry run: scrape had errors, check the warnings above", "errors", status.LastErrors)
+		return 1
+	}
+	return 0
+}
+
+// configuredOutputs describes where the exporter sends its metrics and state
+func configuredOutputs(cfg *config.Config) []string {
+	var outputs []string
+	if cfg.ExporterPort > 0 && cfg.MetricsEndpoint {
+		outputs = append(outputs, fmt.Sprintf("/metrics on port %d", cfg.ExporterPort))
+	}
+	if cfg.RemoteWriteURL != "" {
+		outputs = append(outputs, "remote write "+cfg.RemoteWriteURL)
+	}
+	if cfg.OTLPEndpoint != "" {
+		outputs = append(outputs, "OTLP "+cfg.OTLPEndpoint)
+	}
+	if cfg.GraphiteAddress != "" {
+		outputs = append(outputs, "Graphite "+cfg.GraphiteAddress)
+	}
+	if cfg.StatsDAddress != "" {
+		outputs = append(outputs, "StatsD "+cfg.StatsDAddress)
+	}
+	if cfg.TextfilePath != "" {
+		outputs = append(outputs, "textfile "+cfg.TextfilePath)
+	}
+	if cfg.HistoryPath != "" {
+		outputs = append(outputs, "history "+cfg.HistoryPath)
+	}
+	if cfg.StateFile != "" {
+		outputs = append(outputs, "state file "+cfg.StateFile)
+	}
+	return outputs
+}
+
+// formatCounts renders counts by key as "a=1,b=2" in key order
+func formatCounts(counts map[string]int) string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%d", key, counts[key]))
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// targetGroup is one entry of a Prometheus file_sd JSON file
+type targetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+func runGenTargets(args []string) int {
+	flags := flag.NewFlagSet("gen-targets", flag.ExitOnError)
+	out := flags.String("out", "", "write targets to this file instead of stdout")
+	exporterAddr := flags.String("exporter-address", "", "address Prometheus uses to reach the exporter (default localhost:EXPORTER_PORT)")
+	activeOnly := flags.Bool("active-only", false, "only include active providers in probe targets")
+	timeout := flags.Duration("timeout", 2*time.Minute, "timeout for reading the registry")
+	flags.Parse(args)
+
+	cfg, exp, err := newCommandExporter()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	defer exp.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	providers, err := exp.ListProviders(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to list providers: %v\n", err)
+		return 1
+	}
+
+	if *exporterAddr == "" {
+		*exporterAddr = fmt.Sprintf("localhost:%d", cfg.ExporterPort)
+	}
+
+	groups := []targetGroup{
+		{
+			Targets: []string{*exporterAddr},
+			Labels: map[string]string{
+				"job":     "wallet-exporter",
+				"network": cfg.Network,
+			},
+		},
+	}
+
+	for _, p := range providers {
+		if p.ServiceURL == "" || (*activeOnly && !p.IsActive) {
+			continue
+		}
+
+		groups = append(groups, targetGroup{
+			Targets: []string{strings.TrimRight(p.ServiceURL, "/") + "/pdp/ping"},
+			Labels: map[string]string{
+				"job":           "provider-probe",
+				"network":       cfg.Network,
+				"provider_id":   fmt.Sprintf("%d", p.ProviderID),
+				"provider_name": p.Name,
+				"address":       p.Address.Hex(),
+				"is_active":     fmt.Sprintf("%t", p.IsActive),
+				"approved":      fmt.Sprintf("%t", p.IsApproved),
+			},
+		})
+	}
+
+	data, err := json.MarshalIndent(groups, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to encode targets: %v\n", err)
+		return 1
+	}
+	data = append(data, '\n')
+
+	if *out == "" {
+		os.Stdout.Write(data)
+		return 0
+	}
+
+	// Write atomically so Prometheus never reads a partial file
+	tmp := *out + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to write targets: %v\n", err)
+		return 1
+	}
+	if err := os.Rename(tmp, *out); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to write targets: %v\n", err)
+		return 1
+	}
+
+	fmt.Fprintf(os.Stderr, "✓ Wrote %d target groups to %s\n", len(groups), *out)
+	return 0
+}
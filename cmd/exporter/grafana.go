@@ -0,0 +1,358 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// networkGenesis is the Unix time of epoch 0 of each network, used to turn epochs into
+// wall-clock time in PromQL without depending on an optional block number metric
+var networkGenesis = map[string]int64{
+	"calibration": 1667326380,
+	"mainnet":     1598306400,
+}
+
+// fundedDaysExpr returns a PromQL expression for the days until each Payments account runs
+// out, from its funded-until epoch and the current epoch derived from the genesis time
+func fundedDaysExpr(prefix, network, selector string) string {
+	return fmt.Sprintf("(%s_wallet_payments_funded_until_epoch%s - (time() - %d) / 30) * 30 / 86400",
+		prefix, selector, networkGenesis[network])
+}
+
+// grafanaDashboard is the subset of the Grafana dashboard model the generator fills in
+type grafanaDashboard struct {
+	UID           string            `json:"uid"`
+	Title         string            `json:"title"`
+	Tags          []string          `json:"tags"`
+	Timezone      string            `json:"timezone"`
+	SchemaVersion int               `json:"schemaVersion"`
+	Refresh       string            `json:"refresh"`
+	Time          grafanaTimeRange  `json:"time"`
+	Templating    grafanaTemplating `json:"templating"`
+	Panels        []grafanaPanel    `json:"panels"`
+}
+
+type grafanaTimeRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type grafanaTemplating struct {
+	List []grafanaVariable `json:"list"`
+}
+
+type grafanaVariable struct {
+	Name       string             `json:"name"`
+	Label      string             `json:"label"`
+	Type       string             `json:"type"`
+	Query      any                `json:"query"`
+	Datasource *grafanaDatasource `json:"datasource,omitempty"`
+	Refresh    int                `json:"refresh,omitempty"`
+	IncludeAll bool               `json:"includeAll,omitempty"`
+	Multi      bool               `json:"multi,omitempty"`
+	AllValue   string             `json:"allValue,omitempty"`
+	Current    map[string]any     `json:"current,omitempty"`
+}
+
+type grafanaDatasource struct {
+	Type string `json:"type"`
+	UID  string `json:"uid"`
+}
+
+type grafanaPanel struct {
+	ID          int                `json:"id"`
+	Type        string             `json:"type"`
+	Title       string             `json:"title"`
+	Description string             `json:"description,omitempty"`
+	GridPos     grafanaGridPos     `json:"gridPos"`
+	Datasource  *grafanaDatasource `json:"datasource,omitempty"`
+	Targets     []grafanaTarget    `json:"targets,omitempty"`
+	FieldConfig map[string]any     `json:"fieldConfig,omitempty"`
+	Options     map[string]any     `json:"options,omitempty"`
+	Collapsed   bool               `json:"collapsed,omitempty"`
+	Panels      []grafanaPanel     `json:"panels,omitempty"`
+}
+
+type grafanaGridPos struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+type grafanaTarget struct {
+	RefID        string `json:"refId"`
+	Expr         string `json:"expr"`
+	LegendFormat string `json:"legendFormat,omitempty"`
+	Instant      bool   `json:"instant,omitempty"`
+	Format       string `json:"format,omitempty"`
+}
+
+// dashboardLayout places panels left to right on Grafana's 24 column grid, wrapping to a new
+// line when a panel does not fit
+type dashboardLayout struct {
+	panels     []grafanaPanel
+	x, y, rowH int
+}
+
+func (l *dashboardLayout) row(title string) {
+	l.newLine()
+	l.panels = append(l.panels, grafanaPanel{ID: len(l.panels) + 1, Type: "row", Title: title, GridPos: grafanaGridPos{Y: l.y, W: 24, H: 1}})
+	l.y++
+}
+
+func (l *dashboardLayout) add(panel grafanaPanel, w, h int) {
+	if l.x+w > 24 {
+		l.newLine()
+	}
+	panel.ID = len(l.panels) + 1
+	panel.GridPos = grafanaGridPos{X: l.x, Y: l.y, W: w, H: h}
+	panel.Datasource = &grafanaDatasource{Type: "prometheus", UID: "${datasource}"}
+	for i := range panel.Targets {
+		panel.Targets[i].RefID = string(rune('A' + i))
+	}
+	l.panels = append(l.panels, panel)
+	l.x += w
+	l.rowH = max(l.rowH, h)
+}
+
+func (l *dashboardLayout) newLine() {
+	if l.x > 0 {
+		l.y += l.rowH
+	}
+	l.x, l.rowH = 0, 0
+}
+
+// unitConfig sets the unit of a panel's values, and thresholds coloring them when given as
+// value/color steps above green
+func unitConfig(unit string, steps ...any) map[string]any {
+	return thresholdConfig(unit, "green", steps...)
+}
+
+// thresholdConfig is unitConfig with values below the first step colored base
+func thresholdConfig(unit, base string, steps ...any) map[string]any {
+	thresholds := []map[string]any{{"color": base, "value": nil}}
+	for i := 0; i+1 < len(steps); i += 2 {
+		thresholds = append(thresholds, map[string]any{"value": steps[i], "color": steps[i+1]})
+	}
+	return map[string]any{
+		"defaults": map[string]any{
+			"unit":       unit,
+			"thresholds": map[string]any{"mode": "absolute", "steps": thresholds},
+		},
+		"overrides": []any{},
+	}
+}
+
+// generateDashboard builds the dashboard for metrics exported with prefix on network
+func generateDashboard(prefix, network, title, uid string) grafanaDashboard {
+	wallet := `{type=~"$type",name=~"$name"}`
+	ping := `{name=~"$name"}`
+
+	var l dashboardLayout
+
+	l.row("Balances")
+	l.add(grafanaPanel{
+		Type: "stat", Title: "Total FIL",
+		Targets:     []grafanaTarget{{Expr: fmt.Sprintf(`sum(%s_wallet_fil_balance%s)`, prefix, wallet), Instant: true}},
+		FieldConfig: unitConfig("none"),
+	}, 6, 4)
+	l.add(grafanaPanel{
+		Type: "stat", Title: "Total USDFC",
+		Targets:     []grafanaTarget{{Expr: fmt.Sprintf(`sum(%s_wallet_usdfc_balance%s)`, prefix, wallet), Instant: true}},
+		FieldConfig: unitConfig("none"),
+	}, 6, 4)
+	l.add(grafanaPanel{
+		Type: "stat", Title: "Wallets below threshold",
+		Description: "Wallets whose FIL or USDFC balance is below its configured minimum",
+		Targets:     []grafanaTarget{{Expr: fmt.Sprintf(`count(%s_wallet_below_threshold%s == 1) or vector(0)`, prefix, wallet), Instant: true}},
+		FieldConfig: unitConfig("none", 1, "red"),
+	}, 6, 4)
+	l.add(grafanaPanel{
+		Type: "stat", Title: "Active approved providers",
+		Targets:     []grafanaTarget{{Expr: fmt.Sprintf(`count(%s_wallet_info{type="provider",is_active="true",approved="true"}) or vector(0)`, prefix), Instant: true}},
+		FieldConfig: unitConfig("none"),
+	}, 6, 4)
+	l.add(grafanaPanel{
+		Type: "timeseries", Title: "FIL balance",
+		Targets:     []grafanaTarget{{Expr: fmt.Sprintf(`sum by (name, type) (%s_wallet_fil_balance%s)`, prefix, wallet), LegendFormat: "{{name}} ({{type}})"}},
+		FieldConfig: unitConfig("none"),
+	}, 12, 8)
+	l.add(grafanaPanel{
+		Type: "timeseries", Title: "USDFC balance",
+		Targets:     []grafanaTarget{{Expr: fmt.Sprintf(`sum by (name, type) (%s_wallet_usdfc_balance%s)`, prefix, wallet), LegendFormat: "{{name}} ({{type}})"}},
+		FieldConfig: unitConfig("none"),
+	}, 12, 8)
+	l.add(grafanaPanel{
+		Type: "table", Title: "Wallets below threshold",
+		Targets: []grafanaTarget{{
+			Expr:    fmt.Sprintf(`%s_wallet_below_threshold%s == 1`, prefix, wallet),
+			Instant: true, Format: "table",
+		}},
+		Options: map[string]any{"showHeader": true},
+	}, 24, 6)
+
+	l.row("Payments runway")
+	l.add(grafanaPanel{
+		Type: "bargauge", Title: "Payments funded for",
+		Description: "Days until each Payments account runs out at its current lockup rate",
+		Targets: []grafanaTarget{{
+			Expr:         fmt.Sprintf(`sort(min by (name, token) (%s and %s_wallet_payments_lockup_rate%s > 0))`, fundedDaysExpr(prefix, network, wallet), prefix, wallet),
+			LegendFormat: "{{name}} {{token}}", Instant: true,
+		}},
+		FieldConfig: thresholdConfig("d", "red", 3, "orange", 14, "green"),
+		Options:     map[string]any{"orientation": "horizontal", "displayMode": "gradient"},
+	}, 12, 10)
+	l.add(grafanaPanel{
+		Type: "timeseries", Title: "Balance runway",
+		Description: "Projected days until the FIL and USDFC balances run out at the smoothed spend rate",
+		Targets: []grafanaTarget{
+			{Expr: fmt.Sprintf(`%s_wallet_fil_runway_days%s < +Inf`, prefix, wallet), LegendFormat: "{{name}} FIL"},
+			{Expr: fmt.Sprintf(`%s_wallet_usdfc_runway_days%s < +Inf`, prefix, wallet), LegendFormat: "{{name}} USDFC"},
+		},
+		FieldConfig: unitConfig("d"),
+	}, 12, 10)
+	l.add(grafanaPanel{
+		Type: "timeseries", Title: "Payments available",
+		Targets:     []grafanaTarget{{Expr: fmt.Sprintf(`sum by (name, token) (%s_wallet_payments_available%s)`, prefix, wallet), LegendFormat: "{{name}} {{token}}"}},
+		FieldConfig: unitConfig("none"),
+	}, 12, 8)
+	l.add(grafanaPanel{
+		Type: "timeseries", Title: "Payments locked",
+		Targets:     []grafanaTarget{{Expr: fmt.Sprintf(`sum by (name, token) (%s_wallet_payments_locked%s)`, prefix, wallet), LegendFormat: "{{name}} {{token}}"}},
+		FieldConfig: unitConfig("none"),
+	}, 12, 8)
+
+	l.row("Provider pings")
+	l.add(grafanaPanel{
+		Type: "bargauge", Title: "Ping availability",
+		Description: "Share of successful pings over the dashboard time range",
+		Targets: []grafanaTarget{{
+			Expr:         fmt.Sprintf(`sort(avg by (name, product_type) (avg_over_time(%s_provider_ping_success%s[$__range])))`, prefix, ping),
+			LegendFormat: "{{name}} ({{product_type}})", Instant: true,
+		}},
+		FieldConfig: map[string]any{"defaults": map[string]any{
+			"unit": "percentunit", "min": 0, "max": 1,
+			"thresholds": map[string]any{"mode": "absolute", "steps": []map[string]any{
+				{"color": "red", "value": nil}, {"color": "orange", "value": 0.9}, {"color": "green", "value": 0.99},
+			}},
+		}, "overrides": []any{}},
+		Options: map[string]any{"orientation": "horizontal", "displayMode": "gradient"},
+	}, 12, 10)
+	l.add(grafanaPanel{
+		Type: "timeseries", Title: "Ping latency",
+		Targets:     []grafanaTarget{{Expr: fmt.Sprintf(`%s_provider_ping_ms%s`, prefix, ping), LegendFormat: "{{name}} ({{product_type}})"}},
+		FieldConfig: unitConfig("ms"),
+	}, 12, 10)
+
+	l.row("Scrape health")
+	l.add(grafanaPanel{
+		Type: "stat", Title: "Exporter up",
+		Targets:     []grafanaTarget{{Expr: `min(up{job="$job"})`, Instant: true}},
+		FieldConfig: thresholdConfig("bool_yes_no", "red", 1, "green"),
+	}, 6, 4)
+	l.add(grafanaPanel{
+		Type: "stat", Title: "RPC circuit open",
+		Description: "Scrapes are skipped while the RPC endpoint keeps failing",
+		Targets:     []grafanaTarget{{Expr: fmt.Sprintf(`max(%s_rpc_circuit_open)`, prefix), Instant: true}},
+		FieldConfig: unitConfig("bool_yes_no", 1, "red"),
+	}, 6, 4)
+	l.add(grafanaPanel{
+		Type: "stat", Title: "Provider coverage",
+		Description: "Fraction of registry providers refreshed in the last scrape",
+		Targets:     []grafanaTarget{{Expr: fmt.Sprintf(`min(%s_provider_scrape_coverage_ratio)`, prefix), Instant: true}},
+		FieldConfig: unitConfig("percentunit"),
+	}, 6, 4)
+	l.add(grafanaPanel{
+		Type: "stat", Title: "Scrape errors (1h)",
+		Targets:     []grafanaTarget{{Expr: fmt.Sprintf(`sum(increase(%s_scrape_errors_total[1h]))`, prefix), Instant: true}},
+		FieldConfig: unitConfig("none", 1, "orange"),
+	}, 6, 4)
+	l.add(grafanaPanel{
+		Type: "timeseries", Title: "Scrape duration",
+		Targets:     []grafanaTarget{{Expr: fmt.Sprintf(`%s_scrape_duration_seconds`, prefix), LegendFormat: "{{instance}}"}},
+		FieldConfig: unitConfig("s"),
+	}, 12, 8)
+	l.add(grafanaPanel{
+		Type: "timeseries", Title: "Scrape errors",
+		Targets:     []grafanaTarget{{Expr: fmt.Sprintf(`sum by (instance) (rate(%s_scrape_errors_total[$__rate_interval]))`, prefix), LegendFormat: "{{instance}}"}},
+		FieldConfig: unitConfig("short"),
+	}, 12, 8)
+
+	datasource := &grafanaDatasource{Type: "prometheus", UID: "${datasource}"}
+	labelVariable := func(name, label, query string) grafanaVariable {
+		return grafanaVariable{
+			Name: name, Label: label, Type: "query", Datasource: datasource, Refresh: 2,
+			Query:      map[string]any{"query": query, "refId": "PrometheusVariableQueryEditor-VariableQuery"},
+			IncludeAll: true, Multi: true, AllValue: ".*",
+			Current: map[string]any{"text": "All", "value": "$__all"},
+		}
+	}
+
+	return grafanaDashboard{
+		UID:           uid,
+		Title:         title,
+		Tags:          []string{"wallet-exporter", "filecoin", network},
+		Timezone:      "browser",
+		SchemaVersion: 39,
+		Refresh:       "1m",
+		Time:          grafanaTimeRange{From: "now-7d", To: "now"},
+		Templating: grafanaTemplating{List: []grafanaVariable{
+			{Name: "datasource", Label: "Data source", Type: "datasource", Query: "prometheus"},
+			{
+				Name: "job", Label: "Job", Type: "query", Datasource: datasource, Refresh: 2,
+				Query: map[string]any{"query": fmt.Sprintf("label_values(%s_scrape_duration_seconds, job)", prefix), "refId": "PrometheusVariableQueryEditor-VariableQuery"},
+			},
+			labelVariable("type", "Wallet type", fmt.Sprintf("label_values(%s_wallet_info, type)", prefix)),
+			labelVariable("name", "Wallet", fmt.Sprintf(`label_values(%s_wallet_info{type=~"$type"}, name)`, prefix)),
+		}},
+		Panels: l.panels,
+	}
+}
+
+func runGenerateDashboard(args []string) int {
+	flags := flag.NewFlagSet("generate-dashboard", flag.ExitOnError)
+	out := flags.String("out", "", "write the dashboard to this file instead of stdout")
+	prefix := flags.String("prefix", "", "metrics prefix (default METRICS_PREFIX)")
+	network := flags.String("network", "", "network the funded-until epochs are converted for (default NETWORK)")
+	title := flags.String("title", "Filecoin Wallets", "dashboard title")
+	uid := flags.String("uid", "wallet-exporter", "dashboard UID, keep it stable to overwrite the dashboard on import")
+	flags.Parse(args)
+
+	cfg, _, err := loadCommandConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	if *prefix == "" {
+		*prefix = cfg.MetricsPrefix
+	}
+	if *network == "" {
+		*network = cfg.Network
+	}
+	if _, ok := networkGenesis[*network]; !ok {
+		fmt.Fprintf(os.Stderr, "❌ Unknown network %q, use calibration or mainnet\n", *network)
+		return 2
+	}
+
+	dashboard := generateDashboard(*prefix, *network, *title, *uid)
+	data, err := json.MarshalIndent(dashboard, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to encode dashboard: %v\n", err)
+		return 1
+	}
+	data = append(data, '\n')
+
+	if *out == "" {
+		os.Stdout.Write(data)
+		return 0
+	}
+	if err := os.WriteFile(*out, data, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to write dashboard: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "✓ Wrote dashboard with %d panels to %s\n", len(dashboard.Panels), *out)
+	return 0
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+
+	graphql "github.com/graph-gophers/graphql-go"
+
+	"wallet-exporter/internal/exporter"
+	"wallet-exporter/internal/filaddr"
+)
+
+const (
+	maxGraphQLRequestSize = 1 << 20
+	maxGraphQLDepth       = 8
+)
+
+// graphqlSchema describes the wallets of the last scrape. Like the JSON API, token amounts are
+// decimal strings in whole tokens, and epochs and IDs are strings.
+const graphqlSchema = `
+schema {
+	query: Query
+}
+
+type Query {
+	# Wallets of the last scrape, optionally filtered by type and provider state
+	wallets(type: [String!], approved: Boolean, active: Boolean): [Wallet!]!
+	# A wallet by its 0x or f410/t410 address; a wallet monitored under several types is returned once per type
+	wallet(address: String!): [Wallet!]!
+	provider(id: ID!): Wallet
+}
+
+type Wallet {
+	address: String!
+	filAddress: String!
+	name: String!
+	type: String!
+	providerId: ID
+	payee: String
+	isActive: Boolean!
+	isApproved: Boolean!
+	description: String
+	region: String
+	filBalance: String!
+	usdfcBalance: String!
+	payeeFilBalance: String
+	payeeUsdfcBalance: String
+	minFil: Float
+	minUsdfc: Float
+	paymentsAccounts: [PaymentsAccount!]!
+	products: [Product!]!
+	rails: [Rail!]!
+	dataSets: Int
+	pings: [Ping!]!
+}
+
+type PaymentsAccount {
+	token: String!
+	funds: String!
+	available: String!
+	locked: String!
+	fundedUntilEpoch: String!
+	lockupRate: String!
+}
+
+type Product {
+	type: Int!
+	isActive: Boolean!
+	capabilities: [Capability!]!
+}
+
+type Capability {
+	key: String!
+	value: String!
+}
+
+type Rail {
+	railId: ID!
+	role: String!
+	counterparty: String!
+	operator: String!
+	paymentRate: String!
+	lockupPeriod: String!
+	lockupFixed: String!
+	settledUpTo: String!
+	endEpoch: String!
+	isTerminated: Boolean!
+	pendingSettlement: String
+}
+
+type Ping {
+	productType: Int!
+	serviceUrl: String!
+	success: Boolean!
+	durationSeconds: Float!
+}
+`
+
+// graphqlSnapshotKey is the context key of the wallets a GraphQL request is resolved against
+type graphqlSnapshotKey struct{}
+
+// graphqlSnapshot holds the wallets of the last scrape, taken once per request so all fields
+// of a query see the same scrape
+type graphqlSnapshot struct {
+	wallets []exporter.WalletInfo
+	pings   map[uint64][]exporter.PingResult
+}
+
+// graphqlQuery resolves the Query type
+type graphqlQuery struct {
+	api *walletAPI
+}
+
+func (q *graphqlQuery) snapshot(ctx context.Context) *graphqlSnapshot {
+	return ctx.Value(graphqlSnapshotKey{}).(*graphqlSnapshot)
+}
+
+func (q *graphqlQuery) wallet(wallet exporter.WalletInfo, pings map[uint64][]exporter.PingResult) *walletResolver {
+	return &walletResolver{
+		detail:     q.api.detail(wallet, pings),
+		filAddress: filaddr.FromEth(wallet.Address, q.api.cfg.Network),
+	}
+}
+
+func (q *graphqlQuery) Wallets(ctx context.Context, args struct {
+	Type     *[]string
+	Approved *bool
+	Active   *bool
+}) []*walletResolver {
+	snapshot := q.snapshot(ctx)
+	wallets := []*walletResolver{}
+	for _, wallet := range snapshot.wallets {
+		if args.Type != nil && !slices.Contains(*args.Type, wallet.Type) {
+			continue
+		}
+		if args.Approved != nil && wallet.IsApproved != *args.Approved {
+			continue
+		}
+		if args.Active != nil && wallet.IsActive != *args.Active {
+			continue
+		}
+		wallets = append(wallets, q.wallet(wallet, snapshot.pings))
+	}
+	return wallets
+}
+
+func (q *graphqlQuery) Wallet(ctx context.Context, args struct{ Address string }) []*walletResolver {
+	snapshot := q.snapshot(ctx)
+	wallets := []*walletResolver{}
+	address, ok := parseWalletAddress(args.Address)
+	if !ok {
+		return wallets
+	}
+	for _, wallet := range snapshot.wallets {
+		if wallet.Address == address {
+			wallets = append(wallets, q.wallet(wallet, snapshot.pings))
+		}
+	}
+	return wallets
+}
+
+func (q *graphqlQuery) Provider(ctx context.Context, args struct{ ID graphql.ID }) *walletResolver {
+	id, err := strconv.ParseUint(string(args.ID), 10, 64)
+	if err != nil {
+		return nil
+	}
+	snapshot := q.snapshot(ctx)
+	for _, wallet := range snapshot.wallets {
+		if wallet.Type == "provider" && wallet.ProviderID == id {
+			return q.wallet(wallet, snapshot.pings)
+		}
+	}
+	return nil
+}
+
+// walletResolver resolves the Wallet type from the JSON form of a wallet
+type walletResolver struct {
+	detail     walletDetail
+	filAddress string
+}
+
+func (w *walletResolver) Address() string          { return w.detail.Address }
+func (w *walletResolver) FILAddress() string       { return w.filAddress }
+func (w *walletResolver) Name() string             { return w.detail.Name }
+func (w *walletResolver) Type() string             { return w.detail.Type }
+func (w *walletResolver) Payee() *string           { return optionalString(w.detail.Payee) }
+func (w *walletResolver) IsActive() bool           { return w.detail.IsActive }
+func (w *walletResolver) IsApproved() bool         { return w.detail.IsApproved }
+func (w *walletResolver) Description() *string     { return optionalString(w.detail.Description) }
+func (w *walletResolver) Region() *string          { return optionalString(w.detail.Region) }
+func (w *walletResolver) FILBalance() string       { return w.detail.FILBalance }
+func (w *walletResolver) USDFCBalance() string     { return w.detail.USDFCBalance }
+func (w *walletResolver) PayeeFILBalance() *string { return optionalString(w.detail.PayeeFILBalance) }
+func (w *walletResolver) MinFIL() *float64         { return optionalFloat(w.detail.MinFIL) }
+func (w *walletResolver) MinUSDFC() *float64       { return optionalFloat(w.detail.MinUSDFC) }
+
+func (w *walletResolver) PayeeUSDFCBalance() *string {
+	return optionalString(w.detail.PayeeUSDFCBalance)
+}
+
+func (w *walletResolver) ProviderID() *graphql.ID {
+	if w.detail.Type != "provider" {
+		return nil
+	}
+	id := graphql.ID(strconv.FormatUint(w.detail.ProviderID, 10))
+	return &id
+}
+
+func (w *walletResolver) PaymentsAccounts() []*paymentsResolver {
+	accounts := []*paymentsResolver{}
+	for _, account := range w.detail.PaymentsAccounts {
+		accounts = append(accounts, &paymentsResolver{account})
+	}
+	return accounts
+}
+
+func (w *walletResolver) Products() []*productResolver {
+	products := []*productResolver{}
+	for _, product := range w.detail.Products {
+		products = append(products, &productResolver{product})
+	}
+	return products
+}
+
+func (w *walletResolver) Rails() []*railResolver {
+	rails := []*railResolver{}
+	for _, rail := range w.detail.Rails {
+		rails = append(rails, &railResolver{rail})
+	}
+	return rails
+}
+
+func (w *walletResolver) DataSets() *int32 {
+	if w.detail.DataSets == nil {
+		return nil
+	}
+	dataSets := int32(*w.detail.DataSets)
+	return &dataSets
+}
+
+func (w *walletResolver) Pings() []*pingResolver {
+	pings := []*pingResolver{}
+	for _, ping := range w.detail.Pings {
+		pings = append(pings, &pingResolver{ping})
+	}
+	return pings
+}
+
+type paymentsResolver struct {
+	account paymentsStatus
+}
+
+func (p *paymentsResolver) Token() string            { return p.account.Token }
+func (p *paymentsResolver) Funds() string            { return p.account.Funds }
+func (p *paymentsResolver) Available() string        { return p.account.Available }
+func (p *paymentsResolver) Locked() string           { return p.account.Locked }
+func (p *paymentsResolver) FundedUntilEpoch() string { return p.account.FundedUntilEpoch }
+func (p *paymentsResolver) LockupRate() string       { return p.account.LockupRate }
+
+type productResolver struct {
+	product productStatus
+}
+
+func (p *productResolver) Type() int32    { return int32(p.product.Type) }
+func (p *productResolver) IsActive() bool { return p.product.IsActive }
+
+// Capabilities returns the capabilities sorted by key, as GraphQL has no map type
+func (p *productResolver) Capabilities() []*capabilityResolver {
+	capabilities := []*capabilityResolver{}
+	for key, value := range p.product.Capabilities {
+		capabilities = append(capabilities, &capabilityResolver{key: key, value: value})
+	}
+	sort.Slice(capabilities, func(i, j int) bool { return capabilities[i].key < capabilities[j].key })
+	return capabilities
+}
+
+type capabilityResolver struct {
+	key   string
+	value string
+}
+
+func (c *capabilityResolver) Key() string   { return c.key }
+func (c *capabilityResolver) Value() string { return c.value }
+
+type railResolver struct {
+	rail railStatus
+}
+
+func (r *railResolver) RailID() graphql.ID         { return graphql.ID(strconv.FormatUint(r.rail.RailID, 10)) }
+func (r *railResolver) Role() string               { return r.rail.Role }
+func (r *railResolver) Counterparty() string       { return r.rail.Counterparty }
+func (r *railResolver) Operator() string           { return r.rail.Operator }
+func (r *railResolver) PaymentRate() string        { return r.rail.PaymentRate }
+func (r *railResolver) LockupPeriod() string       { return r.rail.LockupPeriod }
+func (r *railResolver) LockupFixed() string        { return r.rail.LockupFixed }
+func (r *railResolver) SettledUpTo() string        { return r.rail.SettledUpTo }
+func (r *railResolver) EndEpoch() string           { return r.rail.EndEpoch }
+func (r *railResolver) IsTerminated() bool         { return r.rail.IsTerminated }
+func (r *railResolver) PendingSettlement() *string { return optionalString(r.rail.PendingSettlement) }
+
+type pingResolver struct {
+	ping pingStatus
+}
+
+func (p *pingResolver) ProductType() int32       { return int32(p.ping.ProductType) }
+func (p *pingResolver) ServiceURL() string       { return p.ping.ServiceURL }
+func (p *pingResolver) Success() bool            { return p.ping.Success }
+func (p *pingResolver) DurationSeconds() float64 { return p.ping.DurationSeconds }
+
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+func optionalFloat(value float64) *float64 {
+	if value == 0 {
+		return nil
+	}
+	return &value
+}
+
+// graphqlHandler serves /graphql. Queries are read from a POST body of the form
+// {"query": ..., "operationName": ..., "variables": ...}, or from ?query= on GET.
+func (a *walletAPI) graphqlHandler() http.HandlerFunc {
+	schema := graphql.MustParseSchema(graphqlSchema, &graphqlQuery{api: a}, graphql.MaxDepth(maxGraphQLDepth))
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var params struct {
+			Query         string         `json:"query"`
+			OperationName string         `json:"operationName"`
+			Variables     map[string]any `json:"variables"`
+		}
+		switch r.Method {
+		case http.MethodGet:
+			query := r.URL.Query()
+			params.Query = query.Get("query")
+			params.OperationName = query.Get("operationName")
+			if variables := query.Get("variables"); variables != "" {
+				if err := json.Unmarshal([]byte(variables), &params.Variables); err != nil {
+					http.Error(w, "invalid variables", http.StatusBadRequest)
+					return
+				}
+			}
+		case http.MethodPost:
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxGraphQLRequestSize)).Decode(&params); err != nil {
+				http.Error(w, "invalid request body", http.StatusBadRequest)
+				return
+			}
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if params.Query == "" {
+			http.Error(w, "missing query", http.StatusBadRequest)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), graphqlSnapshotKey{}, &graphqlSnapshot{
+			wallets: a.exp.GetWallets(),
+			pings:   a.exp.GetPingResults(),
+		})
+		a.writeJSON(w, schema.Exec(ctx, params.Query, params.OperationName, params.Variables))
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// runHealthcheck probes the exporter on this host and exits 0 if it is ready, for Docker
+// HEALTHCHECK in images without curl or wget
+func runHealthcheck(args []string) int {
+	flags := flag.NewFlagSet("healthcheck", flag.ExitOnError)
+	url := flags.String("url", "", "URL to probe (default http(s)://127.0.0.1:EXPORTER_PORT/-/ready)")
+	live := flags.Bool("live", false, "probe /-/healthy instead, which passes before the first scrape")
+	timeout := flags.Duration("timeout", 3*time.Second, "timeout for the probe")
+	flags.Parse(args)
+
+	client := &http.Client{}
+	if *url == "" {
+		cfg, _, err := loadCommandConfig()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			return 1
+		}
+		if cfg.ExporterPort == 0 {
+			fmt.Fprintf(os.Stderr, "❌ EXPORTER_PORT is 0, there is no HTTP server to probe\n")
+			return 1
+		}
+
+		scheme := "http"
+		if cfg.TLSCertFile != "" {
+			scheme = "https"
+			// The certificate is issued for the public name, not 127.0.0.1. When client
+			// certificates are required, the exporter's own certificate is presented.
+			tlsConfig := &tls.Config{InsecureSkipVerify: true}
+			if cfg.TLSClientCAFile != "" {
+				cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "❌ Failed to load TLS certificate: %v\n", err)
+					return 1
+				}
+				tlsConfig.Certificates = []tls.Certificate{cert}
+			}
+			client.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+		}
+
+		path := "/-/ready"
+		if *live {
+			path = "/-/healthy"
+		}
+		*url = fmt.Sprintf("%s://127.0.0.1:%d%s", scheme, cfg.ExporterPort, path)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, *url, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	if resp.StatusCode != http.StatusOK {
+		fmt.Fprintf(os.Stderr, "❌ %s: %s\n", resp.Status, strings.TrimSpace(string(body)))
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"wallet-exporter/internal/exporter"
+	"wallet-exporter/internal/history"
+)
+
+const (
+	defaultHistoryRange = 30 * 24 * time.Hour
+	maxHistoryPoints    = 10000
+)
+
+// historyResponse is the history of one address, one series per type it was monitored as
+type historyResponse struct {
+	Address string          `json:"address"`
+	From    time.Time       `json:"from"`
+	To      time.Time       `json:"to"`
+	Step    string          `json:"step,omitempty"`
+	Series  []historySeries `json:"series"`
+}
+
+type historySeries struct {
+	Type   string         `json:"type"`
+	Name   string         `json:"name"` // Name as of the last point
+	Points []historyPoint `json:"points"`
+}
+
+type historyPoint struct {
+	Time             time.Time        `json:"time"`
+	Block            uint64           `json:"block"`
+	FILBalance       string           `json:"fil_balance"`
+	USDFCBalance     string           `json:"usdfc_balance"`
+	PaymentsAccounts []paymentsStatus `json:"payments_accounts"`
+}
+
+// getHistory serves GET /api/v1/history?address=0x...&from=...&to=...&step=1h. from and to are
+// RFC 3339 times or Unix seconds and default to the last 30 days. With step, each series keeps
+// the last point of every step.
+func (a *walletAPI) getHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	store := a.exp.GetHistory()
+	if store == nil {
+		http.Error(w, "history is not enabled, set HISTORY_PATH", http.StatusNotFound)
+		return
+	}
+
+	query := r.URL.Query()
+	address, ok := parseWalletAddress(query.Get("address"))
+	if !ok {
+		http.Error(w, "address must be a 0x or f410/t410 address", http.StatusBadRequest)
+		return
+	}
+	to, err := parseHistoryTime(query.Get("to"), time.Now())
+	if err != nil {
+		http.Error(w, "to must be an RFC 3339 time or Unix seconds", http.StatusBadRequest)
+		return
+	}
+	from, err := parseHistoryTime(query.Get("from"), to.Add(-defaultHistoryRange))
+	if err != nil || from.After(to) {
+		http.Error(w, "from must be an RFC 3339 time or Unix seconds before to", http.StatusBadRequest)
+		return
+	}
+	var step time.Duration
+	if value := query.Get("step"); value != "" {
+		if step, err = time.ParseDuration(value); err != nil || step <= 0 {
+			http.Error(w, "step must be a positive duration like 1h", http.StatusBadRequest)
+			return
+		}
+	}
+
+	records, err := store.Wallet(address, from, to)
+	if err != nil {
+		a.logger.Error("Failed to read history", "address", address.Hex(), "error", err)
+		http.Error(w, "failed to read history", http.StatusInternalServerError)
+		return
+	}
+
+	response := historyResponse{Address: address.Hex(), From: from.UTC(), To: to.UTC(), Series: []historySeries{}}
+	if step > 0 {
+		response.Step = step.String()
+	}
+	points := 0
+	for _, series := range historySeriesOf(downsample(records, step)) {
+		points += len(series.Points)
+		response.Series = append(response.Series, series)
+	}
+	if points > maxHistoryPoints {
+		http.Error(w, "more than "+strconv.Itoa(maxHistoryPoints)+" points, use a larger step or a shorter range", http.StatusBadRequest)
+		return
+	}
+	a.writeJSON(w, response)
+}
+
+// parseHistoryTime parses an RFC 3339 time or Unix seconds, returning fallback if value is empty
+func parseHistoryTime(value string, fallback time.Time) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// downsample keeps the last record of every type in each step, records being oldest first
+func downsample(records []history.Wallet, step time.Duration) []history.Wallet {
+	if step <= 0 {
+		return records
+	}
+	type bucket struct {
+		walletType string
+		start      int64
+	}
+	var kept []history.Wallet
+	index := make(map[bucket]int)
+	for _, record := range records {
+		key := bucket{record.Type, record.Time.Truncate(step).UnixNano()}
+		if i, ok := index[key]; ok {
+			kept[i] = record
+			continue
+		}
+		index[key] = len(kept)
+		kept = append(kept, record)
+	}
+	return kept
+}
+
+// historySeriesOf splits records into one series per wallet type, in order of appearance
+func historySeriesOf(records []history.Wallet) []historySeries {
+	var series []historySeries
+	index := make(map[string]int)
+	for _, record := range records {
+		i, ok := index[record.Type]
+		if !ok {
+			i = len(series)
+			index[record.Type] = i
+			series = append(series, historySeries{Type: record.Type})
+		}
+		series[i].Name = record.Name
+
+		point := historyPoint{
+			Time:             record.Time.UTC(),
+			Block:            record.Block,
+			FILBalance:       exporter.FormatUnits(record.FIL, 18),
+			USDFCBalance:     exporter.FormatUnits(record.USDFC, 18),
+			PaymentsAccounts: []paymentsStatus{},
+		}
+		for _, account := range record.Payments {
+			point.PaymentsAccounts = append(point.PaymentsAccounts, paymentsStatus{
+				Token:            account.Token,
+				Funds:            exporter.FormatUnits(account.Funds, account.Decimals),
+				Available:        exporter.FormatUnits(account.Available, account.Decimals),
+				Locked:           exporter.FormatUnits(account.Locked, account.Decimals),
+				FundedUntilEpoch: intString(account.FundedUntilEpoch),
+				LockupRate:       exporter.FormatUnits(account.LockupRate, account.Decimals),
+			})
+		}
+		series[i].Points = append(series[i].Points, point)
+	}
+	return series
+}
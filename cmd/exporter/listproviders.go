@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+func runListProviders(args []string) int {
+	flags := flag.NewFlagSet("list-providers", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print the providers as a JSON array instead of a table")
+	activeOnly := flags.Bool("active-only", false, "only list active providers")
+	timeout := flags.Duration("timeout", 2*time.Minute, "timeout for reading the registry")
+	flags.Parse(args)
+
+	_, exp, err := newCommandExporter()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	defer exp.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	providers, err := exp.ListProviders(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to list providers: %v\n", err)
+		return 1
+	}
+	if *activeOnly {
+		active := providers[:0]
+		for _, p := range providers {
+			if p.IsActive {
+				active = append(active, p)
+			}
+		}
+		providers = active
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(providers); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Failed to encode providers: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tADDRESS\tACTIVE\tAPPROVED\tSERVICE URL")
+	for _, p := range providers {
+		serviceURL := p.ServiceURL
+		if serviceURL == "" {
+			serviceURL = "-"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%t\t%t\t%s\n", p.ProviderID, p.Name, p.Address.Hex(), p.IsActive, p.IsApproved, serviceURL)
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to write providers: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -2,14 +2,20 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"log/slog"
+	"math"
 	"math/big"
 	"net/http"
 	"os"
 	"os/signal"
 	"runtime/debug"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -27,6 +33,35 @@ func toFloat(balance *big.Int) float64 {
 	return f
 }
 
+// newLogger creates a structured logger writing to w at the given level
+func newLogger(logLevel string, w io.Writer) *slog.Logger {
+	var level slog.Level
+	switch logLevel {
+	case "debug":
+		level = slog.LevelDebug
+	case "warn":
+		level = slog.LevelWarn
+	case "error":
+		level = slog.LevelError
+	default:
+		level = slog.LevelInfo
+	}
+
+	opts := &slog.HandlerOptions{
+		Level: level,
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// reloadConfig re-reads the configuration (environment and .env) and applies its wallet list
+func reloadConfig(ctx context.Context, exp *exporter.WalletExporter) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	return exp.Reload(ctx, cfg)
+}
+
 func main() {
 	// Set up logging
 	log.SetOutput(os.Stdout)
@@ -41,6 +76,18 @@ func main() {
 		}
 	}()
 
+	if len(os.Args) > 1 && (os.Args[1] == "--version" || os.Args[1] == "-version") {
+		os.Exit(runVersion(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && (os.Args[1] == "--dry-run" || os.Args[1] == "-dry-run") {
+		os.Exit(runDryRun(os.Args[2:]))
+	}
+
+	// Run a subcommand if one was given
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		os.Exit(runCommand(os.Args[1], os.Args[2:]))
+	}
+
 	// Load configuration
 	log.Println("Loading configuration...")
 	cfg, err := config.Load()
@@ -49,24 +96,9 @@ func main() {
 	}
 
 	// Initialize structured logger
-	var level slog.Level
-	switch cfg.LogLevel {
-	case "debug":
-		level = slog.LevelDebug
-	case "warn":
-		level = slog.LevelWarn
-	case "error":
-		level = slog.LevelError
-	default:
-		level = slog.LevelInfo
-	}
+	logger := newLogger(cfg.LogLevel, os.Stdout)
 
-	opts := &slog.HandlerOptions{
-		Level: level,
-	}
-	logger := slog.New(slog.NewTextHandler(os.Stdout, opts))
-
-	logger.Info("Starting Dealbot Wallet Exporter...")
+	logger.Info("Starting Dealbot Wallet Exporter...", "version", version, "commit", currentBuildInfo().Commit)
 	logger.Info("Configuration loaded successfully",
 		"network", cfg.Network,
 		"rpc_url", cfg.RPCURL,
@@ -104,128 +136,200 @@ func main() {
 	// Setup HTTP server
 	mux := http.NewServeMux()
 
-	// Metrics endpoint (use custom registry)
-	mux.Handle("/metrics", promhttp.HandlerFor(
-		exp.GetRegistry(),
-		promhttp.HandlerOpts{},
-	))
+	// Metrics endpoint (use custom registry); ?collect[]=<group> limits it to some collector groups.
+	// Responses are gzip-compressed for clients that accept it.
+	metricsHandler := promhttp.HandlerFor(exp.GetRegistry(), promhttp.HandlerOpts{})
+	var metrics http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		collect := r.URL.Query()["collect[]"]
+		if len(collect) == 0 {
+			metricsHandler.ServeHTTP(w, r)
+			return
+		}
 
-	// Health endpoint
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		gatherer, err := exp.GathererFor(collect)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	})
+	if cfg.MetricsCacheTTL > 0 {
+		metrics = newMetricsCache(cfg.MetricsCacheTTL, metrics)
+	}
+	if cfg.MetricsEndpoint {
+		mux.Handle("/metrics", metrics)
+	}
+
+	// Liveness endpoints: the process is up and serving (/health is kept for existing probes)
+	healthy := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "OK\n")
+	}
+	mux.HandleFunc("/health", healthy)
+	mux.HandleFunc("/-/healthy", healthy)
+
+	// Readiness endpoint: a scrape succeeded within READY_MAX_STALENESS
+	mux.HandleFunc("/-/ready", func(w http.ResponseWriter, r *http.Request) {
+		lastSuccess := exp.GetScrapeStatus().LastSuccess
+		if lastSuccess.IsZero() {
+			http.Error(w, "no successful scrape yet", http.StatusServiceUnavailable)
+			return
+		}
+		if age := time.Since(lastSuccess); age > cfg.ReadyMaxStaleness {
+			http.Error(w, fmt.Sprintf("last successful scrape was %s ago", age.Round(time.Second)), http.StatusServiceUnavailable)
+			return
+		}
 		w.WriteHeader(http.StatusOK)
 		fmt.Fprintf(w, "OK\n")
 	})
 
-	// Status endpoint
-	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
-		wallets := exp.GetWallets()
-		lastScrape := exp.GetLastScrape()
-
-		w.Header().Set("Content-Type", "text/plain")
-		fmt.Fprintf(w, "Dealbot Wallet Exporter Status\n")
-		fmt.Fprintf(w, "==============================\n\n")
-		fmt.Fprintf(w, "Network: %s\n", cfg.Network)
-		fmt.Fprintf(w, "Wallets monitored: %d\n", len(wallets))
-		fmt.Fprintf(w, "Last scrape: %s\n", lastScrape.Format(time.RFC3339))
-		fmt.Fprintf(w, "Time since last scrape: %s\n\n", time.Since(lastScrape).Round(time.Second))
-
-		// Group by type
-		providers := []exporter.WalletInfo{}
-		clients := []exporter.WalletInfo{}
-		others := []exporter.WalletInfo{}
-
-		for _, w := range wallets {
-			switch w.Type {
-			case "provider":
-				providers = append(providers, w)
-			case "client":
-				clients = append(clients, w)
-			default:
-				others = append(others, w)
-			}
+	// Status endpoint (plain text, or JSON for /status.json and Accept: application/json)
+	mux.HandleFunc("/status", statusHandler(cfg, exp, logger))
+	mux.HandleFunc("/status.json", statusHandler(cfg, exp, logger))
+
+	// API endpoints are guarded by token scopes (API_TOKEN_N)
+	auth := &apiAuth{tokens: cfg.APITokens, logger: logger}
+
+	// Offboarding report endpoint
+	mux.HandleFunc("/api/v1/offboarding", auth.require(config.ScopeRead, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
 		}
 
-		if len(providers) > 0 {
-			fmt.Fprintf(w, "Storage Providers (%d):\n", len(providers))
-			for _, p := range providers {
-				fmt.Fprintf(w, "  - ID: %d, Name: %s\n", p.ProviderID, p.Name)
-				fmt.Fprintf(w, "    Address: %s\n", p.Address.Hex())
-				fmt.Fprintf(w, "    FIL Balance: %.6f FIL\n", toFloat(p.FILBalance))
-				fmt.Fprintf(w, "    USDFC Balance: %.6f USDFC\n", toFloat(p.USDFCBalance))
-				fmt.Fprintf(w, "    Active: %t\n\n", p.IsActive)
-			}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(exp.GetOffboardingReport()); err != nil {
+			logger.Error("Failed to encode offboarding report", "error", err)
 		}
+	}))
 
-		if len(clients) > 0 {
-			fmt.Fprintf(w, "Client Wallets (%d):\n", len(clients))
-			for _, c := range clients {
-				fmt.Fprintf(w, "  - Name: %s\n", c.Name)
-				fmt.Fprintf(w, "    Address: %s\n", c.Address.Hex())
-				fmt.Fprintf(w, "    FIL Balance: %.6f FIL\n", toFloat(c.FILBalance))
-				fmt.Fprintf(w, "    USDFC Balance: %.6f USDFC\n\n", toFloat(c.USDFCBalance))
-			}
+	// Metrics schema endpoint
+	mux.HandleFunc("/api/v1/metrics-schema", auth.require(config.ScopeRead, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
 		}
 
-		if len(others) > 0 {
-			fmt.Fprintf(w, "Other Wallets (%d):\n", len(others))
-			for _, o := range others {
-				fmt.Fprintf(w, "  - Name: %s (Type: %s)\n", o.Name, o.Type)
-				fmt.Fprintf(w, "    Address: %s\n", o.Address.Hex())
-				fmt.Fprintf(w, "    FIL Balance: %.6f FIL\n", toFloat(o.FILBalance))
-				fmt.Fprintf(w, "    USDFC Balance: %.6f USDFC\n\n", toFloat(o.USDFCBalance))
-			}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(exporter.MetricsSchema(cfg.MetricsPrefix)); err != nil {
+			logger.Error("Failed to encode metrics schema", "error", err)
 		}
-	})
+	}))
 
-	// Root endpoint
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "text/html")
-		fmt.Fprintf(w, `
-<!DOCTYPE html>
-<html>
-<head>
-    <title>Dealbot Wallet Exporter</title>
-    <style>
-        body { font-family: Arial, sans-serif; margin: 40px; }
-        h1 { color: #333; }
-        a { color: #0066cc; text-decoration: none; margin-right: 20px; }
-        a:hover { text-decoration: underline; }
-    </style>
-</head>
-<body>
-    <h1>Dealbot Wallet Exporter</h1>
-    <p>Prometheus exporter for Synapse storage provider wallet balances</p>
-    <div>
-        <a href="/metrics">Metrics</a>
-        <a href="/status">Status</a>
-        <a href="/health">Health</a>
-    </div>
-</body>
-</html>
-`)
-	})
+	// Reload endpoint: re-reads the configuration and applies wallet list changes, like SIGHUP
+	mux.HandleFunc("/-/reload", auth.require(config.ScopeManageWallets, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if err := reloadConfig(r.Context(), exp); err != nil {
+			logger.Error("Failed to reload configuration", "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, "OK\n")
+	}))
+
+	// Synchronous scrape endpoint: scrapes now and answers with the outcome once done
+	mux.HandleFunc("/-/scrape", auth.require(config.ScopeScrape, scrapeNowHandler(exp, logger)))
+
+	// Wallet API endpoints
+	api := &walletAPI{cfg: cfg, exp: exp, logger: logger}
+	mux.HandleFunc("/api/v1/wallets", auth.require(config.ScopeRead, api.listWallets))
+	mux.HandleFunc("/api/v1/wallets/{address}", auth.require(config.ScopeRead, api.getWallet))
+	mux.HandleFunc("/api/v1/providers/{id}", auth.require(config.ScopeRead, api.getProvider))
+	mux.HandleFunc("/api/v1/history", auth.require(config.ScopeRead, api.getHistory))
+	mux.HandleFunc("/api/v1/export.csv", auth.require(config.ScopeRead, api.exportCSV))
+	mux.HandleFunc("/graphql", auth.require(config.ScopeRead, api.graphqlHandler()))
+	mux.HandleFunc("/api/openapi.json", auth.require(config.ScopeRead, api.openAPIHandler()))
+
+	// Wallet detail page (HTML or JSON), open like the dashboard it is linked from
+	mux.HandleFunc("/wallets/{address}", api.walletView)
+
+	// On-demand scrape endpoint, subject to SCRAPE_COOLDOWN like /-/scrape
+	mux.HandleFunc("/api/v1/scrape", auth.require(config.ScopeScrape, scrapeNowHandler(exp, logger)))
+
+	// Root endpoint: status dashboard (STATUS_* settings)
+	mux.HandleFunc("/", dashboardHandler(cfg, exp, logger))
+
+	// Rate limit data and API endpoints per client (HTTP_RATE_LIMIT), and only serve clients
+	// from HTTP_ALLOWED_CIDRS if set
+	var handler http.Handler = mux
+	if cfg.HTTPRateLimit > 0 {
+		handler = newRateLimiter(cfg.HTTPRateLimit, cfg.HTTPRateBurst, exp, logger).wrap(handler)
+	}
+	if len(cfg.HTTPAllowedCIDRs) > 0 {
+		handler = newAllowlist(cfg.HTTPAllowedNetworks(), exp, logger).wrap(handler)
+	}
 
 	server := &http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.ExporterPort),
-		Handler:      mux,
+		Handler:      handler,
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 10 * time.Second,
 	}
 
-	// Start HTTP server in background
-	go func() {
-		logger.Info("Starting HTTP server", "port", cfg.ExporterPort)
-		logger.Info("Metrics available", "url", fmt.Sprintf("http://localhost:%d/metrics", cfg.ExporterPort))
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Error("HTTP server failed", "error", err)
-			os.Exit(1)
+	// Serve HTTPS if a certificate is configured, requiring client certificates if a client CA
+	// bundle is (TLS_* settings)
+	scheme := "http"
+	if cfg.TLSCertFile != "" {
+		certs, err := newCertReloader(cfg.TLSCertFile, cfg.TLSKeyFile, cfg.TLSClientCAFile, logger)
+		if err != nil {
+			log.Fatalf("Failed to load TLS certificate: %v", err)
 		}
-	}()
+		if cfg.TLSReloadInterval > 0 {
+			go certs.watch(ctx, cfg.TLSReloadInterval)
+		}
+		server.TLSConfig = certs.serverConfig()
+		scheme = "https"
+	}
+
+	// Start HTTP server in background, unless EXPORTER_PORT=0 leaves metrics to the push sinks
+	if cfg.ExporterPort > 0 {
+		go func() {
+			logger.Info("Starting HTTP server", "port", cfg.ExporterPort, "tls", scheme == "https")
+			logger.Info("Metrics available", "url", fmt.Sprintf("%s://localhost:%d/metrics", scheme, cfg.ExporterPort))
+
+			var err error
+			if server.TLSConfig != nil {
+				// The certificate comes from TLSConfig.GetCertificate
+				err = server.ListenAndServeTLS("", "")
+			} else {
+				err = server.ListenAndServe()
+			}
+			if err != nil && err != http.ErrServerClosed {
+				logger.Error("HTTP server failed", "error", err)
+				os.Exit(1)
+			}
+		}()
+	} else {
+		logger.Info("HTTP server disabled, EXPORTER_PORT is 0")
+	}
+
+	// Start the profiling server in background (DEBUG_PORT)
+	var debugServer *http.Server
+	if cfg.DebugPort > 0 {
+		debugServer = newDebugServer(cfg.DebugPort)
+		go func() {
+			logger.Info("Starting debug server", "port", cfg.DebugPort)
+			if err := debugServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Error("Debug server failed", "error", err)
+			}
+		}()
+	}
 
-	// Wait for interrupt signal
+	// Wait for interrupt signal, reloading the wallet list on SIGHUP
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-	<-sigChan
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	for sig := <-sigChan; sig == syscall.SIGHUP; sig = <-sigChan {
+		go func() {
+			if err := reloadConfig(ctx, exp); err != nil {
+				logger.Error("Failed to reload configuration", "error", err)
+			}
+		}()
+	}
 
 	logger.Info("Shutting down gracefully...")
 
@@ -235,9 +339,54 @@ func main() {
 	// Shutdown HTTP server
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer shutdownCancel()
-	if err := server.Shutdown(shutdownCtx); err != nil {
-		logger.Error("HTTP server shutdown error", "error", err)
+	if cfg.ExporterPort > 0 {
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Error("HTTP server shutdown error", "error", err)
+		}
+	}
+	if debugServer != nil {
+		if err := debugServer.Shutdown(shutdownCtx); err != nil {
+			logger.Error("Debug server shutdown error", "error", err)
+		}
 	}
 
 	logger.Info("Exporter stopped")
 }
+
+// scrapeNowHandler scrapes now, subject to SCRAPE_COOLDOWN, and answers with the outcome once done
+func scrapeNowHandler(exp *exporter.WalletExporter, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// A scrape may outlast the server's write timeout
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+			logger.Debug("Failed to lift write deadline", "error", err)
+		}
+
+		status, err := exp.ScrapeNow(r.Context())
+		var cooldown *exporter.CooldownError
+		switch {
+		case errors.As(err, &cooldown):
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(cooldown.RetryAfter.Seconds()))))
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		err = json.NewEncoder(w).Encode(scrapeSummary{
+			LastScrape:      status.LastScrape,
+			DurationSeconds: status.LastDuration.Seconds(),
+			Errors:          status.LastErrors,
+			Wallets:         len(exp.GetWallets()),
+		})
+		if err != nil {
+			logger.Error("Failed to encode scrape summary", "error", err)
+		}
+	}
+}
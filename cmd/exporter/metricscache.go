@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metricsCache keeps rendered /metrics responses for METRICS_CACHE_TTL, so Prometheus servers
+// scraping at the same time (an HA pair, Thanos) share one rendering. Responses are cached per
+// collect[] selection, Accept and Accept-Encoding, since each renders differently.
+type metricsCache struct {
+	ttl  time.Duration
+	next http.Handler
+
+	mu      sync.Mutex // Held while rendering, so concurrent misses wait for one rendering
+	entries map[string]cachedResponse
+}
+
+type cachedResponse struct {
+	header   http.Header
+	body     []byte
+	cachedAt time.Time
+}
+
+func newMetricsCache(ttl time.Duration, next http.Handler) *metricsCache {
+	return &metricsCache{ttl: ttl, next: next, entries: make(map[string]cachedResponse)}
+}
+
+func (c *metricsCache) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := strings.Join([]string{
+		strings.Join(r.URL.Query()["collect[]"], ","),
+		r.Header.Get("Accept"),
+		r.Header.Get("Accept-Encoding"),
+	}, "|")
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if !ok || time.Since(entry.cachedAt) >= c.ttl {
+		recorder := &responseRecorder{header: make(http.Header), status: http.StatusOK}
+		c.next.ServeHTTP(recorder, r)
+		if recorder.status != http.StatusOK {
+			c.mu.Unlock()
+			recorder.writeTo(w)
+			return
+		}
+
+		entry = cachedResponse{header: recorder.header, body: recorder.body.Bytes(), cachedAt: time.Now()}
+		c.entries[key] = entry
+
+		// Drop expired entries of selections nobody asks for anymore
+		for k, e := range c.entries {
+			if time.Since(e.cachedAt) >= c.ttl {
+				delete(c.entries, k)
+			}
+		}
+	}
+	c.mu.Unlock()
+
+	for name, values := range entry.header {
+		w.Header()[name] = values
+	}
+	w.Write(entry.body)
+}
+
+// responseRecorder buffers a response so it can be cached
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) Header() http.Header         { return r.header }
+func (r *responseRecorder) Write(b []byte) (int, error) { return r.body.Write(b) }
+func (r *responseRecorder) WriteHeader(status int)      { r.status = status }
+
+func (r *responseRecorder) writeTo(w http.ResponseWriter) {
+	for name, values := range r.header {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(r.status)
+	w.Write(r.body.Bytes())
+}
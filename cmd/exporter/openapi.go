@@ -0,0 +1,292 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"wallet-exporter/internal/config"
+	"wallet-exporter/internal/exporter"
+)
+
+// openAPIEndpoint describes one REST endpoint. Response schemas are derived from the Go type
+// the handler encodes, so the document cannot drift from the actual responses.
+type openAPIEndpoint struct {
+	Path       string
+	Method     string
+	Summary    string
+	Scope      string // Token scope the endpoint requires (empty = open)
+	Parameters []openAPIParameter
+	Status     int
+	Response   any // Zero value of the JSON response type (nil = no JSON body)
+}
+
+type openAPIParameter struct {
+	Name        string
+	In          string // "path" or "query"
+	Type        string
+	Description string
+}
+
+var openAPIEndpoints = []openAPIEndpoint{
+	{
+		Path: "/status.json", Method: http.MethodGet,
+		Summary: "Wallets of the last scrape with scrape timing and error counts",
+		Status:  http.StatusOK, Response: statusReport{},
+	},
+	{
+		Path: "/wallets/{address}", Method: http.MethodGet,
+		Summary: "Detail of one wallet with raw balances; JSON with ?format=json or Accept: application/json",
+		Parameters: []openAPIParameter{
+			{Name: "address", In: "path", Type: "string", Description: "0x or f410/t410 address"},
+			{Name: "format", In: "query", Type: "string", Description: "json to get JSON regardless of the Accept header"},
+		},
+		Status: http.StatusOK, Response: []walletViewEntry{},
+	},
+	{
+		Path: "/api/v1/wallets", Method: http.MethodGet,
+		Summary: "Page of the monitored wallets",
+		Scope:   config.ScopeRead,
+		Parameters: []openAPIParameter{
+			{Name: "type", In: "query", Type: "string", Description: "Comma-separated list of wallet types"},
+			{Name: "offset", In: "query", Type: "integer", Description: "Wallets to skip"},
+			{Name: "limit", In: "query", Type: "integer", Description: "Wallets to return (default 100, at most 1000)"},
+		},
+		Status: http.StatusOK, Response: walletPage{},
+	},
+	{
+		Path: "/api/v1/wallets/{address}", Method: http.MethodGet,
+		Summary: "Entries of one wallet, once per type it is monitored as",
+		Scope:   config.ScopeRead,
+		Parameters: []openAPIParameter{
+			{Name: "address", In: "path", Type: "string", Description: "0x or f410/t410 address"},
+		},
+		Status: http.StatusOK, Response: []walletDetail{},
+	},
+	{
+		Path: "/api/v1/providers/{id}", Method: http.MethodGet,
+		Summary: "Entry of one storage provider",
+		Scope:   config.ScopeRead,
+		Parameters: []openAPIParameter{
+			{Name: "id", In: "path", Type: "integer", Description: "Provider ID"},
+		},
+		Status: http.StatusOK, Response: walletDetail{},
+	},
+	{
+		Path: "/api/v1/history", Method: http.MethodGet,
+		Summary: "Recorded balances and Payments accounts of one wallet over time (requires HISTORY_PATH)",
+		Scope:   config.ScopeRead,
+		Parameters: []openAPIParameter{
+			{Name: "address", In: "query", Type: "string", Description: "0x or f410/t410 address"},
+			{Name: "from", In: "query", Type: "string", Description: "RFC 3339 time or Unix seconds (default 30 days before to)"},
+			{Name: "to", In: "query", Type: "string", Description: "RFC 3339 time or Unix seconds (default now)"},
+			{Name: "step", In: "query", Type: "string", Description: "Keep the last point of every step, e.g. 1h"},
+		},
+		Status: http.StatusOK, Response: historyResponse{},
+	},
+	{
+		Path: "/api/v1/export.csv", Method: http.MethodGet,
+		Summary: "CSV of the wallets of the last scrape with raw balances and Payments accounts",
+		Scope:   config.ScopeRead,
+		Status:  http.StatusOK,
+	},
+	{
+		Path: "/api/v1/offboarding", Method: http.MethodGet,
+		Summary: "Providers that went inactive, lost approval, or hold empty wallets",
+		Scope:   config.ScopeRead,
+		Status:  http.StatusOK, Response: []exporter.OffboardingEntry{},
+	},
+	{
+		Path: "/api/v1/metrics-schema", Method: http.MethodGet,
+		Summary: "Every metric family the exporter can emit",
+		Scope:   config.ScopeRead,
+		Status:  http.StatusOK, Response: []exporter.MetricDefinition{},
+	},
+	{
+		Path: "/api/v1/scrape", Method: http.MethodPost,
+		Summary: "Scrape outside the regular interval and return the outcome once done",
+		Scope:   config.ScopeScrape,
+		Status:  http.StatusOK, Response: scrapeSummary{},
+	},
+	{
+		Path: "/-/scrape", Method: http.MethodPost,
+		Summary: "Scrape now and return the outcome once done",
+		Scope:   config.ScopeScrape,
+		Status:  http.StatusOK, Response: scrapeSummary{},
+	},
+	{
+		Path: "/-/reload", Method: http.MethodPost,
+		Summary: "Re-read the configuration and apply wallet list changes",
+		Scope:   config.ScopeManageWallets,
+		Status:  http.StatusOK,
+	},
+}
+
+// openAPIDocument builds the OpenAPI 3 document of the REST endpoints
+func openAPIDocument() map[string]any {
+	schemas := openAPISchemas{}
+	paths := map[string]map[string]any{}
+
+	for _, endpoint := range openAPIEndpoints {
+		operation := map[string]any{"summary": endpoint.Summary}
+
+		if len(endpoint.Parameters) > 0 {
+			var parameters []map[string]any
+			for _, parameter := range endpoint.Parameters {
+				parameters = append(parameters, map[string]any{
+					"name":        parameter.Name,
+					"in":          parameter.In,
+					"required":    parameter.In == "path",
+					"description": parameter.Description,
+					"schema":      map[string]any{"type": parameter.Type},
+				})
+			}
+			operation["parameters"] = parameters
+		}
+
+		response := map[string]any{"description": http.StatusText(endpoint.Status)}
+		if endpoint.Response != nil {
+			response["content"] = map[string]any{
+				"application/json": map[string]any{"schema": schemas.of(reflect.TypeOf(endpoint.Response))},
+			}
+		}
+		responses := map[string]any{strconv.Itoa(endpoint.Status): response}
+		if endpoint.Scope != "" {
+			operation["security"] = []map[string][]string{{"bearerAuth": {}}}
+			operation["description"] = "Requires a token with the " + endpoint.Scope + " scope"
+			responses["401"] = map[string]any{"description": "Missing or unknown token"}
+			responses["403"] = map[string]any{"description": "Token lacks the required scope"}
+		}
+		operation["responses"] = responses
+
+		if paths[endpoint.Path] == nil {
+			paths[endpoint.Path] = map[string]any{}
+		}
+		paths[endpoint.Path][strings.ToLower(endpoint.Method)] = operation
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "wallet-exporter",
+			"version": "v1",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+}
+
+// openAPISchemas collects the named struct types referenced by the document
+type openAPISchemas map[string]any
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// of returns the schema of t, following encoding/json: fields are named after their json
+// tag, omitempty fields are optional and embedded structs are flattened into their parent
+func (s openAPISchemas) of(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := s.of(t.Elem())
+		if _, ok := schema["$ref"]; ok {
+			return map[string]any{"allOf": []any{schema}, "nullable": true}
+		}
+		schema["nullable"] = true
+		return schema
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": s.of(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": s.of(t.Elem())}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Int, reflect.Int64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Uint, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number", "format": "double"}
+	case reflect.Struct:
+		if t == timeType {
+			return map[string]any{"type": "string", "format": "date-time"}
+		}
+		name := schemaName(t)
+		if _, ok := s[name]; !ok {
+			s[name] = nil // Reserve the name while the fields are resolved
+			s[name] = s.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]any{}
+}
+
+func (s openAPISchemas) object(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	var required []string
+	s.addFields(t, properties, &required)
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func (s openAPISchemas) addFields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		if field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct {
+			s.addFields(field.Type, properties, required)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = s.of(field.Type)
+		if !strings.Contains(options, "omitempty") && field.Type.Kind() != reflect.Pointer {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// schemaName names a component after its Go type, capitalized as the unexported response
+// types would be if they were exported
+func schemaName(t reflect.Type) string {
+	r, size := utf8.DecodeRuneInString(t.Name())
+	return string(unicode.ToUpper(r)) + t.Name()[size:]
+}
+
+// openAPIHandler serves /api/openapi.json
+func (a *walletAPI) openAPIHandler() http.HandlerFunc {
+	document := openAPIDocument()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		a.writeJSON(w, document)
+	}
+}
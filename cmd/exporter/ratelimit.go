@@ -0,0 +1,116 @@
+package main
+
+import (
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimitedPrefixes are the paths rate limited per client. They serve data computed from
+// the last scrape or trigger work; /metrics and the probes stay unlimited for Prometheus and
+// orchestrators.
+var rateLimitedPrefixes = []string{"/status", "/api/", "/wallets/", "/graphql"}
+
+// requestRejecter counts requests a middleware refused, by reason
+type requestRejecter interface {
+	RequestRejected(reason string)
+}
+
+// rateLimiter is a token bucket per client IP: every client may send burst requests at once
+// and rate requests per second on average
+type rateLimiter struct {
+	rate   float64
+	burst  float64
+	exp    requestRejecter
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu          sync.Mutex
+	clients     map[string]*tokenBucket
+	lastCleanup time.Time
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func newRateLimiter(rate float64, burst int, exp requestRejecter, logger *slog.Logger) *rateLimiter {
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		exp:     exp,
+		logger:  logger,
+		now:     time.Now,
+		clients: make(map[string]*tokenBucket),
+	}
+}
+
+// take spends a token of the client and returns how long it must wait if none is left
+func (l *rateLimiter) take(client string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Forget clients whose bucket has refilled completely
+	if now.Sub(l.lastCleanup) > time.Minute {
+		for key, bucket := range l.clients {
+			if l.refill(bucket, now) >= l.burst {
+				delete(l.clients, key)
+			}
+		}
+		l.lastCleanup = now
+	}
+
+	bucket, ok := l.clients[client]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, updated: now}
+		l.clients[client] = bucket
+	}
+	bucket.tokens = l.refill(bucket, now)
+	bucket.updated = now
+
+	if bucket.tokens < 1 {
+		return false, time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+// refill returns the tokens of a bucket at now
+func (l *rateLimiter) refill(bucket *tokenBucket, now time.Time) float64 {
+	return math.Min(l.burst, bucket.tokens+now.Sub(bucket.updated).Seconds()*l.rate)
+}
+
+func (l *rateLimiter) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limited := false
+		for _, prefix := range rateLimitedPrefixes {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				limited = true
+				break
+			}
+		}
+		if !limited {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		client, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			client = r.RemoteAddr
+		}
+		if ok, wait := l.take(client, l.now()); !ok {
+			l.logger.Debug("Rate limited request", "client", client, "path", r.URL.Path)
+			l.exp.RequestRejected("rate_limit")
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// rejectCounter records the reasons of rejected requests
+type rejectCounter struct {
+	reasons []string
+}
+
+func (c *rejectCounter) RequestRejected(reason string) {
+	c.reasons = append(c.reasons, reason)
+}
+
+func TestRateLimiter(t *testing.T) {
+	type request struct {
+		path   string
+		client string
+		after  time.Duration // Since the previous request
+		status int
+	}
+
+	tests := []struct {
+		name     string
+		requests []request
+	}{
+		{"burst", []request{
+			{"/status", "10.0.0.1:1000", 0, http.StatusOK},
+			{"/api/v1/wallets", "10.0.0.1:1001", 0, http.StatusOK},
+			{"/graphql", "10.0.0.1:1002", 0, http.StatusTooManyRequests},
+		}},
+		{"refill", []request{
+			{"/status", "10.0.0.1:1000", 0, http.StatusOK},
+			{"/status", "10.0.0.1:1000", 0, http.StatusOK},
+			{"/status", "10.0.0.1:1000", 0, http.StatusTooManyRequests},
+			{"/status", "10.0.0.1:1000", time.Second, http.StatusOK},
+			{"/status", "10.0.0.1:1000", 0, http.StatusTooManyRequests},
+			{"/status", "10.0.0.1:1000", time.Hour, http.StatusOK},
+			{"/status", "10.0.0.1:1000", 0, http.StatusOK},
+			{"/status", "10.0.0.1:1000", 0, http.StatusTooManyRequests},
+		}},
+		{"per client", []request{
+			{"/status", "10.0.0.1:1000", 0, http.StatusOK},
+			{"/status", "10.0.0.1:1001", 0, http.StatusOK},
+			{"/status", "10.0.0.1:1002", 0, http.StatusTooManyRequests},
+			{"/status", "10.0.0.2:1000", 0, http.StatusOK},
+			{"/status", "[2001:db8::1]:1000", 0, http.StatusOK},
+		}},
+		{"exempt paths", []request{
+			{"/status", "10.0.0.1:1000", 0, http.StatusOK},
+			{"/status", "10.0.0.1:1000", 0, http.StatusOK},
+			{"/metrics", "10.0.0.1:1000", 0, http.StatusOK},
+			{"/health", "10.0.0.1:1000", 0, http.StatusOK},
+			{"/-/ready", "10.0.0.1:1000", 0, http.StatusOK},
+			{"/metrics", "10.0.0.1:1000", 0, http.StatusOK},
+			{"/status", "10.0.0.1:1000", 0, http.StatusTooManyRequests},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rejected := &rejectCounter{}
+			limiter := newRateLimiter(1, 2, rejected, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			now := time.Unix(1700000000, 0)
+			limiter.now = func() time.Time { return now }
+			handler := limiter.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			limited := 0
+			for i, req := range tt.requests {
+				now = now.Add(req.after)
+				r := httptest.NewRequest(http.MethodGet, req.path, nil)
+				r.RemoteAddr = req.client
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, r)
+
+				if w.Code != req.status {
+					t.Fatalf("Request %d (%s from %s): expected status %d, got %d", i, req.path, req.client, req.status, w.Code)
+				}
+				if w.Code == http.StatusTooManyRequests {
+					limited++
+					if retry := w.Header().Get("Retry-After"); retry != "1" {
+						t.Errorf("Request %d: expected Retry-After 1, got %q", i, retry)
+					}
+				}
+			}
+			if len(rejected.reasons) != limited {
+				t.Errorf("Expected %d rejections counted, got %v", limited, rejected.reasons)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"wallet-exporter/internal/exporter"
+)
+
+func runSnapshot(args []string) int {
+	flags := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	out := flags.String("out", "", "write the snapshot to this file instead of stdout")
+	timeout := flags.Duration("timeout", 5*time.Minute, "timeout for the scrape")
+	flags.Parse(args)
+
+	_, exp, err := newCommandExporter()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	defer exp.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	status, err := exp.ScrapeOnce(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Scrape failed: %v\n", err)
+		return 1
+	}
+	if status.LastErrors > 0 {
+		fmt.Fprintf(os.Stderr, "⚠️  Scrape had %d errors, some balances may be missing\n", status.LastErrors)
+	}
+
+	snapshot := exp.Snapshot()
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to encode snapshot: %v\n", err)
+		return 1
+	}
+	data = append(data, '\n')
+
+	if *out == "" {
+		os.Stdout.Write(data)
+		return 0
+	}
+	if err := os.WriteFile(*out, data, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to write snapshot: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "✓ Wrote %d wallets at block %d to %s\n", len(snapshot.Wallets), snapshot.Block, *out)
+	return 0
+}
+
+func runDiff(args []string) int {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	jsonOut := flags.Bool("json", false, "print the changes as JSON")
+	all := flags.Bool("all", false, "also print balances that did not change")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s diff [flags] <snapshot-a.json> <snapshot-b.json>\n\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+	a, err := exporter.ReadSnapshot(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	b, err := exporter.ReadSnapshot(flags.Arg(1))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	if a.Network != b.Network {
+		fmt.Fprintf(os.Stderr, "❌ The snapshots are of different networks (%s and %s)\n", a.Network, b.Network)
+		return 2
+	}
+
+	changes := exporter.DiffSnapshots(a, b, *all)
+	if *jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(changes); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Failed to encode changes: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	fmt.Printf("%s: block %d (%s) → block %d (%s), %s apart\n\n", b.Network,
+		a.Block, a.LastScrape.UTC().Format(time.RFC3339), b.Block, b.LastScrape.UTC().Format(time.RFC3339),
+		b.LastScrape.Sub(a.LastScrape).Round(time.Second))
+	if len(changes) == 0 {
+		fmt.Println("No balance changed")
+		return 0
+	}
+
+	// Net change per asset, in order of first appearance
+	var assets []string
+	totals := make(map[string]*big.Int)
+	decimals := make(map[string]int)
+	for _, change := range changes {
+		if totals[change.Asset] == nil {
+			assets = append(assets, change.Asset)
+			totals[change.Asset] = new(big.Int)
+			decimals[change.Asset] = change.Decimals
+		}
+		totals[change.Asset].Add(totals[change.Asset], change.Change)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tADDRESS\tTYPE\tASSET\tBEFORE\tAFTER\tCHANGE")
+	for _, change := range changes {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", change.Name, change.Address.Hex(), change.Type, change.Asset,
+			diffAmount(change.Before, change.Decimals), diffAmount(change.After, change.Decimals), signedAmount(change.Change, change.Decimals))
+	}
+	fmt.Fprintln(w, "\t\t\t\t\t\t") // Keeps the totals in the same columns
+	for _, asset := range assets {
+		fmt.Fprintf(w, "TOTAL\t\t\t%s\t\t\t%s\n", asset, signedAmount(totals[asset], decimals[asset]))
+	}
+	w.Flush()
+	return 0
+}
+
+// diffAmount formats a balance of a diff, "-" if it is missing from the snapshot
+func diffAmount(amount *big.Int, decimals int) string {
+	if amount == nil {
+		return "-"
+	}
+	return exporter.FormatUnits(amount, decimals)
+}
+
+// signedAmount formats a change with an explicit sign
+func signedAmount(amount *big.Int, decimals int) string {
+	if amount.Sign() > 0 {
+		return "+" + exporter.FormatUnits(amount, decimals)
+	}
+	return exporter.FormatUnits(amount, decimals)
+}
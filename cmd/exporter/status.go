@@ -0,0 +1,286 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"wallet-exporter/internal/config"
+	"wallet-exporter/internal/exporter"
+)
+
+// statusReport is the JSON form of /status
+type statusReport struct {
+	Network                string         `json:"network"`
+	LastScrape             time.Time      `json:"last_scrape"`
+	SecondsSinceLastScrape float64        `json:"seconds_since_last_scrape"`
+	LastScrapeDuration     float64        `json:"last_scrape_duration_seconds"`
+	Restored               bool           `json:"restored"` // Wallets are the last known values from STATE_FILE
+	Errors                 statusErrors   `json:"errors"`
+	Wallets                []walletStatus `json:"wallets"`
+}
+
+// statusErrors summarizes the scrape errors
+type statusErrors struct {
+	LastScrape uint64 `json:"last_scrape"`
+	Total      uint64 `json:"total"`
+}
+
+// scrapeSummary is the outcome of a scrape run through /-/scrape
+type scrapeSummary struct {
+	LastScrape      time.Time `json:"last_scrape"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	Errors          uint64    `json:"errors"`
+	Wallets         int       `json:"wallets"`
+}
+
+// walletStatus is the JSON form of a wallet. Token amounts are exact decimal strings in whole
+// tokens and epochs are integer strings, so no precision is lost in JavaScript clients.
+type walletStatus struct {
+	Address           string           `json:"address"`
+	Name              string           `json:"name"`
+	Type              string           `json:"type"`
+	ProviderID        uint64           `json:"provider_id,omitempty"`
+	Payee             string           `json:"payee,omitempty"`
+	IsActive          bool             `json:"is_active"`
+	IsApproved        bool             `json:"is_approved"`
+	Description       string           `json:"description,omitempty"`
+	Region            string           `json:"region,omitempty"`
+	FILBalance        string           `json:"fil_balance"`
+	USDFCBalance      string           `json:"usdfc_balance"`
+	PayeeFILBalance   string           `json:"payee_fil_balance,omitempty"`
+	PayeeUSDFCBalance string           `json:"payee_usdfc_balance,omitempty"`
+	MinFIL            float64          `json:"min_fil,omitempty"`
+	MinUSDFC          float64          `json:"min_usdfc,omitempty"`
+	PaymentsAccounts  []paymentsStatus `json:"payments_accounts"`
+	Products          []productStatus  `json:"products,omitempty"`
+	Rails             []railStatus     `json:"rails,omitempty"`
+	DataSets          *int             `json:"data_sets,omitempty"`
+}
+
+type paymentsStatus struct {
+	Token            string `json:"token"`
+	Funds            string `json:"funds"`
+	Available        string `json:"available"`
+	Locked           string `json:"locked"`
+	FundedUntilEpoch string `json:"funded_until_epoch"`
+	LockupRate       string `json:"lockup_rate"`
+}
+
+type productStatus struct {
+	Type         uint8             `json:"type"`
+	IsActive     bool              `json:"is_active"`
+	Capabilities map[string]string `json:"capabilities,omitempty"`
+}
+
+type railStatus struct {
+	RailID            uint64 `json:"rail_id"`
+	Role              string `json:"role"`
+	Counterparty      string `json:"counterparty"`
+	Operator          string `json:"operator"`
+	PaymentRate       string `json:"payment_rate"`
+	LockupPeriod      string `json:"lockup_period"`
+	LockupFixed       string `json:"lockup_fixed"`
+	SettledUpTo       string `json:"settled_up_to"`
+	EndEpoch          string `json:"end_epoch"`
+	IsTerminated      bool   `json:"is_terminated"`
+	PendingSettlement string `json:"pending_settlement,omitempty"`
+}
+
+// newWalletStatus converts a wallet to its JSON form. The data set count is only included if
+// data sets are exported (EXPORT_DATA_SETS) and the count could be read.
+func newWalletStatus(w exporter.WalletInfo, withDataSets bool) walletStatus {
+	status := walletStatus{
+		Address:          w.Address.Hex(),
+		Name:             w.Name,
+		Type:             w.Type,
+		IsActive:         w.IsActive,
+		IsApproved:       w.IsApproved,
+		Description:      w.Description,
+		Region:           w.Region(),
+		FILBalance:       exporter.FormatUnits(w.FILBalance, 18),
+		USDFCBalance:     exporter.FormatUnits(w.USDFCBalance, 18),
+		MinFIL:           w.MinFIL,
+		MinUSDFC:         w.MinUSDFC,
+		PaymentsAccounts: []paymentsStatus{},
+	}
+
+	if w.Type == "provider" {
+		status.ProviderID = w.ProviderID
+		status.Payee = w.Payee.Hex()
+	}
+	if w.PayeeFILBalance != nil {
+		status.PayeeFILBalance = exporter.FormatUnits(w.PayeeFILBalance, 18)
+	}
+	if w.PayeeUSDFCBalance != nil {
+		status.PayeeUSDFCBalance = exporter.FormatUnits(w.PayeeUSDFCBalance, 18)
+	}
+
+	for _, account := range w.PaymentsAccounts {
+		if account.PaymentsInfo == nil {
+			continue
+		}
+		status.PaymentsAccounts = append(status.PaymentsAccounts, paymentsStatus{
+			Token:            account.Token,
+			Funds:            exporter.FormatUnits(account.Funds, account.Decimals),
+			Available:        exporter.FormatUnits(account.Available, account.Decimals),
+			Locked:           exporter.FormatUnits(account.Locked, account.Decimals),
+			FundedUntilEpoch: intString(account.FundedUntilEpoch),
+			LockupRate:       exporter.FormatUnits(account.LockupRate, account.Decimals),
+		})
+	}
+
+	for _, product := range w.Products {
+		status.Products = append(status.Products, productStatus{
+			Type:         product.Type,
+			IsActive:     product.IsActive,
+			Capabilities: product.Capabilities,
+		})
+	}
+
+	for _, rail := range w.Rails {
+		r := railStatus{
+			RailID:       rail.RailID,
+			Role:         rail.Role,
+			Counterparty: rail.Counterparty.Hex(),
+			Operator:     rail.Operator.Hex(),
+			PaymentRate:  exporter.FormatUnits(rail.PaymentRate, 18),
+			LockupPeriod: intString(rail.LockupPeriod),
+			LockupFixed:  exporter.FormatUnits(rail.LockupFixed, 18),
+			SettledUpTo:  intString(rail.SettledUpTo),
+			EndEpoch:     intString(rail.EndEpoch),
+			IsTerminated: rail.IsTerminated,
+		}
+		if rail.PendingSettlement != nil {
+			r.PendingSettlement = exporter.FormatUnits(rail.PendingSettlement, 18)
+		}
+		status.Rails = append(status.Rails, r)
+	}
+
+	if withDataSets && w.DataSets >= 0 {
+		dataSets := w.DataSets
+		status.DataSets = &dataSets
+	}
+	return status
+}
+
+// intString renders an integer such as an epoch, treating nil as 0
+func intString(value *big.Int) string {
+	if value == nil {
+		return "0"
+	}
+	return value.String()
+}
+
+// wantsJSON reports whether the request asks for JSON in its Accept header
+func wantsJSON(r *http.Request) bool {
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err == nil && mediaType == "application/json" {
+			return true
+		}
+	}
+	return false
+}
+
+// statusHandler serves the wallet status as plain text, or as JSON for /status.json and
+// requests accepting application/json
+func statusHandler(cfg *config.Config, exp *exporter.WalletExporter, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		wallets := exp.GetWallets()
+		scrape := exp.GetScrapeStatus()
+
+		if r.URL.Path == "/status.json" || wantsJSON(r) {
+			report := statusReport{
+				Network:                cfg.Network,
+				LastScrape:             scrape.LastScrape,
+				SecondsSinceLastScrape: time.Since(scrape.LastScrape).Seconds(),
+				LastScrapeDuration:     scrape.LastDuration.Seconds(),
+				Restored:               scrape.Restored,
+				Errors:                 statusErrors{LastScrape: scrape.LastErrors, Total: scrape.TotalErrors},
+				Wallets:                make([]walletStatus, 0, len(wallets)),
+			}
+			for _, wallet := range wallets {
+				report.Wallets = append(report.Wallets, newWalletStatus(wallet, cfg.ExportDataSets))
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(report); err != nil {
+				logger.Error("Failed to encode status", "error", err)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain")
+		writeStatusText(w, cfg, wallets, scrape)
+	}
+}
+
+// writeStatusText renders the human-readable status. Tools should use the JSON form, which
+// keeps its field names stable.
+func writeStatusText(w io.Writer, cfg *config.Config, wallets []exporter.WalletInfo, scrape exporter.ScrapeStatus) {
+	lastScrape := scrape.LastScrape
+	fmt.Fprintf(w, "Dealbot Wallet Exporter Status\n")
+	fmt.Fprintf(w, "==============================\n\n")
+	fmt.Fprintf(w, "Network: %s\n", cfg.Network)
+	fmt.Fprintf(w, "Wallets monitored: %d\n", len(wallets))
+	fmt.Fprintf(w, "Last scrape: %s\n", lastScrape.Format(time.RFC3339))
+	fmt.Fprintf(w, "Time since last scrape: %s\n", time.Since(lastScrape).Round(time.Second))
+	if scrape.Restored {
+		fmt.Fprintf(w, "Stale: restored from the state file, waiting for the first scrape\n")
+	}
+	fmt.Fprintf(w, "\n")
+
+	// Group by type
+	providers := []exporter.WalletInfo{}
+	clients := []exporter.WalletInfo{}
+	others := []exporter.WalletInfo{}
+
+	for _, w := range wallets {
+		switch w.Type {
+		case "provider":
+			providers = append(providers, w)
+		case "client":
+			clients = append(clients, w)
+		default:
+			others = append(others, w)
+		}
+	}
+
+	if len(providers) > 0 {
+		fmt.Fprintf(w, "Storage Providers (%d):\n", len(providers))
+		for _, p := range providers {
+			fmt.Fprintf(w, "  - ID: %d, Name: %s\n", p.ProviderID, p.Name)
+			fmt.Fprintf(w, "    Address: %s\n", p.Address.Hex())
+			fmt.Fprintf(w, "    FIL Balance: %.6f FIL\n", toFloat(p.FILBalance))
+			fmt.Fprintf(w, "    USDFC Balance: %.6f USDFC\n", toFloat(p.USDFCBalance))
+			fmt.Fprintf(w, "    Active: %t\n\n", p.IsActive)
+		}
+	}
+
+	if len(clients) > 0 {
+		fmt.Fprintf(w, "Client Wallets (%d):\n", len(clients))
+		for _, c := range clients {
+			fmt.Fprintf(w, "  - Name: %s\n", c.Name)
+			fmt.Fprintf(w, "    Address: %s\n", c.Address.Hex())
+			fmt.Fprintf(w, "    FIL Balance: %.6f FIL\n", toFloat(c.FILBalance))
+			fmt.Fprintf(w, "    USDFC Balance: %.6f USDFC\n\n", toFloat(c.USDFCBalance))
+		}
+	}
+
+	if len(others) > 0 {
+		fmt.Fprintf(w, "Other Wallets (%d):\n", len(others))
+		for _, o := range others {
+			fmt.Fprintf(w, "  - Name: %s (Type: %s)\n", o.Name, o.Type)
+			fmt.Fprintf(w, "    Address: %s\n", o.Address.Hex())
+			fmt.Fprintf(w, "    FIL Balance: %.6f FIL\n", toFloat(o.FILBalance))
+			fmt.Fprintf(w, "    USDFC Balance: %.6f USDFC\n\n", toFloat(o.USDFCBalance))
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// runTextfile scrapes once and writes the metrics for the node_exporter textfile collector,
+// for running from cron instead of as a service
+func runTextfile(args []string) int {
+	flags := flag.NewFlagSet("textfile", flag.ExitOnError)
+	out := flags.String("out", "", "write the metrics to this .prom file (default TEXTFILE_PATH)")
+	timeout := flags.Duration("timeout", 5*time.Minute, "timeout for the scrape")
+	flags.Parse(args)
+
+	cfg, exp, err := newCommandExporter()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	defer exp.Close()
+
+	path := *out
+	if path == "" {
+		path = cfg.TextfilePath
+	}
+	if path == "" {
+		fmt.Fprintf(os.Stderr, "❌ Set -out or TEXTFILE_PATH\n")
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	status, err := exp.ScrapeOnce(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Scrape failed: %v\n", err)
+		return 1
+	}
+	if status.LastErrors > 0 {
+		fmt.Fprintf(os.Stderr, "⚠️  Scrape had %d errors, some metrics may be missing\n", status.LastErrors)
+	}
+
+	if err := exp.WriteTextfile(path); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "✓ Wrote metrics of %d wallets to %s\n", len(exp.GetWallets()), path)
+	return 0
+}
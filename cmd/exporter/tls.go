@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// certReloader serves the certificate of TLS_CERT_FILE/TLS_KEY_FILE, and the client CAs of
+// TLS_CLIENT_CA_FILE if set, and swaps in new ones when the files change, so rotated
+// certificates are picked up without a restart
+type certReloader struct {
+	certFile string
+	keyFile  string
+	caFile   string // Empty = clients are not asked for certificates
+	logger   *slog.Logger
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	pem       []byte // Files the current certificate and CAs were loaded from
+}
+
+func newCertReloader(certFile, keyFile, caFile string, logger *slog.Logger) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile, caFile: caFile, logger: logger}
+	if _, err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// reload loads the certificate files and reports whether they changed. A pair that fails to
+// load, e.g. because only one file was replaced so far, keeps the current certificate.
+func (r *certReloader) reload() (bool, error) {
+	certPEM, err := os.ReadFile(r.certFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to read TLS certificate: %w", err)
+	}
+	keyPEM, err := os.ReadFile(r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to read TLS key: %w", err)
+	}
+	var caPEM []byte
+	if r.caFile != "" {
+		if caPEM, err = os.ReadFile(r.caFile); err != nil {
+			return false, fmt.Errorf("failed to read TLS client CA bundle: %w", err)
+		}
+	}
+	combined := append(append(append([]byte{}, certPEM...), keyPEM...), caPEM...)
+
+	r.mu.RLock()
+	unchanged := bytes.Equal(combined, r.pem)
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return false, fmt.Errorf("failed to load TLS key pair: %w", err)
+	}
+	var clientCAs *x509.CertPool
+	if r.caFile != "" {
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(caPEM) {
+			return false, fmt.Errorf("no certificates found in TLS client CA bundle")
+		}
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	r.pem = combined
+	r.mu.Unlock()
+	return true, nil
+}
+
+// watch reloads the certificate every interval until ctx is canceled
+func (r *certReloader) watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := r.reload()
+			if err != nil {
+				r.logger.Warn("Failed to reload TLS certificate, keeping the current one", "error", err)
+				continue
+			}
+			if changed {
+				r.logger.Info("Reloaded TLS certificate", "file", r.certFile, "client_ca_file", r.caFile)
+			}
+		}
+	}
+}
+
+// serverConfig returns the TLS configuration of the HTTP server. With a client CA bundle,
+// every connection must present a certificate issued by one of its CAs.
+func (r *certReloader) serverConfig() *tls.Config {
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: r.getCertificate,
+	}
+	if r.caFile != "" {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+		// Hand out a configuration with the current CAs for every connection
+		config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*r.cert},
+				ClientAuth:   tls.RequireAndVerifyClientCert,
+				ClientCAs:    r.clientCAs,
+			}, nil
+		}
+	}
+	return config
+}
+
+func (r *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCert writes a self-signed certificate for commonName and its key to certFile/keyFile
+func writeCert(t *testing.T, certFile, keyFile, commonName string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// servedName returns the common name of the certificate the reloader currently serves
+func servedName(t *testing.T, r *certReloader) string {
+	t.Helper()
+	cert, err := r.getCertificate(nil)
+	if err != nil {
+		t.Fatalf("getCertificate failed: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("Failed to parse served certificate: %v", err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	writeCert(t, certFile, keyFile, "first")
+
+	r, err := newCertReloader(certFile, keyFile, "", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("newCertReloader failed: %v", err)
+	}
+	if name := servedName(t, r); name != "first" {
+		t.Fatalf("Expected certificate first, got %s", name)
+	}
+
+	if changed, err := r.reload(); err != nil || changed {
+		t.Errorf("Expected unchanged files to be skipped, got changed=%v err=%v", changed, err)
+	}
+
+	// Rotated pair
+	writeCert(t, certFile, keyFile, "second")
+	if changed, err := r.reload(); err != nil || !changed {
+		t.Fatalf("Expected the rotated pair to be loaded, got changed=%v err=%v", changed, err)
+	}
+	if name := servedName(t, r); name != "second" {
+		t.Errorf("Expected certificate second after rotation, got %s", name)
+	}
+
+	// Only the certificate replaced so far: the pair does not match
+	otherDir := t.TempDir()
+	writeCert(t, filepath.Join(otherDir, "tls.crt"), filepath.Join(otherDir, "tls.key"), "third")
+	certPEM, err := os.ReadFile(filepath.Join(otherDir, "tls.crt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(certFile, certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.reload(); err == nil {
+		t.Error("Expected an error for a mismatched key pair")
+	}
+	if name := servedName(t, r); name != "second" {
+		t.Errorf("Expected the previous certificate to be kept, got %s", name)
+	}
+
+	// Garbage in the key file
+	if err := os.WriteFile(keyFile, []byte("not a key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.reload(); err == nil {
+		t.Error("Expected an error for an unparsable key")
+	}
+	if name := servedName(t, r); name != "second" {
+		t.Errorf("Expected the previous certificate to be kept, got %s", name)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sort"
+
+	"wallet-exporter/internal/config"
+)
+
+// Set at build time with -ldflags "-X main.version=... -X main.commit=... -X main.buildDate=...".
+// Without them the commit and date come from the VCS information the Go toolchain embeds.
+var (
+	version   = "dev"
+	commit    = ""
+	buildDate = ""
+)
+
+// buildInfo describes the binary, for support triage and checking where an image came from
+type buildInfo struct {
+	Version   string                     `json:"version"`
+	Commit    string                     `json:"commit"`
+	BuildDate string                     `json:"build_date"`
+	GoVersion string                     `json:"go_version"`
+	Platform  string                     `json:"platform"`
+	Networks  map[string]networkDefaults `json:"networks"`
+}
+
+// networkDefaults are the contract addresses compiled in for a network
+type networkDefaults struct {
+	RPCURL             string `json:"rpc_url"`
+	WarmStorageAddress string `json:"warm_storage_address"`
+	USDFCTokenAddress  string `json:"usdfc_token_address"`
+	PaymentsAddress    string `json:"payments_address"`
+	MulticallAddress   string `json:"multicall_address"`
+}
+
+func currentBuildInfo() buildInfo {
+	info := buildInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		Networks:  make(map[string]networkDefaults),
+	}
+	if embedded, ok := debug.ReadBuildInfo(); ok {
+		modified := false
+		for _, setting := range embedded.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = setting.Value
+				}
+			case "vcs.modified":
+				modified = setting.Value == "true"
+			}
+		}
+		if modified && commit == "" && info.Commit != "" {
+			info.Commit += "-dirty"
+		}
+	}
+	for network, defaults := range config.NetworkDefaults {
+		info.Networks[network] = networkDefaults{
+			RPCURL:             defaults.RPCURL,
+			WarmStorageAddress: defaults.WarmStorageAddress,
+			USDFCTokenAddress:  defaults.USDFCTokenAddress,
+			PaymentsAddress:    defaults.PaymentsAddress,
+			MulticallAddress:   config.DefaultMulticallAddress,
+		}
+	}
+	return info
+}
+
+func runVersion(args []string) int {
+	flags := flag.NewFlagSet("version", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print the build information as JSON")
+	flags.Parse(args)
+
+	info := currentBuildInfo()
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(info); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Failed to encode build information: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	fmt.Printf("wallet-exporter %s\n", info.Version)
+	fmt.Printf("  commit:     %s\n", orUnknown(info.Commit))
+	fmt.Printf("  built:      %s\n", orUnknown(info.BuildDate))
+	fmt.Printf("  go:         %s %s\n", info.GoVersion, info.Platform)
+
+	networks := make([]string, 0, len(info.Networks))
+	for network := range info.Networks {
+		networks = append(networks, network)
+	}
+	sort.Strings(networks)
+	for _, network := range networks {
+		defaults := info.Networks[network]
+		fmt.Printf("\n%s defaults:\n", network)
+		fmt.Printf("  RPC_URL               %s\n", defaults.RPCURL)
+		fmt.Printf("  WARM_STORAGE_ADDRESS  %s\n", defaults.WarmStorageAddress)
+		fmt.Printf("  USDFC_TOKEN_ADDRESS   %s\n", defaults.USDFCTokenAddress)
+		fmt.Printf("  PAYMENTS_ADDRESS      %s\n", defaults.PaymentsAddress)
+		fmt.Printf("  MULTICALL_ADDRESS     %s\n", defaults.MulticallAddress)
+	}
+	return 0
+}
+
+func orUnknown(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	return value
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+	"time"
+
+	"wallet-exporter/internal/exporter"
+	"wallet-exporter/internal/filaddr"
+)
+
+// walletViewEntry is one entry of a wallet on /wallets/{address}, with its raw balances
+type walletViewEntry struct {
+	walletDetail
+	FILAddress string         `json:"fil_address"`
+	Raw        rawWalletValue `json:"raw"`
+}
+
+// rawWalletValue holds balances as integers in the token's base unit (attoFIL for FIL)
+type rawWalletValue struct {
+	FILBalance        string            `json:"fil_balance"`
+	USDFCBalance      string            `json:"usdfc_balance"`
+	PayeeFILBalance   string            `json:"payee_fil_balance,omitempty"`
+	PayeeUSDFCBalance string            `json:"payee_usdfc_balance,omitempty"`
+	PaymentsAccounts  []rawPaymentsInfo `json:"payments_accounts"`
+}
+
+type rawPaymentsInfo struct {
+	Token      string `json:"token"`
+	Funds      string `json:"funds"`
+	Available  string `json:"available"`
+	Locked     string `json:"locked"`
+	LockupRate string `json:"lockup_rate"`
+}
+
+var walletViewTemplate = template.Must(template.New("wallet").Parse(`<!DOCTYPE html>
+<html>
+<head>
+    <title>{{.Title}} - {{.Address}}</title>
+    <style>
+        body { font-family: Arial, sans-serif; margin: 40px; }
+        body.light { background: #fff; color: #333; }
+        body.dark { background: #1e1e1e; color: #ddd; }
+        a { color: #0066cc; text-decoration: none; margin-right: 20px; }
+        body.dark a { color: #66aaff; }
+        a:hover { text-decoration: underline; }
+        table { border-collapse: collapse; margin: 8px 0 24px; }
+        th, td { padding: 4px 12px; text-align: left; border-bottom: 1px solid #8884; }
+        td.raw { font-family: monospace; color: #888; }
+        .failed { color: #c0392b; }
+    </style>
+</head>
+<body class="{{.Theme}}">
+    <div><a href="/">&larr; All wallets</a><a href="?format=json">JSON</a></div>
+    <p>Network: {{.Network}} &middot; Last scrape: {{.LastScrape}}</p>
+    {{- range .Entries}}
+    <h1>{{.Name}} <small>({{.Type}})</small></h1>
+    <table>
+        <tr><th>Address</th><td>{{.Address}}</td></tr>
+        <tr><th>Filecoin address</th><td>{{.FILAddress}}</td></tr>
+        {{- if .Payee}}
+        <tr><th>Provider ID</th><td>{{.ProviderID}}</td></tr>
+        <tr><th>Payee</th><td>{{.Payee}}</td></tr>
+        <tr><th>Active</th><td>{{.IsActive}}</td></tr>
+        <tr><th>Approved</th><td>{{.IsApproved}}</td></tr>
+        {{- if .Description}}<tr><th>Description</th><td>{{.Description}}</td></tr>{{end}}
+        {{- if .Region}}<tr><th>Region</th><td>{{.Region}}</td></tr>{{end}}
+        {{- end}}
+    </table>
+
+    <h2>Balances</h2>
+    <table>
+        <tr><th></th><th>Amount</th><th>Base units</th><th>Minimum</th></tr>
+        <tr><th>FIL</th><td>{{.FILBalance}}</td><td class="raw">{{.Raw.FILBalance}}</td><td>{{if .MinFIL}}{{.MinFIL}}{{end}}</td></tr>
+        <tr><th>USDFC</th><td>{{.USDFCBalance}}</td><td class="raw">{{.Raw.USDFCBalance}}</td><td>{{if .MinUSDFC}}{{.MinUSDFC}}{{end}}</td></tr>
+        {{- if .PayeeFILBalance}}
+        <tr><th>Payee FIL</th><td>{{.PayeeFILBalance}}</td><td class="raw">{{.Raw.PayeeFILBalance}}</td><td></td></tr>
+        {{- end}}
+        {{- if .PayeeUSDFCBalance}}
+        <tr><th>Payee USDFC</th><td>{{.PayeeUSDFCBalance}}</td><td class="raw">{{.Raw.PayeeUSDFCBalance}}</td><td></td></tr>
+        {{- end}}
+    </table>
+
+    {{- if .PaymentsAccounts}}
+    <h2>Payments</h2>
+    <table>
+        <tr><th>Token</th><th>Funds</th><th>Available</th><th>Locked</th><th>Lockup rate / epoch</th><th>Funded until (epoch)</th></tr>
+        {{- range .PaymentsAccounts}}
+        <tr><td>{{.Token}}</td><td>{{.Funds}}</td><td>{{.Available}}</td><td>{{.Locked}}</td><td>{{.LockupRate}}</td><td>{{.FundedUntilEpoch}}</td></tr>
+        {{- end}}
+    </table>
+    {{- end}}
+
+    {{- if .Products}}
+    <h2>Products</h2>
+    <table>
+        <tr><th>Type</th><th>Active</th><th>Capabilities</th></tr>
+        {{- range .Products}}
+        <tr><td>{{.Type}}</td><td>{{.IsActive}}</td><td>{{range $key, $value := .Capabilities}}{{$key}}={{$value}}<br>{{end}}</td></tr>
+        {{- end}}
+    </table>
+    {{- end}}
+
+    {{- if .Pings}}
+    <h2>Pings</h2>
+    <table>
+        <tr><th>Product type</th><th>Service URL</th><th>Result</th><th>Duration (s)</th></tr>
+        {{- range .Pings}}
+        <tr><td>{{.ProductType}}</td><td>{{.ServiceURL}}</td><td{{if not .Success}} class="failed"{{end}}>{{if .Success}}ok{{else}}failed{{end}}</td><td>{{printf "%.3f" .DurationSeconds}}</td></tr>
+        {{- end}}
+    </table>
+    {{- end}}
+    {{- end}}
+</body>
+</html>
+`))
+
+// walletView serves /wallets/{address}: everything the last scrape knows about one wallet, as
+// HTML or, with ?format=json or Accept: application/json, as JSON. Like the dashboard it needs
+// no token, so the URL can be shared while triaging a wallet.
+func (a *walletAPI) walletView(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	address, ok := parseWalletAddress(r.PathValue("address"))
+	if !ok {
+		http.Error(w, "invalid address", http.StatusBadRequest)
+		return
+	}
+
+	pings := a.exp.GetPingResults()
+	var entries []walletViewEntry
+	for _, wallet := range a.exp.GetWallets() {
+		if wallet.Address == address {
+			entries = append(entries, walletViewEntry{
+				walletDetail: a.detail(wallet, pings),
+				FILAddress:   filaddr.FromEth(wallet.Address, a.cfg.Network),
+				Raw:          newRawWalletValue(wallet),
+			})
+		}
+	}
+	if len(entries) == 0 {
+		http.Error(w, "wallet not found", http.StatusNotFound)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "json" || wantsJSON(r) {
+		a.writeJSON(w, entries)
+		return
+	}
+
+	lastScrape := "never"
+	if t := a.exp.GetLastScrape(); !t.IsZero() {
+		lastScrape = t.Format(time.RFC3339)
+	}
+
+	w.Header().Set("Content-Type", "text/html")
+	err := walletViewTemplate.Execute(w, map[string]any{
+		"Title":      a.cfg.StatusTitle,
+		"Theme":      a.cfg.StatusTheme,
+		"Network":    a.cfg.Network,
+		"LastScrape": lastScrape,
+		"Address":    address.Hex(),
+		"Entries":    entries,
+	})
+	if err != nil {
+		a.logger.Error("Failed to render wallet page", "error", err)
+	}
+}
+
+func newRawWalletValue(w exporter.WalletInfo) rawWalletValue {
+	raw := rawWalletValue{
+		FILBalance:       intString(w.FILBalance),
+		USDFCBalance:     intString(w.USDFCBalance),
+		PaymentsAccounts: []rawPaymentsInfo{},
+	}
+	if w.PayeeFILBalance != nil {
+		raw.PayeeFILBalance = w.PayeeFILBalance.String()
+	}
+	if w.PayeeUSDFCBalance != nil {
+		raw.PayeeUSDFCBalance = w.PayeeUSDFCBalance.String()
+	}
+	for _, account := range w.PaymentsAccounts {
+		if account.PaymentsInfo == nil {
+			continue
+		}
+		raw.PaymentsAccounts = append(raw.PaymentsAccounts, rawPaymentsInfo{
+			Token:      account.Token,
+			Funds:      intString(account.Funds),
+			Available:  intString(account.Available),
+			Locked:     intString(account.Locked),
+			LockupRate: intString(account.LockupRate),
+		})
+	}
+	return raw
+}
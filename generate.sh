@@ -40,4 +40,10 @@ abigen --abi contracts/Payments.abi \
        --type Payments \
        --out internal/contracts/payments.go
 
+# Generate Multicall3 binding
+abigen --abi contracts/Multicall3.abi \
+       --pkg contracts \
+       --type Multicall3 \
+       --out internal/contracts/multicall3.go
+
 echo "✅ Contract bindings generated successfully!"
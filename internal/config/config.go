@@ -2,78 +2,428 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
 	"os"
+	"path"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/joho/godotenv"
+
+	"wallet-exporter/internal/filaddr"
 )
 
 type Config struct {
-	Network               string
-	RPCURL                string
-	WarmStorageAddress    string
-	USDFCTokenAddress     string
-	PaymentsAddress       string
-	CustomWallets         []CustomWallet
-	ExporterPort          int
-	ScrapeInterval        time.Duration
-	MetricsPrefix         string
-	LogLevel              string
-	MaxConcurrentRequests int
+	Network                 string
+	RPCURL                  string
+	WarmStorageAddress      string
+	USDFCTokenAddress       string
+	PaymentsAddress         string
+	PaymentsTokens          []PaymentsToken // Token accounts queried in the Payments contract (USDFC first)
+	MulticallAddress        string
+	MulticallBatchSize      int // Calls per Multicall3 aggregate3 request (0 = disabled)
+	RPCBatchSize            int // Requests per JSON-RPC batch (0 = disabled)
+	CustomWallets           []CustomWallet
+	MultisigWallets         []CustomWallet // Custom wallets of type msig, identified by their f0 or f2 address
+	ExporterPort            int
+	HTTPAllowedCIDRs        []string      // Client networks allowed to reach the HTTP server (empty = all)
+	HTTPRateLimit           float64       // Requests per second per client to data and API endpoints (0 = unlimited)
+	HTTPRateBurst           int           // Requests a client may send at once before HTTPRateLimit applies
+	DebugPort               int           // Port serving net/http/pprof (0 = disabled)
+	TLSCertFile             string        // Serve HTTPS with this certificate (PEM, may include the chain)
+	TLSKeyFile              string        // Private key of TLSCertFile (PEM)
+	TLSClientCAFile         string        // Require client certificates issued by these CAs (PEM bundle)
+	TLSReloadInterval       time.Duration // How often the certificate files are checked for changes (0 = never)
+	ScrapeInterval          time.Duration
+	ScrapeTimeout           time.Duration // Deadline of a whole scrape; what was read by then is published (0 = none; unset = SCRAPE_INTERVAL)
+	ReadyMaxStaleness       time.Duration // /-/ready fails once the last successful scrape is older than this
+	ScrapeCooldown          time.Duration // Minimum time between the end of a scrape and a /-/scrape request
+	MetricsPrefix           string
+	MetricsCacheTTL         time.Duration // How long a rendered /metrics response is reused (0 = disabled)
+	LogLevel                string
+	MaxConcurrentRPC        int           // Concurrent RPC fetches (providers, wallets, rails, data sets)
+	MaxConcurrentPings      int           // Concurrent outbound HTTP pings of provider endpoints
+	ProviderFetchTimeout    time.Duration // Bound on fetching one provider's metadata and balances (0 = none)
+	RPCCallTimeout          time.Duration // Bound on every RPC call, including its retries (0 = none)
+	RPCToken                string        // Bearer token sent to the RPC endpoint
+	RPCTokenFile            string        // File the RPC token is read from, re-read every RPCTokenRefresh
+	RPCTokenCommand         string        // Command printing the RPC token, re-run every RPCTokenRefresh
+	RPCTokenRefresh         time.Duration
+	RPCRetryAttempts        int // Total attempts per RPC request, including the first
+	RPCRetryInitialBackoff  time.Duration
+	RPCRetryMaxBackoff      time.Duration
+	RPCCircuitThreshold     int           // Consecutive failed RPC probes before scrapes are skipped (0 = disabled)
+	MaxProvidersPerScrape   int           // 0 = unlimited
+	ApprovedProvidersPage   int           // Approved provider IDs read per call (0 = all in one call)
+	ProviderMetadataTTL     time.Duration // How long provider names, descriptions and products are cached (0 = disabled)
+	PingSpread              bool
+	WatchHeads              bool          // Refresh wallets on new heads when RPC_URL is a websocket
+	IncrementalRefresh      bool          // Reuse the balances of wallets no transaction or log touched since the last scrape
+	IncrementalFullRefresh  time.Duration // How often every balance is read again when IncrementalRefresh is enabled
+	QueryBlockTag           string        // Block scrapes read at: "latest" (the probed head), "safe" or "finalized"
+	ExportFinality          bool
+	ExportRails             bool
+	ExportPendingSettlement bool
+	ExportTVL               bool
+	ExportPaymentsEvents    bool
+	ExportTransfers         bool
+	ExportDataSets          bool
+	ExportRegistrations     bool
+	ExportMempool           bool
+	ExportRawBalances       bool          // Export balances split into parts float64 holds exactly
+	Explorer                string        // Block explorer wallets are enriched from: "filfox", "beryx" or "" (none)
+	ExplorerURL             string        // API base URL overriding the explorer's default for the network
+	ExplorerToken           string        // API token (Beryx)
+	ExplorerRefresh         time.Duration // How long explorer data of a wallet is reused before it is fetched again
+	RegistryStartBlock      int           // First block scanned for ProviderRegistered events
+	EventWalletSelectors    []string      // Selectors limiting which wallets get event tracking (empty = all)
+	RunwayHalfLife          time.Duration
+	OffboardingMinScrapes   int
+	DefaultMinFIL           float64 // Threshold applied to wallets without their own min_fil (0 = disabled)
+	DefaultMinUSDFC         float64 // Threshold applied to wallets without their own min_usdfc (0 = disabled)
+	StatusTitle             string
+	StatusLogoURL           string
+	StatusRefreshSeconds    int      // Auto-refresh interval of the status page (0 = disabled)
+	StatusTheme             string   // "light" or "dark"
+	StatusColumns           []string // Wallet table columns shown on the status page, in order
+	APITokens               []APIToken
+	ExternalURL             string // URL the exporter is reached at, used for links in notifications
+	TelegramBotToken        string // Send alert notifications through this Telegram bot
+	TelegramChatID          string // Chat the Telegram bot posts to
+	TelegramCriticalChatID  string // Chat critical alerts are posted to instead (empty = TelegramChatID)
+	SlackWebhookURL         string // Incoming webhook alerts are posted to unless a route matches
+	SlackRoutes             []SlackRoute
+	SlackTemplate           string // text/template of a Slack message (empty = built-in)
+	SMTPHost                string // Send alert notifications by email through this SMTP server
+	SMTPPort                int
+	SMTPUsername            string // PLAIN auth user (empty = no auth)
+	SMTPPassword            string
+	SMTPTLS                 string                   // "starttls", "tls" (implicit, usually port 465) or "none"
+	EmailFrom               string                   // Sender address
+	EmailTo                 []string                 // Recipient addresses
+	EmailCriticalTo         []string                 // Recipients of critical alerts instead of EmailTo (empty = EmailTo)
+	EmailSubjectTemplate    string                   // text/template of the subject (empty = built-in)
+	EmailBodyTemplate       string                   // text/template of the body (empty = built-in)
+	EmailDigest             bool                     // Send one summary a day instead of an email per alert
+	EmailDigestTime         string                   // Time of day (HH:MM, UTC) the digest is sent at
+	AlertRepeatInterval     time.Duration            // Notify alerts still firing again after this long (0 = never)
+	AlertRepeatIntervals    map[string]time.Duration // Repeat intervals overriding AlertRepeatInterval by rule
+	AlertCooldown           time.Duration            // Minimum time between two notifications of the same alert
+	AlertGroup              bool                     // One notification per rule and state instead of one per wallet
+	AlertRunwayWarningDays  float64                  // Warn when a Payments account is funded for at most this many days (0 = off)
+	AlertRunwayCriticalDays float64                  // Critical alert when a Payments account is funded for at most this many days (0 = off)
+	PingAlertFailures       int                      // Failed pings in a row before a provider_down alert (0 = off)
+	HistoryPath             string                   // BoltDB file every scrape is recorded in (empty = no history)
+	HistoryRetention        time.Duration            // Delete history records older than this (0 = keep forever)
+	RemoteWriteURL          string                   // Push every scrape to this Prometheus remote-write endpoint (empty = off)
+	RemoteWriteUsername     string                   // Basic auth user (empty = no basic auth)
+	RemoteWritePassword     string
+	RemoteWriteBearerToken  string            // Bearer token, instead of basic auth
+	RemoteWriteHeaders      map[string]string // Extra request headers, e.g. X-Scope-OrgID
+	RemoteWriteLabels       map[string]string // Labels added to every pushed series, e.g. job and instance
+	RemoteWriteTimeout      time.Duration
+	OTLPEndpoint            string            // Export metrics to this OpenTelemetry collector (empty = off)
+	OTLPProtocol            string            // "http/protobuf" or "grpc"
+	OTLPHeaders             map[string]string // Extra request headers (gRPC metadata), e.g. authorization
+	OTLPResourceAttributes  map[string]string // Resource attributes besides service.name=wallet-exporter
+	OTLPInterval            time.Duration     // Export every interval instead of after every scrape (0 = after every scrape)
+	OTLPTimeout             time.Duration
+	GraphiteAddress         string        // Send metrics to this Graphite plaintext (carbon) host:port (empty = off)
+	GraphitePrefix          string        // Path prefix of every Graphite metric
+	GraphiteInterval        time.Duration // Send every interval instead of after every scrape (0 = after every scrape)
+	GraphiteTags            bool          // Send labels as Graphite tags instead of path components
+	StatsDAddress           string        // Send metrics to this StatsD host:port over UDP (empty = off)
+	StatsDPrefix            string        // Path prefix of every StatsD metric
+	StatsDInterval          time.Duration // Flush every interval instead of after every scrape (0 = after every scrape)
+	StatsDTags              bool          // Send labels as DogStatsD tags instead of path components
+	TextfilePath            string        // Write the metrics to this .prom file for the node_exporter textfile collector (empty = off)
+	MetricsEndpoint         bool          // Serve /metrics; turn off when metrics only go out through push sinks
+	StateFile               string        // File the last scrape is saved to and restored from on startup (empty = off)
+	StateSaveInterval       time.Duration // Save the state after a scrape once this long passed since the last save (0 = on shutdown only)
 }
 
-type CustomWallet struct {
-	Address string
-	Name    string
-	Type    string // "client", "operator", "other"
+// SlackRoute posts the alerts of one rule, optionally only those of one severity, to their own
+// Slack incoming webhook, and so to the channel the webhook belongs to
+type SlackRoute struct {
+	Rule       string
+	Severity   string // Empty = every severity
+	WebhookURL string
 }
 
-func Load() (*Config, error) {
-	// Try to load .env file (ignore error if file doesn't exist)
-	_ = godotenv.Load()
+// API token scopes
+const (
+	ScopeRead           = "read"            // Read-only API endpoints
+	ScopeScrape         = "scrape"          // Trigger scrapes
+	ScopeManageWallets  = "manage-wallets"  // Modify the monitored wallet list
+	ScopeManageSilences = "manage-silences" // Modify alert silences
+)
 
-	// Default addresses per network
-	// Official contract addresses from Filecoin Synapse
+var apiScopes = map[string]bool{
+	ScopeRead: true, ScopeScrape: true, ScopeManageWallets: true, ScopeManageSilences: true,
+}
 
-	defaultRPC := map[string]string{
-		"calibration": "https://api.calibration.node.glif.io/rpc/v1",
-		"mainnet":     "https://api.node.glif.io/rpc/v1",
-	}
+// APIToken grants a bearer token access to the API endpoints of its scopes
+type APIToken struct {
+	Name   string
+	Token  string
+	Scopes []string
+}
 
-	defaultWarmStorage := map[string]string{
-		"calibration": "0x02925630df557F957f70E112bA06e50965417CA0",
-		"mainnet":     "0x8408502033C418E1bbC97cE9ac48E5528F371A9f",
+// HasScope reports whether the token was granted scope
+func (t APIToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
 	}
+	return false
+}
+
+// statusColumns are the wallet table columns the status page can show
+var statusColumns = map[string]bool{
+	"name": true, "address": true, "type": true, "provider_id": true, "active": true,
+	"approved": true, "fil": true, "usdfc": true, "payments": true, "funded_until": true, "ping": true,
+}
+
+// defaultStatusColumns are shown when STATUS_COLUMNS is not set
+var defaultStatusColumns = []string{"name", "type", "address", "fil", "usdfc", "payments", "funded_until", "approved", "ping"}
+
+// PaymentsToken is a token whose Payments contract account is monitored
+type PaymentsToken struct {
+	Symbol  string
+	Address string // Zero address for the native FIL account
+}
+
+type CustomWallet struct {
+	Address  string // 0x address (f410 addresses are converted when parsed)
+	Name     string
+	Type     string  // "client", "operator", "other"
+	MinFIL   float64 // Minimum FIL balance before the wallet is flagged (0 = use default)
+	MinUSDFC float64 // Minimum USDFC balance before the wallet is flagged (0 = use default)
+}
+
+// dotEnvKeys are the variables the last Load took from the .env file
+var (
+	dotEnvMu   sync.Mutex
+	dotEnvKeys = map[string]bool{}
+)
 
-	defaultUSDFC := map[string]string{
-		"calibration": "0xb3042734b608a1B16e9e86B374A3f3e389B4cDf0",
-		"mainnet":     "0x80B98d3aa09ffff255c3ba4A241111Ff1262F045",
+// loadDotEnv applies the .env file to the environment. Variables set in the process
+// environment take precedence; variables that came from .env are updated, and unset once they
+// are removed from it, so calling Load again picks up an edited .env file.
+func loadDotEnv() {
+	dotEnvMu.Lock()
+	defer dotEnvMu.Unlock()
+
+	values, err := godotenv.Read()
+	if err != nil {
+		// Ignore a missing .env file; keep what was applied before if it cannot be read
+		if !os.IsNotExist(err) {
+			return
+		}
+		values = map[string]string{}
 	}
 
-	// Filecoin Pay contract (Payments)
-	defaultPayments := map[string]string{
-		"calibration": "0x09a0fDc2723fAd1A7b8e3e00eE5DF73841df55a0",
-		"mainnet":     "0x23b1e018F08BB982348b15a86ee926eEBf7F4DAa",
+	for key := range dotEnvKeys {
+		if _, ok := values[key]; !ok {
+			os.Unsetenv(key)
+			delete(dotEnvKeys, key)
+		}
 	}
+	for key, value := range values {
+		if _, set := os.LookupEnv(key); set && !dotEnvKeys[key] {
+			continue
+		}
+		os.Setenv(key, value)
+		dotEnvKeys[key] = true
+	}
+}
+
+// Defaults are the RPC endpoint and contract addresses used for a network unless overridden
+type Defaults struct {
+	RPCURL             string
+	WarmStorageAddress string
+	USDFCTokenAddress  string
+	PaymentsAddress    string // Filecoin Pay contract
+}
+
+// NetworkDefaults are the defaults of every supported network, with the official contract
+// addresses from Filecoin Synapse
+var NetworkDefaults = map[string]Defaults{
+	"calibration": {
+		RPCURL:             "https://api.calibration.node.glif.io/rpc/v1",
+		WarmStorageAddress: "0x02925630df557F957f70E112bA06e50965417CA0",
+		USDFCTokenAddress:  "0xb3042734b608a1B16e9e86B374A3f3e389B4cDf0",
+		PaymentsAddress:    "0x09a0fDc2723fAd1A7b8e3e00eE5DF73841df55a0",
+	},
+	"mainnet": {
+		RPCURL:             "https://api.node.glif.io/rpc/v1",
+		WarmStorageAddress: "0x8408502033C418E1bbC97cE9ac48E5528F371A9f",
+		USDFCTokenAddress:  "0x80B98d3aa09ffff255c3ba4A241111Ff1262F045",
+		PaymentsAddress:    "0x23b1e018F08BB982348b15a86ee926eEBf7F4DAa",
+	},
+}
+
+// DefaultMulticallAddress is the Multicall3 address, which is the same on every network
+const DefaultMulticallAddress = "0xcA11bde05977b3631167028862bE2a173976CA11"
+
+func Load() (*Config, error) {
+	loadDotEnv()
 
 	network := getEnv("NETWORK", "calibration")
 
+	customWallets, err := parseCustomWallets()
+	if err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
 	cfg := &Config{
-		Network:               network,
-		RPCURL:                getEnv("RPC_URL", defaultRPC[network]),
-		WarmStorageAddress:    getEnv("WARM_STORAGE_ADDRESS", defaultWarmStorage[network]),
-		USDFCTokenAddress:     getEnv("USDFC_TOKEN_ADDRESS", defaultUSDFC[network]),
-		PaymentsAddress:       getEnv("PAYMENTS_ADDRESS", defaultPayments[network]),
-		CustomWallets:         parseCustomWallets(),
-		ExporterPort:          getEnvInt("EXPORTER_PORT", 9091),
-		ScrapeInterval:        getEnvDuration("SCRAPE_INTERVAL", 60*time.Second),
-		MetricsPrefix:         getEnv("METRICS_PREFIX", "dealbot"),
-		LogLevel:              getEnv("LOG_LEVEL", "info"),
-		MaxConcurrentRequests: getEnvInt("MAX_CONCURRENT_REQUESTS", 10),
+		Network:                 network,
+		RPCURL:                  getEnv("RPC_URL", NetworkDefaults[network].RPCURL),
+		WarmStorageAddress:      getEnv("WARM_STORAGE_ADDRESS", NetworkDefaults[network].WarmStorageAddress),
+		USDFCTokenAddress:       getEnv("USDFC_TOKEN_ADDRESS", NetworkDefaults[network].USDFCTokenAddress),
+		PaymentsAddress:         getEnv("PAYMENTS_ADDRESS", NetworkDefaults[network].PaymentsAddress),
+		MulticallAddress:        getEnv("MULTICALL_ADDRESS", DefaultMulticallAddress),
+		MulticallBatchSize:      getEnvInt("MULTICALL_BATCH_SIZE", 500),
+		RPCBatchSize:            getEnvInt("RPC_BATCH_SIZE", 100),
+		CustomWallets:           customWallets,
+		ExporterPort:            getEnvInt("EXPORTER_PORT", 9091),
+		HTTPAllowedCIDRs:        getEnvList("HTTP_ALLOWED_CIDRS"),
+		HTTPRateLimit:           getEnvFloat("HTTP_RATE_LIMIT", 0),
+		HTTPRateBurst:           getEnvInt("HTTP_RATE_BURST", 10),
+		DebugPort:               getEnvInt("DEBUG_PORT", 0),
+		TLSCertFile:             getEnv("TLS_CERT_FILE", ""),
+		TLSKeyFile:              getEnv("TLS_KEY_FILE", ""),
+		TLSClientCAFile:         getEnv("TLS_CLIENT_CA_FILE", ""),
+		TLSReloadInterval:       getEnvDuration("TLS_RELOAD_INTERVAL", 0),
+		ScrapeInterval:          getEnvDuration("SCRAPE_INTERVAL", 60*time.Second),
+		ScrapeTimeout:           getEnvDuration("SCRAPE_TIMEOUT", 0),
+		ReadyMaxStaleness:       getEnvDuration("READY_MAX_STALENESS", 0),
+		ScrapeCooldown:          getEnvDuration("SCRAPE_COOLDOWN", 10*time.Second),
+		MetricsPrefix:           getEnv("METRICS_PREFIX", "dealbot"),
+		MetricsCacheTTL:         getEnvDuration("METRICS_CACHE_TTL", 0),
+		LogLevel:                getEnv("LOG_LEVEL", "info"),
+		MaxConcurrentRPC:        getEnvInt("MAX_CONCURRENT_RPC", getEnvInt("MAX_CONCURRENT_REQUESTS", 10)),
+		MaxConcurrentPings:      getEnvInt("MAX_CONCURRENT_PINGS", getEnvInt("MAX_CONCURRENT_REQUESTS", 10)),
+		ProviderFetchTimeout:    getEnvDuration("PROVIDER_FETCH_TIMEOUT", time.Minute),
+		RPCCallTimeout:          getEnvDuration("RPC_CALL_TIMEOUT", 30*time.Second),
+		RPCToken:                getEnv("RPC_TOKEN", ""),
+		RPCTokenFile:            getEnv("RPC_TOKEN_FILE", ""),
+		RPCTokenCommand:         getEnv("RPC_TOKEN_COMMAND", ""),
+		RPCTokenRefresh:         getEnvDuration("RPC_TOKEN_REFRESH", time.Minute),
+		RPCRetryAttempts:        getEnvInt("RPC_RETRY_ATTEMPTS", 3),
+		RPCRetryInitialBackoff:  getEnvDuration("RPC_RETRY_INITIAL_BACKOFF", 500*time.Millisecond),
+		RPCRetryMaxBackoff:      getEnvDuration("RPC_RETRY_MAX_BACKOFF", 10*time.Second),
+		RPCCircuitThreshold:     getEnvInt("RPC_CIRCUIT_THRESHOLD", 3),
+		MaxProvidersPerScrape:   getEnvInt("MAX_PROVIDERS_PER_SCRAPE", 0),
+		ApprovedProvidersPage:   getEnvInt("APPROVED_PROVIDERS_PAGE_SIZE", 100),
+		ProviderMetadataTTL:     getEnvDuration("PROVIDER_METADATA_TTL", 0),
+		PingSpread:              getEnvBool("PING_SPREAD", false),
+		WatchHeads:              getEnvBool("WATCH_HEADS", true),
+		IncrementalRefresh:      getEnvBool("INCREMENTAL_REFRESH", false),
+		IncrementalFullRefresh:  getEnvDuration("INCREMENTAL_FULL_REFRESH_INTERVAL", 10*time.Minute),
+		QueryBlockTag:           getEnv("QUERY_BLOCK_TAG", "latest"),
+		ExportFinality:          getEnvBool("EXPORT_FINALITY", false),
+		ExportRails:             getEnvBool("EXPORT_RAILS", false),
+		ExportPendingSettlement: getEnvBool("EXPORT_PENDING_SETTLEMENT", false),
+		ExportTVL:               getEnvBool("EXPORT_TVL", false),
+		ExportPaymentsEvents:    getEnvBool("EXPORT_PAYMENTS_EVENTS", false),
+		ExportTransfers:         getEnvBool("EXPORT_TRANSFERS", false),
+		ExportDataSets:          getEnvBool("EXPORT_DATA_SETS", false),
+		ExportRegistrations:     getEnvBool("EXPORT_REGISTRATIONS", false),
+		ExportMempool:           getEnvBool("EXPORT_MEMPOOL", false),
+		ExportRawBalances:       getEnvBool("EXPORT_RAW_BALANCES", false),
+		Explorer:                getEnv("EXPLORER", ""),
+		ExplorerURL:             getEnv("EXPLORER_URL", ""),
+		ExplorerToken:           getEnv("EXPLORER_TOKEN", ""),
+		ExplorerRefresh:         getEnvDuration("EXPLORER_REFRESH", time.Hour),
+		RegistryStartBlock:      getEnvInt("REGISTRY_START_BLOCK", 0),
+		EventWalletSelectors:    getEnvList("EVENT_WALLET_SELECTORS"),
+		RunwayHalfLife:          getEnvDuration("RUNWAY_HALF_LIFE", 24*time.Hour),
+		OffboardingMinScrapes:   getEnvInt("OFFBOARDING_MIN_SCRAPES", 3),
+		DefaultMinFIL:           getEnvFloat("DEFAULT_MIN_FIL", 0),
+		DefaultMinUSDFC:         getEnvFloat("DEFAULT_MIN_USDFC", 0),
+		StatusTitle:             getEnv("STATUS_TITLE", "Dealbot Wallet Exporter"),
+		StatusLogoURL:           getEnv("STATUS_LOGO_URL", ""),
+		StatusRefreshSeconds:    getEnvInt("STATUS_REFRESH_SECONDS", 0),
+		StatusTheme:             getEnv("STATUS_THEME", "light"),
+		StatusColumns:           getEnvList("STATUS_COLUMNS"),
+		APITokens:               parseAPITokens(),
+		ExternalURL:             getEnv("EXTERNAL_URL", ""),
+		TelegramBotToken:        getEnv("TELEGRAM_BOT_TOKEN", ""),
+		TelegramChatID:          getEnv("TELEGRAM_CHAT_ID", ""),
+		TelegramCriticalChatID:  getEnv("TELEGRAM_CRITICAL_CHAT_ID", ""),
+		SlackWebhookURL:         getEnv("SLACK_WEBHOOK_URL", ""),
+		SlackRoutes:             parseSlackRoutes(),
+		SlackTemplate:           getEnv("SLACK_TEMPLATE", ""),
+		SMTPHost:                getEnv("SMTP_HOST", ""),
+		SMTPPort:                getEnvInt("SMTP_PORT", 587),
+		SMTPUsername:            getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:            getEnv("SMTP_PASSWORD", ""),
+		SMTPTLS:                 getEnv("SMTP_TLS", "starttls"),
+		EmailFrom:               getEnv("EMAIL_FROM", ""),
+		EmailTo:                 getEnvList("EMAIL_TO"),
+		EmailCriticalTo:         getEnvList("EMAIL_CRITICAL_TO"),
+		EmailSubjectTemplate:    getEnv("EMAIL_SUBJECT_TEMPLATE", ""),
+		EmailBodyTemplate:       getEnv("EMAIL_BODY_TEMPLATE", ""),
+		EmailDigest:             getEnvBool("EMAIL_DIGEST", false),
+		EmailDigestTime:         getEnv("EMAIL_DIGEST_TIME", "08:00"),
+		AlertRepeatInterval:     getEnvDuration("ALERT_REPEAT_INTERVAL", 0),
+		AlertRepeatIntervals:    parseAlertRepeatIntervals(),
+		AlertCooldown:           getEnvDuration("ALERT_COOLDOWN", 0),
+		AlertGroup:              getEnvBool("ALERT_GROUP", false),
+		AlertRunwayWarningDays:  getEnvFloat("ALERT_RUNWAY_WARNING_DAYS", 14),
+		AlertRunwayCriticalDays: getEnvFloat("ALERT_RUNWAY_CRITICAL_DAYS", 3),
+		PingAlertFailures:       getEnvInt("PING_ALERT_FAILURES", 3),
+		HistoryPath:             getEnv("HISTORY_PATH", ""),
+		HistoryRetention:        getEnvDuration("HISTORY_RETENTION", 365*24*time.Hour),
+		RemoteWriteURL:          getEnv("REMOTE_WRITE_URL", ""),
+		RemoteWriteUsername:     getEnv("REMOTE_WRITE_USERNAME", ""),
+		RemoteWritePassword:     getEnv("REMOTE_WRITE_PASSWORD", ""),
+		RemoteWriteBearerToken:  getEnv("REMOTE_WRITE_BEARER_TOKEN", ""),
+		RemoteWriteHeaders:      parsePairs("REMOTE_WRITE_HEADERS", ":"),
+		RemoteWriteLabels:       parsePairs("REMOTE_WRITE_LABELS", "="),
+		RemoteWriteTimeout:      getEnvDuration("REMOTE_WRITE_TIMEOUT", 30*time.Second),
+		OTLPEndpoint:            getEnv("OTLP_ENDPOINT", ""),
+		OTLPProtocol:            getEnv("OTLP_PROTOCOL", "http/protobuf"),
+		OTLPHeaders:             parsePairs("OTLP_HEADERS", "="),
+		OTLPResourceAttributes:  parsePairs("OTLP_RESOURCE_ATTRIBUTES", "="),
+		OTLPInterval:            getEnvDuration("OTLP_INTERVAL", 0),
+		OTLPTimeout:             getEnvDuration("OTLP_TIMEOUT", 10*time.Second),
+		GraphiteAddress:         getEnv("GRAPHITE_ADDRESS", ""),
+		GraphitePrefix:          getEnv("GRAPHITE_PREFIX", ""),
+		GraphiteInterval:        getEnvDuration("GRAPHITE_INTERVAL", 0),
+		GraphiteTags:            getEnvBool("GRAPHITE_TAGS", false),
+		StatsDAddress:           getEnv("STATSD_ADDRESS", ""),
+		StatsDPrefix:            getEnv("STATSD_PREFIX", ""),
+		StatsDInterval:          getEnvDuration("STATSD_INTERVAL", 0),
+		StatsDTags:              getEnvBool("STATSD_TAGS", false),
+		TextfilePath:            getEnv("TEXTFILE_PATH", ""),
+		MetricsEndpoint:         getEnvBool("METRICS_ENDPOINT", true),
+		StateFile:               getEnv("STATE_FILE", ""),
+		StateSaveInterval:       getEnvDuration("STATE_SAVE_INTERVAL", 5*time.Minute),
 	}
 
+	if len(cfg.StatusColumns) == 0 {
+		cfg.StatusColumns = defaultStatusColumns
+	}
+	if cfg.ReadyMaxStaleness == 0 {
+		cfg.ReadyMaxStaleness = 3 * cfg.ScrapeInterval
+	}
+	// An explicit SCRAPE_TIMEOUT=0 turns the deadline off, so only an unset one defaults
+	if os.Getenv("SCRAPE_TIMEOUT") == "" {
+		cfg.ScrapeTimeout = cfg.ScrapeInterval
+	}
+
+	cfg.PaymentsTokens = parsePaymentsTokens(getEnv("PAYMENTS_TOKENS", ""), cfg.USDFCTokenAddress)
+	cfg.CustomWallets, cfg.MultisigWallets = splitMultisigWallets(cfg.CustomWallets)
+
 	if err := cfg.Validate(); err != nil {
 		return nil, fmt.Errorf("config validation failed: %w", err)
 	}
@@ -81,57 +431,252 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// parsePaymentsTokens parses "SYMBOL:address" entries separated by commas.
+// The USDFC account is always monitored and comes first.
+//
+// Example:
+//
+//	PAYMENTS_TOKENS=FIL:0x0000000000000000000000000000000000000000,USDFC:0x80B9...
+func parsePaymentsTokens(tokensStr, usdfcAddress string) []PaymentsToken {
+	tokens := []PaymentsToken{{Symbol: "USDFC", Address: usdfcAddress}}
+
+	for _, entry := range strings.Split(tokensStr, ",") {
+		symbol, address, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok {
+			continue
+		}
+		symbol = strings.TrimSpace(symbol)
+		address = strings.TrimSpace(address)
+		if symbol == "" || address == "" || strings.EqualFold(address, usdfcAddress) {
+			continue
+		}
+		tokens = append(tokens, PaymentsToken{Symbol: symbol, Address: address})
+	}
+
+	return tokens
+}
+
+// parseAPITokens parses API_TOKEN_1, API_TOKEN_2, ... entries of the form "name:token:scope1,scope2"
+//
+// Example:
+//
+//	API_TOKEN_1=oncall-bot:s3cr3t:read,scrape
+//	API_TOKEN_2=admin:t0ps3cr3t:read,scrape,manage-wallets,manage-silences
+func parseAPITokens() []APIToken {
+	var tokens []APIToken
+	for i := 1; i <= 100; i++ {
+		entry := os.Getenv(fmt.Sprintf("API_TOKEN_%d", i))
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) < 3 {
+			continue
+		}
+
+		token := APIToken{
+			Name:  strings.TrimSpace(parts[0]),
+			Token: strings.TrimSpace(parts[1]),
+		}
+		for _, scope := range strings.Split(parts[2], ",") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				token.Scopes = append(token.Scopes, scope)
+			}
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+// parseSlackRoutes parses SLACK_ROUTE_1, SLACK_ROUTE_2, ... entries of the form
+// "rule:webhook_url" or "rule/severity:webhook_url".
+//
+// Example:
+//
+//	SLACK_ROUTE_1=below_threshold:https://hooks.slack.com/services/T000/B000/XXXX
+//	SLACK_ROUTE_2=payments_runway/critical:https://hooks.slack.com/services/T000/B001/YYYY
+func parseSlackRoutes() []SlackRoute {
+	var routes []SlackRoute
+	for i := 1; i <= 100; i++ {
+		entry := os.Getenv(fmt.Sprintf("SLACK_ROUTE_%d", i))
+		if entry == "" {
+			continue
+		}
+
+		rule, webhookURL, _ := strings.Cut(entry, ":")
+		rule, severity, _ := strings.Cut(rule, "/")
+		routes = append(routes, SlackRoute{
+			Rule:       strings.TrimSpace(rule),
+			Severity:   strings.TrimSpace(severity),
+			WebhookURL: strings.TrimSpace(webhookURL),
+		})
+	}
+	return routes
+}
+
+// parseAlertRepeatIntervals parses ALERT_REPEAT_INTERVALS, a comma-separated list of
+// "rule:duration" entries. Malformed durations are kept as -1 so Validate rejects them.
+//
+// Example:
+//
+//	ALERT_REPEAT_INTERVALS=below_threshold:12h
+func parseAlertRepeatIntervals() map[string]time.Duration {
+	intervals := make(map[string]time.Duration)
+	for _, entry := range getEnvList("ALERT_REPEAT_INTERVALS") {
+		rule, value, _ := strings.Cut(entry, ":")
+		interval, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			interval = -1
+		}
+		intervals[strings.TrimSpace(rule)] = interval
+	}
+	return intervals
+}
+
+// parsePairs parses key, a comma-separated list of "name<sep>value" entries. Entries without
+// a name are kept under the empty name so Validate rejects them.
+//
+// Example:
+//
+//	REMOTE_WRITE_HEADERS=X-Scope-OrgID:wallets
+//	REMOTE_WRITE_LABELS=job=wallet-exporter,instance=nat-gateway-1
+func parsePairs(key, sep string) map[string]string {
+	pairs := make(map[string]string)
+	for _, entry := range getEnvList(key) {
+		name, value, _ := strings.Cut(entry, sep)
+		pairs[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return pairs
+}
+
 // parseCustomWallets parses custom wallet configuration
 // Supports two formats:
 //  1. Legacy format (CUSTOM_WALLETS): "address1:name1:type1,address2:name2:type2,..."
 //  2. Multi-line format (recommended): CUSTOM_WALLET_1, CUSTOM_WALLET_2, ...
 //     Each line format: "address:name:type" or "address:name" (type defaults to "other")
 //
+// Optional per-wallet thresholds can follow the type as key=value pairs (min_fil, min_usdfc).
+// The address may be given in its f410 form and is converted to the 0x form used for queries.
+//
 // Example:
 //
 //	CUSTOM_WALLET_1=0x123...:Client A:client
-//	CUSTOM_WALLET_2=0x456...:Operator B:operator
-func parseCustomWallets() []CustomWallet {
+//	CUSTOM_WALLET_2=0x456...:Operator B:operator:min_fil=10:min_usdfc=100
+func parseCustomWallets() ([]CustomWallet, error) {
 	var wallets []CustomWallet
 
 	// First, check for legacy CUSTOM_WALLETS format (for backward compatibility)
 	if legacyWallets := getEnv("CUSTOM_WALLETS", ""); legacyWallets != "" {
-		wallets = append(wallets, parseLegacyFormat(legacyWallets)...)
+		legacy, err := parseLegacyFormat(legacyWallets)
+		if err != nil {
+			return nil, err
+		}
+		wallets = append(wallets, legacy...)
 	}
 
 	// Then, check for new CUSTOM_WALLET_N format
 	for i := 1; i <= 1000; i++ { // Support up to 1000 custom wallets
 		key := fmt.Sprintf("CUSTOM_WALLET_%d", i)
 		if walletStr := os.Getenv(key); walletStr != "" {
-			if wallet := parseWalletEntry(walletStr); wallet != nil {
+			wallet, err := parseWalletEntry(walletStr)
+			if err != nil {
+				return nil, err
+			}
+			if wallet != nil {
 				wallets = append(wallets, *wallet)
 			}
 		}
 	}
 
-	return wallets
+	return mergeDuplicateWallets(wallets)
+}
+
+// mergeDuplicateWallets folds wallets listed more than once into their first entry, so each
+// address is read once and exported as one series. The names are joined and the strictest
+// thresholds kept. Entries of one address with different types are an error, since only one
+// type could be exported.
+func mergeDuplicateWallets(wallets []CustomWallet) ([]CustomWallet, error) {
+	merged := make([]CustomWallet, 0, len(wallets))
+	index := make(map[string]int, len(wallets))
+	for _, wallet := range wallets {
+		key := strings.ToLower(wallet.Address)
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(merged)
+			merged = append(merged, wallet)
+			continue
+		}
+
+		first := &merged[i]
+		if wallet.Type != first.Type {
+			return nil, fmt.Errorf("custom wallet %q (%s) is listed again as %q (%s); give every entry of an address the same type",
+				first.Name, first.Type, wallet.Name, wallet.Type)
+		}
+		if !slices.Contains(strings.Split(first.Name, " / "), wallet.Name) {
+			first.Name += " / " + wallet.Name
+		}
+		first.MinFIL = max(first.MinFIL, wallet.MinFIL)
+		first.MinUSDFC = max(first.MinUSDFC, wallet.MinUSDFC)
+	}
+	return merged, nil
+}
+
+// validateWalletAddress checks that a custom wallet address is 20 bytes of hex and, if it is
+// written in mixed case, that it matches its EIP-55 checksum. Anything else would be read as
+// some other account.
+func validateWalletAddress(address string) error {
+	if !strings.HasPrefix(address, "0x") || !common.IsHexAddress(address) {
+		return fmt.Errorf("address %q is not a 0x address of 40 hex digits (or an f410 address)", address)
+	}
+	digits := address[2:]
+	if digits != strings.ToLower(digits) && digits != strings.ToUpper(digits) {
+		if checksummed := common.HexToAddress(address).Hex(); address != checksummed {
+			return fmt.Errorf("address %q fails its EIP-55 checksum (expected %s); check it for typos", address, checksummed)
+		}
+	}
+	return nil
+}
+
+// splitMultisigWallets separates the multisig actors from the EVM wallets, since they are
+// queried through the Filecoin API instead of eth_ calls
+func splitMultisigWallets(wallets []CustomWallet) (evm, multisig []CustomWallet) {
+	for _, wallet := range wallets {
+		if wallet.Type == "msig" {
+			multisig = append(multisig, wallet)
+		} else {
+			evm = append(evm, wallet)
+		}
+	}
+	return evm, multisig
 }
 
 // parseLegacyFormat parses the old comma-separated format
-func parseLegacyFormat(walletsStr string) []CustomWallet {
+func parseLegacyFormat(walletsStr string) ([]CustomWallet, error) {
 	var wallets []CustomWallet
 	entries := strings.Split(walletsStr, ",")
 
 	for _, entry := range entries {
-		if wallet := parseWalletEntry(entry); wallet != nil {
+		wallet, err := parseWalletEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		if wallet != nil {
 			wallets = append(wallets, *wallet)
 		}
 	}
 
-	return wallets
+	return wallets, nil
 }
 
 // parseWalletEntry parses a single wallet entry
-// Format: "address:name:type" or "address:name"
-func parseWalletEntry(entry string) *CustomWallet {
+// Format: "address:name:type[:key=value...]" or "address:name"
+// Entries without a name are skipped (nil wallet, nil error); an f410 address that does not
+// convert is an error, since the wallet would otherwise silently go unmonitored.
+func parseWalletEntry(entry string) (*CustomWallet, error) {
 	parts := strings.Split(strings.TrimSpace(entry), ":")
 	if len(parts) < 2 {
-		return nil
+		return nil, nil
 	}
 
 	wallet := &CustomWallet{
@@ -140,11 +685,38 @@ func parseWalletEntry(entry string) *CustomWallet {
 		Type:    "other",
 	}
 
+	if filaddr.IsDelegated(wallet.Address) {
+		address, err := filaddr.ToEth(wallet.Address)
+		if err != nil {
+			return nil, fmt.Errorf("custom wallet %q: %w", wallet.Name, err)
+		}
+		wallet.Address = address
+	}
+
 	if len(parts) >= 3 && strings.TrimSpace(parts[2]) != "" {
 		wallet.Type = strings.TrimSpace(parts[2])
 	}
 
-	return wallet
+	for _, option := range parts[min(len(parts), 3):] {
+		key, value, ok := strings.Cut(strings.TrimSpace(option), "=")
+		if !ok {
+			continue
+		}
+
+		threshold, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || threshold < 0 {
+			continue
+		}
+
+		switch strings.TrimSpace(key) {
+		case "min_fil":
+			wallet.MinFIL = threshold
+		case "min_usdfc":
+			wallet.MinUSDFC = threshold
+		}
+	}
+
+	return wallet, nil
 }
 
 func (c *Config) Validate() error {
@@ -154,15 +726,322 @@ func (c *Config) Validate() error {
 	if c.WarmStorageAddress == "" {
 		return fmt.Errorf("WARM_STORAGE_ADDRESS is required")
 	}
-	if c.ExporterPort <= 0 || c.ExporterPort > 65535 {
-		return fmt.Errorf("EXPORTER_PORT must be between 1 and 65535")
+	if c.ExporterPort < 0 || c.ExporterPort > 65535 {
+		return fmt.Errorf("EXPORTER_PORT must be between 0 and 65535")
+	}
+	for _, cidr := range c.HTTPAllowedCIDRs {
+		if _, err := parseCIDR(cidr); err != nil {
+			return fmt.Errorf("HTTP_ALLOWED_CIDRS: %w", err)
+		}
+	}
+	if c.HTTPRateLimit < 0 {
+		return fmt.Errorf("HTTP_RATE_LIMIT must not be negative")
+	}
+	if c.HTTPRateLimit > 0 && c.HTTPRateBurst < 1 {
+		return fmt.Errorf("HTTP_RATE_BURST must be at least 1")
 	}
-	if c.MaxConcurrentRequests <= 0 || c.MaxConcurrentRequests > 1000 {
-		return fmt.Errorf("MAX_CONCURRENT_REQUESTS must be between 1 and 1000")
+	if c.DebugPort < 0 || c.DebugPort > 65535 {
+		return fmt.Errorf("DEBUG_PORT must be between 0 and 65535")
+	}
+	if c.DebugPort > 0 && c.DebugPort == c.ExporterPort {
+		return fmt.Errorf("DEBUG_PORT must differ from EXPORTER_PORT")
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if c.MetricsCacheTTL < 0 {
+		return fmt.Errorf("METRICS_CACHE_TTL must not be negative")
+	}
+	if c.ScrapeCooldown < 0 {
+		return fmt.Errorf("SCRAPE_COOLDOWN must not be negative")
+	}
+	if c.ScrapeTimeout < 0 {
+		return fmt.Errorf("SCRAPE_TIMEOUT must not be negative")
+	}
+	if c.ReadyMaxStaleness < 0 {
+		return fmt.Errorf("READY_MAX_STALENESS must not be negative")
+	}
+	if c.TLSClientCAFile != "" && c.TLSCertFile == "" {
+		return fmt.Errorf("TLS_CLIENT_CA_FILE requires TLS_CERT_FILE and TLS_KEY_FILE")
+	}
+	if c.TLSReloadInterval < 0 {
+		return fmt.Errorf("TLS_RELOAD_INTERVAL must not be negative")
+	}
+	if c.MaxConcurrentRPC <= 0 || c.MaxConcurrentRPC > 1000 {
+		return fmt.Errorf("MAX_CONCURRENT_RPC must be between 1 and 1000")
+	}
+	if c.MaxConcurrentPings <= 0 || c.MaxConcurrentPings > 1000 {
+		return fmt.Errorf("MAX_CONCURRENT_PINGS must be between 1 and 1000")
+	}
+	if c.ProviderFetchTimeout < 0 {
+		return fmt.Errorf("PROVIDER_FETCH_TIMEOUT must not be negative")
+	}
+	if c.IncrementalRefresh && c.IncrementalFullRefresh <= 0 {
+		return fmt.Errorf("INCREMENTAL_FULL_REFRESH_INTERVAL must be positive when INCREMENTAL_REFRESH is enabled")
+	}
+	if c.QueryBlockTag != "latest" && c.QueryBlockTag != "safe" && c.QueryBlockTag != "finalized" {
+		return fmt.Errorf("QUERY_BLOCK_TAG must be latest, safe or finalized")
+	}
+	if c.RPCCallTimeout < 0 {
+		return fmt.Errorf("RPC_CALL_TIMEOUT must not be negative")
+	}
+	if (c.RPCToken != "" && c.RPCTokenFile != "") || (c.RPCToken != "" && c.RPCTokenCommand != "") || (c.RPCTokenFile != "" && c.RPCTokenCommand != "") {
+		return fmt.Errorf("only one of RPC_TOKEN, RPC_TOKEN_FILE and RPC_TOKEN_COMMAND may be set")
+	}
+	if c.RPCTokenRefresh <= 0 {
+		return fmt.Errorf("RPC_TOKEN_REFRESH must be positive")
+	}
+	if c.RPCRetryAttempts < 1 {
+		return fmt.Errorf("RPC_RETRY_ATTEMPTS must be at least 1")
+	}
+	if c.RPCRetryInitialBackoff <= 0 || c.RPCRetryMaxBackoff < c.RPCRetryInitialBackoff {
+		return fmt.Errorf("RPC_RETRY_INITIAL_BACKOFF must be positive and not exceed RPC_RETRY_MAX_BACKOFF")
+	}
+	if c.RPCCircuitThreshold < 0 {
+		return fmt.Errorf("RPC_CIRCUIT_THRESHOLD must not be negative")
+	}
+	if c.MulticallBatchSize < 0 {
+		return fmt.Errorf("MULTICALL_BATCH_SIZE must not be negative")
+	}
+	if c.RPCBatchSize < 0 {
+		return fmt.Errorf("RPC_BATCH_SIZE must not be negative")
+	}
+	if c.MaxProvidersPerScrape < 0 {
+		return fmt.Errorf("MAX_PROVIDERS_PER_SCRAPE must not be negative")
+	}
+	if c.ApprovedProvidersPage < 0 {
+		return fmt.Errorf("APPROVED_PROVIDERS_PAGE_SIZE must not be negative")
+	}
+	if c.ProviderMetadataTTL < 0 {
+		return fmt.Errorf("PROVIDER_METADATA_TTL must not be negative")
+	}
+	for _, wallet := range c.CustomWallets {
+		if err := validateWalletAddress(wallet.Address); err != nil {
+			return fmt.Errorf("custom wallet %q: %w", wallet.Name, err)
+		}
+	}
+	for _, wallet := range c.MultisigWallets {
+		if protocol, ok := filaddr.Protocol(wallet.Address); !ok || (protocol != filaddr.ProtocolID && protocol != filaddr.ProtocolActor) {
+			return fmt.Errorf("msig wallet %q must have an f0 or f2 address", wallet.Name)
+		}
+	}
+	if c.DefaultMinFIL < 0 || c.DefaultMinUSDFC < 0 {
+		return fmt.Errorf("DEFAULT_MIN_FIL and DEFAULT_MIN_USDFC must not be negative")
+	}
+	if c.RegistryStartBlock < 0 {
+		return fmt.Errorf("REGISTRY_START_BLOCK must not be negative")
+	}
+	if c.ExportPendingSettlement && !c.ExportRails {
+		return fmt.Errorf("EXPORT_PENDING_SETTLEMENT requires EXPORT_RAILS")
+	}
+	for _, selector := range c.EventWalletSelectors {
+		key, pattern, ok := strings.Cut(selector, ":")
+		if !ok {
+			pattern = key
+		} else if key != "name" && key != "address" && key != "type" {
+			return fmt.Errorf("EVENT_WALLET_SELECTORS: unknown selector key %q (use name, address, or type)", key)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("EVENT_WALLET_SELECTORS: invalid pattern %q: %w", pattern, err)
+		}
+	}
+	if c.Explorer != "" && c.Explorer != "filfox" && c.Explorer != "beryx" {
+		return fmt.Errorf("EXPLORER must be filfox, beryx or empty")
+	}
+	if c.ExplorerRefresh <= 0 {
+		return fmt.Errorf("EXPLORER_REFRESH must be positive")
+	}
+	if c.StatusRefreshSeconds < 0 {
+		return fmt.Errorf("STATUS_REFRESH_SECONDS must not be negative")
+	}
+	if c.StatusTheme != "light" && c.StatusTheme != "dark" {
+		return fmt.Errorf("STATUS_THEME must be light or dark")
+	}
+	for _, column := range c.StatusColumns {
+		if !statusColumns[column] {
+			return fmt.Errorf("STATUS_COLUMNS: unknown column %q", column)
+		}
+	}
+	seenTokens := make(map[string]bool, len(c.APITokens))
+	for _, token := range c.APITokens {
+		if token.Name == "" || token.Token == "" {
+			return fmt.Errorf("API tokens require a name and a token")
+		}
+		if seenTokens[token.Token] {
+			return fmt.Errorf("API token %q reuses the token of another entry", token.Name)
+		}
+		seenTokens[token.Token] = true
+		for _, scope := range token.Scopes {
+			if !apiScopes[scope] {
+				return fmt.Errorf("API token %q: unknown scope %q", token.Name, scope)
+			}
+		}
+	}
+	if c.OffboardingMinScrapes <= 0 {
+		return fmt.Errorf("OFFBOARDING_MIN_SCRAPES must be positive")
+	}
+	if c.RunwayHalfLife <= 0 {
+		return fmt.Errorf("RUNWAY_HALF_LIFE must be positive")
+	}
+	if (c.TelegramBotToken == "") != (c.TelegramChatID == "") {
+		return fmt.Errorf("TELEGRAM_BOT_TOKEN and TELEGRAM_CHAT_ID must be set together")
+	}
+	if c.SlackWebhookURL != "" && !isHTTPURL(c.SlackWebhookURL) {
+		return fmt.Errorf("SLACK_WEBHOOK_URL must be an http or https URL")
+	}
+	for _, route := range c.SlackRoutes {
+		if route.Rule == "" || !isHTTPURL(route.WebhookURL) {
+			return fmt.Errorf("SLACK_ROUTE_N must be rule:webhook_url or rule/severity:webhook_url")
+		}
+	}
+	if c.SMTPHost != "" {
+		if c.EmailFrom == "" || len(c.EmailTo) == 0 {
+			return fmt.Errorf("SMTP_HOST requires EMAIL_FROM and EMAIL_TO")
+		}
+		if c.SMTPPort <= 0 || c.SMTPPort > 65535 {
+			return fmt.Errorf("SMTP_PORT must be between 1 and 65535")
+		}
+		if c.SMTPTLS != "starttls" && c.SMTPTLS != "tls" && c.SMTPTLS != "none" {
+			return fmt.Errorf("SMTP_TLS must be starttls, tls or none")
+		}
+		if _, err := time.Parse("15:04", c.EmailDigestTime); err != nil {
+			return fmt.Errorf("EMAIL_DIGEST_TIME must be a time of day like 08:00")
+		}
+	}
+	if c.AlertRepeatInterval < 0 {
+		return fmt.Errorf("ALERT_REPEAT_INTERVAL must not be negative")
+	}
+	for rule, interval := range c.AlertRepeatIntervals {
+		if rule == "" || interval < 0 {
+			return fmt.Errorf("ALERT_REPEAT_INTERVALS must be a list of rule:duration")
+		}
+	}
+	if c.AlertCooldown < 0 {
+		return fmt.Errorf("ALERT_COOLDOWN must not be negative")
+	}
+	if c.AlertRunwayWarningDays < 0 || c.AlertRunwayCriticalDays < 0 {
+		return fmt.Errorf("ALERT_RUNWAY_WARNING_DAYS and ALERT_RUNWAY_CRITICAL_DAYS must not be negative")
+	}
+	if c.PingAlertFailures < 0 {
+		return fmt.Errorf("PING_ALERT_FAILURES must not be negative")
+	}
+	if c.HistoryRetention < 0 {
+		return fmt.Errorf("HISTORY_RETENTION must not be negative")
+	}
+	if c.RemoteWriteURL != "" {
+		if !isHTTPURL(c.RemoteWriteURL) {
+			return fmt.Errorf("REMOTE_WRITE_URL must be an http or https URL")
+		}
+		if c.RemoteWriteBearerToken != "" && c.RemoteWriteUsername != "" {
+			return fmt.Errorf("only one of REMOTE_WRITE_USERNAME and REMOTE_WRITE_BEARER_TOKEN may be set")
+		}
+		if c.RemoteWriteTimeout <= 0 {
+			return fmt.Errorf("REMOTE_WRITE_TIMEOUT must be positive")
+		}
+	}
+	if _, ok := c.RemoteWriteHeaders[""]; ok {
+		return fmt.Errorf("REMOTE_WRITE_HEADERS must be a list of Name:value")
+	}
+	for name := range c.RemoteWriteLabels {
+		if !isLabelName(name) {
+			return fmt.Errorf("REMOTE_WRITE_LABELS: invalid label name %q", name)
+		}
+	}
+	if c.OTLPEndpoint != "" {
+		if !isHTTPURL(c.OTLPEndpoint) {
+			return fmt.Errorf("OTLP_ENDPOINT must be an http or https URL")
+		}
+		if c.OTLPProtocol != "http/protobuf" && c.OTLPProtocol != "grpc" {
+			return fmt.Errorf("OTLP_PROTOCOL must be http/protobuf or grpc")
+		}
+		if c.OTLPInterval < 0 {
+			return fmt.Errorf("OTLP_INTERVAL must not be negative")
+		}
+		if c.OTLPTimeout <= 0 {
+			return fmt.Errorf("OTLP_TIMEOUT must be positive")
+		}
+	}
+	if _, ok := c.OTLPHeaders[""]; ok {
+		return fmt.Errorf("OTLP_HEADERS must be a list of name=value")
+	}
+	if _, ok := c.OTLPResourceAttributes[""]; ok {
+		return fmt.Errorf("OTLP_RESOURCE_ATTRIBUTES must be a list of key=value")
+	}
+	for key, address := range map[string]string{"GRAPHITE_ADDRESS": c.GraphiteAddress, "STATSD_ADDRESS": c.StatsDAddress} {
+		if address == "" {
+			continue
+		}
+		if _, port, err := net.SplitHostPort(address); err != nil || port == "" {
+			return fmt.Errorf("%s must be host:port", key)
+		}
+	}
+	if c.GraphiteInterval < 0 || c.StatsDInterval < 0 {
+		return fmt.Errorf("GRAPHITE_INTERVAL and STATSD_INTERVAL must not be negative")
+	}
+	if c.TextfilePath != "" && !strings.HasSuffix(c.TextfilePath, ".prom") {
+		return fmt.Errorf("TEXTFILE_PATH must end in .prom, the textfile collector ignores other files")
+	}
+	pushed := c.RemoteWriteURL != "" || c.OTLPEndpoint != "" || c.GraphiteAddress != "" || c.StatsDAddress != "" || c.TextfilePath != ""
+	if !c.MetricsEndpoint && !pushed {
+		return fmt.Errorf("METRICS_ENDPOINT=false requires REMOTE_WRITE_URL, OTLP_ENDPOINT, GRAPHITE_ADDRESS, STATSD_ADDRESS or TEXTFILE_PATH")
+	}
+	if c.ExporterPort == 0 && !pushed {
+		return fmt.Errorf("EXPORTER_PORT=0 requires REMOTE_WRITE_URL, OTLP_ENDPOINT, GRAPHITE_ADDRESS, STATSD_ADDRESS or TEXTFILE_PATH")
+	}
+	if c.StateSaveInterval < 0 {
+		return fmt.Errorf("STATE_SAVE_INTERVAL must not be negative")
+	}
+	if c.ExternalURL != "" {
+		if !isHTTPURL(c.ExternalURL) {
+			return fmt.Errorf("EXTERNAL_URL must be an http or https URL")
+		}
 	}
 	return nil
 }
 
+// HTTPAllowedNetworks returns the networks of HTTP_ALLOWED_CIDRS. Validate rejects entries
+// that do not parse, so none are dropped.
+func (c *Config) HTTPAllowedNetworks() []netip.Prefix {
+	networks := make([]netip.Prefix, 0, len(c.HTTPAllowedCIDRs))
+	for _, cidr := range c.HTTPAllowedCIDRs {
+		if network, err := parseCIDR(cidr); err == nil {
+			networks = append(networks, network)
+		}
+	}
+	return networks
+}
+
+// parseCIDR parses a network in CIDR notation; a bare IP address is taken as a single host
+func parseCIDR(s string) (netip.Prefix, error) {
+	if prefix, err := netip.ParsePrefix(s); err == nil {
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid network %q", s)
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// isHTTPURL reports whether s is an absolute http or https URL
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// isLabelName reports whether s is a valid Prometheus label name
+func isLabelName(s string) bool {
+	if s == "" || strings.HasPrefix(s, "__") {
+		return false
+	}
+	for i, r := range s {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -170,6 +1049,17 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvList returns the comma-separated, trimmed, non-empty values of key
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 func getEnvInt(key string, defaultValue int) int {
 	if value := os.Getenv(key); value != "" {
 		if intValue, err := strconv.Atoi(value); err == nil {
@@ -179,6 +1069,24 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
 func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if duration, err := time.ParseDuration(value); err == nil {
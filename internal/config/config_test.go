@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strings"
 	"testing"
 	"time"
 )
@@ -107,7 +108,13 @@ func TestParseCustomWallets(t *testing.T) {
 	}
 
 	for _, tt := range tests {
-		wallets := parseCustomWallets()
+		os.Clearenv()
+		os.Setenv("CUSTOM_WALLETS", tt.input)
+
+		wallets, err := parseCustomWallets()
+		if err != nil {
+			t.Fatalf("parseCustomWallets(%q) failed: %v", tt.input, err)
+		}
 		if len(wallets) != tt.expected {
 			t.Errorf("parseCustomWallets(%q) = %d wallets, want %d",
 				tt.input, len(wallets), tt.expected)
@@ -115,6 +122,127 @@ func TestParseCustomWallets(t *testing.T) {
 	}
 }
 
+func TestParseCustomWalletsMergesDuplicates(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("CUSTOM_WALLETS", "0xABC:Treasury:operator:min_fil=5,0x456:Client:client")
+	os.Setenv("CUSTOM_WALLET_1", "0xabc:Ops:operator:min_fil=2:min_usdfc=10")
+	os.Setenv("CUSTOM_WALLET_2", "0x456:Client:client")
+
+	wallets, err := parseCustomWallets()
+	if err != nil {
+		t.Fatalf("parseCustomWallets() failed: %v", err)
+	}
+	if len(wallets) != 2 {
+		t.Fatalf("Expected 2 wallets, got %+v", wallets)
+	}
+	if w := wallets[0]; w.Name != "Treasury / Ops" || w.Type != "operator" || w.MinFIL != 5 || w.MinUSDFC != 10 {
+		t.Errorf("Expected joined names and the strictest thresholds, got %+v", w)
+	}
+	if w := wallets[1]; w.Name != "Client" {
+		t.Errorf("Expected a repeated name not to be joined, got %+v", w)
+	}
+
+	os.Setenv("CUSTOM_WALLET_3", "0xAbc:Ops:client")
+	if _, err := parseCustomWallets(); err == nil {
+		t.Error("Expected an address listed with two types to be rejected")
+	}
+}
+
+func TestParseWalletThresholds(t *testing.T) {
+	wallet, err := parseWalletEntry("0x123:Wallet1:client:min_fil=10:min_usdfc=2.5")
+	if err != nil || wallet == nil {
+		t.Fatalf("parseWalletEntry returned %v, %v", wallet, err)
+	}
+
+	if wallet.Type != "client" {
+		t.Errorf("Expected type 'client', got '%s'", wallet.Type)
+	}
+
+	if wallet.MinFIL != 10 {
+		t.Errorf("Expected min_fil 10, got %f", wallet.MinFIL)
+	}
+
+	if wallet.MinUSDFC != 2.5 {
+		t.Errorf("Expected min_usdfc 2.5, got %f", wallet.MinUSDFC)
+	}
+
+	wallet, _ = parseWalletEntry("0x123:Wallet1:client:min_fil=abc")
+	if wallet.MinFIL != 0 {
+		t.Errorf("Expected invalid min_fil to be ignored, got %f", wallet.MinFIL)
+	}
+}
+
+func TestParseWalletF4Address(t *testing.T) {
+	wallet, err := parseWalletEntry("f410fkkld55ioe7qg24wvt7fu6pbknb56ht7pt4zamxa:Treasury:operator")
+	if err != nil || wallet == nil {
+		t.Fatalf("parseWalletEntry returned %v, %v", wallet, err)
+	}
+	if wallet.Address != "0x52963ef50e27e06d72d59fcb4f3c2a687be3cfef" {
+		t.Errorf("Expected the 0x form of the f410 address, got %s", wallet.Address)
+	}
+
+	_, err = parseWalletEntry("f410fkkld55ioe7qg24wvt7fu6pbknb56ht7pt4zamya:Treasury:operator")
+	if err == nil || !strings.Contains(err.Error(), `"Treasury"`) {
+		t.Errorf("Expected an f410 address with a bad checksum to be rejected naming the wallet, got %v", err)
+	}
+
+	os.Clearenv()
+	defer os.Clearenv()
+	os.Setenv("CUSTOM_WALLET_1", "f410fkkld55ioe7qg24wvt7fu6pbknb56ht7pt4zamya:Treasury:operator")
+	if _, err := Load(); err == nil {
+		t.Error("Expected Load to fail for an f410 address that does not convert")
+	}
+}
+
+func TestValidateCustomWalletAddress(t *testing.T) {
+	tests := map[string]bool{
+		"0x80B98d3aa09ffff255c3ba4A241111Ff1262F045": true,  // Checksummed
+		"0x80b98d3aa09ffff255c3ba4a241111ff1262f045": true,  // No checksum
+		"0x80B98D3AA09FFFF255C3BA4A241111FF1262F045": true,  // No checksum
+		"0x80B98d3aa09ffff255c3ba4A241111Ff1262f045": false, // Typo in the checksum
+		"0x123": false, // Would be read as 0x00...0123
+		"0x80b98d3aa09ffff255c3ba4a241111ff1262f04z": false,
+		"80b98d3aa09ffff255c3ba4a241111ff1262f045":   false,
+	}
+	for address, valid := range tests {
+		if err := validateWalletAddress(address); (err == nil) != valid {
+			t.Errorf("validateWalletAddress(%q) = %v, want valid %t", address, err, valid)
+		}
+	}
+}
+
+func TestSplitMultisigWallets(t *testing.T) {
+	evm, multisig := splitMultisigWallets([]CustomWallet{
+		{Address: "0x123", Name: "Client", Type: "client"},
+		{Address: "f2abc", Name: "Treasury", Type: "msig"},
+	})
+	if len(evm) != 1 || evm[0].Name != "Client" {
+		t.Errorf("Expected the client wallet to stay a custom wallet, got %+v", evm)
+	}
+	if len(multisig) != 1 || multisig[0].Name != "Treasury" {
+		t.Errorf("Expected the msig wallet to be split off, got %+v", multisig)
+	}
+}
+
+func TestValidateMultisigAddress(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("CUSTOM_WALLET_1", "0x0000000000000000000000000000000000000123:Client:client")
+	os.Setenv("CUSTOM_WALLET_2", "t2abc:Treasury:msig")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if len(cfg.CustomWallets) != 1 || len(cfg.MultisigWallets) != 1 {
+		t.Errorf("Expected 1 custom and 1 msig wallet, got %d and %d", len(cfg.CustomWallets), len(cfg.MultisigWallets))
+	}
+
+	cfg.MultisigWallets[0].Address = "0x0000000000000000000000000000000000000123"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected validation error for an msig wallet with a 0x address")
+	}
+}
+
 func TestDefaultUSDFCAddress(t *testing.T) {
 	tests := []struct {
 		network  string
@@ -141,3 +269,246 @@ func TestDefaultUSDFCAddress(t *testing.T) {
 		}
 	}
 }
+
+func TestParsePaymentsTokens(t *testing.T) {
+	usdfc := "0x80B98d3aa09ffff255c3ba4A241111Ff1262F045"
+
+	tokens := parsePaymentsTokens("", usdfc)
+	if len(tokens) != 1 || tokens[0].Symbol != "USDFC" {
+		t.Fatalf("Expected only the USDFC token, got %v", tokens)
+	}
+
+	tokens = parsePaymentsTokens("FIL:0x0000000000000000000000000000000000000000, USDFC:"+usdfc+",invalid", usdfc)
+	if len(tokens) != 2 {
+		t.Fatalf("Expected 2 tokens, got %v", tokens)
+	}
+	if tokens[1].Symbol != "FIL" || tokens[1].Address != "0x0000000000000000000000000000000000000000" {
+		t.Errorf("Expected FIL native account second, got %v", tokens[1])
+	}
+}
+
+func TestValidateStatusPage(t *testing.T) {
+	os.Clearenv()
+	os.Setenv("STATUS_COLUMNS", "name,fil")
+	os.Setenv("STATUS_THEME", "dark")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if len(cfg.StatusColumns) != 2 || cfg.StatusColumns[1] != "fil" {
+		t.Errorf("Expected columns [name fil], got %v", cfg.StatusColumns)
+	}
+
+	cfg.StatusColumns = []string{"name", "balance"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected validation error for unknown status column")
+	}
+
+	cfg.StatusColumns = []string{"name"}
+	cfg.StatusTheme = "solarized"
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected validation error for unknown status theme")
+	}
+}
+
+func TestParseAPITokens(t *testing.T) {
+	os.Clearenv()
+	defer os.Clearenv()
+	os.Setenv("API_TOKEN_1", "oncall-bot:s3cr3t:read, scrape")
+	os.Setenv("API_TOKEN_2", "broken")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if len(cfg.APITokens) != 1 {
+		t.Fatalf("Expected 1 API token, got %d", len(cfg.APITokens))
+	}
+
+	token := cfg.APITokens[0]
+	if !token.HasScope(ScopeScrape) || token.HasScope(ScopeManageWallets) {
+		t.Errorf("Unexpected scopes %v", token.Scopes)
+	}
+
+	cfg.APITokens[0].Scopes = append(cfg.APITokens[0].Scopes, "admin")
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected validation error for unknown scope")
+	}
+}
+
+func TestConcurrencyLimits(t *testing.T) {
+	os.Clearenv()
+	defer os.Clearenv()
+
+	// The legacy limit applies to both RPC fetches and pings unless they are set
+	os.Setenv("MAX_CONCURRENT_REQUESTS", "4")
+	os.Setenv("MAX_CONCURRENT_PINGS", "20")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.MaxConcurrentRPC != 4 || cfg.MaxConcurrentPings != 20 {
+		t.Errorf("Expected 4 RPC fetches and 20 pings, got %d and %d", cfg.MaxConcurrentRPC, cfg.MaxConcurrentPings)
+	}
+
+	cfg.MaxConcurrentPings = 0
+	if err := cfg.Validate(); err == nil {
+		t.Error("Expected validation error for no concurrent pings")
+	}
+}
+
+func TestScrapeTimeout(t *testing.T) {
+	os.Clearenv()
+	defer os.Clearenv()
+
+	os.Setenv("SCRAPE_INTERVAL", "2m")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.ScrapeTimeout != 2*time.Minute {
+		t.Errorf("Expected an unset timeout to default to SCRAPE_INTERVAL, got %s", cfg.ScrapeTimeout)
+	}
+
+	os.Setenv("SCRAPE_TIMEOUT", "0")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.ScrapeTimeout != 0 {
+		t.Errorf("Expected SCRAPE_TIMEOUT=0 to disable the deadline, got %s", cfg.ScrapeTimeout)
+	}
+}
+
+func TestLoadReloadsDotEnv(t *testing.T) {
+	os.Clearenv()
+	t.Chdir(t.TempDir())
+	os.Setenv("CUSTOM_WALLET_2", "0x0000000000000000000000000000000000000456:FromEnv:client")
+
+	writeDotEnv := func(content string) {
+		if err := os.WriteFile(".env", []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeDotEnv("CUSTOM_WALLET_1=0x0000000000000000000000000000000000000123:First:client\nCUSTOM_WALLET_2=0x0000000000000000000000000000000000000789:FromFile:client\n")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if len(cfg.CustomWallets) != 2 || cfg.CustomWallets[0].Name != "First" || cfg.CustomWallets[1].Name != "FromEnv" {
+		t.Errorf("Expected wallets First and FromEnv, got %+v", cfg.CustomWallets)
+	}
+
+	// An edited .env is picked up, but the process environment still wins
+	writeDotEnv("CUSTOM_WALLET_1=0x0000000000000000000000000000000000000123:Renamed:client\nCUSTOM_WALLET_2=0x0000000000000000000000000000000000000789:FromFile:client\n")
+	if cfg, err = Load(); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if len(cfg.CustomWallets) != 2 || cfg.CustomWallets[0].Name != "Renamed" || cfg.CustomWallets[1].Name != "FromEnv" {
+		t.Errorf("Expected wallets Renamed and FromEnv, got %+v", cfg.CustomWallets)
+	}
+
+	// Variables removed from .env are unset
+	writeDotEnv("")
+	if cfg, err = Load(); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if len(cfg.CustomWallets) != 1 || cfg.CustomWallets[0].Name != "FromEnv" {
+		t.Errorf("Expected only wallet FromEnv, got %+v", cfg.CustomWallets)
+	}
+}
+
+func TestParseCIDR(t *testing.T) {
+	tests := map[string]string{
+		"10.0.0.0/8":     "10.0.0.0/8",
+		"192.168.1.7/24": "192.168.1.0/24",
+		"127.0.0.1":      "127.0.0.1/32",
+		"fd00::/8":       "fd00::/8",
+		"::1":            "::1/128",
+	}
+	for input, want := range tests {
+		prefix, err := parseCIDR(input)
+		if err != nil {
+			t.Errorf("parseCIDR(%q) failed: %v", input, err)
+			continue
+		}
+		if prefix.String() != want {
+			t.Errorf("parseCIDR(%q) = %s, want %s", input, prefix, want)
+		}
+	}
+
+	if _, err := parseCIDR("10.0.0.0/33"); err == nil {
+		t.Error("Expected an error for an invalid prefix length")
+	}
+}
+
+func TestParseAlertRepeatIntervals(t *testing.T) {
+	os.Clearenv()
+	defer os.Clearenv()
+	os.Setenv("ALERT_REPEAT_INTERVALS", "below_threshold:12h")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.AlertRepeatIntervals["below_threshold"] != 12*time.Hour {
+		t.Errorf("Expected a 12h repeat interval, got %v", cfg.AlertRepeatIntervals)
+	}
+
+	os.Setenv("ALERT_REPEAT_INTERVALS", "below_threshold:often")
+	if _, err := Load(); err == nil {
+		t.Error("Expected an error for a malformed repeat interval")
+	}
+}
+
+func TestRemoteWriteConfig(t *testing.T) {
+	os.Clearenv()
+	defer os.Clearenv()
+	os.Setenv("REMOTE_WRITE_URL", "https://mimir.example.com/api/v1/push")
+	os.Setenv("REMOTE_WRITE_HEADERS", "X-Scope-OrgID:wallets")
+	os.Setenv("REMOTE_WRITE_LABELS", "job=wallet-exporter, instance=nat-1")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.RemoteWriteHeaders["X-Scope-OrgID"] != "wallets" || cfg.RemoteWriteLabels["instance"] != "nat-1" {
+		t.Errorf("Unexpected headers %v or labels %v", cfg.RemoteWriteHeaders, cfg.RemoteWriteLabels)
+	}
+
+	os.Setenv("REMOTE_WRITE_LABELS", "1job=wallet-exporter")
+	if _, err := Load(); err == nil {
+		t.Error("Expected an error for an invalid label name")
+	}
+	os.Setenv("REMOTE_WRITE_LABELS", "")
+	os.Setenv("REMOTE_WRITE_USERNAME", "user")
+	os.Setenv("REMOTE_WRITE_BEARER_TOKEN", "token")
+	if _, err := Load(); err == nil {
+		t.Error("Expected an error for basic and bearer auth together")
+	}
+}
+
+func TestTextfileConfig(t *testing.T) {
+	os.Clearenv()
+	defer os.Clearenv()
+	os.Setenv("EXPORTER_PORT", "0")
+	if _, err := Load(); err == nil {
+		t.Error("Expected an error for EXPORTER_PORT=0 without a push sink")
+	}
+
+	os.Setenv("TEXTFILE_PATH", "/var/lib/node_exporter/textfile/wallet-exporter.txt")
+	if _, err := Load(); err == nil {
+		t.Error("Expected an error for a TEXTFILE_PATH not ending in .prom")
+	}
+
+	os.Setenv("TEXTFILE_PATH", "/var/lib/node_exporter/textfile/wallet-exporter.prom")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.ExporterPort != 0 {
+		t.Errorf("Expected EXPORTER_PORT 0, got %d", cfg.ExporterPort)
+	}
+}
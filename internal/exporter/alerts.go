@@ -0,0 +1,572 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"wallet-exporter/internal/config"
+)
+
+// Alert rules, used as the "rule" label of the alert metrics
+const (
+	AlertBelowThreshold = "below_threshold"
+	AlertPaymentsRunway = "payments_runway"
+	AlertProviderDown   = "provider_down"
+	AlertDeactivated    = "provider_deactivated"
+	AlertUnapproved     = "provider_unapproved"
+)
+
+// alertRules are the rules notifications can be routed by
+var alertRules = map[string]bool{
+	AlertBelowThreshold: true,
+	AlertPaymentsRunway: true,
+	AlertProviderDown:   true,
+	AlertDeactivated:    true,
+	AlertUnapproved:     true,
+}
+
+// Alert severities
+const (
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// alertSeverities are the severities notifications can be routed by
+var alertSeverities = map[string]bool{
+	SeverityWarning:  true,
+	SeverityCritical: true,
+}
+
+// epochDuration is the block time of Filecoin, used to turn epochs into wall-clock time
+const epochDuration = 30 * time.Second
+
+// notifyTimeout bounds the delivery of one notification
+const notifyTimeout = 10 * time.Second
+
+// Alert is a condition an alert rule found on a wallet. Notifiers receive it when it starts
+// firing and again once it resolved.
+type Alert struct {
+	Rule     string
+	Severity string
+	Summary  string     // One line describing the condition, e.g. "FIL balance 3.2 is below the minimum of 5"
+	Wallet   WalletInfo // Wallet as of the scrape that fired or resolved the alert
+	URL      string     // Wallet detail page (empty without EXTERNAL_URL)
+	Firing   bool
+	StartsAt time.Time
+	EndsAt   time.Time // Zero while firing
+
+	key string // Identifies the alert across scrapes
+}
+
+// notifier delivers alerts to an on-call channel. Every call of Notify is one notification:
+// a single alert, or with ALERT_GROUP the alerts of one rule, severity and state.
+type notifier interface {
+	Name() string
+	Notify(ctx context.Context, alerts []Alert) error
+}
+
+// newNotifiers returns the notifiers configured in cfg
+func newNotifiers(cfg *config.Config, logger *slog.Logger) ([]notifier, error) {
+	var notifiers []notifier
+	if cfg.TelegramBotToken != "" {
+		notifiers = append(notifiers, newTelegramNotifier(cfg.TelegramBotToken, cfg.TelegramChatID, cfg.TelegramCriticalChatID))
+	}
+	if cfg.SlackWebhookURL != "" || len(cfg.SlackRoutes) > 0 {
+		slack, err := newSlackNotifier(cfg.SlackWebhookURL, cfg.SlackRoutes, cfg.SlackTemplate)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, slack)
+	}
+	if cfg.SMTPHost != "" {
+		email, err := newEmailNotifier(cfg, logger)
+		if err != nil {
+			return nil, err
+		}
+		notifiers = append(notifiers, email)
+	}
+	return notifiers, nil
+}
+
+// alertManager evaluates the alert rules after every scrape and hands the alerts that started
+// or stopped firing to the notifiers
+type alertManager struct {
+	notifiers   []notifier
+	externalURL string
+	repeat      map[string]time.Duration // Repeat interval by rule (0 = never repeat)
+	cooldown    time.Duration            // Minimum time between two notifications of the same alert
+	group       bool                     // Notify the alerts of a rule and state together
+	runwayTiers []runwayTier             // Payments runway tiers, most severe first
+	pingLimit   int                      // Failed pings in a row raising provider_down (0 = off)
+	queue       chan []Alert
+	logger      *slog.Logger
+
+	// Only touched by the scrape loop
+	active      map[string]*Alert    // Firing alerts by key
+	notified    map[string]time.Time // Alerts last notified as firing, by key
+	resolved    map[string]time.Time // Alerts last notified as resolved, by key
+	pings       map[pingKey]*pingStreak
+	wasActive   map[uint64]bool // Providers seen active
+	wasApproved map[uint64]bool // Providers seen approved
+
+	firingGauge   *prometheus.GaugeVec
+	notifyCounter *prometheus.CounterVec
+}
+
+func newAlertManager(notifiers []notifier, cfg *config.Config, logger *slog.Logger) (*alertManager, error) {
+	m := &alertManager{
+		notifiers:   notifiers,
+		externalURL: strings.TrimRight(cfg.ExternalURL, "/"),
+		repeat:      make(map[string]time.Duration),
+		cooldown:    cfg.AlertCooldown,
+		group:       cfg.AlertGroup,
+		queue:       make(chan []Alert, 16),
+		logger:      logger,
+		active:      make(map[string]*Alert),
+		notified:    make(map[string]time.Time),
+		resolved:    make(map[string]time.Time),
+		pings:       make(map[pingKey]*pingStreak),
+		wasActive:   make(map[uint64]bool),
+		wasApproved: make(map[uint64]bool),
+		pingLimit:   cfg.PingAlertFailures,
+	}
+	if cfg.AlertRunwayCriticalDays > 0 {
+		m.runwayTiers = append(m.runwayTiers, runwayTier{SeverityCritical, cfg.AlertRunwayCriticalDays})
+	}
+	if cfg.AlertRunwayWarningDays > 0 {
+		m.runwayTiers = append(m.runwayTiers, runwayTier{SeverityWarning, cfg.AlertRunwayWarningDays})
+	}
+	for rule := range alertRules {
+		m.repeat[rule] = cfg.AlertRepeatInterval
+	}
+	for rule, interval := range cfg.AlertRepeatIntervals {
+		if !alertRules[rule] {
+			return nil, fmt.Errorf("ALERT_REPEAT_INTERVALS: unknown alert rule %q", rule)
+		}
+		m.repeat[rule] = interval
+	}
+	return m, nil
+}
+
+func (e *WalletExporter) registerAlertMetrics() {
+	e.alerts.firingGauge = newGaugeVec(e.config.MetricsPrefix, "alerts_firing")
+	e.alerts.notifyCounter = newCounterVec(e.config.MetricsPrefix, "alert_notifications_total")
+	e.registry.MustRegister(e.alerts.firingGauge, e.alerts.notifyCounter)
+}
+
+// alertKey identifies an alert across scrapes
+func alertKey(rule string, wallet WalletInfo, detail string) string {
+	return strings.Join([]string{rule, wallet.Type, wallet.Address.Hex(), detail}, "|")
+}
+
+// walletKey identifies a monitored wallet; a wallet monitored under several types has several
+func walletKey(wallet WalletInfo) string {
+	return wallet.Type + "|" + wallet.Address.Hex()
+}
+
+// runwayTier raises a Payments runway alert of its severity once an account is funded for
+// at most days
+type runwayTier struct {
+	severity string
+	days     float64
+}
+
+func newAlert(rule, severity string, wallet WalletInfo, detail, summary string) Alert {
+	return Alert{
+		key:      alertKey(rule, wallet, detail),
+		Rule:     rule,
+		Severity: severity,
+		Summary:  summary,
+		Wallet:   wallet,
+	}
+}
+
+// conditions returns the alerts the rules find on the wallets of one scrape at block head, by key
+func (m *alertManager) conditions(wallets []WalletInfo, head uint64) map[string]Alert {
+	found := make(map[string]Alert)
+	providers := make(map[uint64]WalletInfo)
+	for _, wallet := range wallets {
+		for _, alert := range thresholdAlerts(wallet) {
+			found[alert.key] = alert
+		}
+		for _, alert := range m.runwayAlerts(wallet, head) {
+			found[alert.key] = alert
+		}
+		if wallet.Type == "provider" && wallet.ProviderID != 0 {
+			providers[wallet.ProviderID] = wallet
+		}
+	}
+	for _, alert := range m.pingAlerts(providers) {
+		found[alert.key] = alert
+	}
+	for _, alert := range m.statusAlerts(providers) {
+		found[alert.key] = alert
+	}
+	return found
+}
+
+// thresholdAlerts returns the balances of a wallet below their minimum
+func thresholdAlerts(wallet WalletInfo) []Alert {
+	var alerts []Alert
+	for _, balance := range []struct {
+		token   string
+		amount  float64
+		minimum float64
+	}{
+		{"FIL", balanceAmount(wallet.FILBalance), wallet.MinFIL},
+		{"USDFC", balanceAmount(wallet.USDFCBalance), wallet.MinUSDFC},
+	} {
+		if balance.minimum > 0 && balance.amount < balance.minimum {
+			alerts = append(alerts, newAlert(AlertBelowThreshold, SeverityWarning, wallet, balance.token,
+				fmt.Sprintf("%s balance %g is below the minimum of %g", balance.token, balance.amount, balance.minimum)))
+		}
+	}
+	return alerts
+}
+
+// pingKey identifies the pings of one product of a provider
+type pingKey struct {
+	providerID  uint64
+	productType uint8
+}
+
+// pingStreak counts the failed pings of a product in a row
+type pingStreak struct {
+	failures int
+	last     PingResult
+}
+
+// observePings counts the pings sent since the previous scrape. With PING_SPREAD a result is
+// returned by every scrape until the provider is pinged again, so a ping is only counted once.
+func (m *alertManager) observePings(wallets []WalletInfo, pings map[uint64][]PingResult) {
+	if m.pingLimit <= 0 {
+		return
+	}
+	for providerID, results := range pings {
+		for _, result := range results {
+			key := pingKey{providerID, result.ProductType}
+			streak, ok := m.pings[key]
+			if !ok {
+				streak = &pingStreak{}
+				m.pings[key] = streak
+			}
+			if !result.Time.After(streak.last.Time) {
+				continue
+			}
+			if result.Success {
+				streak.failures = 0
+			} else {
+				streak.failures++
+			}
+			streak.last = result
+		}
+	}
+
+	// Forget providers that are gone, unless the provider fetch failed
+	providers := make(map[uint64]bool)
+	for _, wallet := range wallets {
+		if wallet.Type == "provider" {
+			providers[wallet.ProviderID] = true
+		}
+	}
+	if len(providers) == 0 {
+		return
+	}
+	for key := range m.pings {
+		if !providers[key.providerID] {
+			delete(m.pings, key)
+		}
+	}
+}
+
+// pingAlerts returns the products of providers whose last PING_ALERT_FAILURES pings failed
+func (m *alertManager) pingAlerts(providers map[uint64]WalletInfo) []Alert {
+	if m.pingLimit <= 0 {
+		return nil
+	}
+
+	var alerts []Alert
+	for key, streak := range m.pings {
+		wallet, ok := providers[key.providerID]
+		if !ok || streak.failures < m.pingLimit {
+			continue
+		}
+		reason := streak.last.Error
+		if streak.last.StatusCode != 0 {
+			reason = fmt.Sprintf("HTTP %d", streak.last.StatusCode)
+		}
+		product := productTypeName(key.productType)
+		alerts = append(alerts, newAlert(AlertProviderDown, SeverityCritical, wallet, product,
+			fmt.Sprintf("%s ping failed %d times in a row, last with %s from %s", product, streak.failures, reason, streak.last.PingURL)))
+	}
+	return alerts
+}
+
+// statusAlerts returns the providers that were deactivated or lost their WarmStorage approval
+// since they were first seen active or approved; reactivation or approval resolves the alert.
+// If no provider is approved the approved list likely failed to load, so approval alerts keep
+// their state.
+func (m *alertManager) statusAlerts(providers map[uint64]WalletInfo) []Alert {
+	approvalKnown := false
+	for _, wallet := range providers {
+		if wallet.IsApproved {
+			approvalKnown = true
+			break
+		}
+	}
+
+	var alerts []Alert
+	for id, wallet := range providers {
+		if wallet.IsActive {
+			m.wasActive[id] = true
+		} else if m.wasActive[id] {
+			alerts = append(alerts, newAlert(AlertDeactivated, SeverityWarning, wallet, "",
+				fmt.Sprintf("Provider %d was deactivated in the registry", id)))
+		}
+
+		switch {
+		case !approvalKnown:
+			if active, ok := m.active[alertKey(AlertUnapproved, wallet, "")]; ok {
+				alerts = append(alerts, *active)
+			}
+		case wallet.IsApproved:
+			m.wasApproved[id] = true
+		case m.wasApproved[id]:
+			alerts = append(alerts, newAlert(AlertUnapproved, SeverityCritical, wallet, "",
+				fmt.Sprintf("Provider %d lost its WarmStorage approval", id)))
+		}
+	}
+	return alerts
+}
+
+// runwayAlerts returns the runway tiers every Payments account of a wallet with a lockup rate
+// reached. An account in the critical tier is in the warning tier as well, so de-escalating
+// only resolves the critical alert.
+func (m *alertManager) runwayAlerts(wallet WalletInfo, head uint64) []Alert {
+	if head == 0 || len(m.runwayTiers) == 0 {
+		return nil
+	}
+
+	var alerts []Alert
+	for _, account := range wallet.PaymentsAccounts {
+		if account.PaymentsInfo == nil || account.LockupRate == nil || account.LockupRate.Sign() <= 0 || account.FundedUntilEpoch == nil {
+			continue
+		}
+		days := fundedDays(account.FundedUntilEpoch, head)
+		token := strings.ToUpper(account.Token)
+		for _, tier := range m.runwayTiers {
+			if days <= tier.days {
+				alerts = append(alerts, newAlert(AlertPaymentsRunway, tier.severity, wallet, token+"|"+tier.severity,
+					fmt.Sprintf("%s Payments account is funded for %.1f days (until epoch %s), at most %g", token, days, account.FundedUntilEpoch, tier.days)))
+			}
+		}
+	}
+	return alerts
+}
+
+// fundedDays returns the days until epoch fundedUntil, 0 if it passed
+func fundedDays(fundedUntil *big.Int, head uint64) float64 {
+	epochs := new(big.Int).Sub(fundedUntil, new(big.Int).SetUint64(head))
+	if epochs.Sign() <= 0 {
+		return 0
+	}
+	remaining, _ := new(big.Float).SetInt(epochs).Float64()
+	return remaining * epochDuration.Seconds() / (24 * time.Hour).Seconds()
+}
+
+// evaluate compares the conditions of a scrape at block head with the firing alerts and returns
+// the alerts to notify about. An alert of a wallet missing from the scrape only resolves if the scrape
+// returned other wallets of its type, so a failed provider fetch does not resolve every
+// provider alert.
+func (m *alertManager) evaluate(wallets []WalletInfo, pings map[uint64][]PingResult, head uint64, now time.Time) []Alert {
+	byKey := make(map[string]WalletInfo, len(wallets))
+	types := make(map[string]bool)
+	for _, wallet := range wallets {
+		byKey[walletKey(wallet)] = wallet
+		types[wallet.Type] = true
+	}
+
+	m.observePings(wallets, pings)
+	found := m.conditions(wallets, head)
+	var ended []Alert
+	for key, alert := range found {
+		if active, ok := m.active[key]; ok {
+			active.Wallet = alert.Wallet
+			active.Summary = alert.Summary
+			continue
+		}
+		alert.URL = m.walletURL(alert.Wallet)
+		alert.Firing = true
+		alert.StartsAt = now
+		m.active[key] = &alert
+	}
+
+	for key, active := range m.active {
+		if _, ok := found[key]; ok {
+			continue
+		}
+		wallet, present := byKey[walletKey(active.Wallet)]
+		if !present && !types[active.Wallet.Type] {
+			continue
+		}
+		if present {
+			active.Wallet = wallet
+		}
+		resolved := *active
+		resolved.Firing = false
+		resolved.EndsAt = now
+		delete(m.active, key)
+		ended = append(ended, resolved)
+	}
+
+	if m.firingGauge != nil {
+		m.firingGauge.Reset()
+		for _, active := range m.active {
+			m.firingGauge.WithLabelValues(active.Rule, active.Severity).Inc()
+		}
+	}
+	return m.notifications(ended, now)
+}
+
+// notifications returns the alerts to notify about after a scrape: alerts that started firing
+// and were not notified within the cooldown, alerts due for a repeat, and ended alerts that
+// were notified as firing at least the cooldown ago. An alert that resolves and fires again
+// within the cooldown is not notified at all, so a flapping condition is reported once.
+func (m *alertManager) notifications(ended []Alert, now time.Time) []Alert {
+	var alerts []Alert
+	for key, active := range m.active {
+		last, notified := m.notified[key]
+		if !notified {
+			if resolvedAt, ok := m.resolved[key]; ok && now.Sub(resolvedAt) < m.cooldown {
+				continue
+			}
+		} else if repeat := m.repeat[active.Rule]; repeat <= 0 || now.Sub(last) < repeat {
+			continue
+		}
+		m.notified[key] = now
+		delete(m.resolved, key)
+		alerts = append(alerts, *active)
+	}
+
+	for _, alert := range ended {
+		last, notified := m.notified[alert.key]
+		if !notified {
+			continue // Its firing notification was never sent
+		}
+		if now.Sub(last) < m.cooldown {
+			// Keep it firing until the cooldown passed; it resolves on a later scrape unless
+			// it fires again in the meantime
+			active := alert
+			active.Firing = true
+			active.EndsAt = time.Time{}
+			m.active[alert.key] = &active
+			continue
+		}
+		delete(m.notified, alert.key)
+		m.resolved[alert.key] = now
+		alerts = append(alerts, alert)
+	}
+
+	for key, resolvedAt := range m.resolved {
+		if now.Sub(resolvedAt) >= m.cooldown {
+			delete(m.resolved, key)
+		}
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].key < alerts[j].key })
+	return alerts
+}
+
+// balanceAmount converts an 18-decimal balance to whole tokens, treating nil as 0
+func balanceAmount(balance *big.Int) float64 {
+	if balance == nil {
+		return 0
+	}
+	return tokenAmount(balance, 18)
+}
+
+func (m *alertManager) walletURL(wallet WalletInfo) string {
+	if m.externalURL == "" {
+		return ""
+	}
+	return m.externalURL + "/wallets/" + wallet.Address.Hex()
+}
+
+// enqueue hands alerts to the notifiers without blocking the scrape; alerts are dropped if
+// the notifiers fall too far behind
+func (m *alertManager) enqueue(alerts []Alert) {
+	if len(alerts) == 0 || len(m.notifiers) == 0 {
+		return
+	}
+	select {
+	case m.queue <- alerts:
+	default:
+		m.logger.Warn("Alert notification queue full, dropping alerts", "alerts", len(alerts))
+	}
+}
+
+// groups splits alerts into notifications: one per alert, or with ALERT_GROUP one per rule,
+// severity and state
+func (m *alertManager) groups(alerts []Alert) [][]Alert {
+	var groups [][]Alert
+	index := make(map[string]int)
+	for _, alert := range alerts {
+		if !m.group {
+			groups = append(groups, []Alert{alert})
+			continue
+		}
+		key := fmt.Sprintf("%s|%s|%t", alert.Rule, alert.Severity, alert.Firing)
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], alert)
+	}
+	return groups
+}
+
+// run delivers queued alerts to every notifier until ctx is canceled
+func (m *alertManager) run(ctx context.Context) {
+	// Notifiers with a schedule of their own, like the email digest
+	for _, n := range m.notifiers {
+		if scheduled, ok := n.(interface{ run(context.Context) }); ok {
+			go scheduled.run(ctx)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case alerts := <-m.queue:
+			for _, group := range m.groups(alerts) {
+				for _, n := range m.notifiers {
+					m.notify(ctx, n, group)
+				}
+			}
+		}
+	}
+}
+
+func (m *alertManager) notify(ctx context.Context, n notifier, alerts []Alert) {
+	notifyCtx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	err := n.Notify(notifyCtx, alerts)
+	cancel()
+
+	result := "success"
+	if err != nil {
+		result = "failure"
+		m.logger.Warn("Failed to send alert notification", "notifier", n.Name(), "alerts", len(alerts), "error", err)
+	}
+	if m.notifyCounter != nil {
+		m.notifyCounter.WithLabelValues(n.Name(), result).Inc()
+	}
+}
@@ -0,0 +1,548 @@
+package exporter
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"wallet-exporter/internal/config"
+)
+
+func fil(amount int64) *big.Int {
+	return new(big.Int).Mul(big.NewInt(amount), big.NewInt(1e18))
+}
+
+func TestAlertManagerBelowThreshold(t *testing.T) {
+	manager, err := newAlertManager(nil, &config.Config{ExternalURL: "https://exporter.example.com/"}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+
+	client := WalletInfo{Address: common.HexToAddress("0x01"), Name: "client", Type: "client", FILBalance: fil(10), USDFCBalance: fil(10), MinFIL: 5}
+	sp := WalletInfo{Address: common.HexToAddress("0x02"), Name: "sp", Type: "provider", FILBalance: fil(1), USDFCBalance: fil(0), MinFIL: 5}
+
+	changed := manager.evaluate([]WalletInfo{client, sp}, nil, 0, now)
+	if len(changed) != 1 || !changed[0].Firing || changed[0].Wallet.Name != "sp" || changed[0].Rule != AlertBelowThreshold {
+		t.Fatalf("Expected the provider alert to fire, got %+v", changed)
+	}
+	if changed[0].URL != "https://exporter.example.com/wallets/"+sp.Address.Hex() {
+		t.Errorf("Unexpected wallet URL %q", changed[0].URL)
+	}
+
+	// A firing alert is only reported once
+	if changed := manager.evaluate([]WalletInfo{client, sp}, nil, 0, now.Add(time.Minute)); len(changed) != 0 {
+		t.Fatalf("Expected no changes while the alert keeps firing, got %+v", changed)
+	}
+
+	// A failed provider fetch does not resolve provider alerts
+	if changed := manager.evaluate([]WalletInfo{client}, nil, 0, now.Add(2*time.Minute)); len(changed) != 0 {
+		t.Fatalf("Expected no changes without providers in the scrape, got %+v", changed)
+	}
+
+	sp.FILBalance = fil(6)
+	changed = manager.evaluate([]WalletInfo{client, sp}, nil, 0, now.Add(3*time.Minute))
+	if len(changed) != 1 || changed[0].Firing || !changed[0].EndsAt.Equal(now.Add(3*time.Minute)) || !changed[0].StartsAt.Equal(now) {
+		t.Fatalf("Expected the provider alert to resolve, got %+v", changed)
+	}
+	if len(manager.active) != 0 {
+		t.Errorf("Expected no active alerts, got %d", len(manager.active))
+	}
+}
+
+func TestAlertManagerRepeatAndCooldown(t *testing.T) {
+	cfg := &config.Config{
+		AlertRepeatInterval:  time.Hour,
+		AlertRepeatIntervals: map[string]time.Duration{AlertBelowThreshold: 4 * time.Hour},
+		AlertCooldown:        10 * time.Minute,
+	}
+	manager, err := newAlertManager(nil, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	low := WalletInfo{Address: common.HexToAddress("0x01"), Name: "sp", Type: "provider", FILBalance: fil(1), MinFIL: 5}
+	ok := low
+	ok.FILBalance = fil(6)
+
+	if changed := manager.evaluate([]WalletInfo{low}, nil, 0, now); len(changed) != 1 || !changed[0].Firing {
+		t.Fatalf("Expected the alert to fire, got %+v", changed)
+	}
+
+	// Flapping within the cooldown is not notified, and keeps the original start
+	for i, wallet := range []WalletInfo{ok, low, ok, low} {
+		if changed := manager.evaluate([]WalletInfo{wallet}, nil, 0, now.Add(time.Duration(i+1)*time.Minute)); len(changed) != 0 {
+			t.Fatalf("Expected no notifications while flapping, got %+v", changed)
+		}
+	}
+
+	// The rule's repeat interval overrides the default one
+	if changed := manager.evaluate([]WalletInfo{low}, nil, 0, now.Add(2*time.Hour)); len(changed) != 0 {
+		t.Fatalf("Expected no repeat before 4h, got %+v", changed)
+	}
+	changed := manager.evaluate([]WalletInfo{low}, nil, 0, now.Add(4*time.Hour))
+	if len(changed) != 1 || !changed[0].Firing || !changed[0].StartsAt.Equal(now) {
+		t.Fatalf("Expected the alert to be repeated, got %+v", changed)
+	}
+
+	// Resolving within the cooldown of the repeat waits for the cooldown to pass
+	if changed := manager.evaluate([]WalletInfo{ok}, nil, 0, now.Add(4*time.Hour+time.Minute)); len(changed) != 0 {
+		t.Fatalf("Expected the resolution to wait for the cooldown, got %+v", changed)
+	}
+	changed = manager.evaluate([]WalletInfo{ok}, nil, 0, now.Add(4*time.Hour+10*time.Minute))
+	if len(changed) != 1 || changed[0].Firing {
+		t.Fatalf("Expected the alert to resolve, got %+v", changed)
+	}
+
+	// Firing again right after the resolution is held back until the cooldown passed
+	if changed := manager.evaluate([]WalletInfo{low}, nil, 0, now.Add(4*time.Hour+11*time.Minute)); len(changed) != 0 {
+		t.Fatalf("Expected no notification within the cooldown, got %+v", changed)
+	}
+	if changed := manager.evaluate([]WalletInfo{low}, nil, 0, now.Add(4*time.Hour+20*time.Minute)); len(changed) != 1 || !changed[0].Firing {
+		t.Fatalf("Expected the alert to fire after the cooldown, got %+v", changed)
+	}
+
+	cfg.AlertRepeatIntervals = map[string]time.Duration{"unknown": time.Hour}
+	if _, err := newAlertManager(nil, cfg, slog.New(slog.NewTextHandler(io.Discard, nil))); err == nil {
+		t.Error("Expected an error for a repeat interval of an unknown rule")
+	}
+}
+
+func TestAlertManagerPaymentsRunway(t *testing.T) {
+	cfg := &config.Config{AlertRunwayWarningDays: 14, AlertRunwayCriticalDays: 3}
+	manager, err := newAlertManager(nil, cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	const head = 1000000
+	const epochsPerDay = 2880
+
+	client := WalletInfo{Address: common.HexToAddress("0x01"), Name: "client", Type: "client"}
+	setFundedDays := func(days int64) {
+		client.PaymentsAccounts = []PaymentsAccount{{
+			Token:        "usdfc",
+			Decimals:     18,
+			PaymentsInfo: newPaymentsInfo(fil(10), fil(5), big.NewInt(head+days*epochsPerDay), big.NewInt(1)),
+		}}
+	}
+
+	setFundedDays(30)
+	if changed := manager.evaluate([]WalletInfo{client}, nil, head, now); len(changed) != 0 {
+		t.Fatalf("Expected no alerts with 30 days of runway, got %+v", changed)
+	}
+
+	setFundedDays(10)
+	changed := manager.evaluate([]WalletInfo{client}, nil, head, now.Add(time.Minute))
+	if len(changed) != 1 || changed[0].Rule != AlertPaymentsRunway || changed[0].Severity != SeverityWarning {
+		t.Fatalf("Expected a runway warning, got %+v", changed)
+	}
+	if !strings.HasPrefix(changed[0].Summary, "USDFC Payments account is funded for 10.0 days") {
+		t.Errorf("Unexpected summary %q", changed[0].Summary)
+	}
+
+	// Escalating fires the critical tier, the warning keeps firing
+	setFundedDays(2)
+	changed = manager.evaluate([]WalletInfo{client}, nil, head, now.Add(2*time.Minute))
+	if len(changed) != 1 || changed[0].Severity != SeverityCritical || !changed[0].Firing {
+		t.Fatalf("Expected the runway alert to escalate, got %+v", changed)
+	}
+
+	// Without a lockup rate the account does not run out
+	client.PaymentsAccounts[0].LockupRate = big.NewInt(0)
+	changed = manager.evaluate([]WalletInfo{client}, nil, head, now.Add(3*time.Minute))
+	if len(changed) != 2 || changed[0].Firing || changed[1].Firing {
+		t.Fatalf("Expected both tiers to resolve, got %+v", changed)
+	}
+}
+
+func TestAlertManagerProviderDown(t *testing.T) {
+	manager, err := newAlertManager(nil, &config.Config{PingAlertFailures: 3}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	sp := WalletInfo{Address: common.HexToAddress("0x02"), Name: "sp", Type: "provider", ProviderID: 7}
+	ping := func(at time.Time, status int) map[uint64][]PingResult {
+		result := PingResult{PingURL: "https://sp.example.com/pdp/ping", StatusCode: status, Success: status == http.StatusOK, Time: at}
+		if status == 0 {
+			result.Error = "context deadline exceeded"
+		}
+		return map[uint64][]PingResult{7: {result}}
+	}
+
+	for i, status := range []int{0, 0} {
+		at := now.Add(time.Duration(i) * time.Minute)
+		if changed := manager.evaluate([]WalletInfo{sp}, ping(at, status), 0, at); len(changed) != 0 {
+			t.Fatalf("Expected no alert after %d failures, got %+v", i+1, changed)
+		}
+	}
+
+	// A result returned again by a later scrape is not counted twice
+	if changed := manager.evaluate([]WalletInfo{sp}, ping(now.Add(time.Minute), 0), 0, now.Add(90*time.Second)); len(changed) != 0 {
+		t.Fatalf("Expected a repeated result not to count, got %+v", changed)
+	}
+
+	changed := manager.evaluate([]WalletInfo{sp}, ping(now.Add(2*time.Minute), http.StatusServiceUnavailable), 0, now.Add(2*time.Minute))
+	if len(changed) != 1 || changed[0].Rule != AlertProviderDown || changed[0].Severity != SeverityCritical {
+		t.Fatalf("Expected the provider to be reported down, got %+v", changed)
+	}
+	if want := "pdp ping failed 3 times in a row, last with HTTP 503 from https://sp.example.com/pdp/ping"; changed[0].Summary != want {
+		t.Errorf("Expected summary %q, got %q", want, changed[0].Summary)
+	}
+
+	changed = manager.evaluate([]WalletInfo{sp}, ping(now.Add(3*time.Minute), http.StatusOK), 0, now.Add(3*time.Minute))
+	if len(changed) != 1 || changed[0].Firing {
+		t.Fatalf("Expected the alert to resolve after a successful ping, got %+v", changed)
+	}
+}
+
+func TestAlertManagerProviderStatus(t *testing.T) {
+	manager, err := newAlertManager(nil, &config.Config{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	sp := WalletInfo{Address: common.HexToAddress("0x02"), Name: "sp", Type: "provider", ProviderID: 7, IsActive: true, IsApproved: true}
+	other := WalletInfo{Address: common.HexToAddress("0x03"), Name: "other", Type: "provider", ProviderID: 8, IsActive: true, IsApproved: true}
+
+	if changed := manager.evaluate([]WalletInfo{sp, other}, nil, 0, now); len(changed) != 0 {
+		t.Fatalf("Expected no alerts for approved active providers, got %+v", changed)
+	}
+
+	sp.IsApproved = false
+	changed := manager.evaluate([]WalletInfo{sp, other}, nil, 0, now.Add(time.Minute))
+	if len(changed) != 1 || changed[0].Rule != AlertUnapproved || !changed[0].Firing || changed[0].Summary != "Provider 7 lost its WarmStorage approval" {
+		t.Fatalf("Expected the de-approval to fire, got %+v", changed)
+	}
+
+	// Without any approved provider the approved list failed to load; nothing changes
+	other.IsApproved = false
+	if changed := manager.evaluate([]WalletInfo{sp, other}, nil, 0, now.Add(2*time.Minute)); len(changed) != 0 {
+		t.Fatalf("Expected no changes without approval data, got %+v", changed)
+	}
+	other.IsApproved = true
+
+	sp.IsApproved, sp.IsActive = true, false
+	changed = manager.evaluate([]WalletInfo{sp, other}, nil, 0, now.Add(3*time.Minute))
+	if len(changed) != 2 {
+		t.Fatalf("Expected the approval to resolve and the deactivation to fire, got %+v", changed)
+	}
+	for _, alert := range changed {
+		if (alert.Rule == AlertUnapproved) == alert.Firing || (alert.Rule == AlertDeactivated) != alert.Firing {
+			t.Errorf("Unexpected alert %+v", alert)
+		}
+	}
+}
+
+func TestAlertGroups(t *testing.T) {
+	manager, err := newAlertManager(nil, &config.Config{AlertGroup: true}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	alerts := []Alert{
+		{Rule: AlertBelowThreshold, Severity: SeverityWarning, Firing: true, Summary: "a", Wallet: WalletInfo{Name: "a"}},
+		{Rule: AlertBelowThreshold, Severity: SeverityWarning, Firing: false, Summary: "b", Wallet: WalletInfo{Name: "b"}},
+		{Rule: AlertBelowThreshold, Severity: SeverityWarning, Firing: true, Summary: "c", Wallet: WalletInfo{Name: "c"}},
+	}
+
+	groups := manager.groups(alerts)
+	if len(groups) != 2 || len(groups[0]) != 2 || len(groups[1]) != 1 {
+		t.Fatalf("Expected a firing group of 2 and a resolved group of 1, got %+v", groups)
+	}
+	text := formatTelegramAlerts(groups[0])
+	if !strings.HasPrefix(text, "<b>FIRING</b> [warning] below_threshold: 2 wallets") || !strings.Contains(text, "<b>c</b>") {
+		t.Errorf("Unexpected grouped message %q", text)
+	}
+
+	manager.group = false
+	if groups := manager.groups(alerts); len(groups) != 3 {
+		t.Errorf("Expected one notification per alert without grouping, got %d", len(groups))
+	}
+}
+
+func TestTelegramNotifier(t *testing.T) {
+	var messages []map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/botsecret/sendMessage" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		var message map[string]any
+		json.NewDecoder(r.Body).Decode(&message)
+		messages = append(messages, message)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	notifier := newTelegramNotifier("secret", "-100123", "-100456")
+	notifier.baseURL = server.URL
+
+	alert := Alert{
+		Rule:     AlertBelowThreshold,
+		Severity: SeverityWarning,
+		Summary:  "FIL balance 1 is below the minimum of 5",
+		Wallet:   WalletInfo{Address: common.HexToAddress("0x02"), Name: "sp <1>", Type: "provider", FILBalance: fil(1)},
+		URL:      "https://exporter.example.com/wallets/0x02",
+		Firing:   true,
+	}
+	if err := notifier.Notify(context.Background(), []Alert{alert}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(messages) != 1 || messages[0]["chat_id"] != "-100123" || messages[0]["parse_mode"] != "HTML" {
+		t.Fatalf("Unexpected messages %v", messages)
+	}
+	text := messages[0]["text"].(string)
+	for _, want := range []string{"FIRING", "sp &lt;1&gt;", alert.Wallet.Address.Hex(), "Balance: 1 FIL, 0 USDFC", `href="https://exporter.example.com/wallets/0x02"`} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected message to contain %q, got %q", want, text)
+		}
+	}
+
+	// Critical alerts go to their own chat
+	alert.Severity = SeverityCritical
+	if err := notifier.Notify(context.Background(), []Alert{alert}); err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 || messages[1]["chat_id"] != "-100456" {
+		t.Errorf("Expected the critical alert in the critical chat, got %v", messages)
+	}
+}
+
+func TestSlackNotifier(t *testing.T) {
+	posts := make(map[string][]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message struct{ Text string }
+		json.NewDecoder(r.Body).Decode(&message)
+		posts[r.URL.Path] = append(posts[r.URL.Path], message.Text)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	alert := Alert{
+		Rule:     AlertBelowThreshold,
+		Severity: SeverityWarning,
+		Summary:  "FIL balance 1 is below the minimum of 5",
+		Wallet:   WalletInfo{Address: common.HexToAddress("0x02"), Name: "sp <1>", Type: "provider", FILBalance: fil(1)},
+		URL:      "https://exporter.example.com/wallets/0x02",
+		Firing:   true,
+	}
+
+	// Without a route, alerts go to the default webhook
+	notifier, err := newSlackNotifier(server.URL+"/default", nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := notifier.Notify(context.Background(), []Alert{alert}); err != nil {
+		t.Fatal(err)
+	}
+	if len(posts["/default"]) != 1 {
+		t.Fatalf("Expected one post to the default webhook, got %v", posts)
+	}
+	for _, want := range []string{"*FIRING*", "*sp &lt;1&gt;*", "Balance: 1 FIL, 0 USDFC", "<https://exporter.example.com/wallets/0x02|Wallet details>"} {
+		if !strings.Contains(posts["/default"][0], want) {
+			t.Errorf("Expected message to contain %q, got %q", want, posts["/default"][0])
+		}
+	}
+
+	// A route sends the rule to its own webhook, rendered with the custom template
+	routes := []config.SlackRoute{{Rule: AlertBelowThreshold, WebhookURL: server.URL + "/treasury"}}
+	notifier, err = newSlackNotifier(server.URL+"/default", routes, "{{.Wallet.Name}}: {{.Summary}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := notifier.Notify(context.Background(), []Alert{alert}); err != nil {
+		t.Fatal(err)
+	}
+	if got := posts["/treasury"]; len(got) != 1 || got[0] != "sp <1>: FIL balance 1 is below the minimum of 5" {
+		t.Errorf("Unexpected routed posts %v", got)
+	}
+
+	// A route of a rule and severity takes precedence over the route of the rule
+	routes = []config.SlackRoute{
+		{Rule: AlertPaymentsRunway, WebhookURL: server.URL + "/clients"},
+		{Rule: AlertPaymentsRunway, Severity: SeverityCritical, WebhookURL: server.URL + "/oncall"},
+	}
+	notifier, err = newSlackNotifier("", routes, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	runway := alert
+	runway.Rule = AlertPaymentsRunway
+	for _, severity := range []string{SeverityWarning, SeverityCritical} {
+		runway.Severity = severity
+		if err := notifier.Notify(context.Background(), []Alert{runway}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(posts["/clients"]) != 1 || len(posts["/oncall"]) != 1 {
+		t.Errorf("Expected one post per tier, got %v", posts)
+	}
+	if _, err := newSlackNotifier("", []config.SlackRoute{{Rule: AlertPaymentsRunway, Severity: "page", WebhookURL: server.URL}}, ""); err == nil {
+		t.Error("Expected an error for a route of an unknown severity")
+	}
+
+	if _, err := newSlackNotifier("", []config.SlackRoute{{Rule: "nope", WebhookURL: server.URL}}, ""); err == nil {
+		t.Error("Expected an error for a route of an unknown rule")
+	}
+	if _, err := newSlackNotifier(server.URL, nil, "{{.Nope"); err == nil {
+		t.Error("Expected an error for an invalid template")
+	}
+}
+
+func TestEmailNotifier(t *testing.T) {
+	cfg := &config.Config{EmailFrom: "exporter@example.com", EmailTo: []string{"finance@example.com"}, EmailDigestTime: "08:00"}
+	notifier, err := newEmailNotifier(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type email struct {
+		to            []string
+		subject, body string
+	}
+	var sent []email
+	notifier.send = func(ctx context.Context, to []string, subject, body string) error {
+		sent = append(sent, email{to, subject, body})
+		return nil
+	}
+
+	start := time.Date(2026, 1, 2, 3, 4, 0, 0, time.UTC)
+	alert := Alert{
+		key:      "below_threshold|provider|0x02|FIL",
+		Rule:     AlertBelowThreshold,
+		Severity: SeverityWarning,
+		Summary:  "FIL balance 1 is below the minimum of 5",
+		Wallet:   WalletInfo{Address: common.HexToAddress("0x02"), Name: "sp", Type: "provider", FILBalance: fil(1)},
+		Firing:   true,
+		StartsAt: start,
+	}
+
+	// One email per alert
+	if err := notifier.Notify(context.Background(), []Alert{alert}); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 1 || sent[0].subject != "[FIRING] sp: FIL balance 1 is below the minimum of 5" {
+		t.Fatalf("Unexpected emails %+v", sent)
+	}
+	if !strings.Contains(sent[0].body, "Balance: 1 FIL, 0 USDFC") || !strings.Contains(sent[0].body, "Since:   2026-01-02 03:04 UTC") {
+		t.Errorf("Unexpected body %q", sent[0].body)
+	}
+
+	// Critical alerts go to their own recipients
+	cfg.EmailCriticalTo = []string{"oncall@example.com"}
+	critical := alert
+	critical.Severity = SeverityCritical
+	if err := notifier.Notify(context.Background(), []Alert{critical}); err != nil {
+		t.Fatal(err)
+	}
+	if len(sent) != 2 || len(sent[1].to) != 1 || sent[1].to[0] != "oncall@example.com" {
+		t.Fatalf("Expected the critical alert to go to the critical recipients, got %+v", sent)
+	}
+
+	// With the digest, alerts are collected until the digest is due
+	cfg.EmailDigest = true
+	sent = nil
+	other := alert
+	other.key, other.Summary = "below_threshold|provider|0x02|USDFC", "USDFC balance 0 is below the minimum of 5"
+	resolved := alert
+	resolved.Firing, resolved.EndsAt = false, start.Add(time.Hour)
+	notifier.Notify(context.Background(), []Alert{alert, other})
+	notifier.Notify(context.Background(), []Alert{resolved})
+	if len(sent) != 0 {
+		t.Fatalf("Expected no emails before the digest, got %+v", sent)
+	}
+
+	subject, body, ok := notifier.digest()
+	if !ok || subject != "Wallet alert digest: 1 firing, 1 resolved" {
+		t.Fatalf("Unexpected digest %q", subject)
+	}
+	if !strings.Contains(body, "USDFC balance 0") || !strings.Contains(body, "2026-01-02 03:04 UTC until 2026-01-02 04:04 UTC") {
+		t.Errorf("Unexpected digest body %q", body)
+	}
+	if subject, _, _ := notifier.digest(); subject != "Wallet alert digest: 1 firing, 0 resolved" {
+		t.Errorf("Expected resolved alerts to be reported once, got %q", subject)
+	}
+
+	if next := nextDigest(time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC), "08:00"); !next.Equal(time.Date(2026, 1, 3, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected next digest %v", next)
+	}
+}
+
+func TestEmailNotifierSMTP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		fmt.Fprint(conn, "220 localhost ESMTP\r\n")
+		var data strings.Builder
+		inData := false
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch {
+			case inData && line == ".\r\n":
+				inData = false
+				received <- data.String()
+				fmt.Fprint(conn, "250 OK\r\n")
+			case inData:
+				data.WriteString(line)
+			case strings.HasPrefix(line, "EHLO"):
+				fmt.Fprint(conn, "250 localhost\r\n")
+			case strings.HasPrefix(line, "DATA"):
+				inData = true
+				fmt.Fprint(conn, "354 Go ahead\r\n")
+			case strings.HasPrefix(line, "QUIT"):
+				fmt.Fprint(conn, "221 Bye\r\n")
+				return
+			default:
+				fmt.Fprint(conn, "250 OK\r\n")
+			}
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+	cfg := &config.Config{SMTPHost: host, SMTPPort: portNumber, SMTPTLS: "none", EmailFrom: "exporter@example.com", EmailTo: []string{"a@example.com", "b@example.com"}}
+	notifier, err := newEmailNotifier(cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := notifier.sendSMTP(ctx, cfg.EmailTo, "Wallet alert", "line 1\nline 2\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	message := <-received
+	for _, want := range []string{"To: a@example.com, b@example.com\r\n", "Subject: Wallet alert\r\n", "\r\n\r\nline 1\r\nline 2\r\n"} {
+		if !strings.Contains(message, want) {
+			t.Errorf("Expected message to contain %q, got %q", want, message)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+
+	"wallet-exporter/internal/contracts"
+)
+
+// ABIs used to encode and decode batched calls
+var (
+	erc20ABI     = mustParseABI(contracts.ERC20MetaData)
+	paymentsABI  = mustParseABI(contracts.PaymentsMetaData)
+	multicallABI = mustParseABI(contracts.Multicall3MetaData)
+)
+
+func mustParseABI(metaData *bind.MetaData) *abi.ABI {
+	parsed, err := metaData.GetAbi()
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+// batchedCall is one read of a batch: an eth_call of data on target, or the FIL balance of
+// target if data is nil. decode receives the ABI-encoded result (a uint256 for FIL balances),
+// or an error if the call failed.
+type batchedCall struct {
+	target common.Address
+	data   []byte
+	decode func(returnData []byte, err error)
+}
+
+// unpackBigInt decodes the first return value of a method as a big.Int
+func unpackBigInt(contract *abi.ABI, method string, returnData []byte) (*big.Int, error) {
+	out, err := contract.Unpack(method, returnData)
+	if err != nil {
+		return nil, err
+	}
+	return *abi.ConvertType(out[0], new(*big.Int)).(**big.Int), nil
+}
+
+// batchesBalances reports whether wallet balances are read in one batch once all wallets are
+// known, rather than by each wallet fetch
+func (e *WalletExporter) batchesBalances() bool {
+	return e.multicall != nil || e.config.RPCBatchSize > 0
+}
+
+// balanceCalls builds the calls reading a wallet's FIL, USDFC and Payments balances, plus
+// those of its payee when payments go to a separate address. Failures follow the rules of
+// fetchWalletBalances; a failed FIL balance is reported through failed.
+func (e *WalletExporter) balanceCalls(wallet *WalletInfo, failed func(error)) []batchedCall {
+	usdfcAddr := common.HexToAddress(e.config.USDFCTokenAddress)
+	paymentsAddr := common.HexToAddress(e.config.PaymentsAddress)
+	var calls []batchedCall
+
+	filBalance := func(address common.Address, decode func(*big.Int, error)) {
+		calls = append(calls, batchedCall{target: address, decode: func(returnData []byte, err error) {
+			if err != nil {
+				decode(nil, err)
+				return
+			}
+			decode(unpackBigInt(multicallABI, "getEthBalance", returnData))
+		}})
+	}
+	usdfcBalance := func(address common.Address, decode func(*big.Int, error)) {
+		data, _ := erc20ABI.Pack("balanceOf", address)
+		calls = append(calls, batchedCall{target: usdfcAddr, data: data, decode: func(returnData []byte, err error) {
+			if err != nil {
+				decode(nil, err)
+				return
+			}
+			decode(unpackBigInt(erc20ABI, "balanceOf", returnData))
+		}})
+	}
+
+	filBalance(wallet.Address, func(balance *big.Int, err error) {
+		if err != nil {
+			failed(fmt.Errorf("failed to get FIL balance: %w", err))
+			return
+		}
+		wallet.FILBalance = balance
+	})
+	usdfcBalance(wallet.Address, func(balance *big.Int, err error) {
+		if err != nil {
+			e.logger.Warn("Failed to get USDFC balance", "address", wallet.Address.Hex(), "error", err)
+			balance = big.NewInt(0)
+		}
+		wallet.USDFCBalance = balance
+	})
+
+	if wallet.hasSeparatePayee() {
+		filBalance(wallet.Payee, func(balance *big.Int, err error) {
+			if err != nil {
+				e.logger.Warn("Failed to get payee FIL balance", "address", wallet.Payee.Hex(), "error", err)
+			}
+			wallet.PayeeFILBalance = balance
+		})
+		usdfcBalance(wallet.Payee, func(balance *big.Int, err error) {
+			if err != nil {
+				e.logger.Warn("Failed to get payee USDFC balance", "address", wallet.Payee.Hex(), "error", err)
+			}
+			wallet.PayeeUSDFCBalance = balance
+		})
+	}
+
+	accounts := make([]PaymentsAccount, len(e.paymentsTokens))
+	for i, token := range e.paymentsTokens {
+		accounts[i] = PaymentsAccount{Token: token.Symbol, Decimals: token.Decimals, PaymentsInfo: emptyPaymentsInfo()}
+
+		data, _ := paymentsABI.Pack("getAccountInfoIfSettled", token.Address, wallet.Address)
+		calls = append(calls, batchedCall{target: paymentsAddr, data: data, decode: func(returnData []byte, err error) {
+			info, err := decodePaymentsInfo(returnData, err)
+			if err != nil {
+				e.logger.Warn("Failed to get Payments info", "address", wallet.Address.Hex(), "token", token.Symbol, "error", err)
+				e.scrapeErrors.Inc()
+			}
+			accounts[i].PaymentsInfo, accounts[i].QueryFailed = info, err != nil
+		}})
+	}
+	wallet.PaymentsAccounts = accounts
+
+	return calls
+}
+
+// decodePaymentsInfo decodes a batched getAccountInfoIfSettled call. Like fetchPaymentsInfo, any
+// failure including a revert is an error: the contract reports missing accounts as zeros.
+func decodePaymentsInfo(returnData []byte, err error) (*PaymentsInfo, error) {
+	if err != nil {
+		return nil, err
+	}
+	out, err := paymentsABI.Unpack("getAccountInfoIfSettled", returnData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode account info: %w", err)
+	}
+	if len(out) < 4 {
+		return nil, fmt.Errorf("failed to decode account info: %d return values", len(out))
+	}
+	return newPaymentsInfo(
+		*abi.ConvertType(out[1], new(*big.Int)).(**big.Int),
+		*abi.ConvertType(out[2], new(*big.Int)).(**big.Int),
+		*abi.ConvertType(out[0], new(*big.Int)).(**big.Int),
+		*abi.ConvertType(out[3], new(*big.Int)).(**big.Int),
+	), nil
+}
+
+// fetchBalancesBatched reads the balances of all wallets through Multicall3 or, where that is
+// disabled or fails, JSON-RPC batches, and returns the wallets whose FIL balance could be read.
+// If no batch succeeds, every wallet falls back to individual calls.
+func (e *WalletExporter) fetchBalancesBatched(ctx context.Context, wallets []WalletInfo) []WalletInfo {
+	failures := make([]error, len(wallets))
+
+	var calls []batchedCall
+	for i := range wallets {
+		calls = append(calls, e.balanceCalls(&wallets[i], func(err error) { failures[i] = err })...)
+	}
+
+	batched := false
+	if e.multicall != nil {
+		if err := e.multicall.aggregate(ctx, calls); err != nil {
+			e.logger.Warn("Multicall3 batch failed", "calls", len(calls), "error", err)
+			e.scrapeErrors.Inc()
+		} else {
+			batched = true
+			e.logger.Debug("Read balances through Multicall3", "wallets", len(wallets), "calls", len(calls))
+		}
+	}
+	if !batched && e.config.RPCBatchSize > 0 {
+		clear(failures)
+		if err := e.batchRPC(ctx, calls); err != nil {
+			e.logger.Warn("JSON-RPC batch failed", "calls", len(calls), "error", err)
+			e.scrapeErrors.Inc()
+		} else {
+			batched = true
+			e.logger.Debug("Read balances through JSON-RPC batches", "wallets", len(wallets), "calls", len(calls))
+		}
+	}
+	if !batched {
+		e.logger.Warn("Falling back to individual balance calls", "wallets", len(wallets))
+		return e.fetchBalancesIndividually(ctx, wallets)
+	}
+
+	now := time.Now()
+	fetched := make([]WalletInfo, 0, len(wallets))
+	for i, wallet := range wallets {
+		if failures[i] != nil {
+			e.logger.Warn("Balance fetch warning", "address", wallet.Address.Hex(), "error", failures[i])
+			e.scrapeErrors.Inc()
+			continue
+		}
+		wallet.setPaymentsAccounts(wallet.PaymentsAccounts)
+		wallet.UpdatedAt = now
+		fetched = append(fetched, wallet)
+	}
+	return fetched
+}
+
+// fetchBalancesIndividually reads the balances of all wallets with one call per balance
+// and returns the wallets whose FIL balance could be read
+func (e *WalletExporter) fetchBalancesIndividually(ctx context.Context, wallets []WalletInfo) []WalletInfo {
+	failures := make([]error, len(wallets))
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, e.config.MaxConcurrentRPC)
+
+	for i := range wallets {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			failures[i] = e.fetchWalletBalances(ctx, &wallets[i])
+		}(i)
+	}
+	wg.Wait()
+
+	fetched := make([]WalletInfo, 0, len(wallets))
+	for i, wallet := range wallets {
+		if failures[i] != nil {
+			e.logger.Warn("Balance fetch warning", "address", wallet.Address.Hex(), "error", failures[i])
+			e.scrapeErrors.Inc()
+			continue
+		}
+		fetched = append(fetched, wallet)
+	}
+	return fetched
+}
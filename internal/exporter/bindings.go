@@ -0,0 +1,225 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"wallet-exporter/internal/contracts"
+)
+
+// eip1967ImplementationSlot is the storage slot holding the implementation of an EIP-1967 proxy
+var eip1967ImplementationSlot = common.HexToHash("0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc")
+
+// bindingCheckTimeout bounds the startup ABI drift check
+const bindingCheckTimeout = 30 * time.Second
+
+// contractBinding is an abigen binding compiled into the binary and the address it is bound to
+type contractBinding struct {
+	Name    string
+	Address common.Address
+	ABI     string
+	// Complete is true if the ABI describes the whole contract interface, so selectors
+	// found on-chain but missing from the binding indicate a contract upgrade
+	Complete bool
+}
+
+func (e *WalletExporter) bindings() []contractBinding {
+	return []contractBinding{
+		{Name: "WarmStorageService", Address: e.warmStorageAddress, ABI: contracts.WarmStorageServiceMetaData.ABI},
+		{Name: "WarmStorageServiceStateView", Address: e.viewAddress, ABI: contracts.WarmStorageServiceStateViewMetaData.ABI},
+		{Name: "ServiceProviderRegistry", Address: e.registryAddress, ABI: contracts.ServiceProviderRegistryMetaData.ABI, Complete: true},
+		{Name: "ERC20", Address: common.HexToAddress(e.config.USDFCTokenAddress), ABI: contracts.ERC20MetaData.ABI},
+		{Name: "Payments", Address: common.HexToAddress(e.config.PaymentsAddress), ABI: contracts.PaymentsMetaData.ABI},
+	}
+}
+
+// ContractAddress is a protocol contract the exporter is bound to
+type ContractAddress struct {
+	Name    string
+	Address common.Address
+}
+
+// Contracts returns the contracts the exporter resolved at startup: WarmStorage, the view and
+// registry contracts it points to, USDFC and Payments
+func (e *WalletExporter) Contracts() []ContractAddress {
+	var addresses []ContractAddress
+	for _, b := range e.bindings() {
+		addresses = append(addresses, ContractAddress{Name: b.Name, Address: b.Address})
+	}
+	return addresses
+}
+
+// abiBundleHash identifies the set of ABIs compiled into the binary
+func abiBundleHash(bindings []contractBinding) string {
+	h := sha256.New()
+	for _, b := range bindings {
+		h.Write([]byte(b.Name))
+		h.Write([]byte(b.ABI))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+func abiHash(abiJSON string) string {
+	sum := sha256.Sum256([]byte(abiJSON))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// buildTime returns the VCS commit time embedded by the Go toolchain, if any
+func buildTime() (time.Time, bool) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return time.Time{}, false
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.time" {
+			t, err := time.Parse(time.RFC3339, setting.Value)
+			return t, err == nil
+		}
+	}
+	return time.Time{}, false
+}
+
+// registerBindingMetrics exports the bound contracts and checks them for ABI drift
+func (e *WalletExporter) registerBindingMetrics() {
+	bindingInfoGauge := newGaugeVec(e.config.MetricsPrefix, "contract_binding_info")
+	bindingsGauge := newGauge(e.config.MetricsPrefix, "contract_bindings")
+	buildTimestampGauge := newGauge(e.config.MetricsPrefix, "contract_bindings_build_timestamp_seconds")
+	e.abiDriftGauge = newGaugeVec(e.config.MetricsPrefix, "contract_abi_drift_methods")
+
+	bindings := e.bindings()
+	bundle := abiBundleHash(bindings)
+	for _, b := range bindings {
+		bindingInfoGauge.With(prometheus.Labels{
+			"contract":   b.Name,
+			"address":    b.Address.Hex(),
+			"abi_hash":   abiHash(b.ABI),
+			"abi_bundle": bundle,
+		}).Set(1)
+	}
+	bindingsGauge.Set(float64(len(bindings)))
+	if t, ok := buildTime(); ok {
+		buildTimestampGauge.Set(float64(t.Unix()))
+	}
+
+	e.registry.MustRegister(bindingInfoGauge)
+	e.registry.MustRegister(bindingsGauge)
+	e.registry.MustRegister(buildTimestampGauge)
+	e.registry.MustRegister(e.abiDriftGauge)
+
+	ctx, cancel := context.WithTimeout(context.Background(), bindingCheckTimeout)
+	defer cancel()
+	e.checkABIDrift(ctx, bindings)
+}
+
+// checkABIDrift compares the binding's method selectors with the PUSH4 immediates found in the
+// contract bytecode (following EIP-1967 proxies). Binding methods missing on-chain break calls;
+// on-chain methods missing from a complete binding mean the exporter should be regenerated.
+func (e *WalletExporter) checkABIDrift(ctx context.Context, bindings []contractBinding) {
+	for _, b := range bindings {
+		parsed, err := abi.JSON(strings.NewReader(b.ABI))
+		if err != nil {
+			e.logger.Warn("Failed to parse binding ABI", "contract", b.Name, "error", err)
+			continue
+		}
+
+		code, err := e.implementationCode(ctx, b.Address)
+		if err != nil {
+			e.logger.Warn("Failed to read contract code for ABI check", "contract", b.Name, "address", b.Address.Hex(), "error", err)
+			continue
+		}
+		if len(code) == 0 {
+			e.logger.Warn("No contract code at bound address", "contract", b.Name, "address", b.Address.Hex())
+			continue
+		}
+
+		onChain := bytecodeSelectors(code)
+		inBinding := make(map[[4]byte]bool, len(parsed.Methods))
+
+		var missingOnChain []string
+		for _, method := range parsed.Methods {
+			var selector [4]byte
+			copy(selector[:], method.ID)
+			inBinding[selector] = true
+			if !onChain[selector] {
+				missingOnChain = append(missingOnChain, method.Sig)
+			}
+		}
+		sort.Strings(missingOnChain)
+
+		var missingInBinding []string
+		if b.Complete {
+			for selector := range onChain {
+				if !inBinding[selector] {
+					missingInBinding = append(missingInBinding, "0x"+hex.EncodeToString(selector[:]))
+				}
+			}
+			sort.Strings(missingInBinding)
+		}
+
+		e.abiDriftGauge.WithLabelValues(b.Name, "missing_onchain").Set(float64(len(missingOnChain)))
+		e.abiDriftGauge.WithLabelValues(b.Name, "missing_in_binding").Set(float64(len(missingInBinding)))
+
+		if len(missingOnChain) > 0 {
+			e.logger.Warn("Binding methods not found in on-chain contract, calls may fail",
+				"contract", b.Name, "address", b.Address.Hex(), "methods", missingOnChain)
+		}
+		if len(missingInBinding) > 0 {
+			e.logger.Warn("On-chain contract exposes methods the binding lacks, regenerate bindings against the new ABI",
+				"contract", b.Name, "address", b.Address.Hex(), "selectors", missingInBinding)
+		}
+	}
+}
+
+// implementationCode returns the code of the contract, or of its implementation if it is an EIP-1967 proxy
+func (e *WalletExporter) implementationCode(ctx context.Context, address common.Address) ([]byte, error) {
+	if implementation, ok := e.implementationAddress(ctx, address); ok {
+		address = implementation
+	}
+	return e.client.CodeAt(ctx, address, snapshotBlock(ctx))
+}
+
+// implementationAddress reads the EIP-1967 implementation slot; ok is false if the
+// contract is not a proxy or the slot could not be read
+func (e *WalletExporter) implementationAddress(ctx context.Context, address common.Address) (common.Address, bool) {
+	slot, err := e.client.StorageAt(ctx, address, eip1967ImplementationSlot, snapshotBlock(ctx))
+	if err != nil || len(slot) != 32 || bytes.Equal(slot, make([]byte, 32)) {
+		return common.Address{}, false
+	}
+	return common.BytesToAddress(slot), true
+}
+
+// bytecodeSelectors collects all PUSH4 immediates, which include the function dispatcher selectors
+func bytecodeSelectors(code []byte) map[[4]byte]bool {
+	const (
+		push1  = 0x60
+		push4  = 0x63
+		push32 = 0x7f
+	)
+
+	selectors := make(map[[4]byte]bool)
+	for i := 0; i < len(code); i++ {
+		op := code[i]
+		if op < push1 || op > push32 {
+			continue
+		}
+
+		size := int(op-push1) + 1
+		if op == push4 && i+4 < len(code) {
+			var selector [4]byte
+			copy(selector[:], code[i+1:i+5])
+			selectors[selector] = true
+		}
+		i += size
+	}
+	return selectors
+}
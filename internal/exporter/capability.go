@@ -0,0 +1,81 @@
+package exporter
+
+import (
+	"encoding/hex"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxCapabilityValueLength caps decoded capability values so a single provider cannot
+// blow up label sizes
+const maxCapabilityValueLength = 256
+
+// Capability decode problems, used as the "reason" label of the decode error counter
+const (
+	capabilityBinary  = "binary"   // Not printable UTF-8, rendered as hex
+	capabilityTooLong = "too_long" // Truncated to maxCapabilityValueLength
+)
+
+// decodeCapabilityValue turns raw registry capability bytes into a label-safe string.
+// Printable UTF-8 is returned as-is; anything else is rendered as 0x-prefixed hex.
+// Values longer than maxCapabilityValueLength are truncated. The second return value
+// names the problem encountered, or is empty if the value decoded cleanly.
+func decodeCapabilityValue(raw []byte) (string, string) {
+	value, reason := string(raw), ""
+	if !isPrintableText(raw) {
+		value, reason = "0x"+hex.EncodeToString(raw), capabilityBinary
+	}
+
+	if len(value) > maxCapabilityValueLength {
+		value = strings.ToValidUTF8(value[:maxCapabilityValueLength], "")
+		if reason == "" {
+			reason = capabilityTooLong
+		}
+	}
+
+	return value, reason
+}
+
+func isPrintableText(raw []byte) bool {
+	if !utf8.Valid(raw) {
+		return false
+	}
+	for _, r := range string(raw) {
+		if !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *WalletExporter) registerCapabilityMetrics() {
+	e.capabilityDecodeErrors = newCounterVec(e.config.MetricsPrefix, "provider_capability_decode_errors_total")
+
+	e.registry.MustRegister(e.capabilityDecodeErrors)
+}
+
+// capabilityKeyLabel returns the "key" label of a capability. Keys are written by providers, so
+// only the well-known ones are used as is and every other key is counted as "other", which keeps
+// invalid UTF-8 and unbounded cardinality out of the label.
+func capabilityKeyLabel(key string) string {
+	if _, ok := productCapabilityLabels[key]; ok {
+		return key
+	}
+	for _, region := range regionCapabilities {
+		if key == region {
+			return key
+		}
+	}
+	return "other"
+}
+
+// decodeCapability decodes a capability value and counts decode problems per key
+func (e *WalletExporter) decodeCapability(providerID uint64, key string, raw []byte) (string, string) {
+	value, reason := decodeCapabilityValue(raw)
+	if reason != "" {
+		e.capabilityDecodeErrors.WithLabelValues(e.config.Network, capabilityKeyLabel(key), reason).Inc()
+		e.logger.Debug("Capability value did not decode cleanly", "provider_id", providerID, "key", key, "reason", reason)
+	}
+	return value, reason
+}
@@ -0,0 +1,62 @@
+package exporter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeCapabilityValue(t *testing.T) {
+	tests := []struct {
+		raw      []byte
+		expected string
+		reason   string
+	}{
+		{[]byte("https://sp.example.com"), "https://sp.example.com", ""},
+		{[]byte{}, "", ""},
+		{[]byte{0x00, 0x01, 0xff}, "0x0001ff", capabilityBinary},
+		{[]byte("line\nbreak"), "0x6c696e650a627265616b", capabilityBinary},
+		{[]byte(strings.Repeat("a", 300)), strings.Repeat("a", maxCapabilityValueLength), capabilityTooLong},
+	}
+
+	for _, tt := range tests {
+		value, reason := decodeCapabilityValue(tt.raw)
+		if value != tt.expected || reason != tt.reason {
+			t.Errorf("decodeCapabilityValue(%q) = (%q, %q), want (%q, %q)", tt.raw, value, reason, tt.expected, tt.reason)
+		}
+	}
+}
+
+func TestDecodeProductCapability(t *testing.T) {
+	e := &WalletExporter{}
+	tests := []struct {
+		key      string
+		raw      []byte
+		expected string
+	}{
+		{"minPieceSizeInBytes", []byte{0x01, 0x00}, "256"},
+		{"minPieceSizeInBytes", []byte("127"), "127"},
+		{"ipniPiece", []byte{0x01}, "true"},
+		{"ipniIpfs", []byte{}, "false"},
+		{"paymentTokenAddress", make([]byte, 20), "0x0000000000000000000000000000000000000000"},
+		{"location", []byte("EU"), "EU"},
+	}
+
+	for _, tt := range tests {
+		if value := e.decodeProductCapability(1, tt.key, tt.raw); value != tt.expected {
+			t.Errorf("decodeProductCapability(%q, %x) = %q, want %q", tt.key, tt.raw, value, tt.expected)
+		}
+	}
+}
+
+func TestCapabilityKeyLabel(t *testing.T) {
+	for key, expected := range map[string]string{
+		"serviceURL":      "serviceURL",
+		"region":          "region",
+		"customKey":       "other",
+		"\xff\xfeinvalid": "other",
+	} {
+		if label := capabilityKeyLabel(key); label != expected {
+			t.Errorf("capabilityKeyLabel(%q) = %q, want %q", key, label, expected)
+		}
+	}
+}
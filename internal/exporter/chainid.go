@@ -0,0 +1,81 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// networkChainIDs are the EVM chain IDs of the networks the exporter has defaults for. Other
+// networks are not verified.
+var networkChainIDs = map[string]uint64{
+	"mainnet":     314,
+	"calibration": 314159,
+}
+
+// verifyChainID returns the chain ID of the RPC endpoint, or an error if it differs from the
+// one network expects. Networks without a known chain ID accept any chain.
+func verifyChainID(ctx context.Context, client *rpcClient, network string) (uint64, error) {
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get chain ID: %w", err)
+	}
+
+	expected, ok := networkChainIDs[network]
+	if ok && (!chainID.IsUint64() || chainID.Uint64() != expected) {
+		return 0, fmt.Errorf("%w: RPC endpoint serves chain ID %s but NETWORK=%s expects %d", errChainIDMismatch, chainID, network, expected)
+	}
+	return chainID.Uint64(), nil
+}
+
+// errChainIDMismatch marks a chain ID that differs from the one NETWORK expects
+var errChainIDMismatch = errors.New("chain ID mismatch")
+
+func (e *WalletExporter) registerChainIDMetrics(chainID uint64) {
+	e.chainIDMismatchGauge = newGauge(e.config.MetricsPrefix, "rpc_chain_id_mismatch")
+	networkInfoGauge := newGaugeVec(e.config.MetricsPrefix, "network_info")
+
+	e.registry.MustRegister(e.chainIDMismatchGauge, networkInfoGauge)
+
+	networkInfoGauge.With(prometheus.Labels{
+		"network":      e.config.Network,
+		"chain_id":     strconv.FormatUint(chainID, 10),
+		"rpc_url_host": rpcURLHost(e.config.RPCURL),
+	}).Set(1)
+}
+
+// rpcURLHost returns the host of an RPC URL, leaving out credentials, paths and query
+// parameters that may carry API tokens
+func rpcURLHost(rpcURL string) string {
+	parsed, err := url.Parse(rpcURL)
+	if err != nil {
+		return ""
+	}
+	return parsed.Host
+}
+
+// checkChainID verifies the chain of the RPC endpoint before a scrape and reports whether the
+// scrape should run. The endpoint can change chains after startup, e.g. behind a load
+// balancer, so scrapes are skipped while it serves the wrong chain rather than exporting
+// another network's balances. A failed lookup does not block the scrape; the RPC probe
+// already covers an unavailable endpoint.
+func (e *WalletExporter) checkChainID(ctx context.Context) bool {
+	_, err := verifyChainID(ctx, e.client, e.config.Network)
+	switch {
+	case err == nil:
+		e.chainIDMismatchGauge.Set(0)
+		return true
+	case errors.Is(err, errChainIDMismatch):
+		e.logger.Error("Skipping scrape", "error", err)
+		e.chainIDMismatchGauge.Set(1)
+		e.scrapeErrors.Inc()
+		return false
+	default:
+		e.logger.Warn("Failed to verify chain ID", "error", err)
+		return true
+	}
+}
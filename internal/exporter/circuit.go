@@ -0,0 +1,80 @@
+package exporter
+
+import (
+	"context"
+	"time"
+)
+
+// rpcProbeTimeout bounds the health probe sent before every scrape
+const rpcProbeTimeout = 15 * time.Second
+
+// circuitBreaker tracks consecutive failed RPC probes. The circuit opens once threshold
+// probes in a row have failed and closes again on the first successful probe.
+type circuitBreaker struct {
+	threshold int // 0 = never open
+	failures  int
+	open      bool
+}
+
+// Record adds the outcome of a probe and reports whether it opened or closed the circuit
+func (b *circuitBreaker) Record(ok bool) (changed bool) {
+	if ok {
+		b.failures = 0
+		changed = b.open
+		b.open = false
+		return changed
+	}
+
+	b.failures++
+	if !b.open && b.threshold > 0 && b.failures >= b.threshold {
+		b.open = true
+		return true
+	}
+	return false
+}
+
+func (e *WalletExporter) registerCircuitMetrics() {
+	e.circuitOpenGauge = newGauge(e.config.MetricsPrefix, "rpc_circuit_open")
+
+	e.registry.MustRegister(e.circuitOpenGauge)
+}
+
+// checkRPC probes the RPC endpoint before a scrape and returns the head block (0 if the probe
+// failed) and whether the scrape should run. While the circuit is open scrapes are skipped,
+// metrics keep their last values and the client is redialed after every failed probe in case
+// the connection itself is dead.
+func (e *WalletExporter) checkRPC(ctx context.Context) (uint64, bool) {
+	probeCtx, cancel := context.WithTimeout(ctx, rpcProbeTimeout)
+	head, err := e.client.BlockNumber(probeCtx)
+	cancel()
+
+	changed := e.circuit.Record(err == nil)
+	if err == nil {
+		if changed {
+			e.logger.Info("RPC endpoint recovered, closing circuit and resuming scrapes")
+			e.circuitOpenGauge.Set(0)
+		}
+		return head, true
+	}
+
+	e.scrapeErrors.Inc()
+	if !e.circuit.open {
+		e.logger.Warn("RPC probe failed", "consecutive_failures", e.circuit.failures, "error", err)
+		return 0, true
+	}
+
+	if changed {
+		e.logger.Error("RPC endpoint keeps failing, opening circuit and skipping scrapes",
+			"consecutive_failures", e.circuit.failures, "error", err)
+		e.circuitOpenGauge.Set(1)
+	} else {
+		e.logger.Warn("RPC endpoint still failing, skipping scrape", "consecutive_failures", e.circuit.failures, "error", err)
+	}
+
+	if err := e.client.Reconnect(); err != nil {
+		e.logger.Warn("Failed to reconnect to RPC endpoint", "error", err)
+	} else {
+		e.logger.Info("Reconnected to RPC endpoint")
+	}
+	return 0, false
+}
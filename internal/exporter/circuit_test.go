@@ -0,0 +1,36 @@
+package exporter
+
+import "testing"
+
+func TestCircuitBreakerOpensAndCloses(t *testing.T) {
+	b := circuitBreaker{threshold: 3}
+
+	for i := 1; i < 3; i++ {
+		if b.Record(false) || b.open {
+			t.Fatalf("Expected the circuit to stay closed after %d failures", i)
+		}
+	}
+	if !b.Record(false) || !b.open {
+		t.Fatal("Expected the third failure to open the circuit")
+	}
+	if b.Record(false) {
+		t.Error("Expected further failures not to report a change")
+	}
+	if !b.Record(true) || b.open {
+		t.Error("Expected a successful probe to close the circuit")
+	}
+	if b.failures != 0 {
+		t.Errorf("Expected failures to reset, got %d", b.failures)
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	b := circuitBreaker{}
+
+	for i := 0; i < 10; i++ {
+		b.Record(false)
+	}
+	if b.open {
+		t.Error("Expected a zero threshold to never open the circuit")
+	}
+}
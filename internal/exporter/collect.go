@@ -0,0 +1,54 @@
+package exporter
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// MetricCollectors returns the collector groups that can be selected with collect[] on /metrics
+func MetricCollectors() []string {
+	var collectors []string
+	for _, def := range metricDefinitions {
+		if !slices.Contains(collectors, def.Collector) {
+			collectors = append(collectors, def.Collector)
+		}
+	}
+	slices.Sort(collectors)
+	return collectors
+}
+
+// GathererFor returns a gatherer that only yields the metric families of the given collector
+// groups, so Prometheus jobs can scrape subsets of the metrics at different intervals
+func (e *WalletExporter) GathererFor(collectors []string) (prometheus.Gatherer, error) {
+	return filterGatherer(e.registry, e.config.MetricsPrefix, collectors)
+}
+
+func filterGatherer(gatherer prometheus.Gatherer, prefix string, collectors []string) (prometheus.Gatherer, error) {
+	known := MetricCollectors()
+	for _, collector := range collectors {
+		if !slices.Contains(known, collector) {
+			return nil, fmt.Errorf("unknown collector %q (known: %v)", collector, known)
+		}
+	}
+
+	names := make(map[string]bool)
+	for _, def := range metricDefinitions {
+		if slices.Contains(collectors, def.Collector) {
+			names[fmt.Sprintf("%s_%s", prefix, def.Name)] = true
+		}
+	}
+
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		families, err := gatherer.Gather()
+		selected := families[:0]
+		for _, family := range families {
+			if names[family.GetName()] {
+				selected = append(selected, family)
+			}
+		}
+		return selected, err
+	}), nil
+}
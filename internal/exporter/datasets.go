@@ -0,0 +1,132 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"wallet-exporter/internal/contracts"
+)
+
+func (e *WalletExporter) registerDataSetMetrics() {
+	e.providerDataSetsGauge = newGaugeVec(e.config.MetricsPrefix, "provider_data_sets_total")
+	e.clientDataSetsGauge = newGaugeVec(e.config.MetricsPrefix, "client_data_sets_total")
+
+	e.registry.MustRegister(e.providerDataSetsGauge)
+	e.registry.MustRegister(e.clientDataSetsGauge)
+}
+
+// fetchDataSets fills in the WarmStorage data set count of every wallet concurrently.
+// Wallets whose count could not be read get -1 and are left out of the metrics.
+func (e *WalletExporter) fetchDataSets(ctx context.Context, wallets []WalletInfo) {
+	paymentsAddr := common.HexToAddress(e.config.PaymentsAddress)
+	paymentsContract, err := contracts.NewPaymentsCaller(paymentsAddr, e.client)
+	if err != nil {
+		e.logger.Warn("Failed to create Payments contract", "error", err)
+		e.scrapeErrors.Inc()
+		return
+	}
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, e.config.MaxConcurrentRPC)
+
+	for i := range wallets {
+		wg.Add(1)
+		go func(wallet *WalletInfo) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			if ctx.Err() != nil {
+				wallet.DataSets = -1
+				return
+			}
+
+			var count int
+			var err error
+			if wallet.Type == "provider" {
+				count, err = e.countProviderDataSets(ctx, paymentsContract, wallet.Payee)
+			} else {
+				count, err = e.countClientDataSets(ctx, wallet.Address)
+			}
+			if err != nil {
+				e.logger.Warn("Failed to count data sets", "address", wallet.Address.Hex(), "error", err)
+				e.scrapeErrors.Inc()
+				wallet.DataSets = -1
+				return
+			}
+			wallet.DataSets = count
+		}(&wallets[i])
+	}
+
+	wg.Wait()
+}
+
+// countProviderDataSets counts the distinct data sets behind the payee's non-terminated USDFC
+// rails. Every WarmStorage data set pays the provider through a PDP rail (and a cache-miss rail
+// when CDN is enabled), so rails are mapped back to data sets to avoid double counting. Rails
+// that do not belong to WarmStorage map to data set 0 and are ignored.
+func (e *WalletExporter) countProviderDataSets(ctx context.Context, paymentsContract *contracts.PaymentsCaller, payee common.Address) (int, error) {
+	usdfcAddr := common.HexToAddress(e.config.USDFCTokenAddress)
+
+	rails, err := e.listRails(ctx, paymentsContract, "payee", payee, usdfcAddr)
+	if err != nil {
+		return 0, err
+	}
+
+	dataSets := make(map[uint64]bool)
+	for _, rail := range rails {
+		if rail.IsTerminated {
+			continue
+		}
+
+		dataSetID, err := e.viewContract.RailToDataSet(callOpts(ctx), rail.RailId)
+		if err != nil {
+			return 0, fmt.Errorf("failed to map rail %s to a data set: %w", rail.RailId, err)
+		}
+		if dataSetID.Sign() > 0 {
+			dataSets[dataSetID.Uint64()] = true
+		}
+	}
+
+	return len(dataSets), nil
+}
+
+// countClientDataSets counts the data sets the address created as a WarmStorage client
+func (e *WalletExporter) countClientDataSets(ctx context.Context, client common.Address) (int, error) {
+	ids, err := e.viewContract.ClientDataSets(callOpts(ctx), client)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get client data sets: %w", err)
+	}
+	return len(ids), nil
+}
+
+func (e *WalletExporter) updateDataSetMetrics(wallets []WalletInfo) {
+	e.providerDataSetsGauge.Reset()
+	e.clientDataSetsGauge.Reset()
+
+	for _, wallet := range wallets {
+		if wallet.DataSets < 0 {
+			continue
+		}
+
+		if wallet.Type == "provider" {
+			e.providerDataSetsGauge.With(prometheus.Labels{
+				"address":     wallet.Address.Hex(),
+				"network":     e.config.Network,
+				"name":        wallet.Name,
+				"provider_id": fmt.Sprintf("%d", wallet.ProviderID),
+			}).Set(float64(wallet.DataSets))
+		} else {
+			e.clientDataSetsGauge.With(prometheus.Labels{
+				"address": wallet.Address.Hex(),
+				"network": e.config.Network,
+				"name":    wallet.Name,
+				"type":    wallet.Type,
+			}).Set(float64(wallet.DataSets))
+		}
+	}
+}
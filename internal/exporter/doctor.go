@@ -0,0 +1,303 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"wallet-exporter/internal/config"
+	"wallet-exporter/internal/contracts"
+)
+
+// doctorSampleProviders is how many provider IDs the doctor tries to find one to ping
+const doctorSampleProviders = 20
+
+// DoctorCheck is the outcome of one check of the doctor command
+type DoctorCheck struct {
+	Name     string
+	OK       bool
+	Skipped  bool   // Not run because a check it depends on failed
+	Detail   string // What was found, or why the check failed
+	Hint     string // How to fix a failed check
+	Duration time.Duration
+}
+
+// doctor runs the checks in order; later checks use what earlier ones resolved
+type doctor struct {
+	ctx    context.Context
+	cfg    *config.Config
+	logger *slog.Logger
+	checks []DoctorCheck
+
+	client      *rpcClient
+	warmStorage *contracts.WarmStorageService
+	view        *contracts.WarmStorageServiceStateView
+	registry    *contracts.ServiceProviderRegistry
+}
+
+// Diagnose checks the RPC endpoint and every contract the exporter depends on, the way New
+// and a scrape use them, and returns what passed and failed with hints for the failures.
+// Unlike New it keeps going after a failure where later checks do not depend on it.
+func Diagnose(ctx context.Context, cfg *config.Config, logger *slog.Logger) []DoctorCheck {
+	d := &doctor{ctx: ctx, cfg: cfg, logger: logger}
+	defer func() {
+		if d.client != nil {
+			d.client.Close()
+		}
+	}()
+
+	if !d.run("RPC reachable", d.checkRPC) {
+		d.skip("Chain ID", "WarmStorage contract", "USDFC contract", "Payments contract", "View contract address",
+			"Registry contract address", "View contract", "Registry contract", "Sample provider", "Sample ping")
+		return d.checks
+	}
+	d.run("Chain ID", d.checkChainID)
+
+	warmStorageOK := d.run("WarmStorage contract", d.checkCode(cfg.WarmStorageAddress, "WARM_STORAGE_ADDRESS", config.NetworkDefaults[cfg.Network].WarmStorageAddress))
+	if d.run("USDFC contract", d.checkCode(cfg.USDFCTokenAddress, "USDFC_TOKEN_ADDRESS", config.NetworkDefaults[cfg.Network].USDFCTokenAddress)) {
+		d.run("USDFC token", d.checkUSDFC)
+	} else {
+		d.skip("USDFC token")
+	}
+	if d.run("Payments contract", d.checkCode(cfg.PaymentsAddress, "PAYMENTS_ADDRESS", config.NetworkDefaults[cfg.Network].PaymentsAddress)) {
+		d.run("Payments account lookup", d.checkPayments)
+	} else {
+		d.skip("Payments account lookup")
+	}
+
+	if !warmStorageOK {
+		d.skip("View contract address", "Registry contract address", "View contract", "Registry contract", "Sample provider", "Sample ping")
+		return d.checks
+	}
+	if d.run("View contract address", d.checkViewAddress) {
+		d.run("View contract", d.checkView)
+	} else {
+		d.skip("View contract")
+	}
+	if !d.run("Registry contract address", d.checkRegistryAddress) {
+		d.skip("Registry contract", "Sample provider", "Sample ping")
+		return d.checks
+	}
+	if !d.run("Registry contract", d.checkRegistry) {
+		d.skip("Sample provider", "Sample ping")
+		return d.checks
+	}
+	if d.run("Sample provider", d.checkSampleProvider) {
+		d.run("Sample ping", d.checkSamplePing)
+	} else {
+		d.skip("Sample ping")
+	}
+	return d.checks
+}
+
+// run records the outcome of check, which returns a detail on success or an error and a hint
+func (d *doctor) run(name string, check func() (string, string, error)) bool {
+	start := time.Now()
+	detail, hint, err := check()
+	result := DoctorCheck{Name: name, OK: err == nil, Detail: detail, Duration: time.Since(start)}
+	if err != nil {
+		result.Detail = err.Error()
+		result.Hint = hint
+	}
+	d.checks = append(d.checks, result)
+	return result.OK
+}
+
+func (d *doctor) skip(names ...string) {
+	for _, name := range names {
+		d.checks = append(d.checks, DoctorCheck{Name: name, Skipped: true})
+	}
+}
+
+func (d *doctor) checkRPC() (string, string, error) {
+	hint := fmt.Sprintf("Check RPC_URL (%s) and RPC_TOKEN, RPC_TOKEN_FILE or RPC_TOKEN_COMMAND; the endpoint must serve the Ethereum JSON-RPC API (/rpc/v1 on Lotus and Glif)", d.cfg.RPCURL)
+
+	token := &rpcToken{static: d.cfg.RPCToken, file: d.cfg.RPCTokenFile, command: d.cfg.RPCTokenCommand}
+	// Retries would hide how flaky the endpoint is
+	retries := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "doctor_rpc_retries_total"}, []string{"reason"})
+	client, err := newRPCClient(func() (*ethclient.Client, error) {
+		bearer, err := token.dialToken()
+		if err != nil {
+			return nil, err
+		}
+		return dialRPC(d.cfg, bearer, retries, d.logger)
+	}, d.cfg.RPCCallTimeout)
+	if err != nil {
+		return "", hint, fmt.Errorf("failed to connect: %w", err)
+	}
+	d.client = client
+
+	// The first call pays for the connection; report the latency of a warm one
+	if _, err := client.BlockNumber(d.ctx); err != nil {
+		return "", hint, fmt.Errorf("eth_blockNumber failed: %w", err)
+	}
+	start := time.Now()
+	head, err := client.BlockNumber(d.ctx)
+	if err != nil {
+		return "", hint, fmt.Errorf("eth_blockNumber failed: %w", err)
+	}
+	return fmt.Sprintf("head %d, %s per call", head, time.Since(start).Round(time.Millisecond)), "", nil
+}
+
+func (d *doctor) checkChainID() (string, string, error) {
+	chainID, err := verifyChainID(d.ctx, d.client, d.cfg.Network)
+	if errors.Is(err, errChainIDMismatch) {
+		return "", fmt.Sprintf("Point RPC_URL at a %s endpoint, or set NETWORK to the network it serves", d.cfg.Network), err
+	}
+	if err != nil {
+		return "", "The endpoint does not answer eth_chainId; use an Ethereum JSON-RPC endpoint", err
+	}
+	return fmt.Sprintf("%d (%s)", chainID, d.cfg.Network), "", nil
+}
+
+// checkCode returns a check that a contract is deployed at address
+func (d *doctor) checkCode(address, envVar, defaultAddress string) func() (string, string, error) {
+	return func() (string, string, error) {
+		hint := fmt.Sprintf("Check that %s is the %s address of the contract", envVar, d.cfg.Network)
+		if defaultAddress != "" && !strings.EqualFold(address, defaultAddress) {
+			hint += fmt.Sprintf("; the %s default is %s", d.cfg.Network, defaultAddress)
+		}
+		if !common.IsHexAddress(address) {
+			return "", hint, fmt.Errorf("%q is not an address", address)
+		}
+		code, err := d.client.CodeAt(d.ctx, common.HexToAddress(address), nil)
+		if err != nil {
+			return "", hint, fmt.Errorf("failed to read code: %w", err)
+		}
+		if len(code) == 0 {
+			return "", hint, fmt.Errorf("no contract deployed at %s", address)
+		}
+		return common.HexToAddress(address).Hex(), "", nil
+	}
+}
+
+func (d *doctor) checkUSDFC() (string, string, error) {
+	hint := "USDFC_TOKEN_ADDRESS must point at an ERC-20 token"
+	token, err := contracts.NewERC20(common.HexToAddress(d.cfg.USDFCTokenAddress), d.client)
+	if err != nil {
+		return "", hint, err
+	}
+	symbol, err := token.Symbol(callOpts(d.ctx))
+	if err != nil {
+		return "", hint, fmt.Errorf("symbol() failed: %w", err)
+	}
+	decimals, err := token.Decimals(callOpts(d.ctx))
+	if err != nil {
+		return "", hint, fmt.Errorf("decimals() failed: %w", err)
+	}
+	if _, err := token.BalanceOf(callOpts(d.ctx), common.Address{}); err != nil {
+		return "", hint, fmt.Errorf("balanceOf() failed: %w", err)
+	}
+	return fmt.Sprintf("%s, %d decimals", symbol, decimals), "", nil
+}
+
+func (d *doctor) checkPayments() (string, string, error) {
+	hint := "PAYMENTS_ADDRESS must point at the Filecoin Pay (Payments) contract WarmStorage uses"
+	payments, err := contracts.NewPayments(common.HexToAddress(d.cfg.PaymentsAddress), d.client)
+	if err != nil {
+		return "", hint, err
+	}
+	_, err = payments.GetAccountInfoIfSettled(callOpts(d.ctx), common.HexToAddress(d.cfg.USDFCTokenAddress), common.Address{})
+	if err != nil {
+		return "", hint, fmt.Errorf("getAccountInfoIfSettled() failed: %w", err)
+	}
+	return "getAccountInfoIfSettled() answers", "", nil
+}
+
+func (d *doctor) checkViewAddress() (string, string, error) {
+	hint := "WARM_STORAGE_ADDRESS must point at the WarmStorageService contract, not its view or the registry"
+	warmStorage, err := contracts.NewWarmStorageService(common.HexToAddress(d.cfg.WarmStorageAddress), d.client)
+	if err != nil {
+		return "", hint, err
+	}
+	d.warmStorage = warmStorage
+	address, err := warmStorage.ViewContractAddress(callOpts(d.ctx))
+	if err != nil {
+		return "", hint, fmt.Errorf("viewContractAddress() failed: %w", err)
+	}
+	if address == (common.Address{}) {
+		return "", "The WarmStorage deployment has no view contract set; ask its operator or use another WARM_STORAGE_ADDRESS", errors.New("view contract address is not set")
+	}
+	d.view, err = contracts.NewWarmStorageServiceStateView(address, d.client)
+	if err != nil {
+		return "", hint, err
+	}
+	return address.Hex(), "", nil
+}
+
+func (d *doctor) checkView() (string, string, error) {
+	approved, err := fetchApprovedProviders(d.ctx, d.view, d.cfg.ApprovedProvidersPage)
+	if err != nil {
+		return "", "The view contract WarmStorage points to does not match the bindings; the exporter may need updated contract bindings", fmt.Errorf("getApprovedProviders() failed: %w", err)
+	}
+	return fmt.Sprintf("%d approved providers", len(approved)), "", nil
+}
+
+func (d *doctor) checkRegistryAddress() (string, string, error) {
+	hint := "WARM_STORAGE_ADDRESS must point at the WarmStorageService contract"
+	if d.warmStorage == nil {
+		warmStorage, err := contracts.NewWarmStorageService(common.HexToAddress(d.cfg.WarmStorageAddress), d.client)
+		if err != nil {
+			return "", hint, err
+		}
+		d.warmStorage = warmStorage
+	}
+	address, err := d.warmStorage.ServiceProviderRegistry(callOpts(d.ctx))
+	if err != nil {
+		return "", hint, fmt.Errorf("serviceProviderRegistry() failed: %w", err)
+	}
+	d.registry, err = contracts.NewServiceProviderRegistry(address, d.client)
+	if err != nil {
+		return "", hint, err
+	}
+	return address.Hex(), "", nil
+}
+
+func (d *doctor) checkRegistry() (string, string, error) {
+	count, err := d.registry.GetProviderCount(callOpts(d.ctx))
+	if err != nil {
+		return "", "The registry WarmStorage points to does not match the bindings; the exporter may need updated contract bindings", fmt.Errorf("getProviderCount() failed: %w", err)
+	}
+	return fmt.Sprintf("%d providers", count), "", nil
+}
+
+func (d *doctor) checkSampleProvider() (string, string, error) {
+	result, err := d.registry.GetProvider(callOpts(d.ctx), big.NewInt(1))
+	if err != nil {
+		return "", "The registry does not decode provider 1; the exporter may need updated contract bindings", fmt.Errorf("getProvider(1) failed: %w", err)
+	}
+	return fmt.Sprintf("provider 1 is %q at %s", result.Info.Name, result.Info.ServiceProvider.Hex()), "", nil
+}
+
+// checkSamplePing pings the PDP service of the first active provider with one, the way scrapes do
+func (d *doctor) checkSamplePing() (string, string, error) {
+	e := &WalletExporter{config: d.cfg, registryContract: d.registry, metadataCache: newMetadataCache(0), logger: d.logger}
+	for id := uint64(1); id <= doctorSampleProviders; id++ {
+		result, err := d.registry.GetProvider(callOpts(d.ctx), new(big.Int).SetUint64(id))
+		if err != nil || !result.Info.IsActive {
+			continue
+		}
+		ping, ok := e.pingProduct(d.ctx, WalletInfo{ProviderID: id, Name: result.Info.Name}, pdpProductType)
+		if !ok {
+			continue
+		}
+		if !ping.Success {
+			reason := ping.Error
+			if reason == "" {
+				reason = fmt.Sprintf("HTTP %d", ping.StatusCode)
+			}
+			return "", "Providers may be down; if every ping fails, check that outbound HTTPS is allowed from this host",
+				fmt.Errorf("%s (provider %d) failed: %s", ping.PingURL, id, reason)
+		}
+		return fmt.Sprintf("%s (provider %d) answered in %s", ping.PingURL, id, ping.Duration.Round(time.Millisecond)), "", nil
+	}
+	return "", "", fmt.Errorf("none of the first %d providers is active with a PDP service URL", doctorSampleProviders)
+}
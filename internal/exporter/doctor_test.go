@@ -0,0 +1,63 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"wallet-exporter/internal/config"
+)
+
+func TestDiagnoseWrongChainAndAddresses(t *testing.T) {
+	// An endpoint of another chain with no contracts deployed
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		result := map[string]string{"eth_blockNumber": "0x10", "eth_chainId": "0x1", "eth_getCode": "0x"}[req.Method]
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": result})
+	}))
+	defer server.Close()
+
+	defaults := config.NetworkDefaults["calibration"]
+	cfg := &config.Config{
+		Network:            "calibration",
+		RPCURL:             server.URL,
+		RPCCallTimeout:     5 * time.Second,
+		WarmStorageAddress: defaults.WarmStorageAddress,
+		USDFCTokenAddress:  "0x0000000000000000000000000000000000000001",
+		PaymentsAddress:    defaults.PaymentsAddress,
+	}
+	checks := Diagnose(context.Background(), cfg, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	results := make(map[string]DoctorCheck, len(checks))
+	for _, check := range checks {
+		results[check.Name] = check
+	}
+	if !results["RPC reachable"].OK {
+		t.Errorf("Expected the RPC check to pass, got %q", results["RPC reachable"].Detail)
+	}
+	if check := results["Chain ID"]; check.OK || !strings.Contains(check.Hint, "NETWORK") {
+		t.Errorf("Expected the chain ID check to fail with a NETWORK hint, got %+v", check)
+	}
+	if check := results["USDFC contract"]; check.OK || !strings.Contains(check.Hint, "USDFC_TOKEN_ADDRESS") || !strings.Contains(check.Hint, defaults.USDFCTokenAddress) {
+		t.Errorf("Expected the USDFC check to fail with the default address in its hint, got %+v", check)
+	}
+	if check := results["Payments contract"]; check.OK || strings.Contains(check.Hint, "default is") {
+		t.Errorf("Expected the Payments check to fail without suggesting the configured default, got %+v", check)
+	}
+	for _, name := range []string{"USDFC token", "View contract address", "Sample ping"} {
+		if !results[name].Skipped {
+			t.Errorf("Expected %s to be skipped, got %+v", name, results[name])
+		}
+	}
+}
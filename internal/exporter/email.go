@@ -0,0 +1,294 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	"net/smtp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"wallet-exporter/internal/config"
+)
+
+const (
+	defaultEmailSubject = `[{{if .Firing}}FIRING{{else}}RESOLVED{{end}}] {{.Wallet.Name}}: {{.Summary}}`
+	defaultEmailBody    = `{{if .Firing}}Alert firing{{else}}Alert resolved{{end}}: {{.Rule}} ({{.Severity}})
+
+{{.Summary}}
+
+Wallet:  {{.Wallet.Name}} ({{.Wallet.Type}})
+Address: {{.Wallet.Address.Hex}}
+Balance: {{units .Wallet.FILBalance 18}} FIL, {{units .Wallet.USDFCBalance 18}} USDFC
+Since:   {{.StartsAt.UTC.Format "2006-01-02 15:04 MST"}}
+{{- if not .Firing}}
+Until:   {{.EndsAt.UTC.Format "2006-01-02 15:04 MST"}}{{end}}
+{{- if .URL}}
+
+{{.URL}}{{end}}
+`
+)
+
+// emailTimeFormat is how times are written in digests
+const emailTimeFormat = "2006-01-02 15:04 MST"
+
+// emailNotifier sends alerts by email, either one message per alert or, with EMAIL_DIGEST,
+// one summary a day
+type emailNotifier struct {
+	cfg     *config.Config
+	subject *template.Template
+	body    *template.Template
+	send    func(ctx context.Context, to []string, subject, body string) error
+	logger  *slog.Logger
+
+	// Digest state: alerts firing now, and alerts that resolved since the last digest
+	mu       sync.Mutex
+	firing   map[string]Alert
+	resolved []Alert
+}
+
+func newEmailNotifier(cfg *config.Config, logger *slog.Logger) (*emailNotifier, error) {
+	subject, body := cfg.EmailSubjectTemplate, cfg.EmailBodyTemplate
+	if subject == "" {
+		subject = defaultEmailSubject
+	}
+	if body == "" {
+		body = defaultEmailBody
+	}
+
+	funcs := template.FuncMap{"units": FormatUnits}
+	subjectTmpl, err := template.New("subject").Funcs(funcs).Parse(subject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EMAIL_SUBJECT_TEMPLATE: %w", err)
+	}
+	bodyTmpl, err := template.New("body").Funcs(funcs).Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EMAIL_BODY_TEMPLATE: %w", err)
+	}
+
+	n := &emailNotifier{
+		cfg:     cfg,
+		subject: subjectTmpl,
+		body:    bodyTmpl,
+		logger:  logger,
+		firing:  make(map[string]Alert),
+	}
+	n.send = n.sendSMTP
+	return n, nil
+}
+
+func (n *emailNotifier) Name() string { return "email" }
+
+// Notify emails the alerts as one message, or records them for the next digest
+func (n *emailNotifier) Notify(ctx context.Context, alerts []Alert) error {
+	if n.cfg.EmailDigest {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		for _, alert := range alerts {
+			if alert.Firing {
+				n.firing[alert.key] = alert
+			} else {
+				delete(n.firing, alert.key)
+				n.resolved = append(n.resolved, alert)
+			}
+		}
+		return nil
+	}
+
+	if len(alerts) == 0 {
+		return nil
+	}
+
+	var body bytes.Buffer
+	for i, alert := range alerts {
+		if i > 0 {
+			body.WriteString("\n---\n\n")
+		}
+		if err := n.body.Execute(&body, alert); err != nil {
+			return fmt.Errorf("failed to render email body: %w", err)
+		}
+	}
+
+	// The subject template describes one alert, a group is summarized instead
+	if len(alerts) > 1 {
+		state := "FIRING"
+		if !alerts[0].Firing {
+			state = "RESOLVED"
+		}
+		return n.send(ctx, n.recipients(alerts[0]), fmt.Sprintf("[%s] %d wallets: %s", state, len(alerts), alerts[0].Rule), body.String())
+	}
+	var subject bytes.Buffer
+	if err := n.subject.Execute(&subject, alerts[0]); err != nil {
+		return fmt.Errorf("failed to render email subject: %w", err)
+	}
+	return n.send(ctx, n.recipients(alerts[0]), strings.TrimSpace(subject.String()), body.String())
+}
+
+// recipients returns EMAIL_CRITICAL_TO for critical alerts if set, else EMAIL_TO
+func (n *emailNotifier) recipients(alert Alert) []string {
+	if alert.Severity == SeverityCritical && len(n.cfg.EmailCriticalTo) > 0 {
+		return n.cfg.EmailCriticalTo
+	}
+	return n.cfg.EmailTo
+}
+
+// run sends the digest every day at EMAIL_DIGEST_TIME (UTC) until ctx is canceled
+func (n *emailNotifier) run(ctx context.Context) {
+	if !n.cfg.EmailDigest {
+		return
+	}
+
+	for {
+		timer := time.NewTimer(time.Until(nextDigest(time.Now(), n.cfg.EmailDigestTime)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		subject, body, ok := n.digest()
+		if !ok {
+			continue
+		}
+		sendCtx, cancel := context.WithTimeout(ctx, notifyTimeout)
+		if err := n.send(sendCtx, n.cfg.EmailTo, subject, body); err != nil {
+			n.logger.Warn("Failed to send alert digest", "error", err)
+		}
+		cancel()
+	}
+}
+
+// nextDigest returns the next time after now at the given "HH:MM" UTC time of day
+func nextDigest(now time.Time, timeOfDay string) time.Time {
+	hour, minute, _ := strings.Cut(timeOfDay, ":")
+	h, _ := strconv.Atoi(hour)
+	m, _ := strconv.Atoi(minute)
+
+	now = now.UTC()
+	next := time.Date(now.Year(), now.Month(), now.Day(), h, m, 0, 0, time.UTC)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// digest renders the alerts firing now and the ones resolved since the last digest, and
+// forgets the resolved ones. It returns false if there is nothing to report.
+func (n *emailNotifier) digest() (string, string, bool) {
+	n.mu.Lock()
+	firing := make([]Alert, 0, len(n.firing))
+	for _, alert := range n.firing {
+		firing = append(firing, alert)
+	}
+	resolved := n.resolved
+	n.resolved = nil
+	n.mu.Unlock()
+
+	if len(firing) == 0 && len(resolved) == 0 {
+		return "", "", false
+	}
+	sort.Slice(firing, func(i, j int) bool { return firing[i].StartsAt.Before(firing[j].StartsAt) })
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Firing (%d)\n", len(firing))
+	for _, alert := range firing {
+		writeDigestAlert(&b, alert)
+		fmt.Fprintf(&b, "    since %s\n", alert.StartsAt.UTC().Format(emailTimeFormat))
+	}
+	fmt.Fprintf(&b, "\nResolved since the last digest (%d)\n", len(resolved))
+	for _, alert := range resolved {
+		writeDigestAlert(&b, alert)
+		fmt.Fprintf(&b, "    %s until %s\n", alert.StartsAt.UTC().Format(emailTimeFormat), alert.EndsAt.UTC().Format(emailTimeFormat))
+	}
+
+	subject := fmt.Sprintf("Wallet alert digest: %d firing, %d resolved", len(firing), len(resolved))
+	return subject, b.String(), true
+}
+
+func writeDigestAlert(b *strings.Builder, alert Alert) {
+	fmt.Fprintf(b, "  - %s (%s, %s): %s\n", alert.Wallet.Name, alert.Wallet.Type, alert.Wallet.Address.Hex(), alert.Summary)
+	if alert.URL != "" {
+		fmt.Fprintf(b, "    %s\n", alert.URL)
+	}
+}
+
+// sendSMTP delivers a plain text email to the recipients through the SMTP server
+func (n *emailNotifier) sendSMTP(ctx context.Context, to []string, subject, body string) error {
+	addr := net.JoinHostPort(n.cfg.SMTPHost, strconv.Itoa(n.cfg.SMTPPort))
+	dialer := &net.Dialer{}
+
+	var conn net.Conn
+	var err error
+	if n.cfg.SMTPTLS == "tls" {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: n.cfg.SMTPHost}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, n.cfg.SMTPHost)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("SMTP handshake failed: %w", err)
+	}
+	defer client.Close()
+
+	if n.cfg.SMTPTLS == "starttls" {
+		if err := client.StartTLS(&tls.Config{ServerName: n.cfg.SMTPHost}); err != nil {
+			return fmt.Errorf("SMTP STARTTLS failed: %w", err)
+		}
+	}
+	if n.cfg.SMTPUsername != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.cfg.SMTPUsername, n.cfg.SMTPPassword, n.cfg.SMTPHost)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(n.cfg.EmailFrom); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM failed: %w", err)
+	}
+	for _, recipient := range to {
+		if err := client.Rcpt(recipient); err != nil {
+			return fmt.Errorf("SMTP RCPT TO %s failed: %w", recipient, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA failed: %w", err)
+	}
+	if _, err := w.Write(n.message(to, subject, body, time.Now())); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("SMTP server rejected the email: %w", err)
+	}
+	return client.Quit()
+}
+
+// message builds the email with its headers
+func (n *emailNotifier) message(to []string, subject, body string, now time.Time) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", n.cfg.EmailFrom)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+	return b.Bytes()
+}
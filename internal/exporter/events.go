@@ -0,0 +1,152 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"wallet-exporter/internal/contracts"
+)
+
+// eventsMaxBlockRange is the widest block range requested per eth_getLogs call
+// (Lotus rejects filters spanning more than 2880 epochs by default)
+const eventsMaxBlockRange = 2880
+
+func (e *WalletExporter) registerPaymentsEventMetrics() {
+	e.depositsCounter = newCounterVec(e.config.MetricsPrefix, "wallet_payments_deposits_total")
+	e.depositedAmountCounter = newCounterVec(e.config.MetricsPrefix, "wallet_payments_deposited_amount_total")
+	e.withdrawalsCounter = newCounterVec(e.config.MetricsPrefix, "wallet_payments_withdrawals_total")
+	e.withdrawnAmountCounter = newCounterVec(e.config.MetricsPrefix, "wallet_payments_withdrawn_amount_total")
+
+	e.registry.MustRegister(e.depositsCounter)
+	e.registry.MustRegister(e.depositedAmountCounter)
+	e.registry.MustRegister(e.withdrawalsCounter)
+	e.registry.MustRegister(e.withdrawnAmountCounter)
+}
+
+// collectPaymentsEvents counts DepositRecorded and WithdrawRecorded events of the given wallets
+// in the blocks mined since the previous call. The first call only records the head block, so
+// counters start at zero at startup. A failed range is retried on the next scrape.
+func (e *WalletExporter) collectPaymentsEvents(ctx context.Context, wallets []WalletInfo) {
+	head, err := e.headBlock(ctx)
+	if err != nil {
+		e.logger.Warn("Failed to get head block for Payments events", "error", err)
+		e.scrapeErrors.Inc()
+		return
+	}
+
+	byAddress := make(map[common.Address]WalletInfo, len(wallets))
+	addresses := make([]common.Address, 0, len(wallets))
+	for _, wallet := range wallets {
+		byAddress[wallet.Address] = wallet
+		addresses = append(addresses, wallet.Address)
+
+		// Initialize series so the first event shows up as an increase
+		for _, token := range e.paymentsTokens {
+			labels := e.eventLabels(wallet, token.Symbol)
+			e.depositsCounter.With(labels)
+			e.depositedAmountCounter.With(labels)
+			e.withdrawalsCounter.With(labels)
+			e.withdrawnAmountCounter.With(labels)
+		}
+	}
+
+	if len(addresses) == 0 {
+		return
+	}
+
+	paymentsAddr := common.HexToAddress(e.config.PaymentsAddress)
+	filterer, err := contracts.NewPaymentsFilterer(paymentsAddr, e.client)
+	if err != nil {
+		e.logger.Warn("Failed to create Payments filterer", "error", err)
+		e.scrapeErrors.Inc()
+		return
+	}
+
+	tokens := make(map[common.Address]paymentsToken, len(e.paymentsTokens))
+	tokenAddresses := make([]common.Address, 0, len(e.paymentsTokens))
+	for _, token := range e.paymentsTokens {
+		tokens[token.Address] = token
+		tokenAddresses = append(tokenAddresses, token.Address)
+	}
+
+	err = filterNewBlocks(ctx, &e.eventsNextBlock, head, func(opts *bind.FilterOpts) error {
+		if err := e.filterDeposits(filterer, opts, tokenAddresses, addresses, tokens, byAddress); err != nil {
+			return fmt.Errorf("failed to filter deposits: %w", err)
+		}
+		if err := e.filterWithdrawals(filterer, opts, tokenAddresses, addresses, tokens, byAddress); err != nil {
+			return fmt.Errorf("failed to filter withdrawals: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		e.logger.Warn("Failed to collect Payments events", "error", err)
+		e.scrapeErrors.Inc()
+	}
+}
+
+// filterNewBlocks calls filter for the blocks from *next up to head in chunks of at most
+// eventsMaxBlockRange, advancing *next past every chunk that was filtered successfully.
+// When *next is zero the cursor is only initialized to head+1, so history is never backfilled.
+func filterNewBlocks(ctx context.Context, next *uint64, head uint64, filter func(opts *bind.FilterOpts) error) error {
+	if *next == 0 {
+		*next = head + 1
+		return nil
+	}
+
+	for start := *next; start <= head; start += eventsMaxBlockRange {
+		end := min(start+eventsMaxBlockRange-1, head)
+		if err := filter(&bind.FilterOpts{Start: start, End: &end, Context: ctx}); err != nil {
+			return fmt.Errorf("blocks %d-%d: %w", start, end, err)
+		}
+		*next = end + 1
+	}
+	return nil
+}
+
+// filterDeposits counts deposits credited to the monitored wallets
+func (e *WalletExporter) filterDeposits(filterer *contracts.PaymentsFilterer, opts *bind.FilterOpts, tokenAddresses, addresses []common.Address, tokens map[common.Address]paymentsToken, wallets map[common.Address]WalletInfo) error {
+	it, err := filterer.FilterDepositRecorded(opts, tokenAddresses, nil, addresses)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	for it.Next() {
+		token := tokens[it.Event.Token]
+		labels := e.eventLabels(wallets[it.Event.To], token.Symbol)
+		e.depositsCounter.With(labels).Inc()
+		e.depositedAmountCounter.With(labels).Add(tokenAmount(it.Event.Amount, token.Decimals))
+	}
+	return it.Error()
+}
+
+// filterWithdrawals counts withdrawals debited from the monitored wallets
+func (e *WalletExporter) filterWithdrawals(filterer *contracts.PaymentsFilterer, opts *bind.FilterOpts, tokenAddresses, addresses []common.Address, tokens map[common.Address]paymentsToken, wallets map[common.Address]WalletInfo) error {
+	it, err := filterer.FilterWithdrawRecorded(opts, tokenAddresses, addresses, nil)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	for it.Next() {
+		token := tokens[it.Event.Token]
+		labels := e.eventLabels(wallets[it.Event.From], token.Symbol)
+		e.withdrawalsCounter.With(labels).Inc()
+		e.withdrawnAmountCounter.With(labels).Add(tokenAmount(it.Event.Amount, token.Decimals))
+	}
+	return it.Error()
+}
+
+func (e *WalletExporter) eventLabels(wallet WalletInfo, token string) prometheus.Labels {
+	return prometheus.Labels{
+		"address": wallet.Address.Hex(),
+		"network": e.config.Network,
+		"name":    wallet.Name,
+		"type":    wallet.Type,
+		"token":   token,
+	}
+}
@@ -0,0 +1,220 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"wallet-exporter/internal/config"
+	"wallet-exporter/internal/filaddr"
+)
+
+// explorerTimeout bounds a single block explorer request
+const explorerTimeout = 10 * time.Second
+
+// ExplorerAddressInfo is data about an address that a block explorer indexes but an RPC node
+// cannot serve without scanning history
+type ExplorerAddressInfo struct {
+	ActorType       string    // e.g. "evm", "account", "multisig" (empty if unknown)
+	MessageCount    int64     // -1 if the explorer does not report it
+	LastMessageTime time.Time // Zero if the explorer does not report it
+}
+
+// explorerSource is a block explorer API wallets are enriched from. RPC-only deployments run
+// without one.
+type explorerSource interface {
+	Name() string
+	AddressInfo(ctx context.Context, address string) (ExplorerAddressInfo, error)
+}
+
+// explorerEntry is the cached explorer data of a wallet (only touched by the scrape loop)
+type explorerEntry struct {
+	Info      ExplorerAddressInfo
+	FetchedAt time.Time
+}
+
+// newExplorerSource returns the explorer selected by EXPLORER, or nil if none is configured
+func newExplorerSource(cfg *config.Config) (explorerSource, error) {
+	client := &http.Client{Timeout: explorerTimeout}
+
+	switch cfg.Explorer {
+	case "":
+		return nil, nil
+	case "filfox":
+		baseURL := cfg.ExplorerURL
+		if baseURL == "" {
+			baseURL = "https://filfox.info/api/v1"
+			if cfg.Network != "mainnet" {
+				baseURL = "https://calibration.filfox.info/api/v1"
+			}
+		}
+		return &filfoxSource{baseURL: strings.TrimRight(baseURL, "/"), client: client}, nil
+	case "beryx":
+		baseURL := cfg.ExplorerURL
+		if baseURL == "" {
+			baseURL = "https://api.zondax.ch/fil/data/v3/" + cfg.Network
+		}
+		return &beryxSource{baseURL: strings.TrimRight(baseURL, "/"), token: cfg.ExplorerToken, client: client}, nil
+	default:
+		return nil, fmt.Errorf("unknown explorer %q", cfg.Explorer)
+	}
+}
+
+// getJSON fetches a URL and decodes its JSON body into result
+func getJSON(ctx context.Context, client *http.Client, rawURL string, header http.Header, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// filfoxSource reads address data from the Filfox API
+type filfoxSource struct {
+	baseURL string
+	client  *http.Client
+}
+
+func (s *filfoxSource) Name() string { return "filfox" }
+
+func (s *filfoxSource) AddressInfo(ctx context.Context, address string) (ExplorerAddressInfo, error) {
+	var result struct {
+		Actor        string `json:"actor"`
+		MessageCount int64  `json:"messageCount"`
+		LastSeen     int64  `json:"lastSeen"`
+	}
+	if err := getJSON(ctx, s.client, s.baseURL+"/address/"+url.PathEscape(address), nil, &result); err != nil {
+		return ExplorerAddressInfo{}, err
+	}
+
+	info := ExplorerAddressInfo{ActorType: result.Actor, MessageCount: result.MessageCount}
+	if result.LastSeen > 0 {
+		info.LastMessageTime = time.Unix(result.LastSeen, 0)
+	}
+	return info, nil
+}
+
+// beryxSource reads address data from the Beryx (Zondax) API. Beryx only reports the actor
+// type here; message counts and activity are left unknown.
+type beryxSource struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+func (s *beryxSource) Name() string { return "beryx" }
+
+func (s *beryxSource) AddressInfo(ctx context.Context, address string) (ExplorerAddressInfo, error) {
+	var result struct {
+		AccountInfo struct {
+			ActorType string `json:"actor_type"`
+		} `json:"account_info"`
+	}
+	header := http.Header{}
+	if s.token != "" {
+		header.Set("Authorization", "Bearer "+s.token)
+	}
+	if err := getJSON(ctx, s.client, s.baseURL+"/account/info/"+url.PathEscape(address), header, &result); err != nil {
+		return ExplorerAddressInfo{}, err
+	}
+	return ExplorerAddressInfo{ActorType: result.AccountInfo.ActorType, MessageCount: -1}, nil
+}
+
+func (e *WalletExporter) registerExplorerMetrics() {
+	e.actorInfoGauge = newGaugeVec(e.config.MetricsPrefix, "wallet_actor_info")
+	e.messageCountGauge = newGaugeVec(e.config.MetricsPrefix, "wallet_message_count")
+	e.lastMessageGauge = newGaugeVec(e.config.MetricsPrefix, "wallet_last_message_timestamp_seconds")
+
+	e.registry.MustRegister(e.actorInfoGauge, e.messageCountGauge, e.lastMessageGauge)
+}
+
+// refreshExplorerInfo fetches explorer data for wallets whose cached data is older than
+// EXPLORER_REFRESH. Requests are sent one at a time to stay within public rate limits;
+// wallets that fail keep their previous data and are retried on the next scrape.
+func (e *WalletExporter) refreshExplorerInfo(ctx context.Context, wallets []WalletInfo) {
+	now := time.Now()
+	for _, wallet := range wallets {
+		if entry, ok := e.explorerInfo[wallet.Address]; ok && now.Sub(entry.FetchedAt) < e.config.ExplorerRefresh {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		info, err := e.explorer.AddressInfo(ctx, filaddr.FromEth(wallet.Address, e.config.Network))
+		if err != nil {
+			e.logger.Warn("Failed to fetch explorer data", "explorer", e.explorer.Name(), "address", wallet.Address.Hex(), "error", err)
+			e.scrapeErrors.Inc()
+			continue
+		}
+		e.explorerInfo[wallet.Address] = explorerEntry{Info: info, FetchedAt: now}
+	}
+}
+
+func (e *WalletExporter) updateExplorerMetrics(wallets []WalletInfo) {
+	e.actorInfoGauge.Reset()
+	e.messageCountGauge.Reset()
+	e.lastMessageGauge.Reset()
+
+	monitored := make(map[common.Address]bool, len(wallets))
+	for _, wallet := range wallets {
+		monitored[wallet.Address] = true
+
+		entry, ok := e.explorerInfo[wallet.Address]
+		if !ok {
+			continue
+		}
+		labels := prometheus.Labels{
+			"address": wallet.Address.Hex(),
+			"network": e.config.Network,
+			"name":    wallet.Name,
+			"type":    wallet.Type,
+		}
+
+		if entry.Info.ActorType != "" {
+			e.actorInfoGauge.With(withLabel(labels, "actor_type", entry.Info.ActorType)).Set(1)
+		}
+		if entry.Info.MessageCount >= 0 {
+			e.messageCountGauge.With(labels).Set(float64(entry.Info.MessageCount))
+		}
+		if !entry.Info.LastMessageTime.IsZero() {
+			e.lastMessageGauge.With(labels).Set(float64(entry.Info.LastMessageTime.Unix()))
+		}
+	}
+
+	// Forget wallets that are no longer monitored
+	for address := range e.explorerInfo {
+		if !monitored[address] {
+			delete(e.explorerInfo, address)
+		}
+	}
+}
+
+// withLabel returns a copy of labels with one label added
+func withLabel(labels prometheus.Labels, name, value string) prometheus.Labels {
+	out := prometheus.Labels{name: value}
+	for k, v := range labels {
+		out[k] = v
+	}
+	return out
+}
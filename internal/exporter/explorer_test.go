@@ -0,0 +1,32 @@
+package exporter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFilfoxAddressInfo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/address/t410fabc" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"actor":"evm","messageCount":42,"lastSeen":1700000000}`))
+	}))
+	defer server.Close()
+
+	source := &filfoxSource{baseURL: server.URL, client: server.Client()}
+	info, err := source.AddressInfo(context.Background(), "t410fabc")
+	if err != nil {
+		t.Fatalf("AddressInfo failed: %v", err)
+	}
+	if info.ActorType != "evm" || info.MessageCount != 42 || info.LastMessageTime.Unix() != 1700000000 {
+		t.Errorf("Unexpected address info %+v", info)
+	}
+
+	if _, err := source.AddressInfo(context.Background(), "t410fmissing"); err == nil {
+		t.Error("Expected an error for a non-200 response")
+	}
+}
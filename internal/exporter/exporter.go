@@ -2,6 +2,7 @@ package exporter
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"math/big"
@@ -16,33 +17,71 @@ import (
 
 	"wallet-exporter/internal/config"
 	"wallet-exporter/internal/contracts"
+	"wallet-exporter/internal/history"
 )
 
 type WalletInfo struct {
 	Address      common.Address
 	Name         string
-	Type         string // "provider", "client", "operator", "other"
-	ProviderID   uint64 // Only for providers
-	IsActive     bool   // Only for providers
-	IsApproved   bool   // Only for providers - whether approved in WarmStorage
-	Description  string // Only for providers
+	Type         string         // "provider", "client", "operator", "other"
+	ProviderID   uint64         // Only for providers
+	Payee        common.Address // Only for providers - address the provider's rails pay out to
+	IsActive     bool           // Only for providers
+	IsApproved   bool           // Only for providers - whether approved in WarmStorage
+	Description  string         // Only for providers
 	FILBalance   *big.Int
 	USDFCBalance *big.Int
 
+	// Balances of the payee address (only for providers whose payee differs from the
+	// service provider address; nil otherwise or if the lookup failed)
+	PayeeFILBalance   *big.Int
+	PayeeUSDFCBalance *big.Int
+
 	// Payments contract account info
 	PaymentsFunds       *big.Int // Total funds in Payments contract
 	PaymentsAvailable   *big.Int // Available funds (funds - actualLockup)
 	PaymentsLocked      *big.Int // Current locked funds
 	PaymentsFundedUntil *big.Int // Epoch when funds run out (calculated)
+	PaymentsLockupRate  *big.Int // Current lockup rate per epoch
+
+	// Payments contract accounts for every configured token (USDFC first)
+	PaymentsAccounts []PaymentsAccount
+
+	// Balance thresholds (0 = no threshold)
+	MinFIL   float64
+	MinUSDFC float64
+
+	// Products registered by the provider (only for providers)
+	Products []ProviderProduct
+
+	// Payments rails the wallet takes part in (only when EXPORT_RAILS is enabled)
+	Rails []RailInfo
+
+	// Active WarmStorage data sets (only when EXPORT_DATA_SETS is enabled, -1 if the count failed)
+	DataSets int
+
+	// Carried over from an earlier scrape because the last one timed out before reaching it
+	Stale bool
+
+	// When the balances were last read or confirmed unchanged (zero if unknown)
+	UpdatedAt time.Time
 }
 
 type WalletExporter struct {
 	config              *config.Config
-	client              *ethclient.Client
+	client              *rpcClient
+	rpcToken            *rpcToken
 	warmStorageContract *contracts.WarmStorageService
 	viewContract        *contracts.WarmStorageServiceStateView
 	registryContract    *contracts.ServiceProviderRegistry
 	usdfcContract       *contracts.ERC20
+	paymentsTokens      []paymentsToken
+	multicall           *multicallBatcher // Nil when balances are read with individual calls
+
+	// Addresses the contract bindings were resolved to at startup
+	warmStorageAddress common.Address
+	viewAddress        common.Address
+	registryAddress    common.Address
 
 	// Prometheus metrics
 	registry                 *prometheus.Registry
@@ -53,28 +92,227 @@ type WalletExporter struct {
 	paymentsAvailableGauge   *prometheus.GaugeVec
 	paymentsLockedGauge      *prometheus.GaugeVec
 	paymentsFundedUntilGauge *prometheus.GaugeVec
+	paymentsLockupRateGauge  *prometheus.GaugeVec
+	paymentsQueryErrorGauge  *prometheus.GaugeVec
+	filRunwayGauge           *prometheus.GaugeVec
+	usdfcRunwayGauge         *prometheus.GaugeVec
+	filMinThresholdGauge     *prometheus.GaugeVec
+	usdfcMinThresholdGauge   *prometheus.GaugeVec
+	belowThresholdGauge      *prometheus.GaugeVec
+	rawBalanceHighGauge      *prometheus.GaugeVec // Only registered when EXPORT_RAW_BALANCES is enabled
+	rawBalanceLowGauge       *prometheus.GaugeVec
 	scrapeDuration           prometheus.Gauge
-	scrapeErrors             prometheus.Counter
+	lastSuccessGauge         prometheus.Gauge
+	scrapeErrors             *errorCounter
+	scrapeTimeoutsCounter    prometheus.Counter
+	reorgsCounter            prometheus.Counter
+	walletStaleGauge         *prometheus.GaugeVec
+	walletUpdatedGauge       *prometheus.GaugeVec
+	rpcRetriesCounter        *prometheus.CounterVec
+	rejectedRequestsCounter  *prometheus.CounterVec
+	circuitOpenGauge         prometheus.Gauge
+	chainIDMismatchGauge     prometheus.Gauge
+	abiDriftGauge            *prometheus.GaugeVec
+
+	// Contract upgrade detection (state only touched by the scrape loop)
+	contractStates              map[string]contractState // By binding name
+	contractImplementationGauge *prometheus.GaugeVec
+	contractChangesCounter      *prometheus.CounterVec
+	contractStaleGauge          *prometheus.GaugeVec
+	capabilityDecodeErrors      *prometheus.CounterVec
+	providerCoverageGauge       prometheus.Gauge
+	productInfoGauge            *prometheus.GaugeVec
+	productActiveGauge          *prometheus.GaugeVec
+	capabilityGauges            map[string]*prometheus.GaugeVec // By capability key
+
+	// WarmStorage pricing metrics
+	storagePriceGauge         prometheus.Gauge
+	storageRateGauge          prometheus.Gauge
+	cdnEgressPriceGauge       prometheus.Gauge
+	cacheMissEgressPriceGauge prometheus.Gauge
+	minimumPriceGauge         prometheus.Gauge
+	epochsPerMonthGauge       prometheus.Gauge
+
+	// RPC circuit breaker (only touched by the scrape loop)
+	circuit circuitBreaker
 
 	// Cache
 	wallets    []WalletInfo
 	walletsMux sync.RWMutex
 	lastScrape time.Time
 
+	// Outcome of the last scrape, guarded by walletsMux like lastScrape
+	lastScrapeDuration time.Duration
+	lastScrapeErrors   uint64
+	lastSuccess        time.Time // End of the last scrape that found wallets or had no errors
+
+	// Provider rotation state (only touched by the scrape loop)
+	providerCursor uint64
+	providerCache  map[uint64]WalletInfo
+	productTypes   []uint8 // Product types the registry defined at the last scrape
+
+	// Provider names, descriptions and products kept between scrapes
+	metadataCache *metadataCache
+
+	// Label sets of the exported wallets, kept between metric updates
+	labelSets *walletLabelCache
+
+	// Wallet series set so far, swapped in at the end of every scrape
+	series *seriesTracker
+
+	// Custom wallets by address, so providers published mid-scrape carry their configured name
+	// and thresholds (only touched by the scrape loop)
+	customByAddress map[common.Address]WalletInfo
+
+	// Providers approved in WarmStorage at the last scrape (only touched by the scrape loop)
+	approvedProviders map[uint64]bool
+
+	// Spend rate trackers for runway projection
+	filRunway   *runwayTracker
+	usdfcRunway *runwayTracker
+
+	// Provider offboarding detection
+	offboarding *offboardingTracker
+
+	// Provider lifecycle transitions (only touched by the scrape loop)
+	lifecycle              *lifecycleTracker
+	lifecycleEventsCounter *prometheus.CounterVec
+	stateChangeGauge       *prometheus.GaugeVec
+
+	// Wallets eligible for event tracking (EVENT_WALLET_SELECTORS)
+	eventSelector *walletSelector
+
+	// On-demand scrape requests from the API
+	scrapeRequests chan struct{}
+	scrapeWaitMux  sync.Mutex
+	scrapeWaiters  chan struct{} // Closed once the next scrape completes (nil if nobody waits)
+
+	// Reloaded configurations to apply (SIGHUP and /-/reload)
+	reloads chan reloadRequest
+
+	// New heads to refresh wallets for (only when subscribed over a websocket)
+	heads           chan uint64
+	lastScrapeBlock uint64                  // Snapshot block of the last scrape (only touched by the scrape loop)
+	lastPingResults map[uint64][]PingResult // Ping results of the last scrape (written by the scrape loop under walletsMux)
+	observedBlock   blockRef                // Snapshot block the next scrape checks for a reorg (only touched by the scrape loop)
+
+	// Incremental refresh (only when INCREMENTAL_REFRESH is enabled; only written by the scrape loop)
+	idleWallets          map[common.Address]WalletInfo // Wallets of the last scrape untouched since, by address
+	lastFullRefresh      time.Time
+	incrementalIdleGauge prometheus.Gauge
+
+	// Time-sliced ping scheduling (only when PING_SPREAD is enabled)
+	pinger *pingScheduler
+
 	// Ping metrics
 	pingSuccessGauge  *prometheus.GaugeVec
 	pingDurationGauge *prometheus.GaugeVec
 
+	// Finality metrics (only registered when EXPORT_FINALITY is enabled)
+	snapshotBlockGauge     prometheus.Gauge
+	snapshotFinalizedGauge prometheus.Gauge
+	finalityDistanceGauge  prometheus.Gauge
+
+	// Rail metrics (only registered when EXPORT_RAILS is enabled)
+	railPaymentRateGauge  *prometheus.GaugeVec
+	railSettledUpToGauge  *prometheus.GaugeVec
+	railEndEpochGauge     *prometheus.GaugeVec
+	railLockupFixedGauge  *prometheus.GaugeVec
+	railLockupPeriodGauge *prometheus.GaugeVec
+
+	// Settlement preview metrics (only registered when EXPORT_PENDING_SETTLEMENT is enabled)
+	railPendingSettlementGauge   *prometheus.GaugeVec
+	walletPendingSettlementGauge *prometheus.GaugeVec
+
+	// Protocol TVL metrics (only registered when EXPORT_TVL is enabled)
+	contractBalanceGauge *prometheus.GaugeVec
+
+	// Block explorer enrichment (only when EXPLORER is set)
+	explorer          explorerSource
+	explorerInfo      map[common.Address]explorerEntry // Only touched by the scrape loop
+	actorInfoGauge    *prometheus.GaugeVec
+	messageCountGauge *prometheus.GaugeVec
+	lastMessageGauge  *prometheus.GaugeVec
+
+	// Alerting (only when a notifier is configured)
+	alerts *alertManager
+
+	// Long-term history of every scrape (only when HISTORY_PATH is set)
+	history *history.Store
+
+	// Last scrape saved to and restored from STATE_FILE
+	stateRestoredGauge prometheus.Gauge
+	restored           bool      // Wallets come from the state file, guarded by walletsMux
+	lastStateSave      time.Time // Only touched by the scrape loop and Close
+
+	// Push-based metric sinks (REMOTE_WRITE_URL, OTLP_ENDPOINT, GRAPHITE_ADDRESS, STATSD_ADDRESS,
+	// TEXTFILE_PATH)
+	pushers []*pusher
+
+	// Mempool metrics (only registered when EXPORT_MEMPOOL is enabled)
+	mempoolTransactionsGauge *prometheus.GaugeVec
+	mempoolValueGauge        *prometheus.GaugeVec
+	mempoolStuckGauge        *prometheus.GaugeVec
+
+	// Multisig metrics (only registered when msig wallets are configured)
+	msigBalanceGauge      *prometheus.GaugeVec
+	msigAvailableGauge    *prometheus.GaugeVec
+	msigLockedGauge       *prometheus.GaugeVec
+	msigVestingEndGauge   *prometheus.GaugeVec
+	msigSignersGauge      *prometheus.GaugeVec
+	msigThresholdGauge    *prometheus.GaugeVec
+	msigPendingGauge      *prometheus.GaugeVec
+	msigPendingValueGauge *prometheus.GaugeVec
+
+	// Payments deposit/withdrawal counters (only registered when EXPORT_PAYMENTS_EVENTS is enabled)
+	depositsCounter        *prometheus.CounterVec
+	depositedAmountCounter *prometheus.CounterVec
+	withdrawalsCounter     *prometheus.CounterVec
+	withdrawnAmountCounter *prometheus.CounterVec
+	eventsNextBlock        uint64 // First block not yet filtered (only touched by the scrape loop)
+
+	// USDFC transfer counters (only registered when EXPORT_TRANSFERS is enabled)
+	transfersCounter         *prometheus.CounterVec
+	transferredAmountCounter *prometheus.CounterVec
+	transfersNextBlock       uint64 // First block not yet filtered (only touched by the scrape loop)
+
+	// Data set counts (only registered when EXPORT_DATA_SETS is enabled)
+	providerDataSetsGauge *prometheus.GaugeVec
+	clientDataSetsGauge   *prometheus.GaugeVec
+
+	// Provider registration metrics (only registered when EXPORT_REGISTRATIONS is enabled)
+	registeredBlockGauge     *prometheus.GaugeVec
+	registeredTimestampGauge *prometheus.GaugeVec
+	registrations            map[uint64]ProviderRegistration // By provider ID (only touched by the scrape loop)
+	registrationNextBlock    uint64                          // First block not yet scanned (only touched by the scrape loop)
+
 	logger *slog.Logger
 }
 
 func New(cfg *config.Config, logger *slog.Logger) (*WalletExporter, error) {
-	// Connect to Ethereum client
-	client, err := ethclient.Dial(cfg.RPCURL)
+	ctx := context.Background()
+
+	// Connect to Ethereum client, retrying transient RPC failures
+	rpcRetriesCounter := newCounterVec(cfg.MetricsPrefix, "rpc_retries_total")
+	token := &rpcToken{static: cfg.RPCToken, file: cfg.RPCTokenFile, command: cfg.RPCTokenCommand}
+	client, err := newRPCClient(func() (*ethclient.Client, error) {
+		// Read the token on every dial so reconnects pick up a rotated token
+		bearer, err := token.dialToken()
+		if err != nil {
+			return nil, err
+		}
+		return dialRPC(cfg, bearer, rpcRetriesCounter, logger)
+	}, cfg.RPCCallTimeout)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to Ethereum client: %w", err)
 	}
 
+	// Refuse to export another network's balances under this network's name
+	chainID, err := verifyChainID(ctx, client, cfg.Network)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create contract instances
 	warmStorageAddr := common.HexToAddress(cfg.WarmStorageAddress)
 	warmStorageContract, err := contracts.NewWarmStorageService(warmStorageAddr, client)
@@ -83,7 +321,7 @@ func New(cfg *config.Config, logger *slog.Logger) (*WalletExporter, error) {
 	}
 
 	// Get view contract address
-	viewAddr, err := warmStorageContract.ViewContractAddress(nil)
+	viewAddr, err := warmStorageContract.ViewContractAddress(callOpts(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get view contract address: %w", err)
 	}
@@ -94,7 +332,7 @@ func New(cfg *config.Config, logger *slog.Logger) (*WalletExporter, error) {
 	}
 
 	// Get registry contract address
-	registryAddr, err := warmStorageContract.ServiceProviderRegistry(nil)
+	registryAddr, err := warmStorageContract.ServiceProviderRegistry(callOpts(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get registry address: %w", err)
 	}
@@ -115,91 +353,26 @@ func New(cfg *config.Config, logger *slog.Logger) (*WalletExporter, error) {
 	registry := prometheus.NewRegistry()
 
 	// Create Prometheus metrics
-	filBalanceGauge := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: fmt.Sprintf("%s_wallet_fil_balance", cfg.MetricsPrefix),
-			Help: "FIL (native token) balance for each wallet",
-		},
-		[]string{"address", "name", "type", "provider_id", "is_active", "approved"},
-	)
-
-	usdfcBalanceGauge := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: fmt.Sprintf("%s_wallet_usdfc_balance", cfg.MetricsPrefix),
-			Help: "USDFC token balance for each wallet",
-		},
-		[]string{"address", "name", "type", "provider_id", "is_active", "approved"},
-	)
-
-	walletInfoGauge := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: fmt.Sprintf("%s_wallet_info", cfg.MetricsPrefix),
-			Help: "Wallet information (always 1)",
-		},
-		[]string{"address", "name", "type", "provider_id", "description", "is_active", "approved"},
-	)
-
-	paymentsFundsGauge := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: fmt.Sprintf("%s_wallet_payments_funds", cfg.MetricsPrefix),
-			Help: "Total funds in Payments contract for each wallet",
-		},
-		[]string{"address", "name", "type", "provider_id", "is_active", "approved"},
-	)
-
-	paymentsAvailableGauge := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: fmt.Sprintf("%s_wallet_payments_available", cfg.MetricsPrefix),
-			Help: "Available funds in Payments contract (after lockup)",
-		},
-		[]string{"address", "name", "type", "provider_id", "is_active", "approved"},
-	)
-
-	paymentsLockedGauge := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: fmt.Sprintf("%s_wallet_payments_locked", cfg.MetricsPrefix),
-			Help: "Locked funds in Payments contract",
-		},
-		[]string{"address", "name", "type", "provider_id", "is_active", "approved"},
-	)
-
-	paymentsFundedUntilGauge := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: fmt.Sprintf("%s_wallet_payments_funded_until_epoch", cfg.MetricsPrefix),
-			Help: "Estimated epoch when Payments funds will run out",
-		},
-		[]string{"address", "name", "type", "provider_id", "is_active", "approved"},
-	)
-
-	scrapeDuration := prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: fmt.Sprintf("%s_scrape_duration_seconds", cfg.MetricsPrefix),
-			Help: "Duration of the last scrape in seconds",
-		},
-	)
-
-	scrapeErrors := prometheus.NewCounter(
-		prometheus.CounterOpts{
-			Name: fmt.Sprintf("%s_scrape_errors_total", cfg.MetricsPrefix),
-			Help: "Total number of scrape errors",
-		},
-	)
-
-	pingSuccessGauge := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: fmt.Sprintf("%s_provider_ping_success", cfg.MetricsPrefix),
-			Help: "1 if the provider ping was successful (HTTP 200), 0 otherwise",
-		},
-		[]string{"address", "name", "provider_id", "service_url"},
-	)
-
-	pingDurationGauge := prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: fmt.Sprintf("%s_provider_ping_ms", cfg.MetricsPrefix),
-			Help: "Duration of the ping request in milliseconds",
-		},
-		[]string{"address", "name", "provider_id", "service_url"},
-	)
+	filBalanceGauge := newGaugeVec(cfg.MetricsPrefix, "wallet_fil_balance")
+	usdfcBalanceGauge := newGaugeVec(cfg.MetricsPrefix, "wallet_usdfc_balance")
+	walletInfoGauge := newGaugeVec(cfg.MetricsPrefix, "wallet_info")
+	paymentsFundsGauge := newGaugeVec(cfg.MetricsPrefix, "wallet_payments_funds")
+	paymentsAvailableGauge := newGaugeVec(cfg.MetricsPrefix, "wallet_payments_available")
+	paymentsLockedGauge := newGaugeVec(cfg.MetricsPrefix, "wallet_payments_locked")
+	paymentsFundedUntilGauge := newGaugeVec(cfg.MetricsPrefix, "wallet_payments_funded_until_epoch")
+	paymentsLockupRateGauge := newGaugeVec(cfg.MetricsPrefix, "wallet_payments_lockup_rate")
+	paymentsQueryErrorGauge := newGaugeVec(cfg.MetricsPrefix, "wallet_payments_query_error")
+	filRunwayGauge := newGaugeVec(cfg.MetricsPrefix, "wallet_fil_runway_days")
+	usdfcRunwayGauge := newGaugeVec(cfg.MetricsPrefix, "wallet_usdfc_runway_days")
+	filMinThresholdGauge := newGaugeVec(cfg.MetricsPrefix, "wallet_fil_min_threshold")
+	usdfcMinThresholdGauge := newGaugeVec(cfg.MetricsPrefix, "wallet_usdfc_min_threshold")
+	belowThresholdGauge := newGaugeVec(cfg.MetricsPrefix, "wallet_below_threshold")
+	scrapeDuration := newGauge(cfg.MetricsPrefix, "scrape_duration_seconds")
+	lastSuccessGauge := newGauge(cfg.MetricsPrefix, "last_successful_scrape_timestamp_seconds")
+	scrapeErrors := newCounter(cfg.MetricsPrefix, "scrape_errors_total")
+	providerCoverageGauge := newGauge(cfg.MetricsPrefix, "provider_scrape_coverage_ratio")
+	pingSuccessGauge := newGaugeVec(cfg.MetricsPrefix, "provider_ping_success")
+	pingDurationGauge := newGaugeVec(cfg.MetricsPrefix, "provider_ping_ms")
 
 	// Register metrics with custom registry
 	registry.MustRegister(filBalanceGauge)
@@ -209,18 +382,32 @@ func New(cfg *config.Config, logger *slog.Logger) (*WalletExporter, error) {
 	registry.MustRegister(paymentsAvailableGauge)
 	registry.MustRegister(paymentsLockedGauge)
 	registry.MustRegister(paymentsFundedUntilGauge)
+	registry.MustRegister(paymentsLockupRateGauge)
+	registry.MustRegister(paymentsQueryErrorGauge)
+	registry.MustRegister(filRunwayGauge)
+	registry.MustRegister(usdfcRunwayGauge)
+	registry.MustRegister(filMinThresholdGauge)
+	registry.MustRegister(usdfcMinThresholdGauge)
+	registry.MustRegister(belowThresholdGauge)
 	registry.MustRegister(scrapeDuration)
+	registry.MustRegister(lastSuccessGauge)
 	registry.MustRegister(scrapeErrors)
+	registry.MustRegister(rpcRetriesCounter)
+	registry.MustRegister(providerCoverageGauge)
 	registry.MustRegister(pingSuccessGauge)
 	registry.MustRegister(pingDurationGauge)
 
-	return &WalletExporter{
+	exp := &WalletExporter{
 		config:                   cfg,
+		rpcToken:                 token,
 		client:                   client,
 		warmStorageContract:      warmStorageContract,
 		viewContract:             viewContract,
 		registryContract:         registryContract,
 		usdfcContract:            usdfcContract,
+		warmStorageAddress:       warmStorageAddr,
+		viewAddress:              viewAddr,
+		registryAddress:          registryAddr,
 		registry:                 registry,
 		filBalanceGauge:          filBalanceGauge,
 		usdfcBalanceGauge:        usdfcBalanceGauge,
@@ -229,20 +416,178 @@ func New(cfg *config.Config, logger *slog.Logger) (*WalletExporter, error) {
 		paymentsAvailableGauge:   paymentsAvailableGauge,
 		paymentsLockedGauge:      paymentsLockedGauge,
 		paymentsFundedUntilGauge: paymentsFundedUntilGauge,
+		paymentsLockupRateGauge:  paymentsLockupRateGauge,
+		paymentsQueryErrorGauge:  paymentsQueryErrorGauge,
+		filRunwayGauge:           filRunwayGauge,
+		usdfcRunwayGauge:         usdfcRunwayGauge,
+		filMinThresholdGauge:     filMinThresholdGauge,
+		usdfcMinThresholdGauge:   usdfcMinThresholdGauge,
+		belowThresholdGauge:      belowThresholdGauge,
 		scrapeDuration:           scrapeDuration,
-		scrapeErrors:             scrapeErrors,
+		lastSuccessGauge:         lastSuccessGauge,
+		scrapeErrors:             &errorCounter{Counter: scrapeErrors},
+		rpcRetriesCounter:        rpcRetriesCounter,
+		providerCoverageGauge:    providerCoverageGauge,
 		pingSuccessGauge:         pingSuccessGauge,
 		pingDurationGauge:        pingDurationGauge,
 		wallets:                  []WalletInfo{},
+		providerCache:            make(map[uint64]WalletInfo),
+		filRunway:                newRunwayTracker(cfg.RunwayHalfLife),
+		usdfcRunway:              newRunwayTracker(cfg.RunwayHalfLife),
+		offboarding:              newOffboardingTracker(cfg.OffboardingMinScrapes),
+		lifecycle:                newLifecycleTracker(),
+		metadataCache:            newMetadataCache(cfg.ProviderMetadataTTL),
+		labelSets:                newWalletLabelCache(cfg.Network),
+		series:                   newSeriesTracker(),
+		circuit:                  circuitBreaker{threshold: cfg.RPCCircuitThreshold},
+		eventSelector:            newWalletSelector(cfg.EventWalletSelectors),
+		scrapeRequests:           make(chan struct{}, 1),
+		reloads:                  make(chan reloadRequest),
+		heads:                    make(chan uint64, 1),
+		registrations:            make(map[uint64]ProviderRegistration),
+		registrationNextBlock:    uint64(cfg.RegistryStartBlock),
 		logger:                   logger,
-	}, nil
+	}
+
+	exp.paymentsTokens = exp.resolvePaymentsTokens(ctx, cfg.PaymentsTokens)
+	exp.multicall = exp.newMulticallBatcher()
+	exp.registerCircuitMetrics()
+	exp.registerScrapeTimeoutMetrics()
+	exp.registerReorgMetrics()
+	exp.registerChainIDMetrics(chainID)
+	exp.registerBindingMetrics()
+	exp.registerUpgradeMetrics()
+	exp.registerCapabilityMetrics()
+	exp.registerProductMetrics()
+	exp.registerLifecycleMetrics()
+	exp.registerPricingMetrics()
+	exp.registerHTTPMetrics()
+
+	if cfg.IncrementalRefresh {
+		exp.registerIncrementalMetrics()
+	}
+	if cfg.ExportFinality {
+		exp.registerFinalityMetrics()
+	}
+	if cfg.ExportRails {
+		exp.registerRailMetrics()
+	}
+	if len(cfg.MultisigWallets) > 0 {
+		exp.registerMultisigMetrics()
+	}
+	if cfg.ExportRawBalances {
+		exp.registerRawBalanceMetrics()
+	}
+	if cfg.ExportMempool {
+		exp.registerMempoolMetrics()
+	}
+	if exp.explorer, err = newExplorerSource(cfg); err != nil {
+		return nil, fmt.Errorf("failed to create explorer source: %w", err)
+	}
+	if exp.explorer != nil {
+		exp.explorerInfo = make(map[common.Address]explorerEntry)
+		exp.registerExplorerMetrics()
+	}
+	if cfg.ExportTVL {
+		exp.registerTVLMetrics()
+	}
+	if cfg.ExportPaymentsEvents {
+		exp.registerPaymentsEventMetrics()
+	}
+	if cfg.ExportTransfers {
+		exp.registerTransferMetrics()
+	}
+	if cfg.ExportDataSets {
+		exp.registerDataSetMetrics()
+	}
+	if cfg.ExportRegistrations {
+		exp.registerRegistrationMetrics()
+	}
+	if cfg.PingSpread {
+		exp.pinger = newPingScheduler(exp, cfg.ScrapeInterval)
+	}
+	notifiers, err := newNotifiers(cfg, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create notifiers: %w", err)
+	}
+	if len(notifiers) > 0 {
+		exp.alerts, err = newAlertManager(notifiers, cfg, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create alert manager: %w", err)
+		}
+		exp.registerAlertMetrics()
+	}
+	if cfg.HistoryPath != "" {
+		exp.history, err = history.Open(cfg.HistoryPath, cfg.HistoryRetention)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cfg.RemoteWriteURL != "" {
+		exp.pushers = append(exp.pushers, newPusher(newRemoteWriter(cfg), registry, 0, cfg.RemoteWriteTimeout, logger))
+	}
+	if cfg.OTLPEndpoint != "" {
+		otlp, err := newOTLPExporter(cfg, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		exp.pushers = append(exp.pushers, newPusher(otlp, registry, cfg.OTLPInterval, cfg.OTLPTimeout, logger))
+	}
+	if cfg.GraphiteAddress != "" {
+		graphite := &graphiteSink{address: cfg.GraphiteAddress, prefix: cfg.GraphitePrefix, tags: cfg.GraphiteTags}
+		exp.pushers = append(exp.pushers, newPusher(graphite, registry, cfg.GraphiteInterval, pushTimeout, logger))
+	}
+	if cfg.StatsDAddress != "" {
+		statsd := newStatsdSink(cfg.StatsDAddress, cfg.StatsDPrefix, cfg.StatsDTags)
+		exp.pushers = append(exp.pushers, newPusher(statsd, registry, cfg.StatsDInterval, pushTimeout, logger))
+	}
+	if cfg.TextfilePath != "" {
+		exp.pushers = append(exp.pushers, newPusher(&textfileSink{path: cfg.TextfilePath}, registry, 0, pushTimeout, logger))
+	}
+	if len(exp.pushers) > 0 {
+		exp.registerPushMetrics()
+	}
+	if cfg.StateFile != "" {
+		exp.registerStateMetrics()
+		if err := exp.restoreState(); err != nil {
+			logger.Warn("Failed to restore state, starting empty", "error", err)
+		}
+	}
+
+	return exp, nil
 }
 
 func (e *WalletExporter) Start(ctx context.Context) error {
 	e.logger.Info("Starting wallet exporter", "scrape_interval", e.config.ScrapeInterval)
 
+	// Spread pings across the interval instead of firing them with each scrape
+	if e.pinger != nil {
+		go e.pinger.Run(ctx)
+	}
+
+	// Reconnect when a rotated RPC token appears
+	if e.rpcToken.rotates() {
+		go e.watchRPCToken(ctx)
+	}
+
+	// Deliver alert notifications without holding up scrapes
+	if e.alerts != nil {
+		go e.alerts.run(ctx)
+	}
+
+	// Push metrics to the configured sinks without holding up scrapes
+	for _, p := range e.pushers {
+		go p.run(ctx)
+	}
+
+	// Refresh wallets touched by every new block in between scrapes, unless reads are held back
+	// to a safe or finalized block
+	if e.config.WatchHeads && isWebsocketURL(e.config.RPCURL) && e.config.QueryBlockTag == "latest" {
+		go e.watchHeads(ctx)
+	}
+
 	// Initial scrape
-	if err := e.scrape(ctx); err != nil {
+	if err := e.scrapeAndNotify(ctx); err != nil {
 		e.logger.Error("Initial scrape failed", "error", err)
 		e.scrapeErrors.Inc()
 	}
@@ -257,28 +602,102 @@ func (e *WalletExporter) Start(ctx context.Context) error {
 			e.logger.Info("Stopping wallet exporter")
 			return ctx.Err()
 		case <-ticker.C:
-			if err := e.scrape(ctx); err != nil {
+			if err := e.scrapeAndNotify(ctx); err != nil {
 				e.logger.Error("Scrape failed", "error", err)
 				e.scrapeErrors.Inc()
 			}
+		case <-e.scrapeRequests:
+			e.logger.Info("Running requested scrape")
+			if err := e.scrapeAndNotify(ctx); err != nil {
+				e.logger.Error("Requested scrape failed", "error", err)
+				e.scrapeErrors.Inc()
+			}
+		case req := <-e.reloads:
+			e.applyReload(req.cfg)
+			close(req.done)
+			if err := e.scrapeAndNotify(ctx); err != nil {
+				e.logger.Error("Scrape after reload failed", "error", err)
+				e.scrapeErrors.Inc()
+			}
+		case head := <-e.heads:
+			e.refreshHead(ctx, head)
 		}
 	}
 }
 
 func (e *WalletExporter) scrape(ctx context.Context) error {
+	head, ok := e.checkRPC(ctx)
+	if !ok || !e.checkChainID(ctx) {
+		return nil
+	}
+
+	// A reorg since the last scrape means it read balances on a fork that was abandoned
+	e.checkReorg(ctx)
+
+	// Pin every read of this scrape to one block so sums across wallets are consistent
+	snapshot := e.snapshotRef(ctx, head)
+	block := uint64(snapshot.Number)
+	if block > 0 {
+		ctx = withSnapshotBlock(ctx, block)
+	}
+	previousBlock := e.lastScrapeBlock
+	e.lastScrapeBlock = block
+
+	// Bound the whole scrape, so one hung call cannot hold back every metric
+	if e.config.ScrapeTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.config.ScrapeTimeout)
+		defer cancel()
+	}
+
+	// Skip the balances no block since the last scrape could have changed
+	if e.config.IncrementalRefresh {
+		e.planIncrementalRefresh(ctx, previousBlock, block)
+	}
+
 	start := time.Now()
+	startErrors := e.scrapeErrors.count.Load()
+	restarted := false
 	defer func() {
-		duration := time.Since(start).Seconds()
-		e.scrapeDuration.Set(duration)
+		// A restarted scrape published nothing, the next one does the bookkeeping
+		if restarted {
+			return
+		}
+		duration := time.Since(start)
+		e.scrapeDuration.Set(duration.Seconds())
+
+		e.walletsMux.Lock()
 		e.lastScrape = time.Now()
-		e.logger.Info("Scrape completed", "duration_seconds", duration)
+		e.lastScrapeDuration = duration
+		e.lastScrapeErrors = e.scrapeErrors.count.Load() - startErrors
+		if len(e.wallets) > 0 || e.lastScrapeErrors == 0 {
+			e.lastSuccess = e.lastScrape
+			e.lastSuccessGauge.Set(float64(e.lastSuccess.Unix()))
+		}
+		e.walletsMux.Unlock()
+
+		e.logger.Info("Scrape completed", "duration_seconds", duration.Seconds())
+
+		// Replace restored values and save the state now and then
+		if e.config.StateFile != "" {
+			e.stateScraped()
+		}
+
+		for _, p := range e.pushers {
+			p.scraped(time.Now())
+		}
 	}()
 
 	e.logger.Info("Starting scrape...")
 
+	e.customByAddress = make(map[common.Address]WalletInfo, len(e.config.CustomWallets))
+	for _, cw := range e.config.CustomWallets {
+		e.customByAddress[common.HexToAddress(cw.Address)] = e.customWalletInfo(cw)
+	}
+
 	var allWallets []WalletInfo
 	var wg sync.WaitGroup
-	var pingResults map[uint64]PingResult
+	var pingResults map[uint64][]PingResult
 
 	// 1. Fetch storage provider wallets
 	providerWallets, err := e.fetchProviderWallets(ctx)
@@ -287,34 +706,167 @@ func (e *WalletExporter) scrape(ctx context.Context) error {
 	} else {
 		allWallets = append(allWallets, providerWallets...)
 		e.logger.Info("Found storage providers", "count", len(providerWallets))
-
-		// Start concurrent pings for providers
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			pingResults = e.pingProviders(ctx, providerWallets)
-		}()
+		e.offboarding.Observe(providerWallets, time.Now())
+		e.observeLifecycle(providerWallets, time.Now())
+
+		if e.pinger != nil {
+			// Pings run on their own schedule; publish the latest results
+			e.pinger.SetProviders(providerWallets)
+			pingResults = e.pinger.Results()
+		} else {
+			// Start concurrent pings for providers
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				pingResults = e.pingProviders(ctx, providerWallets)
+			}()
+		}
 	}
 
-	// 2. Fetch custom wallets
-	customWallets, err := e.fetchCustomWallets(ctx)
+	// 2. Fetch custom wallets, folding in the ones that are registered providers
+	customWallets, err := e.fetchCustomWallets(ctx, providerWallets)
 	if err != nil {
 		e.logger.Warn("Failed to fetch custom wallets", "error", err)
 	} else {
-		allWallets = append(allWallets, customWallets...)
+		allWallets = mergeProviderCustomWallets(allWallets, customWallets, e.approvedProviders)
 		e.logger.Info("Found custom wallets", "count", len(customWallets))
 	}
 
+	// 3. Fetch chain finality status for this snapshot
+	if e.config.ExportFinality {
+		status, err := e.fetchFinalityStatus(ctx)
+		if err != nil {
+			e.logger.Warn("Failed to fetch finality status", "error", err)
+		} else {
+			e.updateFinalityMetrics(status)
+		}
+	}
+
+	// 4. Enumerate Payments rails for every wallet
+	if e.config.ExportRails {
+		e.fetchRails(ctx, allWallets)
+	}
+
+	// 5. Read the holdings of the protocol contracts
+	if e.config.ExportTVL {
+		balances, err := e.fetchContractBalances(ctx)
+		if err != nil {
+			e.logger.Warn("Failed to fetch contract balances", "error", err)
+		} else {
+			e.updateTVLMetrics(balances)
+		}
+	}
+
+	// 6. Count Payments deposits and withdrawals since the previous scrape
+	if e.config.ExportPaymentsEvents {
+		e.collectPaymentsEvents(ctx, e.eventSelector.Filter(allWallets))
+	}
+
+	// 7. Count USDFC transfers into and out of the wallets since the previous scrape
+	if e.config.ExportTransfers {
+		e.collectTransfers(ctx, e.eventSelector.Filter(allWallets))
+	}
+
+	// 8. Read the current WarmStorage price list
+	if pricing, err := e.fetchServicePricing(ctx); err != nil {
+		e.logger.Warn("Failed to fetch WarmStorage pricing", "error", err)
+	} else {
+		e.updatePricingMetrics(pricing)
+	}
+
+	// 9. Count the WarmStorage data sets of every wallet
+	if e.config.ExportDataSets {
+		e.fetchDataSets(ctx, allWallets)
+	}
+
+	// 10. Check the protocol contracts for upgrades
+	e.checkContractUpgrades(ctx)
+
+	// 11. Catch up on provider registrations
+	if e.config.ExportRegistrations {
+		e.scanRegistrations(ctx)
+	}
+
+	// 12. Read the state of the multisig actors
+	if len(e.config.MultisigWallets) > 0 {
+		e.updateMultisigMetrics(e.fetchMultisigs(ctx))
+	}
+
+	// 13. Look for messages of the wallets waiting in the mempool
+	if e.config.ExportMempool {
+		pending, err := e.fetchMempool(ctx, allWallets)
+		if err != nil {
+			e.logger.Warn("Failed to read mempool", "error", err)
+			e.scrapeErrors.Inc()
+		} else {
+			e.updateMempoolMetrics(pending)
+		}
+	}
+
+	// 14. Enrich the wallets with block explorer data
+	if e.explorer != nil {
+		e.refreshExplorerInfo(ctx, allWallets)
+		e.updateExplorerMetrics(allWallets)
+	}
+
 	// Wait for pings to complete
 	wg.Wait()
 
+	// Run the scrape again rather than publish balances of a fork abandoned while it ran
+	e.observedBlock = snapshot
+	if e.checkReorg(ctx) {
+		e.lastScrapeBlock = previousBlock
+		restarted = true
+		e.TriggerScrape()
+		return errScrapeRestarted
+	}
+
+	e.walletsMux.RLock()
+	previous, previousPings := e.wallets, e.lastPingResults
+	e.walletsMux.RUnlock()
+
+	// Payments accounts that failed to load keep their last values
+	carryOverPayments(allWallets, previous)
+
+	// Publish what was read in time; wallets not reached keep their last values
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		var stale int
+		allWallets, pingResults, stale = carryOverStaleWallets(allWallets, previous, pingResults, previousPings)
+		e.logger.Warn("Scrape timed out, publishing partial results", "timeout", e.config.ScrapeTimeout, "wallets", len(allWallets), "stale", stale)
+		e.scrapeTimeoutsCounter.Inc()
+		e.scrapeErrors.Inc()
+	}
+
 	// Update cache
 	e.walletsMux.Lock()
 	e.wallets = allWallets
+	e.lastPingResults = pingResults
 	e.walletsMux.Unlock()
 
 	// Update Prometheus metrics
 	e.updateMetrics(allWallets, pingResults)
+	e.updateProductMetrics(allWallets)
+	if e.config.ExportRails {
+		e.updateRailMetrics(allWallets)
+	}
+	if e.config.ExportDataSets {
+		e.updateDataSetMetrics(allWallets)
+	}
+	if e.config.ExportRegistrations {
+		e.updateRegistrationMetrics(allWallets)
+	}
+
+	// Notify about alerts that started or stopped firing
+	if e.alerts != nil {
+		e.alerts.enqueue(e.alerts.evaluate(allWallets, pingResults, block, time.Now()))
+	}
+
+	// Keep the scrape beyond the Prometheus retention
+	if e.history != nil {
+		if err := e.history.Record(time.Now(), historyWallets(allWallets, pingResults, block)); err != nil {
+			e.logger.Warn("Failed to record history", "error", err)
+		}
+	}
 
 	e.logger.Info("Successfully scraped total wallets", "count", len(allWallets))
 	return nil
@@ -322,138 +874,149 @@ func (e *WalletExporter) scrape(ctx context.Context) error {
 
 func (e *WalletExporter) fetchProviderWallets(ctx context.Context) ([]WalletInfo, error) {
 	// Get total provider count
-	providerCount, err := e.registryContract.GetProviderCount(nil)
+	providerCount, err := e.registryContract.GetProviderCount(callOpts(ctx))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get provider count: %w", err)
 	}
 
 	// Get approved provider IDs for checking
-	approvedIDs, err := e.viewContract.GetApprovedProviders(nil, big.NewInt(0), big.NewInt(0))
+	approvedMap, err := fetchApprovedProviders(ctx, e.viewContract, e.config.ApprovedProvidersPage)
 	if err != nil {
 		e.logger.Warn("Failed to get approved providers", "error", err)
 		e.scrapeErrors.Inc()
-		approvedIDs = []*big.Int{} // Continue with empty approved list
-	}
-
-	// Create a map for quick lookup of approved providers
-	approvedMap := make(map[uint64]bool)
-	for _, id := range approvedIDs {
-		approvedMap[id.Uint64()] = true
+		approvedMap = map[uint64]bool{} // Continue with empty approved list
 	}
 
-	e.logger.Info("Provider count stats", "total", providerCount.Uint64(), "approved", len(approvedIDs))
+	e.approvedProviders = approvedMap
 
-	// Fetch all providers (provider IDs start from 1)
-	wallets := make([]WalletInfo, 0, int(providerCount.Int64()))
-	walletChan := make(chan WalletInfo, int(providerCount.Int64()))
-	errorChan := make(chan error, int(providerCount.Int64()))
+	e.logger.Info("Provider count stats", "total", providerCount.Uint64(), "approved", len(approvedMap))
 
-	var wg sync.WaitGroup
-	semaphore := make(chan struct{}, e.config.MaxConcurrentRequests) // Limit concurrent requests
+	// Product types can be added by a registry upgrade, so rediscover them every scrape
+	e.productTypes = e.discoverProductTypes(ctx)
 
-	for i := uint64(1); i <= providerCount.Uint64(); i++ {
-		wg.Add(1)
-		go func(providerID uint64) {
-			defer wg.Done()
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
-
-			isApproved := approvedMap[providerID]
-			wallet, err := e.fetchProviderWallet(ctx, big.NewInt(int64(providerID)), isApproved)
-			if err != nil {
-				errorChan <- fmt.Errorf("failed to fetch provider %d: %w", providerID, err)
-				return
-			}
-			walletChan <- wallet
-		}(i)
+	if e.metadataCache.Invalidate(providerCount.Uint64(), e.productTypes) {
+		e.logger.Info("Provider count or product types changed, refreshing provider metadata")
 	}
 
-	// Wait for all goroutines to finish
-	go func() {
-		wg.Wait()
-		close(walletChan)
-		close(errorChan)
-	}()
-
-	// Collect results
-	for wallet := range walletChan {
-		wallets = append(wallets, wallet)
+	// Fetch providers (provider IDs start from 1), possibly a rotating subset
+	providerIDs := e.selectProviderIDs(providerCount.Uint64())
+	wallets := make([]WalletInfo, 0, len(providerIDs))
+	fetch := func(ctx context.Context, providerID uint64) (WalletInfo, error) {
+		return e.fetchProviderWallet(ctx, new(big.Int).SetUint64(providerID), approvedMap[providerID])
 	}
-
-	// Log any errors and increment scrape error counter
-	for err := range errorChan {
-		e.logger.Warn("Provider fetch warning", "error", err)
+	done := 0
+	streamProviders(ctx, providerIDs, e.config.MaxConcurrentRPC, e.config.ProviderFetchTimeout, fetch, func(result providerResult) {
+		done++
+		if result.err != nil {
+			e.logger.Warn("Provider fetch warning", "error", fmt.Errorf("failed to fetch provider %d: %w", result.id, result.err))
+			e.scrapeErrors.Inc()
+			return
+		}
+		wallets = append(wallets, result.wallet)
+		if !e.batchesBalances() {
+			e.publishWallet(result.wallet)
+		}
+	})
+	if skipped := len(providerIDs) - done; skipped > 0 {
+		e.logger.Warn("Scrape ended before every provider was fetched", "skipped", skipped, "error", ctx.Err())
 		e.scrapeErrors.Inc()
 	}
 
-	return wallets, nil
+	if e.batchesBalances() {
+		wallets = e.fetchChangedBalancesBatched(ctx, wallets)
+		e.publishWallets(wallets)
+	}
+
+	return e.mergeCachedProviders(wallets, providerIDs, providerCount.Uint64()), nil
 }
 
-func (e *WalletExporter) fetchProviderWallet(ctx context.Context, providerID *big.Int, isApproved bool) (WalletInfo, error) {
+// fetchProviderMetadata returns the registry info and products of a provider, from the
+// metadata cache when it holds a fresh copy
+func (e *WalletExporter) fetchProviderMetadata(ctx context.Context, providerID *big.Int) (providerMetadata, error) {
+	now := time.Now()
+	if metadata, ok := e.metadataCache.Get(providerID.Uint64(), now); ok {
+		return metadata, nil
+	}
+
 	// Get provider info from registry
-	result, err := e.registryContract.GetProvider(nil, providerID)
+	result, err := e.registryContract.GetProvider(callOpts(ctx), providerID)
 	if err != nil {
-		return WalletInfo{}, fmt.Errorf("failed to get provider info: %w", err)
+		return providerMetadata{}, fmt.Errorf("failed to get provider info: %w", err)
 	}
 
-	// Extract the nested info struct
-	info := result.Info
-
-	// Get FIL balance
-	filBalance, err := e.client.BalanceAt(ctx, info.ServiceProvider, nil)
+	// Get every registered product and its capabilities
+	products, err := e.fetchProviderProducts(ctx, providerID.Uint64(), e.productTypes)
+	metadata := providerMetadata{Info: result.Info, Products: products, FetchedAt: now}
 	if err != nil {
-		return WalletInfo{}, fmt.Errorf("failed to get FIL balance: %w", err)
+		// Not cached, so the products are read again on the next scrape
+		e.logger.Debug("Failed to get provider products", "provider_id", providerID, "error", err)
+		return metadata, nil
 	}
 
-	// Get USDFC balance
-	usdfcBalance, err := e.usdfcContract.BalanceOf(nil, info.ServiceProvider)
+	e.metadataCache.Put(providerID.Uint64(), metadata)
+	return metadata, nil
+}
+
+func (e *WalletExporter) fetchProviderWallet(ctx context.Context, providerID *big.Int, isApproved bool) (WalletInfo, error) {
+	metadata, err := e.fetchProviderMetadata(ctx, providerID)
 	if err != nil {
-		e.logger.Warn("Failed to get USDFC balance", "address", info.ServiceProvider.Hex(), "error", err)
-		usdfcBalance = big.NewInt(0)
+		return WalletInfo{}, err
+	}
+	info, products := metadata.Info, metadata.Products
+
+	wallet := WalletInfo{
+		Address:     info.ServiceProvider,
+		Name:        info.Name,
+		Type:        "provider",
+		ProviderID:  providerID.Uint64(),
+		Payee:       info.Payee,
+		IsActive:    info.IsActive,
+		IsApproved:  isApproved,
+		Description: info.Description,
+		Products:    products,
+		MinFIL:      e.config.DefaultMinFIL,
+		MinUSDFC:    e.config.DefaultMinUSDFC,
 	}
 
-	// Get Payments contract info
-	paymentsInfo, err := e.fetchPaymentsInfo(ctx, info.ServiceProvider)
-	if err != nil {
-		e.logger.Warn("Failed to get Payments info", "address", info.ServiceProvider.Hex(), "error", err)
-		paymentsInfo = &PaymentsInfo{
-			Funds:            big.NewInt(0),
-			Available:        big.NewInt(0),
-			Locked:           big.NewInt(0),
-			FundedUntilEpoch: big.NewInt(0),
+	// When batching, the balances of all providers are read in one batch afterwards
+	if !e.batchesBalances() && !e.reuseIdleBalances(&wallet) {
+		if err := e.fetchWalletBalances(ctx, &wallet); err != nil {
+			return WalletInfo{}, err
 		}
 	}
 
-	return WalletInfo{
-		Address:             info.ServiceProvider,
-		Name:                info.Name,
-		Type:                "provider",
-		ProviderID:          providerID.Uint64(),
-		IsActive:            info.IsActive,
-		IsApproved:          isApproved,
-		Description:         info.Description,
-		FILBalance:          filBalance,
-		USDFCBalance:        usdfcBalance,
-		PaymentsFunds:       paymentsInfo.Funds,
-		PaymentsAvailable:   paymentsInfo.Available,
-		PaymentsLocked:      paymentsInfo.Locked,
-		PaymentsFundedUntil: paymentsInfo.FundedUntilEpoch,
-	}, nil
-}
-
-func (e *WalletExporter) fetchCustomWallets(ctx context.Context) ([]WalletInfo, error) {
+	return wallet, nil
+}
+
+// fetchCustomWallets reads the configured wallets. Wallets that are also among the given
+// providers are not read again; they only carry their name and thresholds to be merged into
+// the provider's entry.
+func (e *WalletExporter) fetchCustomWallets(ctx context.Context, providers []WalletInfo) ([]WalletInfo, error) {
 	if len(e.config.CustomWallets) == 0 {
 		return []WalletInfo{}, nil
 	}
 
+	providerIDs := make(map[common.Address]uint64, len(providers))
+	for _, provider := range providers {
+		providerIDs[provider.Address] = provider.ProviderID
+	}
+
+	var known []WalletInfo
 	wallets := make([]WalletInfo, 0, len(e.config.CustomWallets))
 	walletChan := make(chan WalletInfo, len(e.config.CustomWallets))
 	errorChan := make(chan error, len(e.config.CustomWallets))
 
 	var wg sync.WaitGroup
-	semaphore := make(chan struct{}, e.config.MaxConcurrentRequests)
+	semaphore := make(chan struct{}, e.config.MaxConcurrentRPC)
 
 	for _, customWallet := range e.config.CustomWallets {
+		if providerID, ok := providerIDs[common.HexToAddress(customWallet.Address)]; ok {
+			wallet := e.customWalletInfo(customWallet)
+			wallet.ProviderID = providerID
+			known = append(known, wallet)
+			continue
+		}
+
 		wg.Add(1)
 		go func(cw config.CustomWallet) {
 			defer wg.Done()
@@ -477,6 +1040,9 @@ func (e *WalletExporter) fetchCustomWallets(ctx context.Context) ([]WalletInfo,
 
 	for wallet := range walletChan {
 		wallets = append(wallets, wallet)
+		if !e.batchesBalances() {
+			e.publishWallet(wallet)
+		}
 	}
 
 	for err := range errorChan {
@@ -484,187 +1050,394 @@ func (e *WalletExporter) fetchCustomWallets(ctx context.Context) ([]WalletInfo,
 		e.scrapeErrors.Inc()
 	}
 
-	return wallets, nil
+	if e.batchesBalances() {
+		wallets = e.fetchChangedBalancesBatched(ctx, wallets)
+		e.publishWallets(wallets)
+	}
+
+	return append(wallets, known...), nil
+}
+
+// customWalletInfo returns a configured wallet with its thresholds, before any lookups
+func (e *WalletExporter) customWalletInfo(cw config.CustomWallet) WalletInfo {
+	wallet := WalletInfo{
+		Address:  common.HexToAddress(cw.Address),
+		Name:     cw.Name,
+		Type:     cw.Type,
+		MinFIL:   e.config.DefaultMinFIL,
+		MinUSDFC: e.config.DefaultMinUSDFC,
+	}
+
+	// Per-wallet thresholds override the defaults
+	if cw.MinFIL > 0 {
+		wallet.MinFIL = cw.MinFIL
+	}
+	if cw.MinUSDFC > 0 {
+		wallet.MinUSDFC = cw.MinUSDFC
+	}
+	return wallet
 }
 
 func (e *WalletExporter) fetchCustomWallet(ctx context.Context, cw config.CustomWallet) (WalletInfo, error) {
-	address := common.HexToAddress(cw.Address)
+	wallet := e.customWalletInfo(cw)
+	address := wallet.Address
+
+	// When batching, the balances of all custom wallets are read in one batch afterwards
+	if !e.batchesBalances() && !e.reuseIdleBalances(&wallet) {
+		if err := e.fetchWalletBalances(ctx, &wallet); err != nil {
+			return WalletInfo{}, err
+		}
+	}
 
+	// Record the provider metadata if the wallet is also a registered provider
+	result, err := e.registryContract.GetProviderByAddress(callOpts(ctx), address)
+	if err != nil {
+		e.logger.Debug("Failed to look up provider by address", "address", address.Hex(), "error", err)
+	} else if result.ProviderId != nil && result.ProviderId.Sign() > 0 {
+		wallet.ProviderID = result.ProviderId.Uint64()
+		wallet.IsActive = result.Info.IsActive
+		wallet.Description = result.Info.Description
+		wallet.Payee = result.Info.Payee
+	}
+
+	return wallet, nil
+}
+
+// fetchWalletBalances reads the FIL, USDFC and Payments balances of a wallet, plus those of
+// its payee when payments go to a separate address, with one call per balance
+func (e *WalletExporter) fetchWalletBalances(ctx context.Context, wallet *WalletInfo) error {
 	// Get FIL balance
-	filBalance, err := e.client.BalanceAt(ctx, address, nil)
+	filBalance, err := e.client.BalanceAt(ctx, wallet.Address, snapshotBlock(ctx))
 	if err != nil {
-		return WalletInfo{}, fmt.Errorf("failed to get FIL balance: %w", err)
+		return fmt.Errorf("failed to get FIL balance: %w", err)
 	}
+	wallet.FILBalance = filBalance
 
 	// Get USDFC balance
-	usdfcBalance, err := e.usdfcContract.BalanceOf(nil, address)
+	usdfcBalance, err := e.usdfcContract.BalanceOf(callOpts(ctx), wallet.Address)
 	if err != nil {
-		e.logger.Warn("Failed to get USDFC balance", "address", address.Hex(), "error", err)
+		e.logger.Warn("Failed to get USDFC balance", "address", wallet.Address.Hex(), "error", err)
 		usdfcBalance = big.NewInt(0)
 	}
+	wallet.USDFCBalance = usdfcBalance
+
+	// Get balances of the payee when payments go to a separate address
+	if wallet.hasSeparatePayee() {
+		wallet.PayeeFILBalance, err = e.client.BalanceAt(ctx, wallet.Payee, snapshotBlock(ctx))
+		if err != nil {
+			e.logger.Warn("Failed to get payee FIL balance", "address", wallet.Payee.Hex(), "error", err)
+			wallet.PayeeFILBalance = nil
+		}
+		wallet.PayeeUSDFCBalance, err = e.usdfcContract.BalanceOf(callOpts(ctx), wallet.Payee)
+		if err != nil {
+			e.logger.Warn("Failed to get payee USDFC balance", "address", wallet.Payee.Hex(), "error", err)
+			wallet.PayeeUSDFCBalance = nil
+		}
+	}
+
+	// Get Payments contract info for every configured token
+	wallet.setPaymentsAccounts(e.fetchPaymentsAccounts(ctx, wallet.Address))
 
-	// Get Payments contract info
-	paymentsInfo, err := e.fetchPaymentsInfo(ctx, address)
+	wallet.UpdatedAt = time.Now()
+	return nil
+}
+
+// CheckWallet reads the FIL, USDFC and Payments balances of one address outside of a scrape,
+// pinned to the block scrapes would read at, and returns them with that block
+func (e *WalletExporter) CheckWallet(ctx context.Context, address common.Address) (WalletInfo, uint64, error) {
+	head, err := e.client.BlockNumber(ctx)
 	if err != nil {
-		e.logger.Warn("Failed to get Payments info", "address", address.Hex(), "error", err)
-		paymentsInfo = &PaymentsInfo{
-			Funds:            big.NewInt(0),
-			Available:        big.NewInt(0),
-			Locked:           big.NewInt(0),
-			FundedUntilEpoch: big.NewInt(0),
-		}
+		return WalletInfo{}, 0, fmt.Errorf("failed to get block number: %w", err)
+	}
+	block := uint64(e.snapshotRef(ctx, head).Number)
+	wallet := WalletInfo{Address: address, Type: "other"}
+	if err := e.fetchWalletBalances(withSnapshotBlock(ctx, block), &wallet); err != nil {
+		return WalletInfo{}, 0, err
+	}
+	return wallet, block, nil
+}
+
+// hasSeparatePayee reports whether a provider receives payments at an address other than its own
+func (w WalletInfo) hasSeparatePayee() bool {
+	return w.Type == "provider" && w.Payee != w.Address && w.Payee != (common.Address{})
+}
+
+// setPaymentsAccounts stores the Payments accounts of a wallet and copies the USDFC
+// account into the single-token fields
+func (w *WalletInfo) setPaymentsAccounts(accounts []PaymentsAccount) {
+	paymentsInfo := accounts[0].PaymentsInfo
+	if paymentsInfo == nil {
+		paymentsInfo = &PaymentsInfo{} // Unknown
 	}
 
-	return WalletInfo{
-		Address:             address,
-		Name:                cw.Name,
-		Type:                cw.Type,
-		ProviderID:          0,
-		IsActive:            false,
-		IsApproved:          false,
-		Description:         "",
-		FILBalance:          filBalance,
-		USDFCBalance:        usdfcBalance,
-		PaymentsFunds:       paymentsInfo.Funds,
-		PaymentsAvailable:   paymentsInfo.Available,
-		PaymentsLocked:      paymentsInfo.Locked,
-		PaymentsFundedUntil: paymentsInfo.FundedUntilEpoch,
-	}, nil
+	w.PaymentsAccounts = accounts
+	w.PaymentsFunds = paymentsInfo.Funds
+	w.PaymentsAvailable = paymentsInfo.Available
+	w.PaymentsLocked = paymentsInfo.Locked
+	w.PaymentsFundedUntil = paymentsInfo.FundedUntilEpoch
+	w.PaymentsLockupRate = paymentsInfo.LockupRate
 }
 
 type PingResult struct {
-	Success    bool
-	Duration   time.Duration
-	ServiceURL string
-}
-
-func (e *WalletExporter) updateMetrics(wallets []WalletInfo, pingResults map[uint64]PingResult) {
-	// Reset metrics to avoid stale data
-	e.filBalanceGauge.Reset()
-	e.usdfcBalanceGauge.Reset()
-	e.walletInfoGauge.Reset()
-	e.paymentsFundsGauge.Reset()
-	e.paymentsAvailableGauge.Reset()
-	e.paymentsLockedGauge.Reset()
-	e.paymentsFundedUntilGauge.Reset()
-	e.pingSuccessGauge.Reset()
-	e.pingDurationGauge.Reset()
+	ProductType uint8
+	Success     bool
+	Duration    time.Duration
+	ServiceURL  string
+	PingURL     string    // URL that was requested
+	StatusCode  int       // HTTP status (0 if the request failed)
+	Error       string    // Why the request failed (empty if a response arrived)
+	Time        time.Time // When the ping was sent
+}
+
+// updateMetrics replaces the wallet metrics with the given wallets. The series of wallets that
+// are gone, and any other series not set again, are deleted once the new values are in place.
+func (e *WalletExporter) updateMetrics(wallets []WalletInfo, pingResults map[uint64][]PingResult) {
+	now := time.Now()
+	monitored := make(map[common.Address]bool, len(wallets))
 
+	e.series.Begin()
 	for _, wallet := range wallets {
-		providerID := fmt.Sprintf("%d", wallet.ProviderID)
-		if wallet.Type != "provider" {
-			providerID = ""
+		monitored[wallet.Address] = true
+		if wallet.PayeeFILBalance != nil && wallet.PayeeUSDFCBalance != nil {
+			monitored[wallet.Payee] = true
 		}
+		e.setWalletMetrics(wallet, pingResults[wallet.ProviderID])
+		e.setRunwayMetrics(wallet, now)
+	}
+	e.series.Swap()
 
-		isActive := fmt.Sprintf("%t", wallet.IsActive)
-		if wallet.Type != "provider" {
-			isActive = ""
-		}
+	e.labelSets.Forget(monitored)
+	e.filRunway.Forget(monitored)
+	e.usdfcRunway.Forget(monitored)
+}
 
-		approved := fmt.Sprintf("%t", wallet.IsApproved)
-		if wallet.Type != "provider" {
-			approved = ""
+// publishWallet sets the metrics of a wallet as soon as its balances are read, so the results
+// collected so far are exported even if the rest of the scrape fails. The series stay
+// provisional until updateMetrics publishes the complete scrape, which also projects the
+// runways, so each scrape adds one runway sample.
+func (e *WalletExporter) publishWallet(wallet WalletInfo) {
+	if wallet.Type == "provider" {
+		// Named and thresholded like the entry the custom wallet is merged into
+		if custom, ok := e.customByAddress[wallet.Address]; ok {
+			wallet.Name, wallet.MinFIL, wallet.MinUSDFC = custom.Name, custom.MinFIL, custom.MinUSDFC
 		}
+	} else if wallet.ProviderID != 0 {
+		return // A custom wallet that is a provider is only exported once merged
+	}
+
+	// Pings are published with the rest of the scrape
+	e.setWalletMetrics(wallet, nil)
+}
 
-		labels := prometheus.Labels{
-			"address":     wallet.Address.Hex(),
-			"name":        wallet.Name,
-			"type":        wallet.Type,
-			"provider_id": providerID,
-			"is_active":   isActive,
-			"approved":    approved,
+// publishWallets publishes every wallet of a batch
+func (e *WalletExporter) publishWallets(wallets []WalletInfo) {
+	for _, wallet := range wallets {
+		e.publishWallet(wallet)
+	}
+}
+
+// setWalletMetrics sets every series of a wallet
+func (e *WalletExporter) setWalletMetrics(wallet WalletInfo, pings []PingResult) {
+	// Providers are reported for their service provider (operator) address and,
+	// when it differs, for their payee address
+	labels := e.labelSets.Get(wallet)
+	e.setBalanceMetrics(labels.operator, wallet.FILBalance, wallet.USDFCBalance, wallet.MinFIL, wallet.MinUSDFC)
+
+	stale := 0.0
+	if wallet.Stale {
+		stale = 1.0
+	}
+	e.series.Set(e.walletStaleGauge, labels.operator.labels, stale)
+	if !wallet.UpdatedAt.IsZero() {
+		e.series.Set(e.walletUpdatedGauge, labels.operator.labels, float64(wallet.UpdatedAt.Unix()))
+	}
+
+	if wallet.PayeeFILBalance != nil && wallet.PayeeUSDFCBalance != nil {
+		e.setBalanceMetrics(labels.Payee(), wallet.PayeeFILBalance, wallet.PayeeUSDFCBalance, wallet.MinFIL, wallet.MinUSDFC)
+		if !wallet.UpdatedAt.IsZero() {
+			e.series.Set(e.walletUpdatedGauge, labels.Payee().labels, float64(wallet.UpdatedAt.Unix()))
 		}
+	}
 
-		// Set FIL balance (in FIL, not wei)
-		filFloat, _ := new(big.Float).Quo(
-			new(big.Float).SetInt(wallet.FILBalance),
-			big.NewFloat(1e18),
-		).Float64()
-		e.filBalanceGauge.With(labels).Set(filFloat)
-
-		// Set USDFC balance (USDFC has 18 decimals)
-		usdfcFloat, _ := new(big.Float).Quo(
-			new(big.Float).SetInt(wallet.USDFCBalance),
-			big.NewFloat(1e18),
-		).Float64()
-		e.usdfcBalanceGauge.With(labels).Set(usdfcFloat)
-
-		// Set Payments contract metrics (USDFC has 18 decimals)
-		paymentsFundsFloat, _ := new(big.Float).Quo(
-			new(big.Float).SetInt(wallet.PaymentsFunds),
-			big.NewFloat(1e18),
-		).Float64()
-		e.paymentsFundsGauge.With(labels).Set(paymentsFundsFloat)
-
-		paymentsAvailableFloat, _ := new(big.Float).Quo(
-			new(big.Float).SetInt(wallet.PaymentsAvailable),
-			big.NewFloat(1e18),
-		).Float64()
-		e.paymentsAvailableGauge.With(labels).Set(paymentsAvailableFloat)
-
-		paymentsLockedFloat, _ := new(big.Float).Quo(
-			new(big.Float).SetInt(wallet.PaymentsLocked),
-			big.NewFloat(1e18),
-		).Float64()
-		e.paymentsLockedGauge.With(labels).Set(paymentsLockedFloat)
-
-		// PaymentsFundedUntil is an epoch (block number), not a token amount
-		paymentsFundedUntilFloat, _ := new(big.Float).SetInt(wallet.PaymentsFundedUntil).Float64()
-		e.paymentsFundedUntilGauge.With(labels).Set(paymentsFundedUntilFloat)
-
-		// Set info metric
-		infoLabels := prometheus.Labels{
-			"address":     wallet.Address.Hex(),
-			"name":        wallet.Name,
-			"type":        wallet.Type,
-			"provider_id": providerID,
-			"description": wallet.Description,
-			"is_active":   isActive,
-			"approved":    approved,
+	// Set Payments contract metrics for every token account
+	for _, account := range wallet.PaymentsAccounts {
+		tokenLabels := labels.operator.withToken(account.Token)
+		queryError := 0.0
+		if account.QueryFailed {
+			queryError = 1.0
+		}
+		e.series.Set(e.paymentsQueryErrorGauge, tokenLabels, queryError)
+		if account.PaymentsInfo == nil {
+			continue // Never read successfully, so there is no value to keep
 		}
-		e.walletInfoGauge.With(infoLabels).Set(1)
-
-		// Set Ping metrics if available (only for providers)
-		if wallet.Type == "provider" {
-			if result, ok := pingResults[wallet.ProviderID]; ok {
-				pingLabels := prometheus.Labels{
-					"address":     wallet.Address.Hex(),
-					"name":        wallet.Name,
-					"provider_id": providerID,
-					"service_url": result.ServiceURL,
-				}
-
-				successVal := 0.0
-				if result.Success {
-					successVal = 1.0
-				}
-				e.pingSuccessGauge.With(pingLabels).Set(successVal)
-				e.pingDurationGauge.With(pingLabels).Set(float64(result.Duration.Milliseconds()))
+
+		e.series.Set(e.paymentsFundsGauge, tokenLabels, tokenAmount(account.Funds, account.Decimals))
+		e.series.Set(e.paymentsAvailableGauge, tokenLabels, tokenAmount(account.Available, account.Decimals))
+		e.series.Set(e.paymentsLockedGauge, tokenLabels, tokenAmount(account.Locked, account.Decimals))
+
+		// FundedUntilEpoch is an epoch (block number), not a token amount
+		paymentsFundedUntilFloat, _ := new(big.Float).SetInt(account.FundedUntilEpoch).Float64()
+		e.series.Set(e.paymentsFundedUntilGauge, tokenLabels, paymentsFundedUntilFloat)
+		e.series.Set(e.paymentsLockupRateGauge, tokenLabels, tokenAmount(account.LockupRate, account.Decimals))
+	}
+
+	// Set info metric
+	e.series.Set(e.walletInfoGauge, labels.info, 1)
+
+	// Set Ping metrics if available (only for providers)
+	if wallet.Type == "provider" {
+		for _, result := range pings {
+			pingLabels := labels.Ping(result)
+
+			successVal := 0.0
+			if result.Success {
+				successVal = 1.0
 			}
+			e.series.Set(e.pingSuccessGauge, pingLabels, successVal)
+			e.series.Set(e.pingDurationGauge, pingLabels, float64(result.Duration.Milliseconds()))
 		}
 	}
 }
 
+// setBalanceMetrics sets the FIL and USDFC balances of an address together with its
+// thresholds
+func (e *WalletExporter) setBalanceMetrics(addressLabels *addressLabels, filBalance, usdfcBalance *big.Int, minFIL, minUSDFC float64) {
+	labels := addressLabels.labels
+
+	// Set FIL balance (in FIL, not wei)
+	filFloat, _ := new(big.Float).Quo(
+		new(big.Float).SetInt(filBalance),
+		big.NewFloat(1e18),
+	).Float64()
+	e.series.Set(e.filBalanceGauge, labels, filFloat)
+
+	// Set USDFC balance (USDFC has 18 decimals)
+	usdfcFloat, _ := new(big.Float).Quo(
+		new(big.Float).SetInt(usdfcBalance),
+		big.NewFloat(1e18),
+	).Float64()
+	e.series.Set(e.usdfcBalanceGauge, labels, usdfcFloat)
+
+	// Float64 loses the last digits of large balances; the split parts keep them
+	if e.config.ExportRawBalances {
+		e.setRawBalanceMetrics(addressLabels, "fil", filBalance)
+		e.setRawBalanceMetrics(addressLabels, "usdfc", usdfcBalance)
+	}
+
+	// Set balance thresholds
+	if minFIL > 0 {
+		e.series.Set(e.filMinThresholdGauge, labels, minFIL)
+		e.setBelowThreshold(addressLabels, "fil", filFloat < minFIL)
+	}
+	if minUSDFC > 0 {
+		e.series.Set(e.usdfcMinThresholdGauge, labels, minUSDFC)
+		e.setBelowThreshold(addressLabels, "usdfc", usdfcFloat < minUSDFC)
+	}
+}
+
+// setRunwayMetrics adds the balances of a wallet to the runway trackers and sets the runway
+// projections once a spend rate is available. It must run once per scrape, since every call
+// is a sample of the spend rate.
+func (e *WalletExporter) setRunwayMetrics(wallet WalletInfo, now time.Time) {
+	labels := e.labelSets.Get(wallet)
+	e.observeRunway(labels.operator, wallet.Address, wallet.FILBalance, wallet.USDFCBalance, now)
+	if wallet.PayeeFILBalance != nil && wallet.PayeeUSDFCBalance != nil {
+		e.observeRunway(labels.Payee(), wallet.Payee, wallet.PayeeFILBalance, wallet.PayeeUSDFCBalance, now)
+	}
+}
+
+func (e *WalletExporter) observeRunway(addressLabels *addressLabels, address common.Address, filBalance, usdfcBalance *big.Int, now time.Time) {
+	if days, ok := e.filRunway.Observe(address, tokenAmount(filBalance, nativeTokenDecimals), now); ok {
+		e.series.Set(e.filRunwayGauge, addressLabels.labels, days)
+	}
+	// USDFC has 18 decimals
+	if days, ok := e.usdfcRunway.Observe(address, tokenAmount(usdfcBalance, 18), now); ok {
+		e.series.Set(e.usdfcRunwayGauge, addressLabels.labels, days)
+	}
+}
+
+func (e *WalletExporter) setBelowThreshold(labels *addressLabels, token string, below bool) {
+	thresholdLabels := labels.withToken(token)
+
+	belowVal := 0.0
+	if below {
+		belowVal = 1.0
+	}
+	e.series.Set(e.belowThresholdGauge, thresholdLabels, belowVal)
+}
+
+// withToken returns a copy of labels with the token label added
+func withToken(labels prometheus.Labels, token string) prometheus.Labels {
+	tokenLabels := prometheus.Labels{"token": token}
+	for k, v := range labels {
+		tokenLabels[k] = v
+	}
+	return tokenLabels
+}
+
+// tokenAmount converts a raw token amount to whole tokens
+func tokenAmount(amount *big.Int, decimals int) float64 {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	value, _ := new(big.Float).Quo(new(big.Float).SetInt(amount), new(big.Float).SetInt(scale)).Float64()
+	return value
+}
+
+// TriggerScrape requests a scrape outside the regular interval. It returns false if a
+// requested scrape is already pending.
+func (e *WalletExporter) TriggerScrape() bool {
+	select {
+	case e.scrapeRequests <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
 func (e *WalletExporter) GetWallets() []WalletInfo {
 	e.walletsMux.RLock()
 	defer e.walletsMux.RUnlock()
 	return e.wallets
 }
 
+// GetPingResults returns the ping results of the last scrape by provider ID
+func (e *WalletExporter) GetPingResults() map[uint64][]PingResult {
+	e.walletsMux.RLock()
+	defer e.walletsMux.RUnlock()
+	return e.lastPingResults
+}
+
 func (e *WalletExporter) GetLastScrape() time.Time {
 	e.walletsMux.RLock()
 	defer e.walletsMux.RUnlock()
 	return e.lastScrape
 }
 
+// GetOffboardingReport returns providers that appear to be leaving the network
+func (e *WalletExporter) GetOffboardingReport() []OffboardingEntry {
+	return e.offboarding.Report()
+}
+
+// GetHistory returns the history store, or nil if HISTORY_PATH is not set
+func (e *WalletExporter) GetHistory() *history.Store {
+	return e.history
+}
+
 func (e *WalletExporter) GetRegistry() *prometheus.Registry {
 	return e.registry
 }
 
 func (e *WalletExporter) Close() {
+	if e.config.StateFile != "" {
+		if err := e.saveState(); err != nil {
+			e.logger.Warn("Failed to save state", "error", err)
+		}
+	}
 	if e.client != nil {
 		e.client.Close()
 	}
+	if e.history != nil {
+		e.history.Close()
+	}
 }
 
 // PaymentsInfo holds the calculated Payments contract account information
@@ -673,11 +1446,11 @@ type PaymentsInfo struct {
 	Available        *big.Int // Available funds (funds - actualLockup)
 	Locked           *big.Int // Current locked funds
 	FundedUntilEpoch *big.Int // Estimated epoch when funds run out
+	LockupRate       *big.Int // Current lockup rate per epoch
 }
 
-// fetchPaymentsInfo fetches account info from Payments contract using getAccountInfoIfSettled
-func (e *WalletExporter) fetchPaymentsInfo(ctx context.Context, address common.Address) (*PaymentsInfo, error) {
-	usdfcAddr := common.HexToAddress(e.config.USDFCTokenAddress)
+// fetchPaymentsInfo fetches the token account from Payments contract using getAccountInfoIfSettled
+func (e *WalletExporter) fetchPaymentsInfo(ctx context.Context, token common.Address, address common.Address) (*PaymentsInfo, error) {
 	paymentsAddr := common.HexToAddress(e.config.PaymentsAddress)
 
 	// Create Payments contract instance using abigen generated binding
@@ -686,24 +1459,18 @@ func (e *WalletExporter) fetchPaymentsInfo(ctx context.Context, address common.A
 		return nil, fmt.Errorf("failed to create Payments contract: %w", err)
 	}
 
-	// Call getAccountInfoIfSettled - type-safe method from abigen
-	result, err := paymentsContract.GetAccountInfoIfSettled(nil, usdfcAddr, address)
+	// Call getAccountInfoIfSettled - type-safe method from abigen. Wallets without an account
+	// read as zeros, so a revert means the contract or its address is wrong and is an error too.
+	result, err := paymentsContract.GetAccountInfoIfSettled(callOpts(ctx), token, address)
 	if err != nil {
-		// Handle error - might be account doesn't exist
-		return &PaymentsInfo{
-			Funds:            big.NewInt(0),
-			Available:        big.NewInt(0),
-			Locked:           big.NewInt(0),
-			FundedUntilEpoch: big.NewInt(0),
-		}, nil
+		return nil, fmt.Errorf("failed to get account info: %w", err)
 	}
 
-	// Extract values from the result struct
-	fundedUntilEpoch := result.FundedUntilEpoch
-	currentFunds := result.CurrentFunds
-	availableFunds := result.AvailableFunds
-	// currentLockupRate := result.CurrentLockupRate // not needed for now
+	return newPaymentsInfo(result.CurrentFunds, result.AvailableFunds, result.FundedUntilEpoch, result.CurrentLockupRate), nil
+}
 
+// newPaymentsInfo builds a PaymentsInfo from a getAccountInfoIfSettled result
+func newPaymentsInfo(currentFunds, availableFunds, fundedUntilEpoch, currentLockupRate *big.Int) *PaymentsInfo {
 	// Calculate locked amount: locked = currentFunds - availableFunds
 	locked := new(big.Int).Sub(currentFunds, availableFunds)
 	if locked.Cmp(big.NewInt(0)) < 0 {
@@ -715,15 +1482,27 @@ func (e *WalletExporter) fetchPaymentsInfo(ctx context.Context, address common.A
 		Available:        availableFunds,
 		Locked:           locked,
 		FundedUntilEpoch: fundedUntilEpoch,
-	}, nil
+		LockupRate:       currentLockupRate,
+	}
+}
+
+// emptyPaymentsInfo is an account without funds
+func emptyPaymentsInfo() *PaymentsInfo {
+	return &PaymentsInfo{
+		Funds:            big.NewInt(0),
+		Available:        big.NewInt(0),
+		Locked:           big.NewInt(0),
+		FundedUntilEpoch: big.NewInt(0),
+		LockupRate:       big.NewInt(0),
+	}
 }
 
 // pingProviders pings all providers concurrently and returns results
-func (e *WalletExporter) pingProviders(ctx context.Context, providers []WalletInfo) map[uint64]PingResult {
+func (e *WalletExporter) pingProviders(ctx context.Context, providers []WalletInfo) map[uint64][]PingResult {
 	var wg sync.WaitGroup
-	semaphore := make(chan struct{}, e.config.MaxConcurrentRequests)
+	semaphore := make(chan struct{}, e.config.MaxConcurrentPings)
 
-	results := make(map[uint64]PingResult)
+	results := make(map[uint64][]PingResult)
 	var mu sync.Mutex
 
 	for _, p := range providers {
@@ -738,10 +1517,9 @@ func (e *WalletExporter) pingProviders(ctx context.Context, providers []WalletIn
 			semaphore <- struct{}{}
 			defer func() { <-semaphore }()
 
-			result, ok := e.pingProvider(ctx, p)
-			if ok {
+			if pings := e.pingProvider(ctx, p); len(pings) > 0 {
 				mu.Lock()
-				results[p.ProviderID] = result
+				results[p.ProviderID] = pings
 				mu.Unlock()
 			}
 		}(p)
@@ -751,62 +1529,66 @@ func (e *WalletExporter) pingProviders(ctx context.Context, providers []WalletIn
 	return results
 }
 
-func (e *WalletExporter) pingProvider(ctx context.Context, p WalletInfo) (PingResult, bool) {
-	// 1. Get Provider with Product (Product Type 0 for PDP)
-	// We use the generated struct directly
-	result, err := e.registryContract.GetProviderWithProduct(nil, big.NewInt(int64(p.ProviderID)), 0)
-	if err != nil {
-		// Log detailed error to debug
-		e.logger.Debug("Failed to get PDP product", "provider_id", p.ProviderID, "error", err)
-		return PingResult{}, false
+// pingProvider pings the PDP service of a provider, the only product with a ping route, and
+// returns its result if the provider has one
+func (e *WalletExporter) pingProvider(ctx context.Context, p WalletInfo) []PingResult {
+	if result, ok := e.pingProduct(ctx, p, pdpProductType); ok {
+		return []PingResult{result}
 	}
+	return nil
+}
 
-	// Check if product is active
-	if !result.Product.IsActive {
-		return PingResult{}, false
-	}
+func (e *WalletExporter) pingProduct(ctx context.Context, p WalletInfo, productType uint8) (PingResult, bool) {
+	productName := productTypeName(productType)
 
-	// 2. Decode Capabilities to find Service URL
-	var serviceURL string
-	for i, key := range result.Product.CapabilityKeys {
-		if key == "serviceURL" {
-			if i < len(result.ProductCapabilityValues) {
-				serviceURL = string(result.ProductCapabilityValues[i])
-			}
-			break
-		}
+	// 1-2. Look up the Service URL of the product
+	serviceURL, err := e.lookupServiceURL(ctx, p.ProviderID, productType)
+	if err != nil {
+		// Log detailed error to debug
+		e.logger.Debug("Failed to get product", "provider_id", p.ProviderID, "product_type", productName, "error", err)
+		return PingResult{}, false
 	}
 
 	if serviceURL == "" {
-		e.logger.Debug("PDP product has no serviceURL", "provider_id", p.ProviderID)
+		e.logger.Debug("Product has no serviceURL", "provider_id", p.ProviderID, "product_type", productName)
 		return PingResult{}, false
 	}
 
-	e.logger.Debug("Found serviceURL", "provider_id", p.ProviderID, "url", serviceURL)
+	e.logger.Debug("Found serviceURL", "provider_id", p.ProviderID, "product_type", productName, "url", serviceURL)
 
-	// 3. Ping
+	// 3. Ping the product's ping route (/pdp/ping)
 	// Remove trailing slash if present
 	baseURL := strings.TrimRight(serviceURL, "/")
-	pingURL := baseURL + "/pdp/ping"
+	pingURL := baseURL + "/" + productName + "/ping"
 
 	client := http.Client{
 		Timeout: 5 * time.Second,
 	}
 
+	// Bound to ctx, so SCRAPE_TIMEOUT and shutdown cancel pings in flight
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, pingURL, nil)
+	if err != nil {
+		e.logger.Debug("Invalid ping URL", "provider_id", p.ProviderID, "url", pingURL, "error", err)
+		return PingResult{}, false
+	}
+
 	start := time.Now()
-	resp, err := client.Get(pingURL)
+	resp, err := client.Do(req)
 	duration := time.Since(start)
 
+	result := PingResult{ProductType: productType, Duration: duration, ServiceURL: serviceURL, PingURL: pingURL, Time: start}
 	if err != nil {
 		e.logger.Warn("Ping failed", "provider_id", p.ProviderID, "name", p.Name, "url", pingURL, "error", err)
-		return PingResult{Success: false, Duration: duration, ServiceURL: serviceURL}, true
+		result.Error = stripURLError(err).Error()
+		return result, true
 	}
 	defer resp.Body.Close()
 
-	success := resp.StatusCode == http.StatusOK
-	if !success {
+	result.StatusCode = resp.StatusCode
+	result.Success = resp.StatusCode == http.StatusOK
+	if !result.Success {
 		e.logger.Warn("Ping returned non-200 status", "status", resp.StatusCode, "provider_id", p.ProviderID, "name", p.Name, "url", pingURL)
 	}
 
-	return PingResult{Success: success, Duration: duration, ServiceURL: serviceURL}, true
+	return result, true
 }
@@ -0,0 +1,63 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// FinalityStatus describes how far the snapshot block of a scrape is from the finalized tip
+type FinalityStatus struct {
+	SnapshotBlock  uint64 // Head block the scrape was taken at
+	FinalizedBlock uint64 // Latest finalized block reported by the node (F3 or EC finality)
+	Finalized      bool   // Whether the snapshot block is already finalized
+	Distance       uint64 // Number of blocks between the snapshot block and the finalized tip
+}
+
+func (e *WalletExporter) registerFinalityMetrics() {
+	e.snapshotBlockGauge = newGauge(e.config.MetricsPrefix, "snapshot_block_number")
+	e.snapshotFinalizedGauge = newGauge(e.config.MetricsPrefix, "snapshot_finalized")
+	e.finalityDistanceGauge = newGauge(e.config.MetricsPrefix, "snapshot_finality_distance_blocks")
+
+	e.registry.MustRegister(e.snapshotBlockGauge)
+	e.registry.MustRegister(e.snapshotFinalizedGauge)
+	e.registry.MustRegister(e.finalityDistanceGauge)
+}
+
+// fetchFinalityStatus reads the scrape's snapshot block and the "finalized" tagged block from the RPC.
+// Nodes without support for the finalized tag return an error, which callers treat as "unknown".
+func (e *WalletExporter) fetchFinalityStatus(ctx context.Context) (*FinalityStatus, error) {
+	head, err := e.headBlock(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get head block: %w", err)
+	}
+
+	finalized, err := e.client.HeaderByNumber(ctx, big.NewInt(int64(rpc.FinalizedBlockNumber)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get finalized block (RPC may not support the finalized tag): %w", err)
+	}
+
+	status := &FinalityStatus{
+		SnapshotBlock:  head,
+		FinalizedBlock: finalized.Number.Uint64(),
+	}
+	status.Finalized = status.SnapshotBlock <= status.FinalizedBlock
+	if !status.Finalized {
+		status.Distance = status.SnapshotBlock - status.FinalizedBlock
+	}
+
+	return status, nil
+}
+
+func (e *WalletExporter) updateFinalityMetrics(status *FinalityStatus) {
+	e.snapshotBlockGauge.Set(float64(status.SnapshotBlock))
+
+	finalizedVal := 0.0
+	if status.Finalized {
+		finalizedVal = 1.0
+	}
+	e.snapshotFinalizedGauge.Set(finalizedVal)
+	e.finalityDistanceGauge.Set(float64(status.Distance))
+}
@@ -0,0 +1,70 @@
+package exporter
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// graphiteSink sends metrics to Graphite (carbon) over the plaintext protocol, one TCP
+// connection per push
+type graphiteSink struct {
+	address string
+	prefix  string // Path prefix, e.g. "wallets.prod"
+	tags    bool   // Send labels as Graphite 1.1 tags instead of path components
+}
+
+func (g *graphiteSink) Name() string { return "graphite" }
+
+// Push writes one "path value timestamp" line per sample with a finite value
+func (g *graphiteSink) Push(ctx context.Context, families []*dto.MetricFamily, now time.Time) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", g.address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	w := bufio.NewWriter(conn)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	for _, sample := range pushSamples(families) {
+		if math.IsInf(sample.value, 0) || math.IsNaN(sample.value) {
+			continue // Like the runway of a wallet that is not spending
+		}
+		fmt.Fprintf(w, "%s %s %s\n", g.path(sample), strconv.FormatFloat(sample.value, 'f', -1, 64), timestamp)
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write to Graphite: %w", err)
+	}
+	return nil
+}
+
+// path returns the metric path of a sample, with tags if enabled
+func (g *graphiteSink) path(sample pushSample) string {
+	if !g.tags {
+		return metricPath(g.prefix, sample)
+	}
+
+	var b strings.Builder
+	if g.prefix != "" {
+		b.WriteString(strings.Trim(g.prefix, ".") + ".")
+	}
+	b.WriteString(sample.name)
+	for _, label := range sample.labels {
+		if label.GetValue() == "" {
+			continue // Graphite rejects empty tag values
+		}
+		b.WriteString(";" + label.GetName() + "=" + strings.NewReplacer(";", "_", "~", "_", " ", "_").Replace(label.GetValue()))
+	}
+	return b.String()
+}
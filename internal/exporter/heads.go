@@ -0,0 +1,188 @@
+package exporter
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// headResubscribeDelay is how long the head watcher waits before renewing a failed subscription
+const headResubscribeDelay = 5 * time.Second
+
+// isWebsocketURL reports whether an RPC URL supports subscriptions
+func isWebsocketURL(url string) bool {
+	return strings.HasPrefix(url, "ws://") || strings.HasPrefix(url, "wss://")
+}
+
+// watchHeads subscribes to new heads and hands their numbers to the scrape loop. Only the
+// newest head waiting to be processed is kept, so a slow refresh never queues up blocks. The
+// subscription is renewed whenever it fails, including after the client was redialed.
+func (e *WalletExporter) watchHeads(ctx context.Context) {
+	for {
+		e.followHeads(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(headResubscribeDelay):
+		}
+	}
+}
+
+// followHeads forwards heads until the subscription fails or ctx is canceled
+func (e *WalletExporter) followHeads(ctx context.Context) {
+	headers := make(chan *types.Header)
+	sub, err := e.client.SubscribeNewHead(ctx, headers)
+	if err != nil {
+		e.logger.Warn("Failed to subscribe to new heads", "error", err)
+		return
+	}
+	defer sub.Unsubscribe()
+	e.logger.Info("Subscribed to new heads")
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err := <-sub.Err():
+			e.logger.Warn("New heads subscription failed", "error", err)
+			return
+		case header := <-headers:
+			head := header.Number.Uint64()
+			select {
+			case e.heads <- head:
+			default:
+				// Replace the head the scrape loop has not picked up yet
+				select {
+				case <-e.heads:
+				default:
+				}
+				e.heads <- head
+			}
+		}
+	}
+}
+
+// refreshHead re-reads the balances of the monitored wallets the block at head may have
+// changed and republishes their metrics. Everything else keeps the values of the last scrape.
+func (e *WalletExporter) refreshHead(ctx context.Context, head uint64) {
+	if e.circuit.open {
+		return
+	}
+
+	e.walletsMux.RLock()
+	wallets := append([]WalletInfo(nil), e.wallets...)
+	e.walletsMux.RUnlock()
+	if len(wallets) == 0 || e.lastScrapeBlock >= head {
+		return
+	}
+
+	ctx = withSnapshotBlock(ctx, head)
+	touched, err := e.touchedAddresses(ctx, head, head)
+	if err != nil {
+		e.logger.Debug("Failed to read block for head refresh", "block", head, "error", err)
+		return
+	}
+
+	var indices []int
+	var refresh []WalletInfo
+	for i, wallet := range wallets {
+		if touched[wallet.Address] || (wallet.hasSeparatePayee() && touched[wallet.Payee]) {
+			indices = append(indices, i)
+			refresh = append(refresh, wallet)
+		}
+	}
+	if len(refresh) == 0 {
+		return
+	}
+
+	var fetched []WalletInfo
+	if e.batchesBalances() {
+		fetched = e.fetchBalancesBatched(ctx, refresh)
+	} else {
+		fetched = e.fetchBalancesIndividually(ctx, refresh)
+	}
+
+	carryOverPayments(fetched, wallets)
+
+	// Wallets whose FIL balance failed are dropped from fetched; keep their last values
+	byAddress := make(map[common.Address]WalletInfo, len(fetched))
+	for _, wallet := range fetched {
+		byAddress[wallet.Address] = wallet
+	}
+	for _, i := range indices {
+		if wallet, ok := byAddress[wallets[i].Address]; ok {
+			wallets[i] = wallet
+		}
+	}
+
+	e.walletsMux.Lock()
+	e.wallets = wallets
+	e.walletsMux.Unlock()
+
+	e.updateMetrics(wallets, e.lastPingResults)
+	e.logger.Debug("Refreshed wallets for new head", "block", head, "wallets", len(fetched))
+}
+
+// touchedAddresses returns the senders and recipients of the transactions in the blocks from
+// through to, plus the addresses in indexed topics of the USDFC and Payments logs they emitted,
+// which covers token transfers and Payments account changes made on a wallet's behalf
+func (e *WalletExporter) touchedAddresses(ctx context.Context, from, to uint64) (map[common.Address]bool, error) {
+	touched := make(map[common.Address]bool)
+	for number := from; number <= to; number++ {
+		var block struct {
+			Transactions []struct {
+				From common.Address  `json:"from"`
+				To   *common.Address `json:"to"`
+			} `json:"transactions"`
+		}
+		callCtx, cancel := e.client.withTimeout(ctx)
+		err := e.client.Client().CallContext(callCtx, &block, "eth_getBlockByNumber", hexutil.EncodeUint64(number), true)
+		cancel()
+		if err != nil {
+			if isNullRound(err) {
+				continue // Epochs without a block have no transactions
+			}
+			return nil, err
+		}
+
+		for _, tx := range block.Transactions {
+			touched[tx.From] = true
+			if tx.To != nil {
+				touched[*tx.To] = true
+			}
+		}
+	}
+
+	logs, err := e.client.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(from),
+		ToBlock:   new(big.Int).SetUint64(to),
+		Addresses: []common.Address{
+			common.HexToAddress(e.config.USDFCTokenAddress),
+			common.HexToAddress(e.config.PaymentsAddress),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, log := range logs {
+		for _, topic := range log.Topics[min(1, len(log.Topics)):] {
+			// Indexed addresses are left-padded to 32 bytes
+			if topic == common.BytesToHash(topic[12:]) {
+				touched[common.BytesToAddress(topic[12:])] = true
+			}
+		}
+	}
+	return touched, nil
+}
+
+// isNullRound reports whether Lotus failed a block lookup because no block was mined at that epoch
+func isNullRound(err error) bool {
+	return strings.Contains(err.Error(), "null round")
+}
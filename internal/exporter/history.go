@@ -0,0 +1,48 @@
+package exporter
+
+import (
+	"wallet-exporter/internal/history"
+)
+
+// historyWallets converts the wallets of a scrape at block head to history records
+func historyWallets(wallets []WalletInfo, pingResults map[uint64][]PingResult, head uint64) []history.Wallet {
+	records := make([]history.Wallet, 0, len(wallets))
+	for _, wallet := range wallets {
+		record := history.Wallet{
+			Block:   head,
+			Address: wallet.Address,
+			Name:    wallet.Name,
+			Type:    wallet.Type,
+			FIL:     wallet.FILBalance,
+			USDFC:   wallet.USDFCBalance,
+		}
+		for _, account := range wallet.PaymentsAccounts {
+			if account.PaymentsInfo == nil {
+				continue
+			}
+			record.Payments = append(record.Payments, history.Payments{
+				Token:            account.Token,
+				Decimals:         account.Decimals,
+				Funds:            account.Funds,
+				Available:        account.Available,
+				Locked:           account.Locked,
+				FundedUntilEpoch: account.FundedUntilEpoch,
+				LockupRate:       account.LockupRate,
+			})
+		}
+		if wallet.Type == "provider" {
+			record.ProviderID = wallet.ProviderID
+			for _, result := range pingResults[wallet.ProviderID] {
+				record.Pings = append(record.Pings, history.Ping{
+					ProductType:     productTypeName(result.ProductType),
+					ServiceURL:      result.ServiceURL,
+					Success:         result.Success,
+					StatusCode:      result.StatusCode,
+					DurationSeconds: result.Duration.Seconds(),
+				})
+			}
+		}
+		records = append(records, record)
+	}
+	return records
+}
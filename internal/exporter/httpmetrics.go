@@ -0,0 +1,11 @@
+package exporter
+
+func (e *WalletExporter) registerHTTPMetrics() {
+	e.rejectedRequestsCounter = newCounterVec(e.config.MetricsPrefix, "http_requests_rejected_total")
+	e.registry.MustRegister(e.rejectedRequestsCounter)
+}
+
+// RequestRejected counts a request the HTTP server refused before handling it
+func (e *WalletExporter) RequestRejected(reason string) {
+	e.rejectedRequestsCounter.WithLabelValues(reason).Inc()
+}
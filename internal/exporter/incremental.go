@@ -0,0 +1,104 @@
+package exporter
+
+import (
+	"context"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// incrementalMaxBlocks is the longest block range read for touched addresses; a longer gap
+// since the last scrape is cheaper to cover with a full refresh
+const incrementalMaxBlocks = 120
+
+func (e *WalletExporter) registerIncrementalMetrics() {
+	e.incrementalIdleGauge = newGauge(e.config.MetricsPrefix, "incremental_idle_wallets")
+
+	e.registry.MustRegister(e.incrementalIdleGauge)
+}
+
+// planIncrementalRefresh picks the wallets of the last scrape whose balances can be reused
+// because no transaction or USDFC/Payments log touched them in the blocks after previousBlock
+// up to head. Every balance is read again on the first scrape, once INCREMENTAL_FULL_REFRESH_INTERVAL
+// has passed, after a long gap or when the blocks cannot be read.
+func (e *WalletExporter) planIncrementalRefresh(ctx context.Context, previousBlock, head uint64) {
+	e.idleWallets = nil
+
+	e.walletsMux.RLock()
+	previous := e.wallets
+	e.walletsMux.RUnlock()
+
+	now := time.Now()
+	full := len(previous) == 0 || previousBlock == 0 || head < previousBlock ||
+		head-previousBlock > incrementalMaxBlocks || now.Sub(e.lastFullRefresh) >= e.config.IncrementalFullRefresh
+	var touched map[common.Address]bool
+	if !full && head > previousBlock {
+		var err error
+		if touched, err = e.touchedAddresses(ctx, previousBlock+1, head); err != nil {
+			e.logger.Warn("Failed to read blocks since the last scrape, refreshing every wallet", "from", previousBlock+1, "to", head, "error", err)
+			full = true
+		}
+	}
+	if full {
+		e.lastFullRefresh = now
+		e.incrementalIdleGauge.Set(0)
+		return
+	}
+
+	e.idleWallets = make(map[common.Address]WalletInfo, len(previous))
+	for _, wallet := range previous {
+		if wallet.Stale || wallet.FILBalance == nil || wallet.paymentsQueryFailed() || touched[wallet.Address] ||
+			(wallet.hasSeparatePayee() && touched[wallet.Payee]) || hasLockup(wallet) {
+			continue
+		}
+		e.idleWallets[wallet.Address] = wallet
+	}
+	e.incrementalIdleGauge.Set(float64(len(e.idleWallets)))
+	e.logger.Debug("Planned incremental refresh", "from", previousBlock+1, "to", head, "idle", len(e.idleWallets), "wallets", len(previous))
+}
+
+// hasLockup reports whether a wallet pays into a rail, whose lockup settles every epoch
+// without a transaction of its own
+func hasLockup(wallet WalletInfo) bool {
+	for _, account := range wallet.PaymentsAccounts {
+		if account.PaymentsInfo != nil && account.LockupRate != nil && account.LockupRate.Sign() > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// reuseIdleBalances copies the balances of the last scrape into wallet if it was idle since
+// then, and reports whether it did
+func (e *WalletExporter) reuseIdleBalances(wallet *WalletInfo) bool {
+	idle, ok := e.idleWallets[wallet.Address]
+	if !ok || len(idle.PaymentsAccounts) == 0 || (wallet.hasSeparatePayee() && idle.Payee != wallet.Payee) {
+		return false
+	}
+	wallet.FILBalance = idle.FILBalance
+	wallet.USDFCBalance = idle.USDFCBalance
+	if wallet.hasSeparatePayee() {
+		wallet.PayeeFILBalance = idle.PayeeFILBalance
+		wallet.PayeeUSDFCBalance = idle.PayeeUSDFCBalance
+	}
+	wallet.setPaymentsAccounts(idle.PaymentsAccounts)
+	wallet.UpdatedAt = time.Now() // Unchanged up to the head of this scrape
+	return true
+}
+
+// fetchChangedBalancesBatched reads the balances of the wallets that are not idle in one batch
+// and reuses the last balances of the others
+func (e *WalletExporter) fetchChangedBalancesBatched(ctx context.Context, wallets []WalletInfo) []WalletInfo {
+	var changed, reused []WalletInfo
+	for _, wallet := range wallets {
+		if e.reuseIdleBalances(&wallet) {
+			reused = append(reused, wallet)
+		} else {
+			changed = append(changed, wallet)
+		}
+	}
+	if len(changed) > 0 {
+		changed = e.fetchBalancesBatched(ctx, changed)
+	}
+	return append(changed, reused...)
+}
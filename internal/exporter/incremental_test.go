@@ -0,0 +1,119 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"wallet-exporter/internal/config"
+)
+
+func TestPlanIncrementalRefresh(t *testing.T) {
+	sender := common.HexToAddress("0x0a")
+	recipient := common.HexToAddress("0x0b")
+	idle := common.HexToAddress("0x0c")
+	payer := common.HexToAddress("0x0d")
+	stale := common.HexToAddress("0x0e")
+	usdfc := common.HexToAddress("0x01")
+
+	// Block 11 has a transaction of sender, block 12 is a null round and block 13 a USDFC transfer to recipient
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		response := map[string]any{"jsonrpc": "2.0", "id": req.ID}
+		switch req.Method {
+		case "eth_getBlockByNumber":
+			switch string(req.Params[0]) {
+			case `"0xb"`:
+				response["result"] = map[string]any{"transactions": []map[string]any{{"from": sender.Hex(), "to": usdfc.Hex()}}}
+			case `"0xc"`:
+				response["error"] = map[string]any{"code": 1, "message": "requested epoch was a null round"}
+			default:
+				response["result"] = map[string]any{"transactions": []any{}}
+			}
+		case "eth_getLogs":
+			response["result"] = []map[string]any{{
+				"address":          usdfc.Hex(),
+				"topics":           []string{common.Hash{1}.Hex(), common.BytesToHash(common.HexToAddress("0x99").Bytes()).Hex(), common.BytesToHash(recipient.Bytes()).Hex()},
+				"data":             "0x",
+				"blockNumber":      "0xd",
+				"transactionHash":  common.Hash{2}.Hex(),
+				"transactionIndex": "0x0",
+				"blockHash":        common.Hash{3}.Hex(),
+				"logIndex":         "0x0",
+				"removed":          false,
+			}}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	client, err := newRPCClient(func() (*ethclient.Client, error) { return ethclient.Dial(server.URL) }, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	account := func(lockupRate int64) []PaymentsAccount {
+		return []PaymentsAccount{{Token: "usdfc", PaymentsInfo: &PaymentsInfo{Funds: big.NewInt(100), LockupRate: big.NewInt(lockupRate)}}}
+	}
+	wallet := func(address common.Address, lockupRate int64) WalletInfo {
+		return WalletInfo{Address: address, FILBalance: big.NewInt(5), USDFCBalance: big.NewInt(6), PaymentsAccounts: account(lockupRate)}
+	}
+	staleWallet := wallet(stale, 0)
+	staleWallet.Stale = true
+
+	e := &WalletExporter{
+		config: &config.Config{
+			USDFCTokenAddress:      usdfc.Hex(),
+			PaymentsAddress:        common.HexToAddress("0x02").Hex(),
+			IncrementalFullRefresh: time.Hour,
+		},
+		client:               client,
+		wallets:              []WalletInfo{wallet(sender, 0), wallet(recipient, 0), wallet(idle, 0), wallet(payer, 1), staleWallet},
+		lastFullRefresh:      time.Now(),
+		incrementalIdleGauge: prometheus.NewGauge(prometheus.GaugeOpts{Name: "idle"}),
+		logger:               slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	e.planIncrementalRefresh(context.Background(), 10, 13)
+	if len(e.idleWallets) != 1 {
+		t.Fatalf("Expected only the untouched wallet without lockup to be idle, got %d idle wallets", len(e.idleWallets))
+	}
+
+	fresh := WalletInfo{Address: idle, Name: "renamed"}
+	if !e.reuseIdleBalances(&fresh) || fresh.FILBalance.Int64() != 5 || fresh.PaymentsFunds.Int64() != 100 || fresh.Name != "renamed" || time.Since(fresh.UpdatedAt) > time.Minute {
+		t.Errorf("Expected the balances of the idle wallet to be reused, got %+v", fresh)
+	}
+	if touchedWallet := (WalletInfo{Address: recipient}); e.reuseIdleBalances(&touchedWallet) {
+		t.Error("Expected the balances of a touched wallet to be read again")
+	}
+
+	// Once the full refresh interval passed, every balance is read again
+	e.lastFullRefresh = time.Now().Add(-2 * time.Hour)
+	e.planIncrementalRefresh(context.Background(), 13, 14)
+	if len(e.idleWallets) != 0 || time.Since(e.lastFullRefresh) > time.Minute {
+		t.Errorf("Expected a full refresh, got %d idle wallets", len(e.idleWallets))
+	}
+
+	// As after a gap too long to read
+	e.planIncrementalRefresh(context.Background(), 14, 14+incrementalMaxBlocks+1)
+	if len(e.idleWallets) != 0 {
+		t.Errorf("Expected a full refresh after a long gap, got %d idle wallets", len(e.idleWallets))
+	}
+}
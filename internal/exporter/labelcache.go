@@ -0,0 +1,164 @@
+package exporter
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"wallet-exporter/internal/filaddr"
+)
+
+// walletLabelKey holds the wallet fields its labels are built from; labels are rebuilt when
+// any of them changes
+type walletLabelKey struct {
+	name        string
+	typ         string
+	description string
+	region      string
+	providerID  uint64
+	isActive    bool
+	approved    bool
+	payee       common.Address
+}
+
+// addressLabels are the labels of one exported address of a wallet, plus copies with the
+// token label added
+type addressLabels struct {
+	labels prometheus.Labels
+	tokens map[string]prometheus.Labels
+}
+
+func newAddressLabels(labels prometheus.Labels) *addressLabels {
+	return &addressLabels{labels: labels, tokens: make(map[string]prometheus.Labels)}
+}
+
+// withToken returns the labels with the token label added
+func (a *addressLabels) withToken(token string) prometheus.Labels {
+	labels, ok := a.tokens[token]
+	if !ok {
+		labels = withToken(a.labels, token)
+		a.tokens[token] = labels
+	}
+	return labels
+}
+
+// walletLabelSet holds every label set of a wallet's metrics
+type walletLabelSet struct {
+	key      walletLabelKey
+	operator *addressLabels
+	payee    *addressLabels // Built once the payee is exported
+	info     prometheus.Labels
+	pings    map[uint8]prometheus.Labels // By product type
+}
+
+// walletLabelCache keeps the label sets of the exported wallets between metric updates, so a
+// scrape does not rebuild label maps and reformat addresses for every series of every wallet.
+// Only used by updateMetrics, which the scrape loop calls.
+type walletLabelCache struct {
+	network string
+	sets    map[common.Address]*walletLabelSet
+}
+
+func newWalletLabelCache(network string) *walletLabelCache {
+	return &walletLabelCache{network: network, sets: make(map[common.Address]*walletLabelSet)}
+}
+
+// Get returns the label set of a wallet, building it if the wallet is new or changed
+func (c *walletLabelCache) Get(wallet WalletInfo) *walletLabelSet {
+	key := walletLabelKey{
+		name:        wallet.Name,
+		typ:         wallet.Type,
+		description: wallet.Description,
+		region:      wallet.Region(),
+		providerID:  wallet.ProviderID,
+		isActive:    wallet.IsActive,
+		approved:    wallet.IsApproved,
+		payee:       wallet.Payee,
+	}
+	if set, ok := c.sets[wallet.Address]; ok && set.key == key {
+		return set
+	}
+
+	// Provider-only labels are empty for other wallets
+	providerID, isActive, approved, role := "", "", "", ""
+	if wallet.Type == "provider" {
+		providerID = fmt.Sprintf("%d", wallet.ProviderID)
+		isActive = fmt.Sprintf("%t", wallet.IsActive)
+		approved = fmt.Sprintf("%t", wallet.IsApproved)
+		role = "operator"
+	}
+
+	labels := prometheus.Labels{
+		"address":     wallet.Address.Hex(),
+		"fil_address": filaddr.FromEth(wallet.Address, c.network),
+		"network":     c.network,
+		"name":        wallet.Name,
+		"type":        wallet.Type,
+		"provider_id": providerID,
+		"is_active":   isActive,
+		"approved":    approved,
+		"role":        role,
+		"region":      key.region,
+	}
+	set := &walletLabelSet{
+		key:      key,
+		operator: newAddressLabels(labels),
+		info: prometheus.Labels{
+			"address":     labels["address"],
+			"fil_address": labels["fil_address"],
+			"network":     c.network,
+			"name":        wallet.Name,
+			"type":        wallet.Type,
+			"provider_id": providerID,
+			"description": wallet.Description,
+			"is_active":   isActive,
+			"approved":    approved,
+		},
+		pings: make(map[uint8]prometheus.Labels),
+	}
+	c.sets[wallet.Address] = set
+	return set
+}
+
+// Forget drops the label sets of wallets that are no longer exported
+func (c *walletLabelCache) Forget(monitored map[common.Address]bool) {
+	for address := range c.sets {
+		if !monitored[address] {
+			delete(c.sets, address)
+		}
+	}
+}
+
+// Payee returns the labels of the wallet's payee address
+func (s *walletLabelSet) Payee() *addressLabels {
+	if s.payee == nil {
+		labels := make(prometheus.Labels, len(s.operator.labels))
+		for k, v := range s.operator.labels {
+			labels[k] = v
+		}
+		labels["address"] = s.key.payee.Hex()
+		labels["fil_address"] = filaddr.FromEth(s.key.payee, labels["network"])
+		labels["role"] = "payee"
+		s.payee = newAddressLabels(labels)
+	}
+	return s.payee
+}
+
+// Ping returns the labels of a ping result of the wallet
+func (s *walletLabelSet) Ping(result PingResult) prometheus.Labels {
+	labels, ok := s.pings[result.ProductType]
+	if !ok || labels["service_url"] != result.ServiceURL {
+		labels = prometheus.Labels{
+			"address":      s.operator.labels["address"],
+			"network":      s.operator.labels["network"],
+			"name":         s.key.name,
+			"provider_id":  s.operator.labels["provider_id"],
+			"product_type": productTypeName(result.ProductType),
+			"service_url":  result.ServiceURL,
+			"region":       s.key.region,
+		}
+		s.pings[result.ProductType] = labels
+	}
+	return labels
+}
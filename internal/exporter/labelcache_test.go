@@ -0,0 +1,45 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestWalletLabelCacheReusesLabels(t *testing.T) {
+	c := newWalletLabelCache("mainnet")
+	wallet := WalletInfo{Address: common.HexToAddress("0x01"), Name: "sp", Type: "provider", ProviderID: 7, Payee: common.HexToAddress("0x02")}
+
+	set := c.Get(wallet)
+	if set.operator.labels["provider_id"] != "7" || set.operator.labels["role"] != "operator" || set.info["network"] != "mainnet" {
+		t.Errorf("Unexpected labels %v and %v", set.operator.labels, set.info)
+	}
+	if payee := set.Payee().labels; payee["address"] != wallet.Payee.Hex() || payee["role"] != "payee" || payee["name"] != "sp" {
+		t.Errorf("Unexpected payee labels %v", payee)
+	}
+	set.operator.withToken("usdfc")
+	set.Ping(PingResult{ProductType: 0, ServiceURL: "https://sp.example"})
+
+	allocs := testing.AllocsPerRun(100, func() {
+		set := c.Get(wallet)
+		set.operator.withToken("usdfc")
+		set.Payee()
+		set.Ping(PingResult{ProductType: 0, ServiceURL: "https://sp.example"})
+	})
+	if allocs != 0 {
+		t.Errorf("Expected an unchanged wallet to reuse its labels, got %.0f allocations", allocs)
+	}
+
+	wallet.Name = "renamed"
+	if renamed := c.Get(wallet); renamed == set || renamed.operator.labels["name"] != "renamed" {
+		t.Error("Expected the labels to be rebuilt after a rename")
+	}
+	if ping := c.Get(wallet).Ping(PingResult{ProductType: 0, ServiceURL: "https://new.example"}); ping["service_url"] != "https://new.example" {
+		t.Errorf("Expected the ping labels to follow the service URL, got %v", ping)
+	}
+
+	c.Forget(map[common.Address]bool{})
+	if len(c.sets) != 0 {
+		t.Error("Expected the labels of removed wallets to be dropped")
+	}
+}
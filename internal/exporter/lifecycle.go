@@ -0,0 +1,134 @@
+package exporter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Provider lifecycle events, used as the "event" label of the lifecycle counter
+const (
+	LifecycleAdded       = "added"
+	LifecycleRemoved     = "removed"
+	LifecycleDeactivated = "deactivated"
+	LifecycleReactivated = "reactivated"
+	LifecycleApproved    = "approved"
+	LifecycleUnapproved  = "unapproved"
+)
+
+// lifecycleRemovalScrapes is how many consecutive scrapes a provider must be missing
+// before it counts as removed, so a single failed fetch is not reported as a removal
+const lifecycleRemovalScrapes = 3
+
+// LifecycleEvent is a provider state transition observed between two scrapes
+type LifecycleEvent struct {
+	ProviderID uint64
+	Event      string
+}
+
+// lifecycleTracker compares the providers of consecutive scrapes and reports their
+// transitions. The first scrape only sets the baseline.
+type lifecycleTracker struct {
+	initialized bool
+	providers   map[uint64]*lifecycleState
+}
+
+type lifecycleState struct {
+	wallet    WalletInfo
+	missing   int // Consecutive scrapes the provider was not returned
+	removed   bool
+	changedAt time.Time // Time of the last transition (zero if none was seen)
+}
+
+func newLifecycleTracker() *lifecycleTracker {
+	return &lifecycleTracker{providers: make(map[uint64]*lifecycleState)}
+}
+
+// Observe records the providers of one scrape and returns the transitions since the previous one
+func (t *lifecycleTracker) Observe(providers []WalletInfo, now time.Time) []LifecycleEvent {
+	var events []LifecycleEvent
+	record := func(state *lifecycleState, event string) {
+		events = append(events, LifecycleEvent{ProviderID: state.wallet.ProviderID, Event: event})
+		state.changedAt = now
+	}
+
+	seen := make(map[uint64]bool, len(providers))
+	for _, wallet := range providers {
+		seen[wallet.ProviderID] = true
+
+		state, known := t.providers[wallet.ProviderID]
+		if !known {
+			state = &lifecycleState{wallet: wallet}
+			t.providers[wallet.ProviderID] = state
+			if t.initialized {
+				record(state, LifecycleAdded)
+			}
+			continue
+		}
+
+		prev := state.wallet
+		state.wallet = wallet
+		state.missing = 0
+
+		if state.removed {
+			state.removed = false
+			record(state, LifecycleAdded)
+			continue
+		}
+		if prev.IsActive && !wallet.IsActive {
+			record(state, LifecycleDeactivated)
+		} else if !prev.IsActive && wallet.IsActive {
+			record(state, LifecycleReactivated)
+		}
+		if !prev.IsApproved && wallet.IsApproved {
+			record(state, LifecycleApproved)
+		} else if prev.IsApproved && !wallet.IsApproved {
+			record(state, LifecycleUnapproved)
+		}
+	}
+
+	for id, state := range t.providers {
+		if seen[id] || state.removed {
+			continue
+		}
+		state.missing++
+		if state.missing >= lifecycleRemovalScrapes {
+			state.removed = true
+			record(state, LifecycleRemoved)
+		}
+	}
+
+	t.initialized = true
+	return events
+}
+
+func (e *WalletExporter) registerLifecycleMetrics() {
+	e.lifecycleEventsCounter = newCounterVec(e.config.MetricsPrefix, "provider_lifecycle_events_total")
+	e.stateChangeGauge = newGaugeVec(e.config.MetricsPrefix, "provider_state_change_timestamp_seconds")
+
+	e.registry.MustRegister(e.lifecycleEventsCounter)
+	e.registry.MustRegister(e.stateChangeGauge)
+}
+
+// observeLifecycle counts the provider transitions of this scrape and refreshes the
+// time of each provider's last transition (removed providers keep theirs)
+func (e *WalletExporter) observeLifecycle(providers []WalletInfo, now time.Time) {
+	for _, event := range e.lifecycle.Observe(providers, now) {
+		e.logger.Info("Provider lifecycle change", "provider_id", event.ProviderID, "event", event.Event)
+		e.lifecycleEventsCounter.WithLabelValues(e.config.Network, fmt.Sprintf("%d", event.ProviderID), event.Event).Inc()
+	}
+
+	e.stateChangeGauge.Reset()
+	for id, state := range e.lifecycle.providers {
+		if state.changedAt.IsZero() {
+			continue
+		}
+		e.stateChangeGauge.With(prometheus.Labels{
+			"address":     state.wallet.Address.Hex(),
+			"network":     e.config.Network,
+			"name":        state.wallet.Name,
+			"provider_id": fmt.Sprintf("%d", id),
+		}).Set(float64(state.changedAt.Unix()))
+	}
+}
@@ -0,0 +1,58 @@
+package exporter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLifecycleTracker(t *testing.T) {
+	tracker := newLifecycleTracker()
+	now := time.Unix(1700000000, 0)
+
+	sp1 := WalletInfo{ProviderID: 1, IsActive: true, IsApproved: false}
+	sp2 := WalletInfo{ProviderID: 2, IsActive: true, IsApproved: true}
+
+	// The first scrape only sets the baseline
+	if events := tracker.Observe([]WalletInfo{sp1}, now); len(events) != 0 {
+		t.Fatalf("Expected no events on first scrape, got %v", events)
+	}
+
+	sp1.IsApproved = true
+	events := tracker.Observe([]WalletInfo{sp1, sp2}, now.Add(time.Minute))
+	expectEvents(t, events, LifecycleEvent{1, LifecycleApproved}, LifecycleEvent{2, LifecycleAdded})
+
+	sp1.IsActive = false
+	events = tracker.Observe([]WalletInfo{sp1, sp2}, now.Add(2*time.Minute))
+	expectEvents(t, events, LifecycleEvent{1, LifecycleDeactivated})
+
+	// Provider 2 is removed only after missing lifecycleRemovalScrapes scrapes
+	for i := 3; i < 2+lifecycleRemovalScrapes; i++ {
+		if events := tracker.Observe([]WalletInfo{sp1}, now.Add(time.Duration(i)*time.Minute)); len(events) != 0 {
+			t.Fatalf("Expected no events while provider 2 is missing, got %v", events)
+		}
+	}
+	events = tracker.Observe([]WalletInfo{sp1}, now.Add(10*time.Minute))
+	expectEvents(t, events, LifecycleEvent{2, LifecycleRemoved})
+
+	if changed := tracker.providers[2].changedAt; !changed.Equal(now.Add(10 * time.Minute)) {
+		t.Errorf("Expected state change time of removal, got %v", changed)
+	}
+}
+
+func expectEvents(t *testing.T, got []LifecycleEvent, want ...LifecycleEvent) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("Expected events %v, got %v", want, got)
+	}
+	for _, w := range want {
+		found := false
+		for _, g := range got {
+			if g == w {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected event %v in %v", w, got)
+		}
+	}
+}
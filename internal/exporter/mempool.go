@@ -0,0 +1,120 @@
+package exporter
+
+import (
+	"context"
+	"math/big"
+	"sort"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"wallet-exporter/internal/filaddr"
+)
+
+// PendingTransactions summarizes the mempool messages sent by a wallet
+type PendingTransactions struct {
+	Wallet WalletInfo
+	Count  int
+	Value  *big.Int // FIL the pending messages send
+	Stuck  int      // Messages that cannot be included because an earlier nonce is missing
+}
+
+// mpoolMessage is the part of a Filecoin.MpoolPending entry the exporter reads
+type mpoolMessage struct {
+	Message struct {
+		From  string
+		Nonce uint64
+		Value bigIntString
+	}
+}
+
+func (e *WalletExporter) registerMempoolMetrics() {
+	e.mempoolTransactionsGauge = newGaugeVec(e.config.MetricsPrefix, "wallet_mempool_transactions")
+	e.mempoolValueGauge = newGaugeVec(e.config.MetricsPrefix, "wallet_mempool_value")
+	e.mempoolStuckGauge = newGaugeVec(e.config.MetricsPrefix, "wallet_mempool_stuck_transactions")
+
+	e.registry.MustRegister(e.mempoolTransactionsGauge, e.mempoolValueGauge, e.mempoolStuckGauge)
+}
+
+// fetchMempool reads the node's message pool once and summarizes the pending messages sent by
+// every wallet. Messages are matched by the f410 form of the wallet address, and stuck
+// messages are found by comparing their nonces with the wallet's nonce at the snapshot block.
+func (e *WalletExporter) fetchMempool(ctx context.Context, wallets []WalletInfo) ([]PendingTransactions, error) {
+	var messages []mpoolMessage
+	if err := e.callFilecoin(ctx, &messages, "Filecoin.MpoolPending", nil); err != nil {
+		return nil, err
+	}
+
+	bySender := make(map[string][]mpoolMessage)
+	for _, m := range messages {
+		bySender[m.Message.From] = append(bySender[m.Message.From], m)
+	}
+
+	pending := make([]PendingTransactions, 0, len(wallets))
+	for _, wallet := range wallets {
+		sent := bySender[filaddr.FromEth(wallet.Address, e.config.Network)]
+		p := PendingTransactions{Wallet: wallet, Value: new(big.Int)}
+		if len(sent) == 0 {
+			pending = append(pending, p)
+			continue
+		}
+
+		nonce, err := e.client.NonceAt(ctx, wallet.Address, snapshotBlock(ctx))
+		if err != nil {
+			e.logger.Warn("Failed to get wallet nonce", "address", wallet.Address.Hex(), "error", err)
+			e.scrapeErrors.Inc()
+			continue
+		}
+
+		nonces := make([]uint64, 0, len(sent))
+		for _, m := range sent {
+			// Messages below the chain nonce were included after the snapshot block
+			if m.Message.Nonce < nonce {
+				continue
+			}
+			p.Count++
+			if m.Message.Value.Int != nil {
+				p.Value.Add(p.Value, m.Message.Value.Int)
+			}
+			nonces = append(nonces, m.Message.Nonce)
+		}
+		p.Stuck = countStuck(nonce, nonces)
+		pending = append(pending, p)
+	}
+	return pending, nil
+}
+
+// countStuck returns how many of the pending nonces come after a gap in the sequence that
+// starts at the account's next nonce. Those messages wait until the gap is filled.
+func countStuck(next uint64, nonces []uint64) int {
+	sorted := append([]uint64(nil), nonces...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	for i, nonce := range sorted {
+		switch {
+		case nonce == next:
+			next++
+		case nonce > next:
+			return len(sorted) - i
+		}
+		// A nonce below next duplicates an earlier message and changes nothing
+	}
+	return 0
+}
+
+func (e *WalletExporter) updateMempoolMetrics(pending []PendingTransactions) {
+	e.mempoolTransactionsGauge.Reset()
+	e.mempoolValueGauge.Reset()
+	e.mempoolStuckGauge.Reset()
+
+	for _, p := range pending {
+		labels := prometheus.Labels{
+			"address": p.Wallet.Address.Hex(),
+			"network": e.config.Network,
+			"name":    p.Wallet.Name,
+			"type":    p.Wallet.Type,
+		}
+		e.mempoolTransactionsGauge.With(labels).Set(float64(p.Count))
+		e.mempoolValueGauge.With(labels).Set(tokenAmount(p.Value, nativeTokenDecimals))
+		e.mempoolStuckGauge.With(labels).Set(float64(p.Stuck))
+	}
+}
@@ -0,0 +1,24 @@
+package exporter
+
+import "testing"
+
+func TestCountStuck(t *testing.T) {
+	tests := []struct {
+		next   uint64
+		nonces []uint64
+		stuck  int
+	}{
+		{5, nil, 0},
+		{5, []uint64{5, 6, 7}, 0},
+		{5, []uint64{7, 5, 6}, 0},
+		{5, []uint64{6, 7}, 2},
+		{5, []uint64{5, 7, 8}, 2},
+		{5, []uint64{5, 5, 6}, 0},
+	}
+
+	for _, tt := range tests {
+		if stuck := countStuck(tt.next, tt.nonces); stuck != tt.stuck {
+			t.Errorf("countStuck(%d, %v) = %d, want %d", tt.next, tt.nonces, stuck, tt.stuck)
+		}
+	}
+}
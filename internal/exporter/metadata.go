@@ -0,0 +1,75 @@
+package exporter
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"wallet-exporter/internal/contracts"
+)
+
+// providerMetadata is the registry data of a provider that rarely changes
+type providerMetadata struct {
+	Info      contracts.ServiceProviderRegistryStorageServiceProviderInfo
+	Products  []ProviderProduct
+	FetchedAt time.Time
+}
+
+// metadataCache keeps provider metadata between scrapes for PROVIDER_METADATA_TTL, so a
+// scrape only needs the balance and Payments calls of cached providers. It is emptied when
+// the registry's provider count or product types change.
+type metadataCache struct {
+	mu          sync.Mutex
+	ttl         time.Duration // 0 = disabled
+	fingerprint string        // Provider count and product types the entries were read with
+	providers   map[uint64]providerMetadata
+}
+
+func newMetadataCache(ttl time.Duration) *metadataCache {
+	return &metadataCache{ttl: ttl, providers: make(map[uint64]providerMetadata)}
+}
+
+// Get returns the metadata of a provider if it was fetched less than the TTL ago
+func (c *metadataCache) Get(providerID uint64, now time.Time) (providerMetadata, bool) {
+	if c.ttl <= 0 {
+		return providerMetadata{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	metadata, ok := c.providers[providerID]
+	if !ok || now.Sub(metadata.FetchedAt) >= c.ttl {
+		return providerMetadata{}, false
+	}
+	return metadata, true
+}
+
+func (c *metadataCache) Put(providerID uint64, metadata providerMetadata) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.providers[providerID] = metadata
+}
+
+// Invalidate empties the cache if the provider count or product types differ from those of
+// the previous call and reports whether it did
+func (c *metadataCache) Invalidate(providerCount uint64, productTypes []uint8) bool {
+	fingerprint := fmt.Sprintf("%d/%v", providerCount, productTypes)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if fingerprint == c.fingerprint {
+		return false
+	}
+	c.fingerprint = fingerprint
+	if len(c.providers) == 0 {
+		return false
+	}
+	c.providers = make(map[uint64]providerMetadata)
+	return true
+}
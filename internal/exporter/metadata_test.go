@@ -0,0 +1,73 @@
+package exporter
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMetadataCacheExpires(t *testing.T) {
+	c := newMetadataCache(time.Minute)
+	now := time.Now()
+	c.Put(1, providerMetadata{FetchedAt: now})
+
+	if _, ok := c.Get(1, now.Add(30*time.Second)); !ok {
+		t.Error("Expected metadata to be cached within the TTL")
+	}
+	if _, ok := c.Get(1, now.Add(time.Minute)); ok {
+		t.Error("Expected metadata to expire after the TTL")
+	}
+	if _, ok := c.Get(2, now); ok {
+		t.Error("Expected no metadata for an unknown provider")
+	}
+}
+
+func TestMetadataCacheInvalidate(t *testing.T) {
+	c := newMetadataCache(time.Minute)
+	now := time.Now()
+
+	c.Invalidate(5, []uint8{0})
+	c.Put(1, providerMetadata{FetchedAt: now})
+	if c.Invalidate(5, []uint8{0}) {
+		t.Error("Expected an unchanged provider count to keep the cache")
+	}
+	if !c.Invalidate(6, []uint8{0}) {
+		t.Error("Expected a new provider count to empty the cache")
+	}
+	if _, ok := c.Get(1, now); ok {
+		t.Error("Expected metadata to be gone after invalidation")
+	}
+
+	c.Put(1, providerMetadata{FetchedAt: now})
+	if !c.Invalidate(6, []uint8{0, 1}) {
+		t.Error("Expected new product types to empty the cache")
+	}
+}
+
+func TestMetadataCacheDisabled(t *testing.T) {
+	c := newMetadataCache(0)
+	now := time.Now()
+	c.Put(1, providerMetadata{FetchedAt: now})
+
+	if _, ok := c.Get(1, now); ok {
+		t.Error("Expected a zero TTL to disable the cache")
+	}
+}
+
+func TestLookupServiceURLFromMetadataCache(t *testing.T) {
+	e := &WalletExporter{metadataCache: newMetadataCache(time.Minute)}
+	e.metadataCache.Put(1, providerMetadata{FetchedAt: time.Now(), Products: []ProviderProduct{
+		{Type: 0, IsActive: true, Capabilities: map[string]string{"serviceURL": "https://pdp.example"}},
+	}})
+	e.metadataCache.Put(2, providerMetadata{FetchedAt: time.Now(), Products: []ProviderProduct{
+		{Type: 0, IsActive: false, Capabilities: map[string]string{"serviceURL": "https://pdp.example"}},
+	}})
+
+	// Without a registry contract, only the cache can answer
+	if url, err := e.lookupServiceURL(context.Background(), 1, 0); err != nil || url != "https://pdp.example" {
+		t.Errorf("Expected the cached service URL, got %q, %v", url, err)
+	}
+	if url, err := e.lookupServiceURL(context.Background(), 2, 0); err != nil || url != "" {
+		t.Errorf("Expected no service URL for an inactive product, got %q, %v", url, err)
+	}
+}
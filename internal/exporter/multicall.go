@@ -0,0 +1,85 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+
+	"wallet-exporter/internal/contracts"
+)
+
+// multicallBatcher batches view calls into Multicall3 aggregate3 requests
+type multicallBatcher struct {
+	address   common.Address
+	raw       *contracts.Multicall3CallerRaw
+	batchSize int
+}
+
+// newMulticallBatcher binds Multicall3 at the configured address. It returns nil, disabling
+// batching, when MULTICALL_BATCH_SIZE is 0 or no contract is deployed at the address.
+func (e *WalletExporter) newMulticallBatcher() *multicallBatcher {
+	if e.config.MulticallBatchSize == 0 {
+		return nil
+	}
+
+	address := common.HexToAddress(e.config.MulticallAddress)
+	ctx, cancel := context.WithTimeout(context.Background(), bindingCheckTimeout)
+	defer cancel()
+	code, err := e.client.CodeAt(ctx, address, nil)
+	if err != nil || len(code) == 0 {
+		e.logger.Warn("Multicall3 not available, balances will not be batched through it", "address", address.Hex(), "error", err)
+		return nil
+	}
+
+	caller, err := contracts.NewMulticall3Caller(address, e.client)
+	if err != nil {
+		e.logger.Warn("Failed to create Multicall3 contract", "error", err)
+		return nil
+	}
+
+	e.logger.Info("Batching balance reads through Multicall3", "address", address.Hex(), "batch_size", e.config.MulticallBatchSize)
+	return &multicallBatcher{
+		address:   address,
+		raw:       &contracts.Multicall3CallerRaw{Contract: caller},
+		batchSize: e.config.MulticallBatchSize,
+	}
+}
+
+// aggregate runs the calls in aggregate3 requests of at most batchSize calls each. FIL balances
+// are read through getEthBalance. Reverted calls are passed to their decoder as errors; a failed
+// request fails the whole batch.
+func (m *multicallBatcher) aggregate(ctx context.Context, calls []batchedCall) error {
+	for start := 0; start < len(calls); start += m.batchSize {
+		chunk := calls[start:min(start+m.batchSize, len(calls))]
+
+		call3s := make([]contracts.Multicall3Call3, len(chunk))
+		for i, c := range chunk {
+			target, data := c.target, c.data
+			if data == nil {
+				target = m.address
+				data, _ = multicallABI.Pack("getEthBalance", c.target)
+			}
+			call3s[i] = contracts.Multicall3Call3{Target: target, AllowFailure: true, CallData: data}
+		}
+
+		var out []interface{}
+		if err := m.raw.Call(callOpts(ctx), &out, "aggregate3", call3s); err != nil {
+			return fmt.Errorf("aggregate3 failed: %w", err)
+		}
+		results := *abi.ConvertType(out[0], new([]contracts.Multicall3Result)).(*[]contracts.Multicall3Result)
+		if len(results) != len(chunk) {
+			return fmt.Errorf("aggregate3 returned %d results for %d calls", len(results), len(chunk))
+		}
+
+		for i, result := range results {
+			if !result.Success {
+				chunk[i].decode(nil, errCallReverted)
+				continue
+			}
+			chunk[i].decode(result.ReturnData, nil)
+		}
+	}
+	return nil
+}
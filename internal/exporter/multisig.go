@@ -0,0 +1,197 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"wallet-exporter/internal/config"
+)
+
+// MultisigInfo is the state of a Filecoin multisig actor
+type MultisigInfo struct {
+	Address         string // f0 or f2 address
+	Name            string
+	Balance         *big.Int
+	Available       *big.Int // Balance that is no longer vesting
+	Locked          *big.Int // Balance still vesting
+	VestingEndEpoch int64    // Epoch the whole initial balance is unlocked at (0 = no vesting)
+	Signers         int
+	Threshold       int // Approvals required to execute a proposal
+	Pending         int // Proposals waiting for approvals
+	PendingValue    *big.Int
+}
+
+// multisigState is the part of a multisig actor's state read through Filecoin.StateReadState
+type multisigState struct {
+	Balance bigIntString
+	State   struct {
+		Signers               []string
+		NumApprovalsThreshold int
+		StartEpoch            int64
+		UnlockDuration        int64
+	}
+}
+
+// multisigTransaction is a pending proposal as returned by Filecoin.MsigGetPending
+type multisigTransaction struct {
+	ID    int64
+	Value bigIntString
+}
+
+// bigIntString decodes the decimal strings the Filecoin API uses for token amounts
+type bigIntString struct {
+	*big.Int
+}
+
+func (b *bigIntString) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	value, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return fmt.Errorf("invalid token amount %q", s)
+	}
+	b.Int = value
+	return nil
+}
+
+func (e *WalletExporter) registerMultisigMetrics() {
+	e.msigBalanceGauge = newGaugeVec(e.config.MetricsPrefix, "msig_balance")
+	e.msigAvailableGauge = newGaugeVec(e.config.MetricsPrefix, "msig_available_balance")
+	e.msigLockedGauge = newGaugeVec(e.config.MetricsPrefix, "msig_locked_balance")
+	e.msigVestingEndGauge = newGaugeVec(e.config.MetricsPrefix, "msig_vesting_end_epoch")
+	e.msigSignersGauge = newGaugeVec(e.config.MetricsPrefix, "msig_signers")
+	e.msigThresholdGauge = newGaugeVec(e.config.MetricsPrefix, "msig_approvals_threshold")
+	e.msigPendingGauge = newGaugeVec(e.config.MetricsPrefix, "msig_pending_transactions")
+	e.msigPendingValueGauge = newGaugeVec(e.config.MetricsPrefix, "msig_pending_value")
+
+	e.registry.MustRegister(
+		e.msigBalanceGauge,
+		e.msigAvailableGauge,
+		e.msigLockedGauge,
+		e.msigVestingEndGauge,
+		e.msigSignersGauge,
+		e.msigThresholdGauge,
+		e.msigPendingGauge,
+		e.msigPendingValueGauge,
+	)
+}
+
+// fetchMultisigs reads the state of every configured multisig through the Filecoin API of
+// the RPC endpoint. Reads are pinned to the tipset at the scrape's snapshot block.
+func (e *WalletExporter) fetchMultisigs(ctx context.Context) []MultisigInfo {
+	tipset, err := e.snapshotTipSetKey(ctx)
+	if err != nil {
+		e.logger.Warn("Failed to resolve snapshot tipset for multisigs", "error", err)
+		e.scrapeErrors.Inc()
+		return nil
+	}
+
+	var multisigs []MultisigInfo
+	for _, wallet := range e.config.MultisigWallets {
+		info, err := e.fetchMultisig(ctx, wallet, tipset)
+		if err != nil {
+			e.logger.Warn("Failed to fetch multisig", "address", wallet.Address, "error", err)
+			e.scrapeErrors.Inc()
+			continue
+		}
+		multisigs = append(multisigs, info)
+	}
+	return multisigs
+}
+
+func (e *WalletExporter) fetchMultisig(ctx context.Context, wallet config.CustomWallet, tipset json.RawMessage) (MultisigInfo, error) {
+	var state multisigState
+	if err := e.callFilecoin(ctx, &state, "Filecoin.StateReadState", wallet.Address, tipset); err != nil {
+		return MultisigInfo{}, fmt.Errorf("failed to read actor state: %w", err)
+	}
+	if state.Balance.Int == nil {
+		return MultisigInfo{}, fmt.Errorf("actor state has no balance")
+	}
+
+	var available bigIntString
+	if err := e.callFilecoin(ctx, &available, "Filecoin.MsigGetAvailableBalance", wallet.Address, tipset); err != nil {
+		return MultisigInfo{}, fmt.Errorf("failed to get available balance: %w", err)
+	}
+	if available.Int == nil {
+		return MultisigInfo{}, fmt.Errorf("no available balance returned")
+	}
+
+	var pending []multisigTransaction
+	if err := e.callFilecoin(ctx, &pending, "Filecoin.MsigGetPending", wallet.Address, tipset); err != nil {
+		return MultisigInfo{}, fmt.Errorf("failed to get pending transactions: %w", err)
+	}
+
+	info := MultisigInfo{
+		Address:      wallet.Address,
+		Name:         wallet.Name,
+		Balance:      state.Balance.Int,
+		Available:    available.Int,
+		Locked:       new(big.Int).Sub(state.Balance.Int, available.Int),
+		Signers:      len(state.State.Signers),
+		Threshold:    state.State.NumApprovalsThreshold,
+		Pending:      len(pending),
+		PendingValue: new(big.Int),
+	}
+	if state.State.UnlockDuration > 0 {
+		info.VestingEndEpoch = state.State.StartEpoch + state.State.UnlockDuration
+	}
+	for _, txn := range pending {
+		if txn.Value.Int != nil {
+			info.PendingValue.Add(info.PendingValue, txn.Value.Int)
+		}
+	}
+	return info, nil
+}
+
+// snapshotTipSetKey returns the key of the tipset at the snapshot block of ctx, or null for
+// the current head if reads are not pinned
+func (e *WalletExporter) snapshotTipSetKey(ctx context.Context) (json.RawMessage, error) {
+	block := snapshotBlock(ctx)
+	if block == nil {
+		return json.RawMessage("null"), nil
+	}
+
+	var tipset struct {
+		Cids json.RawMessage
+	}
+	if err := e.callFilecoin(ctx, &tipset, "Filecoin.ChainGetTipSetByHeight", block.Int64(), nil); err != nil {
+		return nil, err
+	}
+	return tipset.Cids, nil
+}
+
+// callFilecoin calls a Filecoin API method, bounded by the per-call timeout like any other call
+func (e *WalletExporter) callFilecoin(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	ctx, cancel := e.client.withTimeout(ctx)
+	defer cancel()
+	return e.client.Client().CallContext(ctx, result, method, args...)
+}
+
+func (e *WalletExporter) updateMultisigMetrics(multisigs []MultisigInfo) {
+	e.msigBalanceGauge.Reset()
+	e.msigAvailableGauge.Reset()
+	e.msigLockedGauge.Reset()
+	e.msigVestingEndGauge.Reset()
+	e.msigSignersGauge.Reset()
+	e.msigThresholdGauge.Reset()
+	e.msigPendingGauge.Reset()
+	e.msigPendingValueGauge.Reset()
+
+	for _, m := range multisigs {
+		labels := prometheus.Labels{"address": m.Address, "network": e.config.Network, "name": m.Name}
+		e.msigBalanceGauge.With(labels).Set(tokenAmount(m.Balance, nativeTokenDecimals))
+		e.msigAvailableGauge.With(labels).Set(tokenAmount(m.Available, nativeTokenDecimals))
+		e.msigLockedGauge.With(labels).Set(tokenAmount(m.Locked, nativeTokenDecimals))
+		e.msigVestingEndGauge.With(labels).Set(float64(m.VestingEndEpoch))
+		e.msigSignersGauge.With(labels).Set(float64(m.Signers))
+		e.msigThresholdGauge.With(labels).Set(float64(m.Threshold))
+		e.msigPendingGauge.With(labels).Set(float64(m.Pending))
+		e.msigPendingValueGauge.With(labels).Set(tokenAmount(m.PendingValue, nativeTokenDecimals))
+	}
+}
@@ -0,0 +1,141 @@
+package exporter
+
+import (
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Offboarding reasons
+const (
+	OffboardingInactive    = "inactive"
+	OffboardingUnapproved  = "unapproved"
+	OffboardingZeroBalance = "zero_balance"
+)
+
+// OffboardingEntry describes a provider that appears to be leaving the network
+type OffboardingEntry struct {
+	ProviderID      uint64    `json:"provider_id"`
+	Name            string    `json:"name"`
+	Address         string    `json:"address"`
+	Reasons         []string  `json:"reasons"`
+	Since           time.Time `json:"since"`
+	LastSeen        time.Time `json:"last_seen"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	Scrapes         int       `json:"scrapes"`
+	FinalFIL        string    `json:"final_fil_balance"`
+	FinalUSDFC      string    `json:"final_usdfc_balance"`
+}
+
+// offboardingTracker follows providers across scrapes and reports the ones that went
+// inactive, lost approval, or have held an empty wallet for several consecutive scrapes
+type offboardingTracker struct {
+	minScrapes int
+	previous   map[uint64]WalletInfo
+	candidates map[uint64]*offboardingCandidate
+	mu         sync.RWMutex
+}
+
+type offboardingCandidate struct {
+	reasons  []string
+	since    time.Time
+	lastSeen time.Time
+	scrapes  int
+	wallet   WalletInfo
+}
+
+func newOffboardingTracker(minScrapes int) *offboardingTracker {
+	return &offboardingTracker{
+		minScrapes: minScrapes,
+		previous:   make(map[uint64]WalletInfo),
+		candidates: make(map[uint64]*offboardingCandidate),
+	}
+}
+
+// Observe records the providers of one scrape
+func (t *offboardingTracker) Observe(providers []WalletInfo, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, wallet := range providers {
+		prev, seen := t.previous[wallet.ProviderID]
+		t.previous[wallet.ProviderID] = wallet
+
+		candidate := t.candidates[wallet.ProviderID]
+
+		var reasons []string
+		// Status flags only count as offboarding when we saw them flip
+		if !wallet.IsActive && ((seen && prev.IsActive) || hasReason(candidate, OffboardingInactive)) {
+			reasons = append(reasons, OffboardingInactive)
+		}
+		if !wallet.IsApproved && ((seen && prev.IsApproved) || hasReason(candidate, OffboardingUnapproved)) {
+			reasons = append(reasons, OffboardingUnapproved)
+		}
+		if isZero(wallet.FILBalance) && isZero(wallet.USDFCBalance) {
+			reasons = append(reasons, OffboardingZeroBalance)
+		}
+
+		if len(reasons) == 0 {
+			delete(t.candidates, wallet.ProviderID)
+			continue
+		}
+
+		if candidate == nil {
+			candidate = &offboardingCandidate{since: now}
+			t.candidates[wallet.ProviderID] = candidate
+		}
+		candidate.reasons = reasons
+		candidate.lastSeen = now
+		candidate.scrapes++
+		candidate.wallet = wallet
+	}
+}
+
+// Report returns the providers that met an offboarding condition for at least minScrapes scrapes
+func (t *offboardingTracker) Report() []OffboardingEntry {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	entries := []OffboardingEntry{}
+	for id, candidate := range t.candidates {
+		if candidate.scrapes < t.minScrapes {
+			continue
+		}
+
+		entries = append(entries, OffboardingEntry{
+			ProviderID:      id,
+			Name:            candidate.wallet.Name,
+			Address:         candidate.wallet.Address.Hex(),
+			Reasons:         candidate.reasons,
+			Since:           candidate.since,
+			LastSeen:        candidate.lastSeen,
+			DurationSeconds: candidate.lastSeen.Sub(candidate.since).Seconds(),
+			Scrapes:         candidate.scrapes,
+			FinalFIL:        FormatUnits(candidate.wallet.FILBalance, 18),
+			FinalUSDFC:      FormatUnits(candidate.wallet.USDFCBalance, 18),
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].ProviderID < entries[j].ProviderID
+	})
+
+	return entries
+}
+
+func hasReason(candidate *offboardingCandidate, reason string) bool {
+	if candidate == nil {
+		return false
+	}
+	for _, r := range candidate.reasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+func isZero(amount *big.Int) bool {
+	return amount == nil || amount.Sign() == 0
+}
@@ -0,0 +1,250 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"golang.org/x/net/http2"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"wallet-exporter/internal/config"
+)
+
+// OTLP protocols
+const (
+	OTLPProtocolHTTP = "http/protobuf"
+	OTLPProtocolGRPC = "grpc"
+)
+
+// otlpGRPCPath is the gRPC method metrics are exported with
+const otlpGRPCPath = "/opentelemetry.proto.collector.metrics.v1.MetricsService/Export"
+
+// otlpExporter sends metrics to an OpenTelemetry collector over OTLP/HTTP or OTLP/gRPC. Metric
+// names and labels are kept as on /metrics, so queries work the same on either path.
+type otlpExporter struct {
+	url        string // Full URL of the export endpoint or gRPC method
+	grpc       bool
+	headers    map[string]string
+	resource   map[string]string // Resource attributes, service.name included
+	startTime  time.Time         // Start of the cumulative counters
+	metricUnit map[string]string // UCUM unit by prefixed metric name
+	client     *http.Client
+}
+
+func newOTLPExporter(cfg *config.Config, startTime time.Time) (*otlpExporter, error) {
+	endpoint, err := url.Parse(cfg.OTLPEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OTLP endpoint: %w", err)
+	}
+
+	o := &otlpExporter{
+		grpc:       cfg.OTLPProtocol == OTLPProtocolGRPC,
+		headers:    cfg.OTLPHeaders,
+		resource:   map[string]string{"service.name": "wallet-exporter"},
+		startTime:  startTime,
+		metricUnit: make(map[string]string, len(metricDefinitions)),
+		client:     &http.Client{},
+	}
+	for key, value := range cfg.OTLPResourceAttributes {
+		o.resource[key] = value
+	}
+	for _, def := range metricDefinitions {
+		o.metricUnit[cfg.MetricsPrefix+"_"+def.Name] = otlpUnit(def.Unit)
+	}
+
+	if o.grpc {
+		// gRPC needs HTTP/2, which net/http only negotiates over TLS
+		transport := &http2.Transport{}
+		if endpoint.Scheme == "http" {
+			transport.AllowHTTP = true
+			transport.DialTLSContext = func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, addr)
+			}
+		}
+		o.client.Transport = transport
+		o.url = strings.TrimRight(endpoint.String(), "/") + otlpGRPCPath
+	} else {
+		o.url = strings.TrimRight(endpoint.String(), "/") + "/v1/metrics"
+	}
+	return o, nil
+}
+
+func (o *otlpExporter) Name() string { return "otlp" }
+
+// Push sends the metrics as one ExportMetricsServiceRequest
+func (o *otlpExporter) Push(ctx context.Context, families []*dto.MetricFamily, now time.Time) error {
+	body := o.encode(families, now)
+
+	contentType := "application/x-protobuf"
+	if o.grpc {
+		// Length-prefixed message, uncompressed
+		frame := make([]byte, 5, 5+len(body))
+		binary.BigEndian.PutUint32(frame[1:], uint32(len(body)))
+		body = append(frame, body...)
+		contentType = "application/grpc"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "wallet-exporter")
+	if o.grpc {
+		req.Header.Set("TE", "trailers")
+	}
+	for name, value := range o.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("OTLP endpoint returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if o.grpc {
+		// Trailers-only responses carry the status in the headers
+		status, message := resp.Trailer.Get("Grpc-Status"), resp.Trailer.Get("Grpc-Message")
+		if status == "" {
+			status, message = resp.Header.Get("Grpc-Status"), resp.Header.Get("Grpc-Message")
+		}
+		if status != "0" {
+			return fmt.Errorf("OTLP endpoint returned gRPC status %s: %s", status, message)
+		}
+	}
+	return nil
+}
+
+// encode encodes the metrics as an ExportMetricsServiceRequest with one resource and scope.
+// Gauges become OTLP gauges and counters cumulative monotonic sums.
+func (o *otlpExporter) encode(families []*dto.MetricFamily, now time.Time) []byte {
+	var resource []byte
+	for key, value := range o.resource {
+		resource = protowire.AppendTag(resource, 1, protowire.BytesType)
+		resource = protowire.AppendBytes(resource, otlpKeyValue(key, value))
+	}
+
+	var scope []byte
+	scope = protowire.AppendTag(scope, 1, protowire.BytesType)
+	scope = protowire.AppendString(scope, "wallet-exporter")
+
+	var scopeMetrics []byte
+	scopeMetrics = protowire.AppendTag(scopeMetrics, 1, protowire.BytesType)
+	scopeMetrics = protowire.AppendBytes(scopeMetrics, scope)
+	for _, family := range families {
+		if metric := o.encodeMetric(family, now); metric != nil {
+			scopeMetrics = protowire.AppendTag(scopeMetrics, 2, protowire.BytesType)
+			scopeMetrics = protowire.AppendBytes(scopeMetrics, metric)
+		}
+	}
+
+	var resourceMetrics []byte
+	resourceMetrics = protowire.AppendTag(resourceMetrics, 1, protowire.BytesType)
+	resourceMetrics = protowire.AppendBytes(resourceMetrics, resource)
+	resourceMetrics = protowire.AppendTag(resourceMetrics, 2, protowire.BytesType)
+	resourceMetrics = protowire.AppendBytes(resourceMetrics, scopeMetrics)
+
+	var request []byte
+	request = protowire.AppendTag(request, 1, protowire.BytesType)
+	return protowire.AppendBytes(request, resourceMetrics)
+}
+
+// encodeMetric encodes a metric family as an OTLP Metric, nil if it is of an unsupported type
+func (o *otlpExporter) encodeMetric(family *dto.MetricFamily, now time.Time) []byte {
+	counter := family.GetType() == dto.MetricType_COUNTER
+	if !counter && family.GetType() != dto.MetricType_GAUGE {
+		return nil // The exporter only emits gauges and counters
+	}
+
+	var data []byte
+	for _, metric := range family.GetMetric() {
+		value := metric.GetGauge().GetValue()
+		if counter {
+			value = metric.GetCounter().GetValue()
+		}
+
+		var point []byte
+		for _, label := range metric.GetLabel() {
+			point = protowire.AppendTag(point, 7, protowire.BytesType)
+			point = protowire.AppendBytes(point, otlpKeyValue(label.GetName(), label.GetValue()))
+		}
+		if counter {
+			point = protowire.AppendTag(point, 2, protowire.Fixed64Type)
+			point = protowire.AppendFixed64(point, uint64(o.startTime.UnixNano()))
+		}
+		point = protowire.AppendTag(point, 3, protowire.Fixed64Type)
+		point = protowire.AppendFixed64(point, uint64(now.UnixNano()))
+		point = protowire.AppendTag(point, 4, protowire.Fixed64Type)
+		point = protowire.AppendFixed64(point, math.Float64bits(value))
+
+		data = protowire.AppendTag(data, 1, protowire.BytesType)
+		data = protowire.AppendBytes(data, point)
+	}
+	if counter {
+		data = protowire.AppendTag(data, 2, protowire.VarintType)
+		data = protowire.AppendVarint(data, 2) // AGGREGATION_TEMPORALITY_CUMULATIVE
+		data = protowire.AppendTag(data, 3, protowire.VarintType)
+		data = protowire.AppendVarint(data, 1) // is_monotonic
+	}
+
+	var metric []byte
+	metric = protowire.AppendTag(metric, 1, protowire.BytesType)
+	metric = protowire.AppendString(metric, family.GetName())
+	metric = protowire.AppendTag(metric, 2, protowire.BytesType)
+	metric = protowire.AppendString(metric, family.GetHelp())
+	metric = protowire.AppendTag(metric, 3, protowire.BytesType)
+	metric = protowire.AppendString(metric, o.metricUnit[family.GetName()])
+	if counter {
+		metric = protowire.AppendTag(metric, 7, protowire.BytesType) // sum
+	} else {
+		metric = protowire.AppendTag(metric, 5, protowire.BytesType) // gauge
+	}
+	return protowire.AppendBytes(metric, data)
+}
+
+// otlpKeyValue encodes a KeyValue with a string value
+func otlpKeyValue(key, value string) []byte {
+	var anyValue []byte
+	anyValue = protowire.AppendTag(anyValue, 1, protowire.BytesType)
+	anyValue = protowire.AppendString(anyValue, value)
+
+	var kv []byte
+	kv = protowire.AppendTag(kv, 1, protowire.BytesType)
+	kv = protowire.AppendString(kv, key)
+	kv = protowire.AppendTag(kv, 2, protowire.BytesType)
+	return protowire.AppendBytes(kv, anyValue)
+}
+
+// otlpUnit converts a metric definition unit to UCUM, as OTLP expects; token and epoch units
+// become annotations
+func otlpUnit(unit string) string {
+	switch unit {
+	case "seconds":
+		return "s"
+	case "milliseconds":
+		return "ms"
+	case "bytes":
+		return "By"
+	case "count", "boolean", "ratio", "info":
+		return "1"
+	default:
+		return "{" + unit + "}"
+	}
+}
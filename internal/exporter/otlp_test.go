@@ -0,0 +1,104 @@
+package exporter
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	"wallet-exporter/internal/config"
+)
+
+func otlpTestFamilies(t *testing.T) []*dto.MetricFamily {
+	t.Helper()
+	registry := prometheus.NewRegistry()
+	gauge := newGauge("test", "scrape_duration_seconds")
+	gauge.Set(1.5)
+	counter := newCounter("test", "scrape_errors_total")
+	counter.Inc()
+	registry.MustRegister(gauge, counter)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return families
+}
+
+func TestOTLPExporterHTTP(t *testing.T) {
+	var path, contentType, auth string
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, contentType, auth = r.URL.Path, r.Header.Get("Content-Type"), r.Header.Get("Authorization")
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{
+		MetricsPrefix:          "test",
+		OTLPEndpoint:           server.URL,
+		OTLPProtocol:           OTLPProtocolHTTP,
+		OTLPHeaders:            map[string]string{"Authorization": "Basic dXNlcjpwYXNz"},
+		OTLPResourceAttributes: map[string]string{"deployment.environment": "staging"},
+	}
+	o, err := newOTLPExporter(cfg, time.Unix(0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Push(context.Background(), otlpTestFamilies(t), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	if path != "/v1/metrics" || contentType != "application/x-protobuf" || auth != "Basic dXNlcjpwYXNz" {
+		t.Errorf("Unexpected request to %s (%s, %q)", path, contentType, auth)
+	}
+	for _, want := range []string{"test_scrape_duration_seconds", "test_scrape_errors_total", "service.name", "wallet-exporter", "deployment.environment", "staging"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("Expected the request to contain %q", want)
+		}
+	}
+}
+
+func TestOTLPExporterGRPC(t *testing.T) {
+	var path string
+	var frame []byte
+	status := "0"
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		frame, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.WriteHeader(http.StatusOK)
+		w.Header().Set("Grpc-Status", status)
+	})
+	server := httptest.NewServer(h2c.NewHandler(handler, &http2.Server{}))
+	defer server.Close()
+
+	cfg := &config.Config{MetricsPrefix: "test", OTLPEndpoint: server.URL, OTLPProtocol: OTLPProtocolGRPC}
+	o, err := newOTLPExporter(cfg, time.Unix(0, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Push(context.Background(), otlpTestFamilies(t), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if path != otlpGRPCPath {
+		t.Errorf("Expected the Export method, got %s", path)
+	}
+	if len(frame) < 5 || frame[0] != 0 || int(binary.BigEndian.Uint32(frame[1:5])) != len(frame)-5 {
+		t.Errorf("Expected a length-prefixed message, got %d bytes", len(frame))
+	}
+
+	status = "14"
+	if err := o.Push(context.Background(), otlpTestFamilies(t), time.Now()); err == nil {
+		t.Error("Expected an error for a non-zero gRPC status")
+	}
+}
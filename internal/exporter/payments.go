@@ -0,0 +1,116 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"wallet-exporter/internal/config"
+	"wallet-exporter/internal/contracts"
+)
+
+// nativeTokenDecimals is the number of decimals of FIL, used for the native Payments account
+const nativeTokenDecimals = 18
+
+// paymentsToken is a token whose Payments contract account is queried for every wallet
+type paymentsToken struct {
+	Symbol   string
+	Address  common.Address // Zero address for the native FIL account
+	Decimals int
+}
+
+// errCallReverted is passed to the decoders of batched calls the contract reverted
+var errCallReverted = errors.New("call reverted")
+
+// PaymentsAccount holds a wallet's Payments contract account for one token
+type PaymentsAccount struct {
+	Token       string // Lowercase token symbol, used as the "token" label
+	Decimals    int
+	QueryFailed bool `json:",omitempty"` // The last lookup failed; PaymentsInfo is the last known account or nil
+	*PaymentsInfo
+}
+
+// paymentsQueryFailed reports whether any Payments account lookup of the wallet failed
+func (w WalletInfo) paymentsQueryFailed() bool {
+	for _, account := range w.PaymentsAccounts {
+		if account.QueryFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// carryOverPayments fills the Payments accounts whose lookup failed with the account of the
+// previous scrape, so a transient RPC failure never shows up as an unfunded account. Accounts
+// without a previous value stay unknown (nil).
+func carryOverPayments(wallets, previous []WalletInfo) {
+	known := make(map[common.Address][]PaymentsAccount, len(previous))
+	for _, wallet := range previous {
+		known[wallet.Address] = wallet.PaymentsAccounts
+	}
+
+	for i := range wallets {
+		wallet := &wallets[i]
+		if !wallet.paymentsQueryFailed() {
+			continue
+		}
+		accounts := append([]PaymentsAccount(nil), wallet.PaymentsAccounts...)
+		for j, account := range accounts {
+			if !account.QueryFailed {
+				continue
+			}
+			for _, last := range known[wallet.Address] {
+				if last.Token == account.Token {
+					accounts[j].PaymentsInfo = last.PaymentsInfo
+				}
+			}
+		}
+		wallet.setPaymentsAccounts(accounts)
+	}
+}
+
+// resolvePaymentsTokens looks up the decimals of every configured ERC-20 token.
+// Tokens whose decimals cannot be read are assumed to use 18 like FIL and USDFC.
+func (e *WalletExporter) resolvePaymentsTokens(ctx context.Context, configured []config.PaymentsToken) []paymentsToken {
+	tokens := make([]paymentsToken, 0, len(configured))
+	for _, t := range configured {
+		token := paymentsToken{
+			Symbol:   strings.ToLower(t.Symbol),
+			Address:  common.HexToAddress(t.Address),
+			Decimals: nativeTokenDecimals,
+		}
+
+		if token.Address != (common.Address{}) {
+			if erc20, err := contracts.NewERC20(token.Address, e.client); err == nil {
+				if decimals, err := erc20.Decimals(callOpts(ctx)); err == nil {
+					token.Decimals = int(decimals)
+				} else {
+					e.logger.Warn("Failed to read token decimals, assuming 18", "token", t.Symbol, "address", t.Address, "error", err)
+				}
+			}
+		}
+
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+// fetchPaymentsAccounts fetches the Payments contract account of every configured token.
+// The result always has one entry per token; failed lookups are flagged QueryFailed.
+func (e *WalletExporter) fetchPaymentsAccounts(ctx context.Context, address common.Address) []PaymentsAccount {
+	accounts := make([]PaymentsAccount, 0, len(e.paymentsTokens))
+	for _, token := range e.paymentsTokens {
+		account := PaymentsAccount{Token: token.Symbol, Decimals: token.Decimals}
+		info, err := e.fetchPaymentsInfo(ctx, token.Address, address)
+		if err != nil {
+			e.logger.Warn("Failed to get Payments info", "address", address.Hex(), "token", token.Symbol, "error", err)
+			e.scrapeErrors.Inc()
+			account.QueryFailed = true
+		}
+		account.PaymentsInfo = info
+		accounts = append(accounts, account)
+	}
+	return accounts
+}
@@ -0,0 +1,55 @@
+package exporter
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestDecodePaymentsInfoErrors(t *testing.T) {
+	// Wallets without an account read as zeros, so a revert is a wrong contract, not a missing account
+	for _, failure := range []struct {
+		data []byte
+		err  error
+	}{
+		{nil, errCallReverted},
+		{nil, errors.New("execution reverted")},
+		{nil, errors.New("connection reset by peer")},
+		{[]byte{1, 2, 3}, nil}, // Not an ABI-encoded account
+	} {
+		if info, err := decodePaymentsInfo(failure.data, failure.err); err == nil || info != nil {
+			t.Errorf("Expected a failed lookup for %v, got %+v", failure, info)
+		}
+	}
+}
+
+func TestCarryOverPayments(t *testing.T) {
+	known := common.HexToAddress("0x01")
+	unknown := common.HexToAddress("0x02")
+	account := func(token string, funds int64) PaymentsAccount {
+		return PaymentsAccount{Token: token, Decimals: 18, PaymentsInfo: &PaymentsInfo{Funds: big.NewInt(funds)}}
+	}
+	failed := func(token string) PaymentsAccount {
+		return PaymentsAccount{Token: token, Decimals: 18, QueryFailed: true}
+	}
+
+	previous := []WalletInfo{{Address: known, PaymentsAccounts: []PaymentsAccount{account("usdfc", 100), account("fil", 7)}}}
+	wallets := []WalletInfo{
+		{Address: known, PaymentsAccounts: []PaymentsAccount{failed("usdfc"), account("fil", 8)}},
+		{Address: unknown, PaymentsAccounts: []PaymentsAccount{failed("usdfc")}},
+	}
+	carryOverPayments(wallets, previous)
+
+	usdfc, fil := wallets[0].PaymentsAccounts[0], wallets[0].PaymentsAccounts[1]
+	if !usdfc.QueryFailed || usdfc.Funds.Int64() != 100 || wallets[0].PaymentsFunds.Int64() != 100 {
+		t.Errorf("Expected the failed USDFC account to keep its last funds, got %+v", usdfc)
+	}
+	if fil.QueryFailed || fil.Funds.Int64() != 8 {
+		t.Errorf("Expected the FIL account read this scrape to be kept, got %+v", fil)
+	}
+	if account := wallets[1].PaymentsAccounts[0]; account.PaymentsInfo != nil || wallets[1].PaymentsFunds != nil {
+		t.Errorf("Expected an account never read to stay unknown rather than zero, got %+v", account)
+	}
+}
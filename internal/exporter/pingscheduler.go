@@ -0,0 +1,151 @@
+package exporter
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// pingSchedulerResolution is how often the scheduler checks for providers that are due
+const pingSchedulerResolution = time.Second
+
+// pingScheduler spreads provider pings evenly across the scrape interval. Each provider
+// gets a fixed offset derived from a hash of its ID, so its pings stay exactly one
+// interval apart while the exporter's outbound requests are smoothed out.
+type pingScheduler struct {
+	exporter  *WalletExporter
+	interval  time.Duration
+	providers map[uint64]WalletInfo
+	results   map[uint64][]PingResult
+	inFlight  map[uint64]bool // Providers whose ping has not returned yet
+	mu        sync.Mutex
+}
+
+func newPingScheduler(e *WalletExporter, interval time.Duration) *pingScheduler {
+	return &pingScheduler{
+		exporter:  e,
+		interval:  interval,
+		providers: make(map[uint64]WalletInfo),
+		results:   make(map[uint64][]PingResult),
+		inFlight:  make(map[uint64]bool),
+	}
+}
+
+// SetProviders replaces the set of providers to ping
+func (s *pingScheduler) SetProviders(providers []WalletInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.providers = make(map[uint64]WalletInfo, len(providers))
+	for _, p := range providers {
+		if p.ProviderID == 0 {
+			continue
+		}
+		s.providers[p.ProviderID] = p
+	}
+
+	// Drop results of providers that are gone
+	for id := range s.results {
+		if _, ok := s.providers[id]; !ok {
+			delete(s.results, id)
+		}
+	}
+}
+
+// Results returns a copy of the latest ping result per provider
+func (s *pingScheduler) Results() map[uint64][]PingResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results := make(map[uint64][]PingResult, len(s.results))
+	for id, result := range s.results {
+		results[id] = result
+	}
+	return results
+}
+
+// Run pings each provider once per interval at its hashed offset until ctx is done. At most
+// MAX_CONCURRENT_PINGS pings run at once, and a provider whose previous ping is still running
+// is skipped until its next turn, so slow providers cannot pile up goroutines.
+func (s *pingScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(pingSchedulerResolution)
+	defer ticker.Stop()
+
+	semaphore := make(chan struct{}, s.exporter.config.MaxConcurrentPings)
+	prevPhase := s.phase(time.Now())
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			phase := s.phase(now)
+			for _, p := range s.due(prevPhase, phase) {
+				go func(p WalletInfo) {
+					// Give up waiting for a slot on shutdown, rather than hold the goroutine forever
+					select {
+					case semaphore <- struct{}{}:
+					case <-ctx.Done():
+						s.mu.Lock()
+						delete(s.inFlight, p.ProviderID)
+						s.mu.Unlock()
+						return
+					}
+					defer func() { <-semaphore }()
+
+					results := s.exporter.pingProvider(ctx, p)
+
+					s.mu.Lock()
+					delete(s.inFlight, p.ProviderID)
+					if _, monitored := s.providers[p.ProviderID]; monitored && len(results) > 0 {
+						s.results[p.ProviderID] = results
+					}
+					s.mu.Unlock()
+				}(p)
+			}
+			prevPhase = phase
+		}
+	}
+}
+
+// due returns providers whose offset lies in (from, to], wrapping around the interval, and
+// marks them in flight. Providers still in flight are left out.
+func (s *pingScheduler) due(from, to time.Duration) []WalletInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []WalletInfo
+	for id, p := range s.providers {
+		if s.inFlight[id] {
+			continue
+		}
+		offset := s.offset(id)
+		if from <= to {
+			if offset <= from || offset > to {
+				continue
+			}
+		} else if offset <= from && offset > to {
+			continue
+		}
+		s.inFlight[id] = true
+		due = append(due, p)
+	}
+	return due
+}
+
+// phase is the position of t within the current interval
+func (s *pingScheduler) phase(t time.Time) time.Duration {
+	return time.Duration(t.UnixNano() % int64(s.interval))
+}
+
+// offset is the fixed position of a provider within the interval
+func (s *pingScheduler) offset(providerID uint64) time.Duration {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], providerID)
+
+	h := fnv.New64a()
+	h.Write(buf[:])
+	return time.Duration(h.Sum64() % uint64(s.interval))
+}
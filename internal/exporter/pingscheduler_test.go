@@ -0,0 +1,24 @@
+package exporter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPingSchedulerSkipsProvidersInFlight(t *testing.T) {
+	s := newPingScheduler(&WalletExporter{}, time.Minute)
+	s.SetProviders([]WalletInfo{{ProviderID: 1}, {ProviderID: 2}})
+
+	// (-1, interval] covers every offset
+	if due := s.due(-1, time.Minute); len(due) != 2 {
+		t.Fatalf("Expected both providers to be due, got %d", len(due))
+	}
+	if due := s.due(-1, time.Minute); len(due) != 0 {
+		t.Errorf("Expected providers with a ping in flight to be skipped, got %d", len(due))
+	}
+
+	delete(s.inFlight, 1)
+	if due := s.due(-1, time.Minute); len(due) != 1 || due[0].ProviderID != 1 {
+		t.Errorf("Expected only the finished provider to be due again, got %+v", due)
+	}
+}
@@ -0,0 +1,68 @@
+package exporter
+
+import (
+	"context"
+	"math/big"
+)
+
+// ServicePricing is the current WarmStorage price list
+type ServicePricing struct {
+	StoragePerTiBMonth    *big.Int // Storage price without CDN
+	CDNEgressPerTiB       *big.Int
+	CacheMissEgressPerTiB *big.Int
+	MinimumPerMonth       *big.Int // Minimum monthly charge per data set
+	EpochsPerMonth        *big.Int
+	StoragePerTiBEpoch    *big.Int // StoragePerTiBMonth / EpochsPerMonth, the rate a rail pays per TiB
+}
+
+func (e *WalletExporter) registerPricingMetrics() {
+	e.storagePriceGauge = newGauge(e.config.MetricsPrefix, "warm_storage_price_per_tib_month")
+	e.storageRateGauge = newGauge(e.config.MetricsPrefix, "warm_storage_rate_per_tib_epoch")
+	e.cdnEgressPriceGauge = newGauge(e.config.MetricsPrefix, "warm_storage_cdn_egress_price_per_tib")
+	e.cacheMissEgressPriceGauge = newGauge(e.config.MetricsPrefix, "warm_storage_cache_miss_egress_price_per_tib")
+	e.minimumPriceGauge = newGauge(e.config.MetricsPrefix, "warm_storage_minimum_price_per_month")
+	e.epochsPerMonthGauge = newGauge(e.config.MetricsPrefix, "warm_storage_epochs_per_month")
+
+	e.registry.MustRegister(e.storagePriceGauge)
+	e.registry.MustRegister(e.storageRateGauge)
+	e.registry.MustRegister(e.cdnEgressPriceGauge)
+	e.registry.MustRegister(e.cacheMissEgressPriceGauge)
+	e.registry.MustRegister(e.minimumPriceGauge)
+	e.registry.MustRegister(e.epochsPerMonthGauge)
+}
+
+// fetchServicePricing reads the price list from the WarmStorage contract
+func (e *WalletExporter) fetchServicePricing(ctx context.Context) (*ServicePricing, error) {
+	result, err := e.warmStorageContract.GetServicePrice(callOpts(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	pricing := &ServicePricing{
+		StoragePerTiBMonth:    result.PricePerTiBPerMonthNoCDN,
+		CDNEgressPerTiB:       result.PricePerTiBCdnEgress,
+		CacheMissEgressPerTiB: result.PricePerTiBCacheMissEgress,
+		MinimumPerMonth:       result.MinimumPricePerMonth,
+		EpochsPerMonth:        result.EpochsPerMonth,
+		StoragePerTiBEpoch:    big.NewInt(0),
+	}
+	if result.EpochsPerMonth.Sign() > 0 {
+		pricing.StoragePerTiBEpoch = new(big.Int).Quo(result.PricePerTiBPerMonthNoCDN, result.EpochsPerMonth)
+	}
+
+	return pricing, nil
+}
+
+func (e *WalletExporter) updatePricingMetrics(pricing *ServicePricing) {
+	// Prices are denominated in USDFC, the first Payments token
+	decimals := e.paymentsTokens[0].Decimals
+
+	e.storagePriceGauge.Set(tokenAmount(pricing.StoragePerTiBMonth, decimals))
+	e.storageRateGauge.Set(tokenAmount(pricing.StoragePerTiBEpoch, decimals))
+	e.cdnEgressPriceGauge.Set(tokenAmount(pricing.CDNEgressPerTiB, decimals))
+	e.cacheMissEgressPriceGauge.Set(tokenAmount(pricing.CacheMissEgressPerTiB, decimals))
+	e.minimumPriceGauge.Set(tokenAmount(pricing.MinimumPerMonth, decimals))
+
+	epochsPerMonth, _ := new(big.Float).SetInt(pricing.EpochsPerMonth).Float64()
+	e.epochsPerMonthGauge.Set(epochsPerMonth)
+}
@@ -0,0 +1,258 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// maxProductTypes bounds product type discovery; the registry enum is a uint8
+const maxProductTypes = 256
+
+// productTypeNames are the registry product types the exporter knows by name
+var productTypeNames = map[uint8]string{
+	0: "pdp",
+}
+
+// pdpProductType is the registry product type of PDP, the only product with a ping route
+const pdpProductType uint8 = 0
+
+// productCapabilityLabels maps the well-known capability keys to provider_product_info labels.
+// Keys outside this list are not exported since every label must be declared up front.
+var productCapabilityLabels = map[string]string{
+	"serviceURL":               "service_url",
+	"location":                 "location",
+	"minPieceSizeInBytes":      "min_piece_size_in_bytes",
+	"maxPieceSizeInBytes":      "max_piece_size_in_bytes",
+	"ipniPiece":                "ipni_piece",
+	"ipniIpfs":                 "ipni_ipfs",
+	"storagePricePerTibPerDay": "storage_price_per_tib_per_day",
+	"minProvingPeriodInEpochs": "min_proving_period_in_epochs",
+	"paymentTokenAddress":      "payment_token_address",
+}
+
+// Capabilities the registry stores ABI-style rather than as text
+var (
+	numericCapabilities = map[string]bool{
+		"minPieceSizeInBytes":      true,
+		"maxPieceSizeInBytes":      true,
+		"storagePricePerTibPerDay": true,
+		"minProvingPeriodInEpochs": true,
+	}
+	booleanCapabilities = map[string]bool{
+		"ipniPiece": true,
+		"ipniIpfs":  true,
+	}
+	addressCapabilities = map[string]bool{
+		"paymentTokenAddress": true,
+	}
+)
+
+// productCapabilityGauges are the numeric PDP capabilities exported as gauges of their own.
+// Prices are in token base units and scaled by the USDFC decimals.
+var productCapabilityGauges = []struct {
+	key    string
+	metric string
+	price  bool
+}{
+	{"storagePricePerTibPerDay", "provider_storage_price_per_tib_per_day", true},
+	{"minPieceSizeInBytes", "provider_min_piece_size_bytes", false},
+	{"maxPieceSizeInBytes", "provider_max_piece_size_bytes", false},
+	{"minProvingPeriodInEpochs", "provider_min_proving_period_epochs", false},
+}
+
+// ProviderProduct is a product registered by a provider, with its decoded capabilities
+type ProviderProduct struct {
+	Type         uint8
+	IsActive     bool
+	Capabilities map[string]string // Capability key -> decoded value
+}
+
+// regionCapabilities are the capability keys a provider may publish its region under, in order of preference
+var regionCapabilities = []string{"region", "location"}
+
+// Region returns the region the provider publishes on its PDP product, or "" if none
+func (w WalletInfo) Region() string {
+	for _, product := range w.Products {
+		if product.Type != 0 {
+			continue
+		}
+		for _, key := range regionCapabilities {
+			if region := product.Capabilities[key]; region != "" {
+				return region
+			}
+		}
+	}
+	return ""
+}
+
+// productTypeName returns the name of a product type, or its number if it is not known
+func productTypeName(productType uint8) string {
+	if name, ok := productTypeNames[productType]; ok {
+		return name
+	}
+	return fmt.Sprintf("%d", productType)
+}
+
+func (e *WalletExporter) registerProductMetrics() {
+	e.productInfoGauge = newGaugeVec(e.config.MetricsPrefix, "provider_product_info")
+	e.productActiveGauge = newGaugeVec(e.config.MetricsPrefix, "provider_product_active")
+
+	e.registry.MustRegister(e.productInfoGauge)
+	e.registry.MustRegister(e.productActiveGauge)
+
+	e.capabilityGauges = make(map[string]*prometheus.GaugeVec, len(productCapabilityGauges))
+	for _, c := range productCapabilityGauges {
+		e.capabilityGauges[c.key] = newGaugeVec(e.config.MetricsPrefix, c.metric)
+		e.registry.MustRegister(e.capabilityGauges[c.key])
+	}
+}
+
+// discoverProductTypes returns the product types the registry currently defines. The registry
+// rejects values outside its ProductType enum, so types are probed in order until a call fails.
+// PDP is assumed if not even the first probe succeeds.
+func (e *WalletExporter) discoverProductTypes(ctx context.Context) []uint8 {
+	var types []uint8
+	for t := 0; t < maxProductTypes; t++ {
+		if _, err := e.registryContract.ProductTypeProviderCount(callOpts(ctx), uint8(t)); err != nil {
+			break
+		}
+		types = append(types, uint8(t))
+	}
+
+	if len(types) == 0 {
+		return []uint8{0}
+	}
+	return types
+}
+
+// fetchProviderProducts reads every product of the given types the provider has registered
+func (e *WalletExporter) fetchProviderProducts(ctx context.Context, providerID uint64, productTypes []uint8) ([]ProviderProduct, error) {
+	id := new(big.Int).SetUint64(providerID)
+
+	var products []ProviderProduct
+	for _, productType := range productTypes {
+		hasProduct, err := e.registryContract.ProviderHasProduct(callOpts(ctx), id, productType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check product type %d: %w", productType, err)
+		}
+		if !hasProduct {
+			continue
+		}
+
+		product, err := e.fetchProviderProduct(ctx, providerID, productType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get product type %d: %w", productType, err)
+		}
+		products = append(products, *product)
+	}
+
+	return products, nil
+}
+
+// fetchProviderProduct reads a product of the provider and decodes all of its capabilities
+func (e *WalletExporter) fetchProviderProduct(ctx context.Context, providerID uint64, productType uint8) (*ProviderProduct, error) {
+	result, err := e.registryContract.GetProviderWithProduct(callOpts(ctx), new(big.Int).SetUint64(providerID), productType)
+	if err != nil {
+		return nil, err
+	}
+
+	product := &ProviderProduct{
+		Type:         productType,
+		IsActive:     result.Product.IsActive,
+		Capabilities: make(map[string]string, len(result.Product.CapabilityKeys)),
+	}
+	for i, key := range result.Product.CapabilityKeys {
+		if i >= len(result.ProductCapabilityValues) {
+			break
+		}
+		product.Capabilities[key] = e.decodeProductCapability(providerID, key, result.ProductCapabilityValues[i])
+	}
+
+	return product, nil
+}
+
+// decodeProductCapability decodes numbers, flags and addresses the way the registry encodes
+// them and falls back to the generic text decoding for everything else
+func (e *WalletExporter) decodeProductCapability(providerID uint64, key string, raw []byte) string {
+	switch {
+	case numericCapabilities[key] && !isPrintableText(raw) && len(raw) <= 32:
+		return new(big.Int).SetBytes(raw).String()
+	case booleanCapabilities[key]:
+		return fmt.Sprintf("%t", new(big.Int).SetBytes(raw).Sign() > 0)
+	case addressCapabilities[key] && len(raw) == common.AddressLength:
+		return common.BytesToAddress(raw).Hex()
+	}
+
+	value, _ := e.decodeCapability(providerID, key, raw)
+	return value
+}
+
+func (e *WalletExporter) updateProductMetrics(wallets []WalletInfo) {
+	e.productInfoGauge.Reset()
+	e.productActiveGauge.Reset()
+	for _, gauge := range e.capabilityGauges {
+		gauge.Reset()
+	}
+
+	for _, wallet := range wallets {
+		for _, product := range wallet.Products {
+			productLabels := prometheus.Labels{
+				"address":      wallet.Address.Hex(),
+				"network":      e.config.Network,
+				"name":         wallet.Name,
+				"provider_id":  fmt.Sprintf("%d", wallet.ProviderID),
+				"product_type": productTypeName(product.Type),
+			}
+
+			activeVal := 0.0
+			if product.IsActive {
+				activeVal = 1.0
+			}
+			e.productActiveGauge.With(productLabels).Set(activeVal)
+
+			labels := prometheus.Labels{"is_active": fmt.Sprintf("%t", product.IsActive)}
+			for name, value := range productLabels {
+				labels[name] = value
+			}
+			for key, label := range productCapabilityLabels {
+				labels[label] = product.Capabilities[key]
+			}
+			e.productInfoGauge.With(labels).Set(1)
+
+			if product.Type == 0 {
+				e.setCapabilityGauges(wallet, product)
+			}
+		}
+	}
+}
+
+// setCapabilityGauges exports the numeric capabilities of a PDP product. Values that
+// are missing or not a number are skipped.
+func (e *WalletExporter) setCapabilityGauges(wallet WalletInfo, product ProviderProduct) {
+	labels := prometheus.Labels{
+		"address":     wallet.Address.Hex(),
+		"network":     e.config.Network,
+		"name":        wallet.Name,
+		"provider_id": fmt.Sprintf("%d", wallet.ProviderID),
+		"is_active":   fmt.Sprintf("%t", wallet.IsActive),
+		"approved":    fmt.Sprintf("%t", wallet.IsApproved),
+	}
+
+	for _, c := range productCapabilityGauges {
+		value, ok := new(big.Int).SetString(product.Capabilities[c.key], 10)
+		if !ok {
+			continue
+		}
+
+		if c.price {
+			e.capabilityGauges[c.key].With(labels).Set(tokenAmount(value, e.paymentsTokens[0].Decimals))
+		} else {
+			floatValue, _ := new(big.Float).SetInt(value).Float64()
+			e.capabilityGauges[c.key].With(labels).Set(floatValue)
+		}
+	}
+}
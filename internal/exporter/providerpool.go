@@ -0,0 +1,69 @@
+package exporter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// providerResult is the outcome of fetching one provider
+type providerResult struct {
+	id     uint64
+	wallet WalletInfo
+	err    error
+}
+
+// streamProviders fetches the given providers with a fixed pool of workers and passes every
+// result to collect as it arrives. Goroutines and buffers are bounded by the pool size, not
+// the registry size, so scrapes of registries with thousands of providers stay flat in
+// memory. Each fetch gets at most timeout (0 = no limit); providers not started before ctx
+// is done are skipped. collect runs on the calling goroutine.
+func streamProviders(ctx context.Context, ids []uint64, workers int, timeout time.Duration,
+	fetch func(ctx context.Context, id uint64) (WalletInfo, error), collect func(providerResult)) {
+	workers = min(workers, len(ids))
+	withTimeout := func() (context.Context, context.CancelFunc) {
+		if timeout <= 0 {
+			return ctx, func() {}
+		}
+		return context.WithTimeout(ctx, timeout)
+	}
+	feed := make(chan uint64)
+	results := make(chan providerResult, workers)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range feed {
+				fetchCtx, cancel := withTimeout()
+				wallet, err := fetch(fetchCtx, id)
+				cancel()
+				results <- providerResult{id: id, wallet: wallet, err: err}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(feed)
+		for _, id := range ids {
+			if ctx.Err() != nil {
+				return
+			}
+			select {
+			case feed <- id:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	for result := range results {
+		collect(result)
+	}
+}
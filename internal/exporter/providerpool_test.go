@@ -0,0 +1,96 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStreamProvidersBoundsWorkers(t *testing.T) {
+	ids := make([]uint64, 5000)
+	for i := range ids {
+		ids[i] = uint64(i + 1)
+	}
+
+	var running, peak atomic.Int64
+	goroutines := runtime.NumGoroutine()
+	var maxGoroutines int
+	fetch := func(ctx context.Context, id uint64) (WalletInfo, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			old := peak.Load()
+			if n <= old || peak.CompareAndSwap(old, n) {
+				break
+			}
+		}
+		if id%100 == 0 {
+			return WalletInfo{}, errors.New("boom")
+		}
+		return WalletInfo{ProviderID: id}, nil
+	}
+
+	seen := make(map[uint64]bool)
+	failed := 0
+	streamProviders(context.Background(), ids, 8, 0, fetch, func(result providerResult) {
+		maxGoroutines = max(maxGoroutines, runtime.NumGoroutine())
+		if result.err != nil {
+			failed++
+			return
+		}
+		seen[result.wallet.ProviderID] = true
+	})
+
+	if len(seen) != 4950 || failed != 50 {
+		t.Errorf("Expected 4950 wallets and 50 errors, got %d and %d", len(seen), failed)
+	}
+	if peak.Load() > 8 {
+		t.Errorf("Expected at most 8 concurrent fetches, got %d", peak.Load())
+	}
+	if extra := maxGoroutines - goroutines; extra > 8+2 {
+		t.Errorf("Expected goroutines bounded by the pool, got %d extra", extra)
+	}
+}
+
+func TestStreamProvidersTimeout(t *testing.T) {
+	fetch := func(ctx context.Context, id uint64) (WalletInfo, error) {
+		if id == 2 {
+			<-ctx.Done() // A stuck provider
+			return WalletInfo{}, ctx.Err()
+		}
+		return WalletInfo{ProviderID: id}, nil
+	}
+
+	var results []providerResult
+	start := time.Now()
+	streamProviders(context.Background(), []uint64{1, 2, 3}, 2, 50*time.Millisecond, fetch, func(result providerResult) {
+		results = append(results, result)
+	})
+	if time.Since(start) > time.Second {
+		t.Errorf("Expected the stuck provider to time out, took %s", time.Since(start))
+	}
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+	for _, result := range results {
+		if (result.id == 2) != errors.Is(result.err, context.DeadlineExceeded) {
+			t.Errorf("Unexpected result %+v", result)
+		}
+	}
+}
+
+func TestStreamProvidersCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	fetched := 0
+	streamProviders(ctx, []uint64{1, 2, 3, 4, 5, 6, 7, 8}, 1, 0, func(ctx context.Context, id uint64) (WalletInfo, error) {
+		return WalletInfo{}, ctx.Err()
+	}, func(providerResult) { fetched++ })
+	if fetched != 0 {
+		t.Error("Expected providers not to be started after the scrape was canceled")
+	}
+}
@@ -0,0 +1,205 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ProviderSummary is the registry view of a provider, without any balance lookups
+type ProviderSummary struct {
+	ProviderID uint64         `json:"provider_id"`
+	Name       string         `json:"name"`
+	Address    common.Address `json:"address"`
+	IsActive   bool           `json:"is_active"`
+	IsApproved bool           `json:"is_approved"`
+	ServiceURL string         `json:"service_url"`
+}
+
+// ListProviders enumerates the registry and resolves each provider's PDP service URL.
+// Providers that fail to load are logged and skipped.
+func (e *WalletExporter) ListProviders(ctx context.Context) ([]ProviderSummary, error) {
+	providerCount, err := e.registryContract.GetProviderCount(callOpts(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider count: %w", err)
+	}
+
+	approvedMap, err := fetchApprovedProviders(ctx, e.viewContract, e.config.ApprovedProvidersPage)
+	if err != nil {
+		e.logger.Warn("Failed to get approved providers", "error", err)
+		approvedMap = map[uint64]bool{}
+	}
+
+	ids := make([]uint64, 0, providerCount.Uint64())
+	for id := uint64(1); id <= providerCount.Uint64(); id++ {
+		ids = append(ids, id)
+	}
+
+	// Shares the worker pool of scrapes; the PDP service URL travels as the provider's PDP product
+	fetch := func(ctx context.Context, id uint64) (WalletInfo, error) {
+		result, err := e.registryContract.GetProvider(callOpts(ctx), new(big.Int).SetUint64(id))
+		if err != nil {
+			return WalletInfo{}, err
+		}
+
+		serviceURL, err := e.lookupServiceURL(ctx, id, pdpProductType)
+		if err != nil {
+			e.logger.Debug("Failed to get PDP product", "provider_id", id, "error", err)
+		}
+
+		return WalletInfo{
+			Address:    result.Info.ServiceProvider,
+			Name:       result.Info.Name,
+			ProviderID: id,
+			IsActive:   result.Info.IsActive,
+			IsApproved: approvedMap[id],
+			Products:   []ProviderProduct{{Type: pdpProductType, Capabilities: map[string]string{"serviceURL": serviceURL}}},
+		}, nil
+	}
+
+	summaries := make([]ProviderSummary, 0, len(ids))
+	streamProviders(ctx, ids, e.config.MaxConcurrentRPC, e.config.ProviderFetchTimeout, fetch, func(result providerResult) {
+		if result.err != nil {
+			e.logger.Warn("Failed to get provider info", "provider_id", result.id, "error", result.err)
+			return
+		}
+		wallet := result.wallet
+		summaries = append(summaries, ProviderSummary{
+			ProviderID: wallet.ProviderID,
+			Name:       wallet.Name,
+			Address:    wallet.Address,
+			IsActive:   wallet.IsActive,
+			IsApproved: wallet.IsApproved,
+			ServiceURL: wallet.Products[0].Capabilities["serviceURL"],
+		})
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].ProviderID < summaries[j].ProviderID
+	})
+
+	return summaries, nil
+}
+
+// approvedProvidersReader reads the approved provider IDs from the WarmStorage view
+type approvedProvidersReader interface {
+	GetApprovedProviders(opts *bind.CallOpts, offset *big.Int, limit *big.Int) ([]*big.Int, error)
+}
+
+// fetchApprovedProviders returns the set of approved provider IDs, read pageSize IDs per call
+// (0 = all in one call). A page shorter than pageSize ends the list. Pages that add no new IDs
+// end it as well, in case the contract ignores the offset.
+func fetchApprovedProviders(ctx context.Context, view approvedProvidersReader, pageSize int) (map[uint64]bool, error) {
+	approved := make(map[uint64]bool)
+	if pageSize <= 0 {
+		ids, err := view.GetApprovedProviders(callOpts(ctx), big.NewInt(0), big.NewInt(0))
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			approved[id.Uint64()] = true
+		}
+		return approved, nil
+	}
+
+	for offset := 0; ; offset += pageSize {
+		page, err := view.GetApprovedProviders(callOpts(ctx), big.NewInt(int64(offset)), big.NewInt(int64(pageSize)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get approved providers at offset %d: %w", offset, err)
+		}
+		before := len(approved)
+		for _, id := range page {
+			approved[id.Uint64()] = true
+		}
+		if len(page) < pageSize || len(approved) == before {
+			return approved, nil
+		}
+	}
+}
+
+// lookupServiceURL returns the serviceURL capability of one of the provider's products
+// (product type 0 is PDP), or "" if the product is inactive or has no URL. Providers with
+// fresh metadata are served from the products in the metadata cache.
+func (e *WalletExporter) lookupServiceURL(ctx context.Context, providerID uint64, productType uint8) (string, error) {
+	if metadata, ok := e.metadataCache.Get(providerID, time.Now()); ok {
+		for _, product := range metadata.Products {
+			if product.Type != productType {
+				continue
+			}
+			if !product.IsActive {
+				return "", nil
+			}
+			return product.Capabilities["serviceURL"], nil
+		}
+	}
+
+	result, err := e.registryContract.GetProviderWithProduct(callOpts(ctx), new(big.Int).SetUint64(providerID), productType)
+	if err != nil {
+		return "", err
+	}
+
+	// Check if product is active
+	if !result.Product.IsActive {
+		return "", nil
+	}
+
+	// Decode Capabilities to find Service URL
+	for i, key := range result.Product.CapabilityKeys {
+		if key == "serviceURL" {
+			if i < len(result.ProductCapabilityValues) {
+				serviceURL, reason := e.decodeCapability(providerID, key, result.ProductCapabilityValues[i])
+				if reason == capabilityBinary {
+					return "", fmt.Errorf("serviceURL capability is not printable text")
+				}
+				return serviceURL, nil
+			}
+			break
+		}
+	}
+
+	return "", nil
+}
+
+// mergeProviderCustomWallets appends the custom wallets to the provider wallets. A custom
+// wallet that is a registered provider replaces that provider's entry, keeping the configured
+// name and thresholds, so the address is exported once with its provider metadata. If the
+// provider itself failed to load, the custom wallet is reported as the provider on its own.
+func mergeProviderCustomWallets(providers, custom []WalletInfo, approved map[uint64]bool) []WalletInfo {
+	byID := make(map[uint64]int, len(providers))
+	for i, provider := range providers {
+		byID[provider.ProviderID] = i
+	}
+
+	merged := append([]WalletInfo{}, providers...)
+	replaced := make(map[int]bool)
+	for _, wallet := range custom {
+		if wallet.ProviderID == 0 {
+			merged = append(merged, wallet)
+			continue
+		}
+
+		if i, ok := byID[wallet.ProviderID]; ok && !replaced[i] {
+			provider := merged[i]
+			provider.Name = wallet.Name
+			provider.MinFIL = wallet.MinFIL
+			provider.MinUSDFC = wallet.MinUSDFC
+			merged[i] = provider
+			replaced[i] = true
+			continue
+		}
+
+		wallet.Type = "provider"
+		wallet.IsApproved = approved[wallet.ProviderID]
+		merged = append(merged, wallet)
+	}
+
+	return merged
+}
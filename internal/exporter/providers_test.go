@@ -0,0 +1,125 @@
+package exporter
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+
+	"wallet-exporter/internal/config"
+)
+
+func TestMergeProviderCustomWallets(t *testing.T) {
+	providers := []WalletInfo{
+		{Address: common.HexToAddress("0x01"), Name: "registry-name", Type: "provider", ProviderID: 1, IsApproved: true},
+	}
+	custom := []WalletInfo{
+		{Address: common.HexToAddress("0x01"), Name: "our-sp", Type: "operator", ProviderID: 1, MinFIL: 5},
+		{Address: common.HexToAddress("0x02"), Name: "unlisted-sp", Type: "other", ProviderID: 2},
+		{Address: common.HexToAddress("0x03"), Name: "treasury", Type: "operator"},
+	}
+
+	merged := mergeProviderCustomWallets(providers, custom, map[uint64]bool{2: true})
+	if len(merged) != 3 {
+		t.Fatalf("Expected 3 wallets, got %d", len(merged))
+	}
+
+	if merged[0].Name != "our-sp" || merged[0].Type != "provider" || !merged[0].IsApproved || merged[0].MinFIL != 5 {
+		t.Errorf("Expected custom name and threshold on the provider entry, got %+v", merged[0])
+	}
+	if merged[1].Type != "provider" || !merged[1].IsApproved {
+		t.Errorf("Expected unlisted provider to be reported as an approved provider, got %+v", merged[1])
+	}
+	if merged[2].Type != "operator" || merged[2].ProviderID != 0 {
+		t.Errorf("Expected plain custom wallet to be unchanged, got %+v", merged[2])
+	}
+}
+
+// pagedView serves approved provider IDs in pages of at most maxPage, like the WarmStorage view
+type pagedView struct {
+	ids     []uint64
+	maxPage int
+	calls   int
+}
+
+func (v *pagedView) GetApprovedProviders(_ *bind.CallOpts, offset *big.Int, limit *big.Int) ([]*big.Int, error) {
+	v.calls++
+	start := min(int(offset.Int64()), len(v.ids))
+	n := int(limit.Int64())
+	if n == 0 || n > v.maxPage {
+		n = v.maxPage
+	}
+	end := min(start+n, len(v.ids))
+	page := make([]*big.Int, 0, end-start)
+	for _, id := range v.ids[start:end] {
+		page = append(page, new(big.Int).SetUint64(id))
+	}
+	return page, nil
+}
+
+func TestFetchApprovedProvidersPaginates(t *testing.T) {
+	view := &pagedView{maxPage: 50}
+	for id := uint64(1); id <= 120; id++ {
+		view.ids = append(view.ids, id*2)
+	}
+
+	approved, err := fetchApprovedProviders(context.Background(), view, 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(approved) != 120 || !approved[240] || view.calls != 3 {
+		t.Errorf("Expected 120 approved providers in 3 calls, got %d in %d", len(approved), view.calls)
+	}
+
+	// A single unpaged call is cut off at the contract's page limit
+	view.calls = 0
+	approved, _ = fetchApprovedProviders(context.Background(), view, 0)
+	if len(approved) != 50 || view.calls != 1 {
+		t.Errorf("Expected 50 approved providers in 1 call, got %d in %d", len(approved), view.calls)
+	}
+}
+
+// offsetIgnoringView returns the same full page for every offset
+type offsetIgnoringView struct{ calls int }
+
+func (v *offsetIgnoringView) GetApprovedProviders(_ *bind.CallOpts, _ *big.Int, limit *big.Int) ([]*big.Int, error) {
+	v.calls++
+	page := make([]*big.Int, limit.Int64())
+	for i := range page {
+		page[i] = big.NewInt(int64(i + 1))
+	}
+	return page, nil
+}
+
+func TestFetchApprovedProvidersStopsOnRepeatedPage(t *testing.T) {
+	view := &offsetIgnoringView{}
+	approved, err := fetchApprovedProviders(context.Background(), view, 10)
+	if err != nil || len(approved) != 10 || view.calls != 2 {
+		t.Errorf("Expected to stop after a page with no new IDs, got %d IDs in %d calls (%v)", len(approved), view.calls, err)
+	}
+}
+
+func TestCustomWalletOfProviderIsNotFetchedAgain(t *testing.T) {
+	address := common.HexToAddress("0x01")
+	e := &WalletExporter{config: &config.Config{
+		CustomWallets:    []config.CustomWallet{{Address: address.Hex(), Name: "our-sp", Type: "operator", MinFIL: 5}},
+		MaxConcurrentRPC: 1,
+		DefaultMinUSDFC:  1,
+	}}
+	providers := []WalletInfo{{Address: address, Name: "registry-name", Type: "provider", ProviderID: 3, FILBalance: big.NewInt(7)}}
+
+	// No RPC client is set, so fetching the wallet again would panic
+	custom, err := e.fetchCustomWallets(context.Background(), providers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged := mergeProviderCustomWallets(providers, custom, nil)
+	if len(merged) != 1 {
+		t.Fatalf("Expected one entry for the address, got %+v", merged)
+	}
+	if w := merged[0]; w.Name != "our-sp" || w.MinFIL != 5 || w.MinUSDFC != 1 || w.FILBalance.Int64() != 7 {
+		t.Errorf("Expected the provider's balances with the custom name and thresholds, got %+v", w)
+	}
+}
@@ -0,0 +1,164 @@
+package exporter
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// pushTimeout bounds a push to a sink without a timeout setting of its own
+const pushTimeout = 30 * time.Second
+
+// metricsSink sends gathered metrics to a push-based monitoring system
+type metricsSink interface {
+	Name() string
+	Push(ctx context.Context, families []*dto.MetricFamily, now time.Time) error
+}
+
+// pusher pushes the metrics to a sink after every scrape, or every interval if one is set.
+// Pushes run on their own goroutine so a slow sink never holds up scrapes; a failed push is
+// not retried, the next one sends fresh values.
+type pusher struct {
+	sink     metricsSink
+	gatherer prometheus.Gatherer
+	interval time.Duration // 0 = after every scrape
+	timeout  time.Duration
+
+	pending       chan time.Time // Scrape times waiting to be pushed; only the latest is kept
+	pushesCounter *prometheus.CounterVec
+	logger        *slog.Logger
+}
+
+func newPusher(sink metricsSink, gatherer prometheus.Gatherer, interval, timeout time.Duration, logger *slog.Logger) *pusher {
+	return &pusher{
+		sink:     sink,
+		gatherer: gatherer,
+		interval: interval,
+		timeout:  timeout,
+		pending:  make(chan time.Time, 1),
+		logger:   logger,
+	}
+}
+
+func (e *WalletExporter) registerPushMetrics() {
+	counter := newCounterVec(e.config.MetricsPrefix, "metrics_pushes_total")
+	e.registry.MustRegister(counter)
+	for _, p := range e.pushers {
+		p.pushesCounter = counter
+	}
+}
+
+// scraped schedules a push of the metrics of a scrape that ended at now, replacing a push
+// still waiting. Pushers with an interval ignore scrapes.
+func (p *pusher) scraped(now time.Time) {
+	if p.interval > 0 {
+		return
+	}
+	select {
+	case <-p.pending:
+	default:
+	}
+	p.pending <- now
+}
+
+// run pushes until ctx is cancelled
+func (p *pusher) run(ctx context.Context) {
+	var tick <-chan time.Time
+	if p.interval > 0 {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-p.pending:
+			p.push(ctx, now)
+		case now := <-tick:
+			p.push(ctx, now)
+		}
+	}
+}
+
+func (p *pusher) push(ctx context.Context, now time.Time) {
+	families, err := p.gatherer.Gather()
+	if err == nil {
+		pushCtx, cancel := context.WithTimeout(ctx, p.timeout)
+		err = p.sink.Push(pushCtx, families, now)
+		cancel()
+	}
+
+	result := "success"
+	if err != nil {
+		result = "failure"
+		p.logger.Warn("Failed to push metrics", "sink", p.sink.Name(), "error", err)
+	}
+	if p.pushesCounter != nil {
+		p.pushesCounter.WithLabelValues(p.sink.Name(), result).Inc()
+	}
+}
+
+// pushSample is one sample of a gathered metric
+type pushSample struct {
+	name    string
+	labels  []*dto.LabelPair // Sorted by name
+	value   float64
+	counter bool
+}
+
+// pushSamples flattens gathered metric families into samples
+func pushSamples(families []*dto.MetricFamily) []pushSample {
+	var samples []pushSample
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			sample := pushSample{name: family.GetName(), labels: metric.GetLabel()}
+			switch family.GetType() {
+			case dto.MetricType_GAUGE:
+				sample.value = metric.GetGauge().GetValue()
+			case dto.MetricType_COUNTER:
+				sample.value = metric.GetCounter().GetValue()
+				sample.counter = true
+			default:
+				continue // The exporter only emits gauges and counters
+			}
+			samples = append(samples, sample)
+		}
+	}
+	return samples
+}
+
+// metricPath builds a dot-separated metric path from a prefix, a metric name and its label
+// values, for sinks without labels. Empty label values become "none" so every series of a
+// metric has the same depth.
+func metricPath(prefix string, sample pushSample) string {
+	parts := make([]string, 0, len(sample.labels)+2)
+	if prefix != "" {
+		parts = append(parts, strings.Trim(prefix, "."))
+	}
+	parts = append(parts, sample.name)
+	for _, label := range sample.labels {
+		value := label.GetValue()
+		if value == "" {
+			value = "none"
+		}
+		parts = append(parts, sanitizePathComponent(value))
+	}
+	return strings.Join(parts, ".")
+}
+
+// sanitizePathComponent replaces the characters Graphite and StatsD give a meaning to, or
+// that are awkward in queries, with underscores
+func sanitizePathComponent(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -0,0 +1,97 @@
+package exporter
+
+import (
+	"context"
+	"io"
+	"math"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func pushTestFamilies(t *testing.T, errors float64) []*dto.MetricFamily {
+	t.Helper()
+	registry := prometheus.NewRegistry()
+	balance := newGaugeVec("test", "wallet_mempool_transactions")
+	balance.WithLabelValues("0x01", "calibration", "Client A", "client").Set(2)
+	runway := newGaugeVec("test", "wallet_fil_runway_days")
+	runway.WithLabelValues("0x01", "t410", "calibration", "client", "client", "", "", "", "", "").Set(math.Inf(1))
+	counter := newCounter("test", "scrape_errors_total")
+	counter.Add(errors)
+	registry.MustRegister(balance, runway, counter)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return families
+}
+
+func TestGraphiteSink(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		data, _ := io.ReadAll(conn)
+		conn.Close()
+		received <- string(data)
+	}()
+
+	sink := &graphiteSink{address: listener.Addr().String(), prefix: "wallets.prod"}
+	if err := sink.Push(context.Background(), pushTestFamilies(t, 3), time.Unix(1700000000, 0)); err != nil {
+		t.Fatal(err)
+	}
+	want := "wallets.prod.test_scrape_errors_total 3 1700000000\n" +
+		"wallets.prod.test_wallet_mempool_transactions.0x01.Client_A.calibration.client 2 1700000000\n"
+	if got := <-received; got != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, got)
+	}
+
+	sink.tags = true
+	if path := sink.path(pushSamples(pushTestFamilies(t, 0))[2]); path != "wallets.prod.test_wallet_mempool_transactions;address=0x01;name=Client_A;network=calibration;type=client" {
+		t.Errorf("Unexpected tagged path %s", path)
+	}
+}
+
+func TestStatsdSink(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	read := func() string {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		buf := make([]byte, 65536)
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(buf[:n])
+	}
+
+	sink := newStatsdSink(conn.LocalAddr().String(), "", true)
+	if err := sink.Push(context.Background(), pushTestFamilies(t, 3), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := read(), "test_scrape_errors_total:3|c\ntest_wallet_mempool_transactions:2|g|#address:0x01,name:Client A,network:calibration,type:client"; got != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, got)
+	}
+
+	// Counters are sent as the increase since the previous push
+	if err := sink.Push(context.Background(), pushTestFamilies(t, 5), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(); !strings.HasPrefix(got, "test_scrape_errors_total:2|c\n") {
+		t.Errorf("Expected a counter delta of 2, got\n%s", got)
+	}
+}
@@ -0,0 +1,317 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"wallet-exporter/internal/contracts"
+)
+
+// railsPageSize is the number of rails requested per getRailsFor*AndToken call
+const railsPageSize = 100
+
+// RailInfo holds the state of a Payments rail that a monitored wallet takes part in
+type RailInfo struct {
+	RailID       uint64
+	Role         string // "payer" or "payee" from the monitored wallet's point of view
+	Counterparty common.Address
+	Operator     common.Address
+	PaymentRate  *big.Int // Tokens per epoch
+	LockupPeriod *big.Int // Epochs
+	LockupFixed  *big.Int // Fixed lockup amount
+	SettledUpTo  *big.Int // Epoch
+	EndEpoch     *big.Int // 0 if the rail is not terminated
+	IsTerminated bool
+
+	// Net amount the payee would receive if the rail were settled now
+	// (only when EXPORT_PENDING_SETTLEMENT is enabled, nil if the preview failed)
+	PendingSettlement *big.Int
+}
+
+func (e *WalletExporter) registerRailMetrics() {
+	e.railPaymentRateGauge = newGaugeVec(e.config.MetricsPrefix, "rail_payment_rate")
+	e.railSettledUpToGauge = newGaugeVec(e.config.MetricsPrefix, "rail_settled_up_to_epoch")
+	e.railEndEpochGauge = newGaugeVec(e.config.MetricsPrefix, "rail_end_epoch")
+	e.railLockupFixedGauge = newGaugeVec(e.config.MetricsPrefix, "rail_lockup_fixed")
+	e.railLockupPeriodGauge = newGaugeVec(e.config.MetricsPrefix, "rail_lockup_period_epochs")
+
+	if e.config.ExportPendingSettlement {
+		e.railPendingSettlementGauge = newGaugeVec(e.config.MetricsPrefix, "rail_pending_settlement")
+		e.walletPendingSettlementGauge = newGaugeVec(e.config.MetricsPrefix, "wallet_pending_settlement")
+
+		e.registry.MustRegister(e.railPendingSettlementGauge)
+		e.registry.MustRegister(e.walletPendingSettlementGauge)
+	}
+
+	e.registry.MustRegister(e.railPaymentRateGauge)
+	e.registry.MustRegister(e.railSettledUpToGauge)
+	e.registry.MustRegister(e.railEndEpochGauge)
+	e.registry.MustRegister(e.railLockupFixedGauge)
+	e.registry.MustRegister(e.railLockupPeriodGauge)
+}
+
+// fetchRails fills in the Payments rails of every wallet concurrently
+func (e *WalletExporter) fetchRails(ctx context.Context, wallets []WalletInfo) {
+	paymentsAddr := common.HexToAddress(e.config.PaymentsAddress)
+	paymentsContract, err := contracts.NewPaymentsCaller(paymentsAddr, e.client)
+	if err != nil {
+		e.logger.Warn("Failed to create Payments contract", "error", err)
+		e.scrapeErrors.Inc()
+		return
+	}
+
+	// Settlement previews settle up to the current epoch
+	var currentEpoch *big.Int
+	if e.config.ExportPendingSettlement {
+		blockNumber, err := e.headBlock(ctx)
+		if err != nil {
+			e.logger.Warn("Failed to get current epoch for settlement preview", "error", err)
+			e.scrapeErrors.Inc()
+		} else {
+			currentEpoch = new(big.Int).SetUint64(blockNumber)
+		}
+	}
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, e.config.MaxConcurrentRPC)
+
+	for i := range wallets {
+		wg.Add(1)
+		go func(wallet *WalletInfo) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			rails, err := e.fetchWalletRails(ctx, paymentsContract, wallet.Address, currentEpoch)
+			if err != nil {
+				e.logger.Warn("Failed to fetch rails", "address", wallet.Address.Hex(), "error", err)
+				e.scrapeErrors.Inc()
+				return
+			}
+			wallet.Rails = rails
+		}(&wallets[i])
+	}
+
+	wg.Wait()
+}
+
+// fetchWalletRails lists the rails where the address is payer or payee and reads each rail.
+// Rails that are terminated and fully settled are skipped since they no longer move funds.
+// When currentEpoch is set, each rail's pending settlement is previewed as well.
+func (e *WalletExporter) fetchWalletRails(ctx context.Context, paymentsContract *contracts.PaymentsCaller, address common.Address, currentEpoch *big.Int) ([]RailInfo, error) {
+	usdfcAddr := common.HexToAddress(e.config.USDFCTokenAddress)
+
+	var rails []RailInfo
+	for _, role := range []string{"payer", "payee"} {
+		ids, err := e.listRailIDs(ctx, paymentsContract, role, address, usdfcAddr)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, id := range ids {
+			rail, err := paymentsContract.GetRail(callOpts(ctx), id)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get rail %s: %w", id, err)
+			}
+
+			isTerminated := rail.EndEpoch.Sign() > 0
+			if isTerminated && rail.SettledUpTo.Cmp(rail.EndEpoch) >= 0 {
+				continue
+			}
+
+			counterparty := rail.To
+			if role == "payee" {
+				counterparty = rail.From
+			}
+
+			info := RailInfo{
+				RailID:       id.Uint64(),
+				Role:         role,
+				Counterparty: counterparty,
+				Operator:     rail.Operator,
+				PaymentRate:  rail.PaymentRate,
+				LockupPeriod: rail.LockupPeriod,
+				LockupFixed:  rail.LockupFixed,
+				SettledUpTo:  rail.SettledUpTo,
+				EndEpoch:     rail.EndEpoch,
+				IsTerminated: isTerminated,
+			}
+
+			if currentEpoch != nil {
+				pending, err := e.previewSettlement(ctx, paymentsContract, id, rail.To, currentEpoch)
+				if err != nil {
+					e.logger.Debug("Failed to preview rail settlement", "rail_id", id, "error", err)
+				} else {
+					info.PendingSettlement = pending
+				}
+			}
+
+			rails = append(rails, info)
+		}
+	}
+
+	return rails, nil
+}
+
+// previewSettlement simulates settleRail up to the given epoch with an eth_call sent from
+// the payee, without submitting a transaction, and returns the net amount the payee would receive
+func (e *WalletExporter) previewSettlement(ctx context.Context, paymentsContract *contracts.PaymentsCaller, railID *big.Int, payee common.Address, untilEpoch *big.Int) (*big.Int, error) {
+	raw := &contracts.PaymentsCallerRaw{Contract: paymentsContract}
+	opts := &bind.CallOpts{From: payee, Context: ctx, BlockNumber: snapshotBlock(ctx)}
+
+	var out []interface{}
+	if err := raw.Call(opts, &out, "settleRail", railID, untilEpoch); err != nil {
+		return nil, fmt.Errorf("settleRail simulation failed: %w", err)
+	}
+
+	if len(out) < 2 {
+		return nil, fmt.Errorf("unexpected settleRail result length %d", len(out))
+	}
+
+	netPayeeAmount, ok := out[1].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("unexpected settleRail result type %T", out[1])
+	}
+
+	return netPayeeAmount, nil
+}
+
+// listRailIDs pages through getRailsForPayerAndToken / getRailsForPayeeAndToken
+func (e *WalletExporter) listRailIDs(ctx context.Context, paymentsContract *contracts.PaymentsCaller, role string, address, token common.Address) ([]*big.Int, error) {
+	rails, err := e.listRails(ctx, paymentsContract, role, address, token)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]*big.Int, 0, len(rails))
+	for _, rail := range rails {
+		ids = append(ids, rail.RailId)
+	}
+	return ids, nil
+}
+
+// listRails returns the rail summaries (ID and termination state) the paging calls report
+func (e *WalletExporter) listRails(ctx context.Context, paymentsContract *contracts.PaymentsCaller, role string, address, token common.Address) ([]contracts.FilecoinPayV1RailInfo, error) {
+	var rails []contracts.FilecoinPayV1RailInfo
+	offset := big.NewInt(0)
+	limit := big.NewInt(railsPageSize)
+
+	for {
+		var (
+			results    []contracts.FilecoinPayV1RailInfo
+			nextOffset *big.Int
+			total      *big.Int
+		)
+
+		if role == "payer" {
+			page, err := paymentsContract.GetRailsForPayerAndToken(callOpts(ctx), address, token, offset, limit)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list payer rails: %w", err)
+			}
+			results, nextOffset, total = page.Results, page.NextOffset, page.Total
+		} else {
+			page, err := paymentsContract.GetRailsForPayeeAndToken(callOpts(ctx), address, token, offset, limit)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list payee rails: %w", err)
+			}
+			results, nextOffset, total = page.Results, page.NextOffset, page.Total
+		}
+
+		rails = append(rails, results...)
+
+		if len(results) == 0 || nextOffset.Cmp(total) >= 0 || nextOffset.Cmp(offset) <= 0 {
+			return rails, nil
+		}
+		offset = nextOffset
+	}
+}
+
+func (e *WalletExporter) updateRailMetrics(wallets []WalletInfo) {
+	e.railPaymentRateGauge.Reset()
+	e.railSettledUpToGauge.Reset()
+	e.railEndEpochGauge.Reset()
+	e.railLockupFixedGauge.Reset()
+	e.railLockupPeriodGauge.Reset()
+	if e.config.ExportPendingSettlement {
+		e.railPendingSettlementGauge.Reset()
+		e.walletPendingSettlementGauge.Reset()
+	}
+
+	for _, wallet := range wallets {
+		walletPending := new(big.Int)
+		hasPending := false
+
+		for _, rail := range wallet.Rails {
+			labels := prometheus.Labels{
+				"address":      wallet.Address.Hex(),
+				"network":      e.config.Network,
+				"name":         wallet.Name,
+				"type":         wallet.Type,
+				"rail_id":      fmt.Sprintf("%d", rail.RailID),
+				"role":         rail.Role,
+				"counterparty": rail.Counterparty.Hex(),
+				"operator":     rail.Operator.Hex(),
+			}
+
+			paymentRateFloat, _ := new(big.Float).Quo(
+				new(big.Float).SetInt(rail.PaymentRate),
+				big.NewFloat(1e18),
+			).Float64()
+			e.railPaymentRateGauge.With(labels).Set(paymentRateFloat)
+
+			lockupFixedFloat, _ := new(big.Float).Quo(
+				new(big.Float).SetInt(rail.LockupFixed),
+				big.NewFloat(1e18),
+			).Float64()
+			e.railLockupFixedGauge.With(labels).Set(lockupFixedFloat)
+
+			// Epoch values are block numbers, not token amounts
+			settledUpToFloat, _ := new(big.Float).SetInt(rail.SettledUpTo).Float64()
+			e.railSettledUpToGauge.With(labels).Set(settledUpToFloat)
+
+			endEpochFloat, _ := new(big.Float).SetInt(rail.EndEpoch).Float64()
+			e.railEndEpochGauge.With(labels).Set(endEpochFloat)
+
+			lockupPeriodFloat, _ := new(big.Float).SetInt(rail.LockupPeriod).Float64()
+			e.railLockupPeriodGauge.With(labels).Set(lockupPeriodFloat)
+
+			if e.config.ExportPendingSettlement && rail.PendingSettlement != nil {
+				pendingFloat, _ := new(big.Float).Quo(
+					new(big.Float).SetInt(rail.PendingSettlement),
+					big.NewFloat(1e18),
+				).Float64()
+				e.railPendingSettlementGauge.With(labels).Set(pendingFloat)
+
+				if rail.Role == "payee" {
+					walletPending.Add(walletPending, rail.PendingSettlement)
+					hasPending = true
+				}
+			}
+		}
+
+		if hasPending {
+			providerID := ""
+			if wallet.Type == "provider" {
+				providerID = fmt.Sprintf("%d", wallet.ProviderID)
+			}
+
+			pendingFloat, _ := new(big.Float).Quo(
+				new(big.Float).SetInt(walletPending),
+				big.NewFloat(1e18),
+			).Float64()
+			e.walletPendingSettlementGauge.With(prometheus.Labels{
+				"address":     wallet.Address.Hex(),
+				"network":     e.config.Network,
+				"name":        wallet.Name,
+				"type":        wallet.Type,
+				"provider_id": providerID,
+			}).Set(pendingFloat)
+		}
+	}
+}
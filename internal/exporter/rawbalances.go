@@ -0,0 +1,29 @@
+package exporter
+
+import "math/big"
+
+// rawBalanceSplit is where raw balances are split for the lossless metrics. Both parts stay
+// below 2^53, so float64 samples hold them exactly: the low part is under 1e15 and the high
+// part, the balance in units of 0.001 tokens, is under 2^53 for balances below 9 trillion tokens.
+var rawBalanceSplit = big.NewInt(1e15)
+
+func (e *WalletExporter) registerRawBalanceMetrics() {
+	e.rawBalanceHighGauge = newGaugeVec(e.config.MetricsPrefix, "wallet_balance_raw_high")
+	e.rawBalanceLowGauge = newGaugeVec(e.config.MetricsPrefix, "wallet_balance_raw_low")
+
+	e.registry.MustRegister(e.rawBalanceHighGauge, e.rawBalanceLowGauge)
+}
+
+// splitRawBalance splits a raw token amount into parts with amount = high*1e15 + low
+func splitRawBalance(amount *big.Int) (high, low float64) {
+	h, l := new(big.Int).QuoRem(amount, rawBalanceSplit, new(big.Int))
+	return float64(h.Int64()), float64(l.Int64())
+}
+
+// setRawBalanceMetrics sets the lossless parts of a raw balance
+func (e *WalletExporter) setRawBalanceMetrics(labels *addressLabels, token string, amount *big.Int) {
+	high, low := splitRawBalance(amount)
+	tokenLabels := labels.withToken(token)
+	e.series.Set(e.rawBalanceHighGauge, tokenLabels, high)
+	e.series.Set(e.rawBalanceLowGauge, tokenLabels, low)
+}
@@ -0,0 +1,28 @@
+package exporter
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestSplitRawBalance(t *testing.T) {
+	for _, raw := range []string{
+		"0",
+		"999999999999999",
+		"123456789012345678901234567", // 123456789.012345678901234567 FIL, beyond float64 precision
+		"9000000000000000000000000000000",
+	} {
+		amount, _ := new(big.Int).SetString(raw, 10)
+		high, low := splitRawBalance(amount)
+
+		// Both parts must be exact integers in float64 for the reconstruction to hold
+		if high != float64(int64(high)) || low != float64(int64(low)) || low >= 1e15 {
+			t.Fatalf("Expected exact integer parts for %s, got %v and %v", raw, high, low)
+		}
+		got := new(big.Int).Mul(big.NewInt(int64(high)), rawBalanceSplit)
+		got.Add(got, big.NewInt(int64(low)))
+		if got.Cmp(amount) != 0 {
+			t.Errorf("Expected %s to be reconstructed exactly, got %s", raw, got)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// registrationScanChunksPerScrape bounds how many eth_getLogs ranges the registration
+// backfill requests per scrape, so catching up on history never stalls a scrape
+const registrationScanChunksPerScrape = 50
+
+// ProviderRegistration is when a provider registered in the registry. The registry has no
+// expiry, so registrations stay valid until the provider is removed.
+type ProviderRegistration struct {
+	Block     uint64 // Epoch of the ProviderRegistered event
+	Timestamp uint64 // Unix time of that block
+}
+
+func (e *WalletExporter) registerRegistrationMetrics() {
+	e.registeredBlockGauge = newGaugeVec(e.config.MetricsPrefix, "provider_registered_block")
+	e.registeredTimestampGauge = newGaugeVec(e.config.MetricsPrefix, "provider_registered_timestamp_seconds")
+
+	e.registry.MustRegister(e.registeredBlockGauge)
+	e.registry.MustRegister(e.registeredTimestampGauge)
+}
+
+// scanRegistrations walks ProviderRegistered events forward from REGISTRY_START_BLOCK,
+// at most registrationScanChunksPerScrape ranges per call, and records the block and
+// time of every registration. A failed range is retried on the next scrape.
+func (e *WalletExporter) scanRegistrations(ctx context.Context) {
+	head, err := e.headBlock(ctx)
+	if err != nil {
+		e.logger.Warn("Failed to get head block for provider registrations", "error", err)
+		e.scrapeErrors.Inc()
+		return
+	}
+
+	for chunk := 0; chunk < registrationScanChunksPerScrape && e.registrationNextBlock <= head; chunk++ {
+		start := e.registrationNextBlock
+		end := min(start+eventsMaxBlockRange-1, head)
+
+		if err := e.filterRegistrations(ctx, &bind.FilterOpts{Start: start, End: &end, Context: ctx}); err != nil {
+			e.logger.Warn("Failed to scan provider registrations", "blocks", fmt.Sprintf("%d-%d", start, end), "error", err)
+			e.scrapeErrors.Inc()
+			return
+		}
+		e.registrationNextBlock = end + 1
+	}
+
+	if e.registrationNextBlock <= head {
+		e.logger.Info("Provider registration backfill in progress", "next_block", e.registrationNextBlock, "head", head)
+	}
+}
+
+func (e *WalletExporter) filterRegistrations(ctx context.Context, opts *bind.FilterOpts) error {
+	it, err := e.registryContract.FilterProviderRegistered(opts, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	for it.Next() {
+		block := it.Event.Raw.BlockNumber
+		header, err := e.client.HeaderByNumber(ctx, new(big.Int).SetUint64(block))
+		if err != nil {
+			return fmt.Errorf("failed to get block %d: %w", block, err)
+		}
+
+		e.registrations[it.Event.ProviderId.Uint64()] = ProviderRegistration{
+			Block:     block,
+			Timestamp: header.Time,
+		}
+	}
+	return it.Error()
+}
+
+func (e *WalletExporter) updateRegistrationMetrics(wallets []WalletInfo) {
+	e.registeredBlockGauge.Reset()
+	e.registeredTimestampGauge.Reset()
+
+	for _, wallet := range wallets {
+		if wallet.Type != "provider" {
+			continue
+		}
+		registration, ok := e.registrations[wallet.ProviderID]
+		if !ok {
+			continue
+		}
+
+		labels := prometheus.Labels{
+			"address":     wallet.Address.Hex(),
+			"network":     e.config.Network,
+			"name":        wallet.Name,
+			"provider_id": fmt.Sprintf("%d", wallet.ProviderID),
+		}
+		e.registeredBlockGauge.With(labels).Set(float64(registration.Block))
+		e.registeredTimestampGauge.With(labels).Set(float64(registration.Timestamp))
+	}
+}
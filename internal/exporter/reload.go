@@ -0,0 +1,47 @@
+package exporter
+
+import (
+	"context"
+
+	"wallet-exporter/internal/config"
+)
+
+// reloadRequest hands a reloaded configuration to the scrape loop
+type reloadRequest struct {
+	cfg  *config.Config
+	done chan struct{}
+}
+
+// Reload applies the wallet list of a reloaded configuration: CUSTOM_WALLET_N entries,
+// including multisigs. Other settings only take effect after a restart. The change is applied
+// by the scrape loop, which scrapes right away; Reload returns once it has been applied.
+func (e *WalletExporter) Reload(ctx context.Context, cfg *config.Config) error {
+	req := reloadRequest{cfg: cfg, done: make(chan struct{})}
+	select {
+	case e.reloads <- req:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	<-req.done
+	return nil
+}
+
+// applyReload swaps in the new wallet list (only called by the scrape loop)
+func (e *WalletExporter) applyReload(cfg *config.Config) {
+	if len(cfg.MultisigWallets) > 0 && e.msigBalanceGauge == nil {
+		e.registerMultisigMetrics()
+	}
+	if len(cfg.MultisigWallets) == 0 && e.msigBalanceGauge != nil {
+		// The scrape skips multisigs without any configured; drop the removed ones now
+		e.updateMultisigMetrics(nil)
+	}
+
+	e.logger.Info("Reloaded wallet list",
+		"custom_wallets", len(cfg.CustomWallets),
+		"previous_custom_wallets", len(e.config.CustomWallets),
+		"multisig_wallets", len(cfg.MultisigWallets),
+		"previous_multisig_wallets", len(e.config.MultisigWallets),
+	)
+	e.config.CustomWallets = cfg.CustomWallets
+	e.config.MultisigWallets = cfg.MultisigWallets
+}
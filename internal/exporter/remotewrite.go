@@ -0,0 +1,158 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"wallet-exporter/internal/config"
+)
+
+// remoteWriter sends metrics to a Prometheus remote-write endpoint, for exporters that cannot
+// be scraped
+type remoteWriter struct {
+	url         string
+	username    string // Basic auth (empty = none)
+	password    string
+	bearerToken string            // Bearer auth (empty = none)
+	headers     map[string]string // Extra headers, e.g. X-Scope-OrgID
+	labels      map[string]string // Labels added to every series, e.g. job and instance
+	client      *http.Client
+}
+
+func newRemoteWriter(cfg *config.Config) *remoteWriter {
+	return &remoteWriter{
+		url:         cfg.RemoteWriteURL,
+		username:    cfg.RemoteWriteUsername,
+		password:    cfg.RemoteWritePassword,
+		bearerToken: cfg.RemoteWriteBearerToken,
+		headers:     cfg.RemoteWriteHeaders,
+		labels:      cfg.RemoteWriteLabels,
+		client:      &http.Client{},
+	}
+}
+
+func (w *remoteWriter) Name() string { return "remote_write" }
+
+// Push sends the metrics as one write request, every sample stamped with now
+func (w *remoteWriter) Push(ctx context.Context, families []*dto.MetricFamily, now time.Time) error {
+	series := remoteWriteSeries(families, w.labels)
+	body := snappy.Encode(nil, encodeWriteRequest(series, now.UnixMilli()))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	req.Header.Set("User-Agent", "wallet-exporter")
+	for name, value := range w.headers {
+		req.Header.Set(name, value)
+	}
+	switch {
+	case w.bearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+w.bearerToken)
+	case w.username != "":
+		req.SetBasicAuth(w.username, w.password)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("remote write endpoint returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// remoteWriteLabel is a label of a series; remote write requires them sorted by name
+type remoteWriteLabel struct {
+	name, value string
+}
+
+// remoteSeries is one sample of a series
+type remoteSeries struct {
+	labels []remoteWriteLabel
+	value  float64
+}
+
+// remoteWriteSeries flattens gathered metric families into series, adding the extra labels
+// unless a metric has a label of that name itself
+func remoteWriteSeries(families []*dto.MetricFamily, extra map[string]string) []remoteSeries {
+	var series []remoteSeries
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			var value float64
+			switch {
+			case metric.GetGauge() != nil:
+				value = metric.GetGauge().GetValue()
+			case metric.GetCounter() != nil:
+				value = metric.GetCounter().GetValue()
+			case metric.GetUntyped() != nil:
+				value = metric.GetUntyped().GetValue()
+			default:
+				continue // The exporter only emits gauges and counters
+			}
+
+			labels := []remoteWriteLabel{{"__name__", family.GetName()}}
+			own := make(map[string]bool, len(metric.GetLabel()))
+			for _, label := range metric.GetLabel() {
+				labels = append(labels, remoteWriteLabel{label.GetName(), label.GetValue()})
+				own[label.GetName()] = true
+			}
+			for name, value := range extra {
+				if !own[name] {
+					labels = append(labels, remoteWriteLabel{name, value})
+				}
+			}
+			sort.Slice(labels, func(i, j int) bool { return labels[i].name < labels[j].name })
+			series = append(series, remoteSeries{labels: labels, value: value})
+		}
+	}
+	return series
+}
+
+// encodeWriteRequest encodes a prometheus.WriteRequest protobuf message (remote write 1.0)
+// holding one sample per series
+func encodeWriteRequest(series []remoteSeries, timestampMs int64) []byte {
+	var out []byte
+	for _, s := range series {
+		var ts []byte
+		for _, label := range s.labels {
+			var l []byte
+			l = protowire.AppendTag(l, 1, protowire.BytesType)
+			l = protowire.AppendString(l, label.name)
+			l = protowire.AppendTag(l, 2, protowire.BytesType)
+			l = protowire.AppendString(l, label.value)
+			ts = protowire.AppendTag(ts, 1, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, l)
+		}
+
+		var sample []byte
+		sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+		sample = protowire.AppendFixed64(sample, math.Float64bits(s.value))
+		sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+		sample = protowire.AppendVarint(sample, uint64(timestampMs))
+		ts = protowire.AppendTag(ts, 2, protowire.BytesType)
+		ts = protowire.AppendBytes(ts, sample)
+
+		out = protowire.AppendTag(out, 1, protowire.BytesType)
+		out = protowire.AppendBytes(out, ts)
+	}
+	return out
+}
@@ -0,0 +1,137 @@
+package exporter
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// decodeWriteRequest decodes the series of a write request into label sets and values
+func decodeWriteRequest(t *testing.T, data []byte) ([]map[string]string, []float64, []int64) {
+	t.Helper()
+	var labelSets []map[string]string
+	var values []float64
+	var timestamps []int64
+	fields := func(b []byte, field func(num protowire.Number, typ protowire.Type, b []byte) int) {
+		for len(b) > 0 {
+			num, typ, n := protowire.ConsumeTag(b)
+			if n < 0 {
+				t.Fatalf("Malformed tag")
+			}
+			b = b[n:]
+			n = field(num, typ, b)
+			if n < 0 {
+				t.Fatalf("Malformed field %d", num)
+			}
+			b = b[n:]
+		}
+	}
+	fields(data, func(_ protowire.Number, _ protowire.Type, b []byte) int {
+		series, n := protowire.ConsumeBytes(b)
+		labels := make(map[string]string)
+		fields(series, func(num protowire.Number, _ protowire.Type, b []byte) int {
+			msg, n := protowire.ConsumeBytes(b)
+			if num == 1 {
+				var name, value string
+				fields(msg, func(num protowire.Number, _ protowire.Type, b []byte) int {
+					s, n := protowire.ConsumeString(b)
+					if num == 1 {
+						name = s
+					} else {
+						value = s
+					}
+					return n
+				})
+				labels[name] = value
+				return n
+			}
+			fields(msg, func(num protowire.Number, typ protowire.Type, b []byte) int {
+				if num == 1 {
+					v, n := protowire.ConsumeFixed64(b)
+					values = append(values, math.Float64frombits(v))
+					return n
+				}
+				v, n := protowire.ConsumeVarint(b)
+				timestamps = append(timestamps, int64(v))
+				return n
+			})
+			return n
+		})
+		labelSets = append(labelSets, labels)
+		return n
+	})
+	return labelSets, values, timestamps
+}
+
+func TestRemoteWriterPush(t *testing.T) {
+	var body []byte
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		compressed, _ := io.ReadAll(r.Body)
+		var err error
+		if body, err = snappy.Decode(nil, compressed); err != nil {
+			t.Errorf("Body is not snappy compressed: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	registry := prometheus.NewRegistry()
+	gauge := newGaugeVec("test", "wallet_fil_balance")
+	gauge.WithLabelValues("0x01", "t410", "calibration", "client", "client", "", "", "", "", "").Set(12.5)
+	registry.MustRegister(gauge)
+
+	w := &remoteWriter{
+		url:      server.URL,
+		username: "user",
+		password: "secret",
+		headers:  map[string]string{"X-Scope-OrgID": "wallets"},
+		labels:   map[string]string{"job": "wallet-exporter", "name": "ignored"},
+		client:   server.Client(),
+	}
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.UnixMilli(1_700_000_000_000)
+	if err := w.Push(context.Background(), families, now); err != nil {
+		t.Fatal(err)
+	}
+
+	if user, password, _ := (&http.Request{Header: header}).BasicAuth(); user != "user" || password != "secret" {
+		t.Errorf("Expected basic auth, got %q:%q", user, password)
+	}
+	if header.Get("X-Scope-OrgID") != "wallets" || header.Get("Content-Encoding") != "snappy" {
+		t.Errorf("Unexpected headers %v", header)
+	}
+
+	labelSets, values, timestamps := decodeWriteRequest(t, body)
+	if len(labelSets) != 1 || values[0] != 12.5 || timestamps[0] != now.UnixMilli() {
+		t.Fatalf("Unexpected series %v values %v timestamps %v", labelSets, values, timestamps)
+	}
+	labels := labelSets[0]
+	if labels["__name__"] != "test_wallet_fil_balance" || labels["job"] != "wallet-exporter" || labels["name"] != "client" {
+		t.Errorf("Unexpected labels %v", labels)
+	}
+}
+
+func TestRemoteWriterError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "out of order sample", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	w := &remoteWriter{url: server.URL, client: server.Client()}
+	if err := w.Push(context.Background(), nil, time.Now()); err == nil {
+		t.Error("Expected an error for a 400 response")
+	}
+}
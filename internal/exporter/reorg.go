@@ -0,0 +1,79 @@
+package exporter
+
+import (
+	"context"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// blockRef identifies a block a scrape read at
+type blockRef struct {
+	Number hexutil.Uint64 `json:"number"`
+	Hash   common.Hash    `json:"hash"`
+}
+
+func (e *WalletExporter) registerReorgMetrics() {
+	e.reorgsCounter = newCounter(e.config.MetricsPrefix, "chain_reorg_detected_total")
+
+	e.registry.MustRegister(e.reorgsCounter)
+}
+
+// blockAt reads the number and hash of a block by number or tag
+func (e *WalletExporter) blockAt(ctx context.Context, block string) (blockRef, error) {
+	var ref blockRef
+	callCtx, cancel := e.client.withTimeout(ctx)
+	defer cancel()
+	err := e.client.Client().CallContext(callCtx, &ref, "eth_getBlockByNumber", block, false)
+	return ref, err
+}
+
+// snapshotRef returns the block a scrape reads at: the probed head, or the block of
+// QUERY_BLOCK_TAG. Nodes without support for the tag fall back to the head. A zero head means
+// the probe failed, and reads use the latest block as they would without a snapshot.
+func (e *WalletExporter) snapshotRef(ctx context.Context, head uint64) blockRef {
+	if head == 0 {
+		return blockRef{}
+	}
+
+	block := hexutil.EncodeUint64(head)
+	if e.config.QueryBlockTag != "latest" {
+		block = e.config.QueryBlockTag
+	}
+	ref, err := e.blockAt(ctx, block)
+	if err == nil && ref.Hash != (common.Hash{}) {
+		return ref
+	}
+
+	if e.config.QueryBlockTag != "latest" {
+		e.logger.Warn("Failed to read tagged block, reading at the head instead", "tag", e.config.QueryBlockTag, "error", err)
+	}
+	return blockRef{Number: hexutil.Uint64(head)} // Without a hash, reorgs of this block go unnoticed
+}
+
+// checkReorg reports whether the block the last scrape read at is no longer on the chain, so
+// its balances came from a fork that was abandoned. A block replaced by a null round counts too.
+// After a reorg the next scrape reads every balance again, even with INCREMENTAL_REFRESH.
+func (e *WalletExporter) checkReorg(ctx context.Context) bool {
+	observed := e.observedBlock
+	if observed.Hash == (common.Hash{}) {
+		return false
+	}
+
+	current, err := e.blockAt(ctx, hexutil.EncodeUint64(uint64(observed.Number)))
+	if err != nil && !isNullRound(err) {
+		e.logger.Debug("Failed to check the last snapshot block for a reorg", "block", uint64(observed.Number), "error", err)
+		return false
+	}
+	if err == nil && (current.Hash == observed.Hash || current.Hash == (common.Hash{})) {
+		return false // Unchanged, or not known to a node that is behind
+	}
+
+	e.logger.Warn("Chain reorg detected, the last snapshot block is no longer on the chain",
+		"block", uint64(observed.Number), "observed_hash", observed.Hash.Hex(), "hash", current.Hash.Hex())
+	e.reorgsCounter.Inc()
+	e.observedBlock = blockRef{}
+	e.lastFullRefresh = time.Time{}
+	return true
+}
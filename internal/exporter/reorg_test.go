@@ -0,0 +1,96 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"wallet-exporter/internal/config"
+)
+
+func TestReorgDetection(t *testing.T) {
+	// Block hashes by block parameter; changed below to simulate reorgs
+	blocks := map[string]string{
+		`"0x64"`:      common.Hash{1}.Hex(),
+		`"finalized"`: common.Hash{2}.Hex(),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Params []json.RawMessage `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		response := map[string]any{"jsonrpc": "2.0", "id": req.ID}
+		switch hash, ok := blocks[string(req.Params[0])]; {
+		case hash == "null round":
+			response["error"] = map[string]any{"code": 1, "message": "requested epoch was a null round"}
+		case ok:
+			response["result"] = map[string]any{"number": "0x64", "hash": hash}
+		default:
+			response["error"] = map[string]any{"code": -32602, "message": "invalid block tag"}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	client, err := newRPCClient(func() (*ethclient.Client, error) { return ethclient.Dial(server.URL) }, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	e := &WalletExporter{
+		config:        &config.Config{QueryBlockTag: "latest"},
+		client:        client,
+		reorgsCounter: prometheus.NewCounter(prometheus.CounterOpts{Name: "reorgs"}),
+		logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	ctx := context.Background()
+
+	snapshot := e.snapshotRef(ctx, 100)
+	if snapshot.Number != 100 || snapshot.Hash != (common.Hash{1}) {
+		t.Fatalf("Expected the probed head with its hash, got %+v", snapshot)
+	}
+	e.config.QueryBlockTag = "finalized"
+	if tagged := e.snapshotRef(ctx, 100); tagged.Hash != (common.Hash{2}) {
+		t.Errorf("Expected the finalized block, got %+v", tagged)
+	}
+	e.config.QueryBlockTag = "safe"
+	if fallback := e.snapshotRef(ctx, 100); fallback.Number != 100 || fallback.Hash != (common.Hash{}) {
+		t.Errorf("Expected an unsupported tag to fall back to the head without a hash, got %+v", fallback)
+	}
+
+	e.observedBlock = snapshot
+	if e.checkReorg(ctx) {
+		t.Error("Expected no reorg while the block is unchanged")
+	}
+
+	e.lastFullRefresh = time.Now()
+	blocks[`"0x64"`] = common.Hash{3}.Hex()
+	if !e.checkReorg(ctx) || !e.lastFullRefresh.IsZero() {
+		t.Error("Expected a reorg once the block hash changed, followed by a full refresh")
+	}
+	if e.checkReorg(ctx) {
+		t.Error("Expected a reorg to be reported once")
+	}
+
+	e.observedBlock = blockRef{Number: 100, Hash: common.Hash{3}}
+	blocks[`"0x64"`] = "null round"
+	if !e.checkReorg(ctx) {
+		t.Error("Expected a block replaced by a null round to count as a reorg")
+	}
+	if got := testutil.ToFloat64(e.reorgsCounter); got != 2 {
+		t.Errorf("Expected 2 reorgs counted, got %v", got)
+	}
+}
@@ -0,0 +1,166 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"wallet-exporter/internal/config"
+)
+
+// Reasons a request is retried, used as the "reason" label of the retry counter
+const (
+	retryRateLimited = "rate_limited"
+	retryUnavailable = "unavailable"
+	retryTimeout     = "timeout"
+	retryConnection  = "connection"
+)
+
+// retryPolicy is how often and how patiently a failed RPC request is retried
+type retryPolicy struct {
+	Attempts       int // Total attempts per request, including the first
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// backoff returns the wait before the given retry (1 = first retry). It doubles from
+// InitialBackoff up to MaxBackoff, with up to 20% jitter so clients do not retry in lockstep.
+func (p retryPolicy) backoff(retry int) time.Duration {
+	wait := p.InitialBackoff
+	for i := 1; i < retry && wait < p.MaxBackoff; i++ {
+		wait *= 2
+	}
+	wait = min(wait, p.MaxBackoff)
+
+	if jitter := int64(wait) / 5; jitter > 0 {
+		wait += time.Duration(rand.Int64N(jitter))
+	}
+	return wait
+}
+
+// retryTransport retries RPC HTTP requests that fail with a retryable error. Retrying at
+// the transport covers every binding, client and batch call without wrapping each one.
+type retryTransport struct {
+	base    http.RoundTripper
+	policy  retryPolicy
+	retries *prometheus.CounterVec // By reason
+	logger  *slog.Logger
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(req)
+		if ctx.Err() != nil {
+			return resp, err
+		}
+		reason := retryReason(resp, err)
+		if reason == "" || attempt >= t.policy.Attempts {
+			return resp, err
+		}
+
+		wait := t.policy.backoff(attempt)
+		if resp != nil {
+			if retryAfter := retryAfterDelay(resp); retryAfter > 0 {
+				wait = min(retryAfter, t.policy.MaxBackoff)
+			}
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		t.retries.WithLabelValues(reason).Inc()
+		t.logger.Debug("Retrying RPC request", "reason", reason, "attempt", attempt, "wait", wait)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryReason classifies the outcome of a request and returns why it should be retried, or ""
+// if it succeeded or failed in a way a retry will not fix
+func retryReason(resp *http.Response, err error) string {
+	if err != nil {
+		var netErr net.Error
+		switch {
+		case errors.Is(err, context.Canceled):
+			return ""
+		case errors.As(err, &netErr) && netErr.Timeout():
+			return retryTimeout
+		case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED),
+			errors.Is(err, syscall.EPIPE), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+			return retryConnection
+		}
+		return ""
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return retryRateLimited
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return retryUnavailable
+	}
+	return ""
+}
+
+// retryAfterDelay returns the delay a Retry-After header asks for, or 0 if there is none.
+// Only the delay-seconds form is supported.
+func retryAfterDelay(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// dialRPC connects to the RPC endpoint through a transport that retries transient failures,
+// sending token as a bearer token if set. Retries only apply to HTTP endpoints; websocket and
+// IPC endpoints are dialed as is.
+func dialRPC(cfg *config.Config, token string, retries *prometheus.CounterVec, logger *slog.Logger) (*ethclient.Client, error) {
+	transport := &retryTransport{
+		base: http.DefaultTransport,
+		policy: retryPolicy{
+			Attempts:       cfg.RPCRetryAttempts,
+			InitialBackoff: cfg.RPCRetryInitialBackoff,
+			MaxBackoff:     cfg.RPCRetryMaxBackoff,
+		},
+		retries: retries,
+		logger:  logger,
+	}
+
+	options := []rpc.ClientOption{rpc.WithHTTPClient(&http.Client{Transport: transport})}
+	if token != "" {
+		options = append(options, rpc.WithHeader("Authorization", "Bearer "+token))
+	}
+
+	rpcClient, err := rpc.DialOptions(context.Background(), cfg.RPCURL, options...)
+	if err != nil {
+		return nil, err
+	}
+	return ethclient.NewClient(rpcClient), nil
+}
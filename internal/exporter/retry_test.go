@@ -0,0 +1,119 @@
+package exporter
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func newTestRetryTransport(attempts int) *retryTransport {
+	return &retryTransport{
+		base:    http.DefaultTransport,
+		policy:  retryPolicy{Attempts: attempts, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond},
+		retries: newCounterVec("test", "rpc_retries_total"),
+		logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+}
+
+func TestRetryTransportRetriesRateLimit(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "request" {
+			t.Errorf("Expected the request body on attempt %d, got %q", calls, body)
+		}
+		if calls < 3 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	transport := newTestRetryTransport(3)
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Post(server.URL, "application/json", strings.NewReader("request"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 after retries, got %d", resp.StatusCode)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 attempts, got %d", calls)
+	}
+	if got := testutil.ToFloat64(transport.retries.WithLabelValues(retryRateLimited)); got != 2 {
+		t.Errorf("Expected 2 counted retries, got %f", got)
+	}
+}
+
+func TestRetryTransportGivesUp(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: newTestRetryTransport(2)}
+
+	resp, err := client.Post(server.URL, "application/json", strings.NewReader("request"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected the last 503 to be returned, got %d", resp.StatusCode)
+	}
+	if calls != 2 {
+		t.Errorf("Expected 2 attempts, got %d", calls)
+	}
+}
+
+func TestRetryReason(t *testing.T) {
+	tests := []struct {
+		status int
+		want   string
+	}{
+		{http.StatusOK, ""},
+		{http.StatusBadRequest, ""},
+		{http.StatusTooManyRequests, retryRateLimited},
+		{http.StatusBadGateway, retryUnavailable},
+		{http.StatusGatewayTimeout, retryUnavailable},
+	}
+
+	for _, tt := range tests {
+		if got := retryReason(&http.Response{StatusCode: tt.status}, nil); got != tt.want {
+			t.Errorf("retryReason(%d) = %q, want %q", tt.status, got, tt.want)
+		}
+	}
+
+	if got := retryReason(nil, io.ErrUnexpectedEOF); got != retryConnection {
+		t.Errorf("Expected an unexpected EOF to be a connection error, got %q", got)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := retryPolicy{Attempts: 10, InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	if wait := policy.backoff(1); wait < 100*time.Millisecond || wait >= 120*time.Millisecond {
+		t.Errorf("Expected the first retry to wait about 100ms, got %s", wait)
+	}
+	if wait := policy.backoff(3); wait < 400*time.Millisecond || wait >= 480*time.Millisecond {
+		t.Errorf("Expected the third retry to wait about 400ms, got %s", wait)
+	}
+	if wait := policy.backoff(8); wait < time.Second || wait >= 1200*time.Millisecond {
+		t.Errorf("Expected the wait to be capped at 1s, got %s", wait)
+	}
+}
@@ -0,0 +1,74 @@
+package exporter
+
+import (
+	"sort"
+)
+
+// selectProviderIDs returns the provider IDs (1..total) to fetch in this scrape.
+// When MAX_PROVIDERS_PER_SCRAPE caps the registry, a window of IDs is taken starting
+// at the rotation cursor so every provider is refreshed within ceil(total/cap) scrapes.
+func (e *WalletExporter) selectProviderIDs(total uint64) []uint64 {
+	limit := uint64(e.config.MaxProvidersPerScrape)
+	if limit == 0 || total <= limit {
+		ids := make([]uint64, 0, total)
+		for id := uint64(1); id <= total; id++ {
+			ids = append(ids, id)
+		}
+		e.providerCursor = 0
+		return ids
+	}
+
+	e.logger.Warn("Provider registry exceeds per-scrape cap, rotating across scrapes",
+		"total", total,
+		"max_providers_per_scrape", limit,
+		"start_id", e.providerCursor%total+1,
+	)
+
+	ids := make([]uint64, 0, limit)
+	for i := uint64(0); i < limit; i++ {
+		ids = append(ids, (e.providerCursor+i)%total+1)
+	}
+	e.providerCursor = (e.providerCursor + limit) % total
+
+	return ids
+}
+
+// mergeCachedProviders remembers freshly fetched providers and fills in the last known
+// state of providers that were not part of this scrape's window, so their series keep
+// being exported (with older data) instead of disappearing.
+func (e *WalletExporter) mergeCachedProviders(fresh []WalletInfo, selected []uint64, total uint64) []WalletInfo {
+	fetched := make(map[uint64]bool, len(fresh))
+	for _, wallet := range fresh {
+		e.providerCache[wallet.ProviderID] = wallet
+		fetched[wallet.ProviderID] = true
+	}
+
+	inWindow := make(map[uint64]bool, len(selected))
+	for _, id := range selected {
+		inWindow[id] = true
+	}
+
+	merged := append([]WalletInfo{}, fresh...)
+	for id, wallet := range e.providerCache {
+		if id > total {
+			// Provider no longer exists in the registry
+			delete(e.providerCache, id)
+			continue
+		}
+		if !inWindow[id] {
+			merged = append(merged, wallet)
+		}
+	}
+
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].ProviderID < merged[j].ProviderID
+	})
+
+	if total > 0 {
+		e.providerCoverageGauge.Set(float64(len(fetched)) / float64(total))
+	} else {
+		e.providerCoverageGauge.Set(1)
+	}
+
+	return merged
+}
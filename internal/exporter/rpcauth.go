@@ -0,0 +1,107 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rpcTokenCommandTimeout bounds a run of RPC_TOKEN_COMMAND
+const rpcTokenCommandTimeout = 30 * time.Second
+
+// rpcToken is the bearer token sent to the RPC endpoint. It is read from RPC_TOKEN_FILE or
+// the output of RPC_TOKEN_COMMAND whenever the client is dialed, so rotated tokens are picked
+// up by reconnecting.
+type rpcToken struct {
+	static  string // RPC_TOKEN
+	file    string // RPC_TOKEN_FILE
+	command string // RPC_TOKEN_COMMAND
+
+	mu    sync.Mutex
+	inUse string // Token of the current connection
+}
+
+// rotates reports whether the token can change while the exporter runs
+func (t *rpcToken) rotates() bool {
+	return t.file != "" || t.command != ""
+}
+
+// read returns the current token without marking it as in use
+func (t *rpcToken) read() (string, error) {
+	switch {
+	case t.file != "":
+		data, err := os.ReadFile(t.file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read RPC token file: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	case t.command != "":
+		ctx, cancel := context.WithTimeout(context.Background(), rpcTokenCommandTimeout)
+		defer cancel()
+		out, err := exec.CommandContext(ctx, "sh", "-c", t.command).Output()
+		if err != nil {
+			return "", fmt.Errorf("RPC token command failed: %w", err)
+		}
+		return strings.TrimSpace(string(out)), nil
+	default:
+		return t.static, nil
+	}
+}
+
+// dialToken reads the token for a new connection and records it as in use
+func (t *rpcToken) dialToken() (string, error) {
+	token, err := t.read()
+	if err != nil {
+		return "", err
+	}
+
+	t.mu.Lock()
+	t.inUse = token
+	t.mu.Unlock()
+	return token, nil
+}
+
+// changed reads the token and reports whether it differs from the one in use
+func (t *rpcToken) changed() (bool, error) {
+	token, err := t.read()
+	if err != nil {
+		return false, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return token != t.inUse, nil
+}
+
+// watchRPCToken checks the RPC token every RPC_TOKEN_REFRESH and reconnects the client when
+// it changed. Calls in flight on the old connection fail and are picked up by the next scrape.
+func (e *WalletExporter) watchRPCToken(ctx context.Context) {
+	ticker := time.NewTicker(e.config.RPCTokenRefresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := e.rpcToken.changed()
+			if err != nil {
+				e.logger.Warn("Failed to refresh RPC token", "error", err)
+				continue
+			}
+			if !changed {
+				continue
+			}
+
+			if err := e.client.Reconnect(); err != nil {
+				e.logger.Warn("Failed to reconnect with the new RPC token", "error", err)
+				continue
+			}
+			e.logger.Info("RPC token changed, reconnected to RPC endpoint")
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package exporter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRPCTokenFileChanges(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(file, []byte("first\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	token := &rpcToken{file: file}
+	if bearer, err := token.dialToken(); err != nil || bearer != "first" {
+		t.Fatalf("Expected token first, got %q (%v)", bearer, err)
+	}
+	if changed, _ := token.changed(); changed {
+		t.Error("Expected an unchanged file not to report a change")
+	}
+
+	if err := os.WriteFile(file, []byte("second\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if changed, _ := token.changed(); !changed {
+		t.Error("Expected a rewritten file to report a change")
+	}
+}
+
+func TestRPCTokenCommand(t *testing.T) {
+	token := &rpcToken{command: "echo s3cr3t"}
+	if bearer, err := token.read(); err != nil || bearer != "s3cr3t" {
+		t.Errorf("Expected token s3cr3t, got %q (%v)", bearer, err)
+	}
+}
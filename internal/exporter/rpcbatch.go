@@ -0,0 +1,63 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// batchRPC sends the calls as eth_call and eth_getBalance requests in JSON-RPC batches of at
+// most RPC_BATCH_SIZE requests. Failed requests are passed to their decoder as errors; a
+// failed batch fails the whole call.
+func (e *WalletExporter) batchRPC(ctx context.Context, calls []batchedCall) error {
+	size := e.config.RPCBatchSize
+	for start := 0; start < len(calls); start += size {
+		chunk := calls[start:min(start+size, len(calls))]
+
+		elems := make([]rpc.BatchElem, len(chunk))
+		for i, c := range chunk {
+			if c.data == nil {
+				elems[i] = rpc.BatchElem{
+					Method: "eth_getBalance",
+					Args:   []interface{}{c.target, blockArg(ctx)},
+					Result: new(hexutil.Big),
+				}
+				continue
+			}
+			elems[i] = rpc.BatchElem{
+				Method: "eth_call",
+				Args:   []interface{}{map[string]interface{}{"to": c.target, "data": hexutil.Bytes(c.data)}, blockArg(ctx)},
+				Result: new(hexutil.Bytes),
+			}
+		}
+
+		if err := e.sendBatch(ctx, elems); err != nil {
+			return fmt.Errorf("batch of %d requests failed: %w", len(elems), err)
+		}
+
+		for i, elem := range elems {
+			if elem.Error != nil {
+				chunk[i].decode(nil, elem.Error)
+				continue
+			}
+			switch result := elem.Result.(type) {
+			case *hexutil.Big:
+				// Encode like getEthBalance so decoders do not depend on how the batch was sent
+				chunk[i].decode(common.LeftPadBytes(result.ToInt().Bytes(), 32), nil)
+			case *hexutil.Bytes:
+				chunk[i].decode(*result, nil)
+			}
+		}
+	}
+	return nil
+}
+
+// sendBatch sends one JSON-RPC batch, bounded by the per-call timeout like any other call
+func (e *WalletExporter) sendBatch(ctx context.Context, elems []rpc.BatchElem) error {
+	ctx, cancel := e.client.withTimeout(ctx)
+	defer cancel()
+	return e.client.Client().BatchCallContext(ctx, elems)
+}
@@ -0,0 +1,163 @@
+package exporter
+
+import (
+	"context"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// rpcClient is an ethclient that can be redialed while in use. Contract bindings are created
+// on top of it, so a reconnect takes effect everywhere without rebinding them. Every call is
+// bounded by the per-call timeout, including its retries.
+type rpcClient struct {
+	current atomic.Pointer[ethclient.Client]
+	dial    func() (*ethclient.Client, error)
+	timeout time.Duration // 0 = calls are only bounded by their context
+}
+
+func newRPCClient(dial func() (*ethclient.Client, error), timeout time.Duration) (*rpcClient, error) {
+	client, err := dial()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &rpcClient{dial: dial, timeout: timeout}
+	c.current.Store(client)
+	return c, nil
+}
+
+// Reconnect dials a new connection and closes the old one. Calls in flight on the old
+// connection fail; calls made afterwards use the new one.
+func (c *rpcClient) Reconnect() error {
+	client, err := c.dial()
+	if err != nil {
+		return err
+	}
+	c.current.Swap(client).Close()
+	return nil
+}
+
+func (c *rpcClient) Close() {
+	c.current.Load().Close()
+}
+
+// Client returns the underlying RPC client of the current connection. Calls made on it
+// directly are not bounded by the per-call timeout.
+func (c *rpcClient) Client() *rpc.Client {
+	return c.current.Load().Client()
+}
+
+// withTimeout bounds a call by the per-call timeout
+func (c *rpcClient) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+func (c *rpcClient) ChainID(ctx context.Context) (*big.Int, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.current.Load().ChainID(ctx)
+}
+
+func (c *rpcClient) BlockNumber(ctx context.Context) (uint64, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.current.Load().BlockNumber(ctx)
+}
+
+func (c *rpcClient) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.current.Load().BalanceAt(ctx, account, blockNumber)
+}
+
+func (c *rpcClient) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.current.Load().NonceAt(ctx, account, blockNumber)
+}
+
+func (c *rpcClient) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.current.Load().StorageAt(ctx, account, key, blockNumber)
+}
+
+// bind.ContractBackend
+
+func (c *rpcClient) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.current.Load().CodeAt(ctx, contract, blockNumber)
+}
+
+func (c *rpcClient) CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.current.Load().CallContract(ctx, call, blockNumber)
+}
+
+func (c *rpcClient) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.current.Load().HeaderByNumber(ctx, number)
+}
+
+func (c *rpcClient) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.current.Load().PendingCodeAt(ctx, account)
+}
+
+func (c *rpcClient) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.current.Load().PendingNonceAt(ctx, account)
+}
+
+func (c *rpcClient) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.current.Load().SuggestGasPrice(ctx)
+}
+
+func (c *rpcClient) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.current.Load().SuggestGasTipCap(ctx)
+}
+
+func (c *rpcClient) EstimateGas(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.current.Load().EstimateGas(ctx, call)
+}
+
+func (c *rpcClient) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.current.Load().SendTransaction(ctx, tx)
+}
+
+func (c *rpcClient) FilterLogs(ctx context.Context, query ethereum.FilterQuery) ([]types.Log, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.current.Load().FilterLogs(ctx, query)
+}
+
+func (c *rpcClient) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	return c.current.Load().SubscribeNewHead(ctx, ch)
+}
+
+func (c *rpcClient) SubscribeFilterLogs(ctx context.Context, query ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	return c.current.Load().SubscribeFilterLogs(ctx, query, ch)
+}
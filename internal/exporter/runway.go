@@ -0,0 +1,87 @@
+package exporter
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// runwayTracker estimates how long a balance lasts using an exponentially weighted
+// moving average of the observed spend rate. Top-ups count as zero spend so that a
+// deposit never produces a negative rate.
+type runwayTracker struct {
+	halfLife time.Duration
+	states   map[common.Address]*runwayState
+	mu       sync.Mutex
+}
+
+type runwayState struct {
+	balance  float64
+	observed time.Time
+	rate     float64 // Spend per second
+	samples  int
+}
+
+func newRunwayTracker(halfLife time.Duration) *runwayTracker {
+	return &runwayTracker{
+		halfLife: halfLife,
+		states:   make(map[common.Address]*runwayState),
+	}
+}
+
+// Observe records a balance sample and returns the projected runway in days.
+// The second return value is false until at least two samples have been seen.
+// A wallet that is not spending has an infinite runway.
+func (t *runwayTracker) Observe(address common.Address, balance float64, now time.Time) (float64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state, ok := t.states[address]
+	if !ok {
+		t.states[address] = &runwayState{balance: balance, observed: now, samples: 1}
+		return 0, false
+	}
+
+	elapsed := now.Sub(state.observed).Seconds()
+	if elapsed <= 0 {
+		return runwayDays(balance, state.rate), state.samples > 1
+	}
+
+	spent := math.Max(state.balance-balance, 0)
+	sample := spent / elapsed
+
+	if state.samples == 1 {
+		state.rate = sample
+	} else {
+		// Weight the new sample by the time it covers so irregular intervals are handled
+		alpha := 1 - math.Exp(-elapsed*math.Ln2/t.halfLife.Seconds())
+		state.rate = alpha*sample + (1-alpha)*state.rate
+	}
+
+	state.balance = balance
+	state.observed = now
+	state.samples++
+
+	return runwayDays(balance, state.rate), true
+}
+
+// Forget drops the state of wallets that are no longer monitored
+func (t *runwayTracker) Forget(keep map[common.Address]bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for address := range t.states {
+		if !keep[address] {
+			delete(t.states, address)
+		}
+	}
+}
+
+func runwayDays(balance, ratePerSecond float64) float64 {
+	if ratePerSecond <= 0 {
+		return math.Inf(1)
+	}
+	return balance / ratePerSecond / 86400
+}
@@ -0,0 +1,51 @@
+package exporter
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestRunwayTrackerFirstSample(t *testing.T) {
+	tracker := newRunwayTracker(24 * time.Hour)
+	addr := common.HexToAddress("0x1234567890123456789012345678901234567890")
+
+	if _, ok := tracker.Observe(addr, 100, time.Now()); ok {
+		t.Error("Expected no runway after a single sample")
+	}
+}
+
+func TestRunwayTrackerConstantSpend(t *testing.T) {
+	tracker := newRunwayTracker(24 * time.Hour)
+	addr := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	start := time.Now()
+
+	// Spend 1 FIL per day, starting from 10 FIL
+	tracker.Observe(addr, 10, start)
+	days, ok := tracker.Observe(addr, 9, start.Add(24*time.Hour))
+	if !ok {
+		t.Fatal("Expected runway after two samples")
+	}
+
+	if math.Abs(days-9) > 1e-9 {
+		t.Errorf("Expected runway of 9 days, got %f", days)
+	}
+}
+
+func TestRunwayTrackerTopUp(t *testing.T) {
+	tracker := newRunwayTracker(24 * time.Hour)
+	addr := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	start := time.Now()
+
+	tracker.Observe(addr, 10, start)
+	days, ok := tracker.Observe(addr, 20, start.Add(time.Hour))
+	if !ok {
+		t.Fatal("Expected runway after two samples")
+	}
+
+	if !math.IsInf(days, 1) {
+		t.Errorf("Expected infinite runway after a top-up, got %f", days)
+	}
+}
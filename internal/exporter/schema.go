@@ -0,0 +1,175 @@
+package exporter
+
+import (
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metric types
+const (
+	metricGauge   = "gauge"
+	metricCounter = "counter"
+)
+
+// Label sets shared by several metrics
+var (
+	walletLabels      = []string{"address", "fil_address", "network", "name", "type", "provider_id", "is_active", "approved", "role", "region"}
+	walletTokenLabels = []string{"address", "fil_address", "network", "name", "type", "provider_id", "is_active", "approved", "role", "region", "token"}
+	capabilityLabels  = []string{"address", "network", "name", "provider_id", "is_active", "approved"}
+	pingLabels        = []string{"address", "network", "name", "provider_id", "product_type", "service_url", "region"}
+	railLabels        = []string{"address", "network", "name", "type", "rail_id", "role", "counterparty", "operator"}
+	msigLabels        = []string{"address", "network", "name"}
+)
+
+// MetricDefinition describes a metric family the exporter can emit
+type MetricDefinition struct {
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	Unit      string   `json:"unit"`
+	Help      string   `json:"help"`
+	Labels    []string `json:"labels"`
+	EnabledBy string   `json:"enabled_by,omitempty"` // Config flag the metric depends on (empty = always exported)
+	Collector string   `json:"collector"`            // Group selected with collect[] on /metrics
+}
+
+// metricDefinitions is the single source of every metric the exporter registers. Names
+// are given without METRICS_PREFIX.
+var metricDefinitions = []MetricDefinition{
+	{Name: "wallet_fil_balance", Type: metricGauge, Unit: "FIL", Help: "FIL (native token) balance for each wallet", Labels: walletLabels, Collector: "balances"},
+	{Name: "wallet_usdfc_balance", Type: metricGauge, Unit: "USDFC", Help: "USDFC token balance for each wallet", Labels: walletLabels, Collector: "balances"},
+	{Name: "wallet_balance_raw_high", Type: metricGauge, Unit: "count", Help: "Raw FIL or USDFC balance (attoFIL or 1e-18 USDFC) divided by 1e15, rounded down; the exact balance is high * 1e15 + low", Labels: walletTokenLabels, EnabledBy: "EXPORT_RAW_BALANCES", Collector: "balances"},
+	{Name: "wallet_balance_raw_low", Type: metricGauge, Unit: "count", Help: "Raw FIL or USDFC balance modulo 1e15", Labels: walletTokenLabels, EnabledBy: "EXPORT_RAW_BALANCES", Collector: "balances"},
+	{Name: "wallet_info", Type: metricGauge, Unit: "info", Help: "Wallet information (always 1)", Labels: []string{"address", "fil_address", "network", "name", "type", "provider_id", "description", "is_active", "approved"}, Collector: "balances"},
+	{Name: "wallet_payments_funds", Type: metricGauge, Unit: "tokens", Help: "Total funds in Payments contract for each wallet", Labels: walletTokenLabels, Collector: "payments"},
+	{Name: "wallet_payments_available", Type: metricGauge, Unit: "tokens", Help: "Available funds in Payments contract (after lockup)", Labels: walletTokenLabels, Collector: "payments"},
+	{Name: "wallet_payments_locked", Type: metricGauge, Unit: "tokens", Help: "Locked funds in Payments contract", Labels: walletTokenLabels, Collector: "payments"},
+	{Name: "wallet_payments_funded_until_epoch", Type: metricGauge, Unit: "epoch", Help: "Estimated epoch when Payments funds will run out", Labels: walletTokenLabels, Collector: "payments"},
+	{Name: "wallet_payments_lockup_rate", Type: metricGauge, Unit: "tokens/epoch", Help: "Current lockup rate in Payments contract (tokens per epoch)", Labels: walletTokenLabels, Collector: "payments"},
+	{Name: "wallet_payments_query_error", Type: metricGauge, Unit: "boolean", Help: "1 if the last lookup of the Payments account failed and the other wallet_payments_* series keep their last known value, 0 otherwise", Labels: walletTokenLabels, Collector: "payments"},
+	{Name: "wallet_fil_runway_days", Type: metricGauge, Unit: "days", Help: "Projected days until the FIL balance runs out at the smoothed spend rate (+Inf if not spending)", Labels: walletLabels, Collector: "balances"},
+	{Name: "wallet_usdfc_runway_days", Type: metricGauge, Unit: "days", Help: "Projected days until the USDFC balance runs out at the smoothed spend rate (+Inf if not spending)", Labels: walletLabels, Collector: "balances"},
+	{Name: "wallet_fil_min_threshold", Type: metricGauge, Unit: "FIL", Help: "Configured minimum FIL balance for each wallet", Labels: walletLabels, Collector: "balances"},
+	{Name: "wallet_usdfc_min_threshold", Type: metricGauge, Unit: "USDFC", Help: "Configured minimum USDFC balance for each wallet", Labels: walletLabels, Collector: "balances"},
+	{Name: "wallet_below_threshold", Type: metricGauge, Unit: "boolean", Help: "1 if the wallet balance is below its configured minimum, 0 otherwise", Labels: walletTokenLabels, Collector: "balances"},
+	{Name: "wallet_last_updated_timestamp_seconds", Type: metricGauge, Unit: "seconds", Help: "Unix time the balances of the wallet were last read or confirmed unchanged; lags behind the scrape for wallets that kept their last values", Labels: walletLabels, Collector: "balances"},
+	{Name: "wallet_stale", Type: metricGauge, Unit: "boolean", Help: "1 if the last scrape timed out before reaching the wallet and its last known values are exported, 0 otherwise", Labels: walletLabels, Collector: "balances"},
+	{Name: "scrape_duration_seconds", Type: metricGauge, Unit: "seconds", Help: "Duration of the last scrape in seconds", Collector: "exporter"},
+	{Name: "last_successful_scrape_timestamp_seconds", Type: metricGauge, Unit: "seconds", Help: "Unix time the last scrape that found wallets or had no errors completed", Collector: "exporter"},
+	{Name: "incremental_idle_wallets", Type: metricGauge, Unit: "count", Help: "Wallets whose balances the last scrape reused because no block since the previous scrape touched them (0 on full refreshes)", EnabledBy: "INCREMENTAL_REFRESH", Collector: "exporter"},
+	{Name: "chain_reorg_detected_total", Type: metricCounter, Unit: "count", Help: "Reorgs that took away the block a scrape read at; the scrape is run again when caught while it runs", Collector: "exporter"},
+	{Name: "scrape_timeouts_total", Type: metricCounter, Unit: "count", Help: "Scrapes that hit SCRAPE_TIMEOUT and published partial results", Collector: "exporter"},
+	{Name: "scrape_errors_total", Type: metricCounter, Unit: "count", Help: "Total number of scrape errors", Collector: "exporter"},
+	{Name: "rpc_circuit_open", Type: metricGauge, Unit: "boolean", Help: "1 while scrapes are skipped because the RPC endpoint keeps failing, 0 otherwise", Collector: "exporter"},
+	{Name: "network_info", Type: metricGauge, Unit: "info", Help: "Network the exporter is configured for and the chain ID and host of its RPC endpoint (always 1)", Labels: []string{"network", "chain_id", "rpc_url_host"}, Collector: "exporter"},
+	{Name: "rpc_chain_id_mismatch", Type: metricGauge, Unit: "boolean", Help: "1 while scrapes are skipped because the RPC endpoint serves a different chain than NETWORK, 0 otherwise", Collector: "exporter"},
+	{Name: "rpc_retries_total", Type: metricCounter, Unit: "count", Help: "RPC requests retried after a transient failure", Labels: []string{"reason"}, Collector: "exporter"},
+	{Name: "alerts_firing", Type: metricGauge, Unit: "count", Help: "Alerts currently firing", Labels: []string{"rule", "severity"}, EnabledBy: "TELEGRAM_BOT_TOKEN, SLACK_WEBHOOK_URL or SMTP_HOST", Collector: "exporter"},
+	{Name: "alert_notifications_total", Type: metricCounter, Unit: "count", Help: "Alert notifications sent (result=success) or failed (result=failure) per notifier", Labels: []string{"notifier", "result"}, EnabledBy: "TELEGRAM_BOT_TOKEN, SLACK_WEBHOOK_URL or SMTP_HOST", Collector: "exporter"},
+	{Name: "state_restored", Type: metricGauge, Unit: "boolean", Help: "1 while the wallet metrics are the last known values restored from the state file, until the first scrape completes", EnabledBy: "STATE_FILE", Collector: "exporter"},
+	{Name: "metrics_pushes_total", Type: metricCounter, Unit: "count", Help: "Pushes of the metrics to a sink that succeeded (result=success) or failed (result=failure)", Labels: []string{"sink", "result"}, EnabledBy: "REMOTE_WRITE_URL, OTLP_ENDPOINT, GRAPHITE_ADDRESS, STATSD_ADDRESS or TEXTFILE_PATH", Collector: "exporter"},
+	{Name: "http_requests_rejected_total", Type: metricCounter, Unit: "count", Help: "HTTP requests refused before reaching a handler", Labels: []string{"reason"}, Collector: "exporter"},
+	{Name: "provider_scrape_coverage_ratio", Type: metricGauge, Unit: "ratio", Help: "Fraction of registry providers refreshed in the last scrape", Collector: "providers"},
+	{Name: "provider_ping_success", Type: metricGauge, Unit: "boolean", Help: "1 if the provider ping was successful (HTTP 200), 0 otherwise", Labels: pingLabels, Collector: "pings"},
+	{Name: "provider_ping_ms", Type: metricGauge, Unit: "milliseconds", Help: "Duration of the ping request in milliseconds", Labels: pingLabels, Collector: "pings"},
+	{Name: "provider_product_info", Type: metricGauge, Unit: "info", Help: "Product registered by the provider with its decoded well-known capabilities (always 1)", Labels: []string{"address", "network", "name", "provider_id", "product_type", "is_active", "service_url", "location", "min_piece_size_in_bytes", "max_piece_size_in_bytes", "ipni_piece", "ipni_ipfs", "storage_price_per_tib_per_day", "min_proving_period_in_epochs", "payment_token_address"}, Collector: "providers"},
+	{Name: "provider_product_active", Type: metricGauge, Unit: "boolean", Help: "1 if the provider's product is active, 0 otherwise", Labels: []string{"address", "network", "name", "provider_id", "product_type"}, Collector: "providers"},
+	{Name: "provider_storage_price_per_tib_per_day", Type: metricGauge, Unit: "USDFC", Help: "Storage price per TiB per day the provider publishes on its PDP product", Labels: capabilityLabels, Collector: "providers"},
+	{Name: "provider_min_piece_size_bytes", Type: metricGauge, Unit: "bytes", Help: "Minimum piece size the provider accepts on its PDP product", Labels: capabilityLabels, Collector: "providers"},
+	{Name: "provider_max_piece_size_bytes", Type: metricGauge, Unit: "bytes", Help: "Maximum piece size the provider accepts on its PDP product", Labels: capabilityLabels, Collector: "providers"},
+	{Name: "provider_min_proving_period_epochs", Type: metricGauge, Unit: "epochs", Help: "Minimum proving period the provider publishes on its PDP product", Labels: capabilityLabels, Collector: "providers"},
+	{Name: "provider_lifecycle_events_total", Type: metricCounter, Unit: "count", Help: "Provider transitions seen between scrapes (added, removed, deactivated, reactivated, approved, unapproved)", Labels: []string{"network", "provider_id", "event"}, Collector: "providers"},
+	{Name: "provider_state_change_timestamp_seconds", Type: metricGauge, Unit: "seconds", Help: "Unix time of the provider's last lifecycle transition", Labels: []string{"address", "network", "name", "provider_id"}, Collector: "providers"},
+	{Name: "provider_capability_decode_errors_total", Type: metricCounter, Unit: "count", Help: "Provider capability values that were not printable text or exceeded the length cap", Labels: []string{"network", "key", "reason"}, Collector: "providers"},
+	{Name: "contract_binding_info", Type: metricGauge, Unit: "info", Help: "Contract bindings compiled into the exporter and the addresses they are bound to (always 1)", Labels: []string{"contract", "address", "abi_hash", "abi_bundle"}, Collector: "contracts"},
+	{Name: "contract_bindings", Type: metricGauge, Unit: "count", Help: "Number of contract bindings compiled into the exporter", Collector: "contracts"},
+	{Name: "contract_bindings_build_timestamp_seconds", Type: metricGauge, Unit: "seconds", Help: "Commit time of the source the bindings were compiled from (0 if unknown)", Collector: "contracts"},
+	{Name: "contract_abi_drift_methods", Type: metricGauge, Unit: "count", Help: "Methods that differ between the binding and the on-chain bytecode", Labels: []string{"contract", "direction"}, Collector: "contracts"},
+	{Name: "contract_implementation_info", Type: metricGauge, Unit: "info", Help: "Current address and EIP-1967 implementation of each protocol contract (always 1)", Labels: []string{"contract", "address", "implementation"}, Collector: "contracts"},
+	{Name: "contract_changes_total", Type: metricCounter, Unit: "count", Help: "Contract address or proxy implementation changes detected since startup", Labels: []string{"contract", "kind"}, Collector: "contracts"},
+	{Name: "contract_binding_stale", Type: metricGauge, Unit: "boolean", Help: "1 if WarmStorage now resolves the contract to a different address than the exporter is bound to", Labels: []string{"contract"}, Collector: "contracts"},
+	{Name: "snapshot_block_number", Type: metricGauge, Unit: "epoch", Help: "Block number the last scrape read at: the head, or the block of QUERY_BLOCK_TAG", EnabledBy: "EXPORT_FINALITY", Collector: "exporter"},
+	{Name: "snapshot_finalized", Type: metricGauge, Unit: "boolean", Help: "1 if the snapshot block of the last scrape is finalized, 0 otherwise", EnabledBy: "EXPORT_FINALITY", Collector: "exporter"},
+	{Name: "snapshot_finality_distance_blocks", Type: metricGauge, Unit: "epochs", Help: "Number of blocks between the snapshot block and the finalized tip", EnabledBy: "EXPORT_FINALITY", Collector: "exporter"},
+	{Name: "rail_payment_rate", Type: metricGauge, Unit: "USDFC/epoch", Help: "Payment rate of the rail in USDFC per epoch", Labels: railLabels, EnabledBy: "EXPORT_RAILS", Collector: "rails"},
+	{Name: "rail_settled_up_to_epoch", Type: metricGauge, Unit: "epoch", Help: "Epoch up to which the rail has been settled", Labels: railLabels, EnabledBy: "EXPORT_RAILS", Collector: "rails"},
+	{Name: "rail_end_epoch", Type: metricGauge, Unit: "epoch", Help: "End epoch of a terminated rail (0 if the rail is not terminated)", Labels: railLabels, EnabledBy: "EXPORT_RAILS", Collector: "rails"},
+	{Name: "rail_lockup_fixed", Type: metricGauge, Unit: "USDFC", Help: "Fixed lockup of the rail in USDFC", Labels: railLabels, EnabledBy: "EXPORT_RAILS", Collector: "rails"},
+	{Name: "rail_lockup_period_epochs", Type: metricGauge, Unit: "epochs", Help: "Lockup period of the rail in epochs", Labels: railLabels, EnabledBy: "EXPORT_RAILS", Collector: "rails"},
+	{Name: "rail_pending_settlement", Type: metricGauge, Unit: "USDFC", Help: "USDFC the payee would receive if the rail were settled now", Labels: railLabels, EnabledBy: "EXPORT_PENDING_SETTLEMENT", Collector: "rails"},
+	{Name: "wallet_pending_settlement", Type: metricGauge, Unit: "USDFC", Help: "Total USDFC accrued but not yet settled to the wallet across its payee rails", Labels: []string{"address", "network", "name", "type", "provider_id"}, EnabledBy: "EXPORT_PENDING_SETTLEMENT", Collector: "rails"},
+	{Name: "warm_storage_price_per_tib_month", Type: metricGauge, Unit: "USDFC", Help: "WarmStorage storage price per TiB per month (without CDN)", Collector: "pricing"},
+	{Name: "warm_storage_rate_per_tib_epoch", Type: metricGauge, Unit: "USDFC/epoch", Help: "WarmStorage storage payment rate per TiB per epoch", Collector: "pricing"},
+	{Name: "warm_storage_cdn_egress_price_per_tib", Type: metricGauge, Unit: "USDFC", Help: "WarmStorage CDN egress price per TiB", Collector: "pricing"},
+	{Name: "warm_storage_cache_miss_egress_price_per_tib", Type: metricGauge, Unit: "USDFC", Help: "WarmStorage cache miss egress price per TiB", Collector: "pricing"},
+	{Name: "warm_storage_minimum_price_per_month", Type: metricGauge, Unit: "USDFC", Help: "WarmStorage minimum monthly charge per data set", Collector: "pricing"},
+	{Name: "warm_storage_epochs_per_month", Type: metricGauge, Unit: "epochs", Help: "Epochs per month used by WarmStorage pricing", Collector: "pricing"},
+	{Name: "provider_data_sets_total", Type: metricGauge, Unit: "count", Help: "Active WarmStorage data sets paying the provider (counted from its non-terminated payee rails)", Labels: []string{"address", "network", "name", "provider_id"}, EnabledBy: "EXPORT_DATA_SETS", Collector: "data_sets"},
+	{Name: "client_data_sets_total", Type: metricGauge, Unit: "count", Help: "WarmStorage data sets created by the wallet as a client", Labels: []string{"address", "network", "name", "type"}, EnabledBy: "EXPORT_DATA_SETS", Collector: "data_sets"},
+	{Name: "provider_registered_block", Type: metricGauge, Unit: "epoch", Help: "Block of the provider's ProviderRegistered event", Labels: []string{"address", "network", "name", "provider_id"}, EnabledBy: "EXPORT_REGISTRATIONS", Collector: "providers"},
+	{Name: "provider_registered_timestamp_seconds", Type: metricGauge, Unit: "seconds", Help: "Unix time the provider registered", Labels: []string{"address", "network", "name", "provider_id"}, EnabledBy: "EXPORT_REGISTRATIONS", Collector: "providers"},
+	{Name: "wallet_mempool_transactions", Type: metricGauge, Unit: "count", Help: "Messages sent by the wallet that are waiting in the node's mempool", Labels: []string{"address", "network", "name", "type"}, EnabledBy: "EXPORT_MEMPOOL", Collector: "mempool"},
+	{Name: "wallet_mempool_value", Type: metricGauge, Unit: "FIL", Help: "FIL sent by the wallet's messages waiting in the mempool", Labels: []string{"address", "network", "name", "type"}, EnabledBy: "EXPORT_MEMPOOL", Collector: "mempool"},
+	{Name: "wallet_mempool_stuck_transactions", Type: metricGauge, Unit: "count", Help: "Mempool messages of the wallet that cannot be included because an earlier nonce is missing", Labels: []string{"address", "network", "name", "type"}, EnabledBy: "EXPORT_MEMPOOL", Collector: "mempool"},
+	{Name: "wallet_actor_info", Type: metricGauge, Unit: "info", Help: "Filecoin actor type of the wallet according to the block explorer (always 1)", Labels: []string{"address", "network", "name", "type", "actor_type"}, EnabledBy: "EXPLORER", Collector: "explorer"},
+	{Name: "wallet_message_count", Type: metricGauge, Unit: "count", Help: "Messages sent and received by the wallet according to the block explorer", Labels: []string{"address", "network", "name", "type"}, EnabledBy: "EXPLORER", Collector: "explorer"},
+	{Name: "wallet_last_message_timestamp_seconds", Type: metricGauge, Unit: "seconds", Help: "Unix time the block explorer last saw the wallet in a message", Labels: []string{"address", "network", "name", "type"}, EnabledBy: "EXPLORER", Collector: "explorer"},
+	{Name: "msig_balance", Type: metricGauge, Unit: "FIL", Help: "FIL balance of the multisig actor", Labels: msigLabels, EnabledBy: "CUSTOM_WALLET_N (msig)", Collector: "msig"},
+	{Name: "msig_available_balance", Type: metricGauge, Unit: "FIL", Help: "FIL the multisig can spend (balance that is no longer vesting)", Labels: msigLabels, EnabledBy: "CUSTOM_WALLET_N (msig)", Collector: "msig"},
+	{Name: "msig_locked_balance", Type: metricGauge, Unit: "FIL", Help: "FIL still vesting in the multisig", Labels: msigLabels, EnabledBy: "CUSTOM_WALLET_N (msig)", Collector: "msig"},
+	{Name: "msig_vesting_end_epoch", Type: metricGauge, Unit: "epoch", Help: "Epoch the multisig's initial balance is fully vested at (0 if it does not vest)", Labels: msigLabels, EnabledBy: "CUSTOM_WALLET_N (msig)", Collector: "msig"},
+	{Name: "msig_signers", Type: metricGauge, Unit: "count", Help: "Number of signers of the multisig", Labels: msigLabels, EnabledBy: "CUSTOM_WALLET_N (msig)", Collector: "msig"},
+	{Name: "msig_approvals_threshold", Type: metricGauge, Unit: "count", Help: "Approvals required to execute a multisig proposal", Labels: msigLabels, EnabledBy: "CUSTOM_WALLET_N (msig)", Collector: "msig"},
+	{Name: "msig_pending_transactions", Type: metricGauge, Unit: "count", Help: "Multisig proposals waiting for approvals", Labels: msigLabels, EnabledBy: "CUSTOM_WALLET_N (msig)", Collector: "msig"},
+	{Name: "msig_pending_value", Type: metricGauge, Unit: "FIL", Help: "FIL the pending multisig proposals would send", Labels: msigLabels, EnabledBy: "CUSTOM_WALLET_N (msig)", Collector: "msig"},
+	{Name: "contract_balance", Type: metricGauge, Unit: "tokens", Help: "Tokens held by the Payments and WarmStorage contracts (protocol TVL, including accumulated fees)", Labels: []string{"contract", "address", "token"}, EnabledBy: "EXPORT_TVL", Collector: "contracts"},
+	{Name: "wallet_payments_deposits_total", Type: metricCounter, Unit: "count", Help: "Number of deposits into the wallet's Payments account observed since startup", Labels: []string{"address", "network", "name", "type", "token"}, EnabledBy: "EXPORT_PAYMENTS_EVENTS", Collector: "events"},
+	{Name: "wallet_payments_deposited_amount_total", Type: metricCounter, Unit: "tokens", Help: "Tokens deposited into the wallet's Payments account since startup", Labels: []string{"address", "network", "name", "type", "token"}, EnabledBy: "EXPORT_PAYMENTS_EVENTS", Collector: "events"},
+	{Name: "wallet_payments_withdrawals_total", Type: metricCounter, Unit: "count", Help: "Number of withdrawals from the wallet's Payments account observed since startup", Labels: []string{"address", "network", "name", "type", "token"}, EnabledBy: "EXPORT_PAYMENTS_EVENTS", Collector: "events"},
+	{Name: "wallet_payments_withdrawn_amount_total", Type: metricCounter, Unit: "tokens", Help: "Tokens withdrawn from the wallet's Payments account since startup", Labels: []string{"address", "network", "name", "type", "token"}, EnabledBy: "EXPORT_PAYMENTS_EVENTS", Collector: "events"},
+	{Name: "wallet_usdfc_transfers_total", Type: metricCounter, Unit: "count", Help: "USDFC Transfer events into (direction=in) or out of (direction=out) the wallet since startup", Labels: []string{"address", "network", "name", "type", "direction"}, EnabledBy: "EXPORT_TRANSFERS", Collector: "events"},
+	{Name: "wallet_usdfc_transferred_amount_total", Type: metricCounter, Unit: "USDFC", Help: "USDFC moved into (direction=in) or out of (direction=out) the wallet since startup", Labels: []string{"address", "network", "name", "type", "direction"}, EnabledBy: "EXPORT_TRANSFERS", Collector: "events"},
+}
+
+// MetricsSchema returns every metric family the exporter can emit, with names prefixed
+func MetricsSchema(prefix string) []MetricDefinition {
+	schema := make([]MetricDefinition, 0, len(metricDefinitions))
+	for _, def := range metricDefinitions {
+		def.Name = fmt.Sprintf("%s_%s", prefix, def.Name)
+		if def.Labels == nil {
+			def.Labels = []string{}
+		}
+		schema = append(schema, def)
+	}
+	return schema
+}
+
+// metricDefinition looks up a metric by name and type; a missing entry is a programming error
+func metricDefinition(name, metricType string) MetricDefinition {
+	for _, def := range metricDefinitions {
+		if def.Name == name && def.Type == metricType {
+			return def
+		}
+	}
+	panic(fmt.Sprintf("metric %s (%s) is missing from metricDefinitions", name, metricType))
+}
+
+func newGaugeVec(prefix, name string) *prometheus.GaugeVec {
+	def := metricDefinition(name, metricGauge)
+	return prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: fmt.Sprintf("%s_%s", prefix, name), Help: def.Help}, def.Labels)
+}
+
+func newGauge(prefix, name string) prometheus.Gauge {
+	def := metricDefinition(name, metricGauge)
+	return prometheus.NewGauge(prometheus.GaugeOpts{Name: fmt.Sprintf("%s_%s", prefix, name), Help: def.Help})
+}
+
+func newCounterVec(prefix, name string) *prometheus.CounterVec {
+	def := metricDefinition(name, metricCounter)
+	return prometheus.NewCounterVec(prometheus.CounterOpts{Name: fmt.Sprintf("%s_%s", prefix, name), Help: def.Help}, def.Labels)
+}
+
+func newCounter(prefix, name string) prometheus.Counter {
+	def := metricDefinition(name, metricCounter)
+	return prometheus.NewCounter(prometheus.CounterOpts{Name: fmt.Sprintf("%s_%s", prefix, name), Help: def.Help})
+}
@@ -0,0 +1,71 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestMetricDefinitionsRegister(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	for _, def := range metricDefinitions {
+		var collector prometheus.Collector
+		switch {
+		case def.Type == metricGauge && len(def.Labels) > 0:
+			collector = newGaugeVec("test", def.Name)
+		case def.Type == metricGauge:
+			collector = newGauge("test", def.Name)
+		case def.Type == metricCounter && len(def.Labels) > 0:
+			collector = newCounterVec("test", def.Name)
+		case def.Type == metricCounter:
+			collector = newCounter("test", def.Name)
+		default:
+			t.Fatalf("metric %s has unknown type %q", def.Name, def.Type)
+		}
+
+		if err := registry.Register(collector); err != nil {
+			t.Errorf("metric %s failed to register: %v", def.Name, err)
+		}
+	}
+}
+
+func TestMetricsSchemaPrefix(t *testing.T) {
+	schema := MetricsSchema("dealbot")
+	if len(schema) != len(metricDefinitions) {
+		t.Fatalf("Expected %d metrics, got %d", len(metricDefinitions), len(schema))
+	}
+	if schema[0].Name != "dealbot_"+metricDefinitions[0].Name {
+		t.Errorf("Expected prefixed name, got %s", schema[0].Name)
+	}
+}
+
+func TestFilterGatherer(t *testing.T) {
+	for _, def := range metricDefinitions {
+		if def.Collector == "" {
+			t.Errorf("metric %s has no collector", def.Name)
+		}
+	}
+
+	registry := prometheus.NewRegistry()
+	balance := newGaugeVec("test", "wallet_fil_balance")
+	ping := newGaugeVec("test", "provider_ping_success")
+	registry.MustRegister(balance, ping)
+	balance.WithLabelValues("0x1", "f410", "calibration", "a", "client", "", "", "", "", "").Set(1)
+	ping.WithLabelValues("0x1", "calibration", "a", "1", "0", "https://sp", "").Set(1)
+
+	gatherer, err := filterGatherer(registry, "test", []string{"pings"})
+	if err != nil {
+		t.Fatalf("filterGatherer() failed: %v", err)
+	}
+	families, err := gatherer.Gather()
+	if err != nil {
+		t.Fatalf("Gather() failed: %v", err)
+	}
+	if len(families) != 1 || families[0].GetName() != "test_provider_ping_success" {
+		t.Errorf("Expected only the ping family, got %v", families)
+	}
+
+	if _, err := filterGatherer(registry, "test", []string{"nope"}); err == nil {
+		t.Error("Expected an error for an unknown collector")
+	}
+}
@@ -0,0 +1,100 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// errScrapeSkipped is returned by ScrapeNow when the scrape it waited for did not run, e.g.
+// because the RPC circuit is open or the chain ID does not match
+var errScrapeSkipped = errors.New("scrape skipped, RPC endpoint unavailable")
+
+// errScrapeRestarted is returned by scrape when a reorg while it ran left its balances on an
+// abandoned fork. It published nothing and requested a new scrape.
+var errScrapeRestarted = errors.New("scrape restarted after a reorg")
+
+// CooldownError is returned by ScrapeNow when the last scrape ended less than SCRAPE_COOLDOWN ago
+type CooldownError struct {
+	RetryAfter time.Duration
+}
+
+func (e *CooldownError) Error() string {
+	return fmt.Sprintf("last scrape finished less than the cooldown ago, retry in %s", e.RetryAfter.Round(time.Second))
+}
+
+// ScrapeNow requests a scrape and waits for it to complete. Callers arriving while a requested
+// scrape is pending share it, so the cooldown only applies to starting a new one.
+func (e *WalletExporter) ScrapeNow(ctx context.Context) (ScrapeStatus, error) {
+	requested := time.Now()
+
+	e.scrapeWaitMux.Lock()
+	if e.scrapeWaiters == nil {
+		lastScrape := e.GetScrapeStatus().LastScrape
+		if since := requested.Sub(lastScrape); !lastScrape.IsZero() && since < e.config.ScrapeCooldown {
+			e.scrapeWaitMux.Unlock()
+			return ScrapeStatus{}, &CooldownError{RetryAfter: e.config.ScrapeCooldown - since}
+		}
+		e.scrapeWaiters = make(chan struct{})
+		e.TriggerScrape()
+	}
+	done := e.scrapeWaiters
+	e.scrapeWaitMux.Unlock()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ScrapeStatus{}, ctx.Err()
+	}
+
+	status := e.GetScrapeStatus()
+	if status.LastScrape.Before(requested) {
+		return status, errScrapeSkipped
+	}
+	return status, nil
+}
+
+// scrapeAndNotify runs a scrape and releases the ScrapeNow callers that were waiting before it
+// started. Callers arriving during the scrape wait for the next one, which they requested.
+// Callers of a scrape restarted after a reorg wait for the one it requested, unless callers
+// arriving meanwhile already did; those are released and told the scrape was skipped.
+func (e *WalletExporter) scrapeAndNotify(ctx context.Context) error {
+	e.scrapeWaitMux.Lock()
+	waiters := e.scrapeWaiters
+	e.scrapeWaiters = nil
+	e.scrapeWaitMux.Unlock()
+
+	err := e.scrape(ctx)
+	if errors.Is(err, errScrapeRestarted) {
+		e.logger.Info("Scrape restarted after a reorg")
+		err = nil
+		e.scrapeWaitMux.Lock()
+		if e.scrapeWaiters == nil {
+			e.scrapeWaiters, waiters = waiters, nil
+		}
+		e.scrapeWaitMux.Unlock()
+	}
+	if waiters != nil {
+		close(waiters)
+	}
+	return err
+}
+
+// ScrapeOnce runs a single scrape without starting the exporter, for one-shot commands
+func (e *WalletExporter) ScrapeOnce(ctx context.Context) (ScrapeStatus, error) {
+	started := time.Now()
+	err := e.scrape(ctx)
+	for errors.Is(err, errScrapeRestarted) {
+		err = e.scrape(ctx)
+	}
+	if err != nil {
+		return ScrapeStatus{}, err
+	}
+
+	status := e.GetScrapeStatus()
+	if status.LastScrape.Before(started) {
+		return status, errScrapeSkipped
+	}
+	return status, nil
+}
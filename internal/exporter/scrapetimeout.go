@@ -0,0 +1,38 @@
+package exporter
+
+import "github.com/ethereum/go-ethereum/common"
+
+func (e *WalletExporter) registerScrapeTimeoutMetrics() {
+	e.scrapeTimeoutsCounter = newCounter(e.config.MetricsPrefix, "scrape_timeouts_total")
+	e.walletStaleGauge = newGaugeVec(e.config.MetricsPrefix, "wallet_stale")
+	e.walletUpdatedGauge = newGaugeVec(e.config.MetricsPrefix, "wallet_last_updated_timestamp_seconds")
+
+	e.registry.MustRegister(e.scrapeTimeoutsCounter, e.walletStaleGauge, e.walletUpdatedGauge)
+}
+
+// carryOverStaleWallets adds the wallets of the previous scrape that a timed-out scrape did not
+// reach, flagged stale and with their last ping results, and returns how many were added
+func carryOverStaleWallets(fresh, previous []WalletInfo, pings, previousPings map[uint64][]PingResult) ([]WalletInfo, map[uint64][]PingResult, int) {
+	seen := make(map[common.Address]bool, len(fresh))
+	for _, wallet := range fresh {
+		seen[wallet.Address] = true
+	}
+	if pings == nil {
+		pings = make(map[uint64][]PingResult)
+	}
+
+	stale := 0
+	for _, wallet := range previous {
+		if seen[wallet.Address] {
+			continue
+		}
+		wallet.Stale = true
+		fresh = append(fresh, wallet)
+		stale++
+
+		if _, ok := pings[wallet.ProviderID]; !ok && wallet.Type == "provider" && previousPings[wallet.ProviderID] != nil {
+			pings[wallet.ProviderID] = previousPings[wallet.ProviderID]
+		}
+	}
+	return fresh, pings, stale
+}
@@ -0,0 +1,34 @@
+package exporter
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestCarryOverStaleWallets(t *testing.T) {
+	updatedAt := time.Unix(1700000000, 0)
+	previous := []WalletInfo{
+		{Address: common.HexToAddress("0x01"), Type: "provider", ProviderID: 1, FILBalance: big.NewInt(1)},
+		{Address: common.HexToAddress("0x02"), Type: "provider", ProviderID: 2, FILBalance: big.NewInt(2), UpdatedAt: updatedAt},
+		{Address: common.HexToAddress("0x03"), Type: "client", FILBalance: big.NewInt(3)},
+	}
+	previousPings := map[uint64][]PingResult{1: {{Success: true}}, 2: {{Success: false}}}
+	fresh := []WalletInfo{{Address: common.HexToAddress("0x01"), Type: "provider", ProviderID: 1, FILBalance: big.NewInt(10)}}
+
+	wallets, pings, stale := carryOverStaleWallets(fresh, previous, nil, previousPings)
+	if stale != 2 || len(wallets) != 3 {
+		t.Fatalf("Expected 2 stale wallets added to the fresh one, got %d of %+v", stale, wallets)
+	}
+	if wallets[0].Stale || wallets[0].FILBalance.Int64() != 10 {
+		t.Errorf("Expected the fresh wallet to be kept as read, got %+v", wallets[0])
+	}
+	if !wallets[1].Stale || wallets[1].FILBalance.Int64() != 2 || !wallets[1].UpdatedAt.Equal(updatedAt) || !wallets[2].Stale {
+		t.Errorf("Expected the missing wallets flagged stale with their last values, got %+v", wallets[1:])
+	}
+	if _, ok := pings[1]; ok || len(pings[2]) != 1 {
+		t.Errorf("Expected only the stale provider's last pings to be carried over, got %+v", pings)
+	}
+}
@@ -0,0 +1,74 @@
+package exporter
+
+import (
+	"path"
+	"strings"
+)
+
+// walletSelector decides which wallets take part in event tracking. Each selector is
+// "key:glob" with key one of name, address, or type; a bare glob matches name or address.
+// An empty selector list matches every wallet.
+type walletSelector struct {
+	selectors []string
+}
+
+func newWalletSelector(selectors []string) *walletSelector {
+	return &walletSelector{selectors: selectors}
+}
+
+// Matches reports whether the wallet matches any selector
+func (s *walletSelector) Matches(wallet WalletInfo) bool {
+	if len(s.selectors) == 0 {
+		return true
+	}
+
+	address := strings.ToLower(wallet.Address.Hex())
+	for _, selector := range s.selectors {
+		key, pattern, ok := strings.Cut(selector, ":")
+		if !ok {
+			pattern = key
+			key = ""
+		}
+
+		switch key {
+		case "name":
+			if globMatch(pattern, wallet.Name) {
+				return true
+			}
+		case "address":
+			if globMatch(strings.ToLower(pattern), address) {
+				return true
+			}
+		case "type":
+			if globMatch(pattern, wallet.Type) {
+				return true
+			}
+		case "":
+			if globMatch(pattern, wallet.Name) || globMatch(strings.ToLower(pattern), address) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Filter returns the wallets that match the selectors
+func (s *walletSelector) Filter(wallets []WalletInfo) []WalletInfo {
+	if len(s.selectors) == 0 {
+		return wallets
+	}
+
+	matched := make([]WalletInfo, 0, len(wallets))
+	for _, wallet := range wallets {
+		if s.Matches(wallet) {
+			matched = append(matched, wallet)
+		}
+	}
+	return matched
+}
+
+func globMatch(pattern, value string) bool {
+	matched, err := path.Match(pattern, value)
+	return err == nil && matched
+}
@@ -0,0 +1,41 @@
+package exporter
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestWalletSelector(t *testing.T) {
+	treasury := WalletInfo{
+		Address: common.HexToAddress("0xa108Be4331296Ec8b8C47c2Cd2FbfDDF06E27523"),
+		Name:    "treasury-main",
+		Type:    "operator",
+	}
+	provider := WalletInfo{
+		Address: common.HexToAddress("0x1234567890123456789012345678901234567890"),
+		Name:    "sp-1",
+		Type:    "provider",
+	}
+
+	tests := []struct {
+		selectors []string
+		wallet    WalletInfo
+		expected  bool
+	}{
+		{nil, provider, true},
+		{[]string{"name:treasury-*"}, treasury, true},
+		{[]string{"name:treasury-*"}, provider, false},
+		{[]string{"type:provider"}, provider, true},
+		{[]string{"address:0xA108*"}, treasury, true},
+		{[]string{"treasury-*"}, treasury, true},
+		{[]string{"type:client", "name:sp-*"}, provider, true},
+		{[]string{"type:client"}, treasury, false},
+	}
+
+	for _, tt := range tests {
+		if got := newWalletSelector(tt.selectors).Matches(tt.wallet); got != tt.expected {
+			t.Errorf("Matches(%v, %s) = %t, want %t", tt.selectors, tt.wallet.Name, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package exporter
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// trackedSeries is one series of a wallet metric
+type trackedSeries struct {
+	vec    *prometheus.GaugeVec
+	labels prometheus.Labels
+	kept   bool // Set during the current swap
+}
+
+// seriesTracker replaces the global Reset of the wallet metrics. Series are set as results
+// arrive and only deleted once the complete set of a scrape is in place: a swap keeps the series
+// set between Begin and Swap and deletes every other one, so a collection never sees a wallet
+// missing while its values are replaced. Only used by the scrape loop.
+type seriesTracker struct {
+	series   map[prometheus.Gauge]trackedSeries // By the series' gauge, which stays the same until deleted
+	swapping bool
+}
+
+func newSeriesTracker() *seriesTracker {
+	return &seriesTracker{series: make(map[prometheus.Gauge]trackedSeries)}
+}
+
+// Set sets a series. Series set outside a swap are provisional, and deleted by the next swap
+// unless it sets them again.
+func (t *seriesTracker) Set(vec *prometheus.GaugeVec, labels prometheus.Labels, value float64) {
+	gauge := vec.With(labels)
+	gauge.Set(value)
+	t.series[gauge] = trackedSeries{vec: vec, labels: labels, kept: t.swapping}
+}
+
+// Begin starts a swap
+func (t *seriesTracker) Begin() {
+	t.swapping = true
+}
+
+// Swap deletes the series not set since Begin
+func (t *seriesTracker) Swap() {
+	for gauge, series := range t.series {
+		if !series.kept {
+			series.vec.Delete(series.labels)
+			delete(t.series, gauge)
+			continue
+		}
+		series.kept = false
+		t.series[gauge] = series
+	}
+	t.swapping = false
+}
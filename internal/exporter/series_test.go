@@ -0,0 +1,100 @@
+package exporter
+
+import (
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"wallet-exporter/internal/config"
+)
+
+func TestSeriesTrackerSwap(t *testing.T) {
+	vec := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "balance"}, []string{"address"})
+	tracker := newSeriesTracker()
+	kept, removed, added := prometheus.Labels{"address": "kept"}, prometheus.Labels{"address": "removed"}, prometheus.Labels{"address": "added"}
+
+	tracker.Begin()
+	tracker.Set(vec, kept, 1)
+	tracker.Set(vec, removed, 2)
+	tracker.Swap()
+
+	// Results arriving during a scrape are exported right away, next to the last values
+	tracker.Set(vec, kept, 10)
+	tracker.Set(vec, added, 3)
+	if got := testutil.CollectAndCount(vec); got != 3 {
+		t.Fatalf("Expected provisional series next to the last ones, got %d series", got)
+	}
+	if got := testutil.ToFloat64(vec.With(kept)); got != 10 {
+		t.Errorf("Expected the provisional value to replace the last one, got %v", got)
+	}
+
+	// The complete scrape no longer has removed, and added turned out not to be final
+	tracker.Begin()
+	tracker.Set(vec, kept, 11)
+	tracker.Swap()
+	if got := testutil.CollectAndCount(vec); got != 1 || testutil.ToFloat64(vec.With(kept)) != 11 {
+		t.Errorf("Expected only the series of the complete scrape to remain, got %d series", got)
+	}
+
+	// Series kept by a swap are only kept by the next one if set again
+	tracker.Begin()
+	tracker.Swap()
+	if got := testutil.CollectAndCount(vec); got != 0 {
+		t.Errorf("Expected every series to be deleted, got %d series", got)
+	}
+}
+
+func TestPublishWalletObservesRunwayOncePerScrape(t *testing.T) {
+	e := &WalletExporter{
+		config:             &config.Config{Network: "calibration"},
+		labelSets:          newWalletLabelCache("calibration"),
+		series:             newSeriesTracker(),
+		filBalanceGauge:    newGaugeVec("test", "wallet_fil_balance"),
+		usdfcBalanceGauge:  newGaugeVec("test", "wallet_usdfc_balance"),
+		walletStaleGauge:   newGaugeVec("test", "wallet_stale"),
+		walletUpdatedGauge: newGaugeVec("test", "wallet_last_updated_timestamp_seconds"),
+		walletInfoGauge:    newGaugeVec("test", "wallet_info"),
+		filRunwayGauge:     newGaugeVec("test", "wallet_fil_runway_days"),
+		usdfcRunwayGauge:   newGaugeVec("test", "wallet_usdfc_runway_days"),
+		filRunway:          newRunwayTracker(24 * time.Hour),
+		usdfcRunway:        newRunwayTracker(24 * time.Hour),
+	}
+	address := common.HexToAddress("0x01")
+	wallet := func(fil int64) WalletInfo {
+		return WalletInfo{
+			Address:      address,
+			Name:         "Client A",
+			Type:         "client",
+			FILBalance:   new(big.Int).Mul(big.NewInt(fil), big.NewInt(1e18)),
+			USDFCBalance: big.NewInt(0),
+		}
+	}
+	scrape := func(w WalletInfo) {
+		e.publishWallet(w)
+		e.updateMetrics([]WalletInfo{w}, nil)
+	}
+
+	// The provisional publish and the complete scrape add one sample together
+	scrape(wallet(100))
+	if state := e.filRunway.states[address]; state == nil || state.samples != 1 {
+		t.Fatalf("Expected one runway sample after the first scrape, got %+v", state)
+	}
+	if got := testutil.CollectAndCount(e.filRunwayGauge); got != 0 {
+		t.Errorf("Expected no runway before a spend rate is known, got %d series", got)
+	}
+
+	// 10 FIL spent over the hour before the second scrape
+	e.filRunway.states[address].observed = time.Now().Add(-time.Hour)
+	scrape(wallet(90))
+	if rate := e.filRunway.states[address].rate * 3600; math.Abs(rate-10) > 0.01 {
+		t.Errorf("Expected a spend rate of 10 FIL per hour, got %v", rate)
+	}
+	if days := testutil.ToFloat64(e.filRunwayGauge); math.Abs(days-0.375) > 0.001 {
+		t.Errorf("Expected a runway of 0.375 days, got %v", days)
+	}
+}
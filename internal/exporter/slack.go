@@ -0,0 +1,138 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"wallet-exporter/internal/config"
+)
+
+// defaultSlackTemplate renders an alert as Slack mrkdwn
+const defaultSlackTemplate = `{{if .Firing}}:rotating_light: *FIRING*{{else}}:white_check_mark: *RESOLVED*{{end}} [{{.Severity}}] {{.Rule}}
+{{escape .Summary}}
+*{{escape .Wallet.Name}}* ({{.Wallet.Type}}) ` + "`{{.Wallet.Address.Hex}}`" + `
+Balance: {{units .Wallet.FILBalance 18}} FIL, {{units .Wallet.USDFCBalance 18}} USDFC
+{{- if .URL}}
+<{{.URL}}|Wallet details>{{end}}`
+
+// slackTemplateFuncs are available to SLACK_TEMPLATE in addition to the Alert fields
+var slackTemplateFuncs = template.FuncMap{
+	"units":  FormatUnits,
+	"escape": escapeSlack,
+}
+
+// slackNotifier posts alerts to Slack incoming webhooks, choosing the webhook by rule and
+// severity
+type slackNotifier struct {
+	defaultURL string            // Webhook of rules without a route (empty = not sent)
+	routes     map[string]string // Webhook by "rule" or "rule/severity"
+	template   *template.Template
+	client     *http.Client
+}
+
+func newSlackNotifier(defaultURL string, routes []config.SlackRoute, text string) (*slackNotifier, error) {
+	if text == "" {
+		text = defaultSlackTemplate
+	}
+	tmpl, err := template.New("slack").Funcs(slackTemplateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SLACK_TEMPLATE: %w", err)
+	}
+
+	n := &slackNotifier{defaultURL: defaultURL, routes: make(map[string]string), template: tmpl, client: &http.Client{}}
+	for _, route := range routes {
+		if !alertRules[route.Rule] {
+			return nil, fmt.Errorf("SLACK_ROUTE_N: unknown alert rule %q", route.Rule)
+		}
+		if route.Severity == "" {
+			n.routes[route.Rule] = route.WebhookURL
+			continue
+		}
+		if !alertSeverities[route.Severity] {
+			return nil, fmt.Errorf("SLACK_ROUTE_N: unknown alert severity %q", route.Severity)
+		}
+		n.routes[route.Rule+"/"+route.Severity] = route.WebhookURL
+	}
+	return n, nil
+}
+
+func (s *slackNotifier) Name() string { return "slack" }
+
+// Notify posts the alerts as one message to the webhook of their rule and severity. Each alert
+// is rendered with the template; a group of alerts gets a header line counting them.
+func (s *slackNotifier) Notify(ctx context.Context, alerts []Alert) error {
+	if len(alerts) == 0 {
+		return nil
+	}
+	webhookURL := s.webhookURL(alerts[0])
+	if webhookURL == "" {
+		return nil
+	}
+
+	var text bytes.Buffer
+	if len(alerts) > 1 {
+		state := "firing"
+		if !alerts[0].Firing {
+			state = "resolved"
+		}
+		fmt.Fprintf(&text, "*%d alerts %s:* %s\n\n", len(alerts), state, alerts[0].Rule)
+	}
+	for i, alert := range alerts {
+		if i > 0 {
+			text.WriteString("\n\n")
+		}
+		if err := s.template.Execute(&text, alert); err != nil {
+			return fmt.Errorf("failed to render Slack message: %w", err)
+		}
+	}
+	return s.post(ctx, webhookURL, text.String())
+}
+
+// webhookURL returns the webhook of the route of the alert's rule and severity, else of its
+// rule, else the default one
+func (s *slackNotifier) webhookURL(alert Alert) string {
+	if webhookURL, ok := s.routes[alert.Rule+"/"+alert.Severity]; ok {
+		return webhookURL
+	}
+	if webhookURL, ok := s.routes[alert.Rule]; ok {
+		return webhookURL
+	}
+	return s.defaultURL
+}
+
+func (s *slackNotifier) post(ctx context.Context, webhookURL, text string) error {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// Webhook URLs are secrets, keep them out of the logs
+		return fmt.Errorf("slack request failed: %w", stripURLError(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		reason, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return fmt.Errorf("slack returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(reason)))
+	}
+	return nil
+}
+
+// escapeSlack escapes the characters Slack treats as markup in message text
+func escapeSlack(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
@@ -0,0 +1,46 @@
+package exporter
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// snapshotBlockKey is the context key of the block a scrape's reads are pinned to
+type snapshotBlockKey struct{}
+
+// withSnapshotBlock returns a context whose reads are pinned to the given block. Every read of
+// a scrape uses it, so balances of different wallets are taken at the same height and sums
+// across wallets are consistent.
+func withSnapshotBlock(ctx context.Context, block uint64) context.Context {
+	return context.WithValue(ctx, snapshotBlockKey{}, new(big.Int).SetUint64(block))
+}
+
+// snapshotBlock returns the block reads made with ctx are pinned to, or nil for the latest block
+func snapshotBlock(ctx context.Context) *big.Int {
+	block, _ := ctx.Value(snapshotBlockKey{}).(*big.Int)
+	return block
+}
+
+// callOpts returns contract call options bound to ctx and reading at its snapshot block
+func callOpts(ctx context.Context) *bind.CallOpts {
+	return &bind.CallOpts{Context: ctx, BlockNumber: snapshotBlock(ctx)}
+}
+
+// blockArg returns the JSON-RPC block parameter for the snapshot block of ctx
+func blockArg(ctx context.Context) string {
+	if block := snapshotBlock(ctx); block != nil {
+		return hexutil.EncodeBig(block)
+	}
+	return "latest"
+}
+
+// headBlock returns the snapshot block of ctx, or the current head if reads are not pinned
+func (e *WalletExporter) headBlock(ctx context.Context) (uint64, error) {
+	if block := snapshotBlock(ctx); block != nil {
+		return block.Uint64(), nil
+	}
+	return e.client.BlockNumber(ctx)
+}
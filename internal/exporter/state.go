@@ -0,0 +1,150 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// stateVersion is bumped when savedState changes incompatibly; files of other versions are ignored
+const stateVersion = 1
+
+// savedState is the last scrape as written to STATE_FILE, so a restarted exporter serves the
+// last known values until its first scrape completes
+type savedState struct {
+	Version     int                     `json:"version"`
+	Network     string                  `json:"network"`
+	SavedAt     time.Time               `json:"saved_at"`
+	LastScrape  time.Time               `json:"last_scrape"`
+	LastSuccess time.Time               `json:"last_success"`
+	Block       uint64                  `json:"block"`
+	Wallets     []WalletInfo            `json:"wallets"`
+	PingResults map[uint64][]PingResult `json:"ping_results,omitempty"`
+}
+
+func (e *WalletExporter) registerStateMetrics() {
+	e.stateRestoredGauge = newGauge(e.config.MetricsPrefix, "state_restored")
+	e.registry.MustRegister(e.stateRestoredGauge)
+}
+
+// saveState writes the last scrape to STATE_FILE
+func (e *WalletExporter) saveState() error {
+	e.walletsMux.RLock()
+	state := savedState{
+		Version:     stateVersion,
+		Network:     e.config.Network,
+		SavedAt:     time.Now(),
+		LastScrape:  e.lastScrape,
+		LastSuccess: e.lastSuccess,
+		Block:       e.lastScrapeBlock,
+		Wallets:     e.wallets,
+		PingResults: e.lastPingResults,
+	}
+	e.walletsMux.RUnlock()
+	if len(state.Wallets) == 0 {
+		return nil // Nothing worth keeping over the previous state
+	}
+
+	if err := writeState(e.config.StateFile, state); err != nil {
+		return err
+	}
+	e.lastStateSave = state.SavedAt
+	return nil
+}
+
+// restoreState loads STATE_FILE and publishes its wallets as if they had just been scraped,
+// flagged as restored until the first scrape replaces them. A missing file is not an error.
+func (e *WalletExporter) restoreState() error {
+	state, err := readState(e.config.StateFile)
+	if err != nil || state == nil {
+		return err
+	}
+	if state.Version != stateVersion || state.Network != e.config.Network {
+		e.logger.Warn("Ignoring state file of another version or network", "path", e.config.StateFile, "version", state.Version, "network", state.Network)
+		return nil
+	}
+
+	e.walletsMux.Lock()
+	e.wallets = state.Wallets
+	e.lastPingResults = state.PingResults
+	e.lastScrape = state.LastScrape
+	e.lastSuccess = state.LastSuccess
+	e.restored = true
+	e.walletsMux.Unlock()
+	e.lastScrapeBlock = state.Block
+	if !state.LastSuccess.IsZero() {
+		e.lastSuccessGauge.Set(float64(state.LastSuccess.Unix()))
+	}
+
+	e.updateMetrics(state.Wallets, state.PingResults)
+	e.updateProductMetrics(state.Wallets)
+	if e.config.ExportRails {
+		e.updateRailMetrics(state.Wallets)
+	}
+	if e.config.ExportDataSets {
+		e.updateDataSetMetrics(state.Wallets)
+	}
+	e.stateRestoredGauge.Set(1)
+
+	e.logger.Info("Restored last scrape from state file", "path", e.config.StateFile, "wallets", len(state.Wallets), "last_scrape", state.LastScrape)
+	return nil
+}
+
+// stateScraped clears the restored flag after a scrape and saves the state if
+// STATE_SAVE_INTERVAL has passed since the last save
+func (e *WalletExporter) stateScraped() {
+	e.walletsMux.Lock()
+	e.restored = false
+	e.walletsMux.Unlock()
+	e.stateRestoredGauge.Set(0)
+
+	if e.config.StateSaveInterval > 0 && time.Since(e.lastStateSave) >= e.config.StateSaveInterval {
+		if err := e.saveState(); err != nil {
+			e.logger.Warn("Failed to save state", "error", err)
+		}
+	}
+}
+
+// writeState replaces the file at path with state. The data is written to a temporary file
+// that is renamed over path, so a crash while saving leaves the previous state in place.
+func writeState(path string, state savedState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	return nil
+}
+
+// readState reads the state at path, nil if there is no file
+func readState(path string) (*savedState, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state: %w", err)
+	}
+
+	var state savedState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to decode state: %w", err)
+	}
+	return &state, nil
+}
@@ -0,0 +1,61 @@
+package exporter
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestStateRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if state, err := readState(path); err != nil || state != nil {
+		t.Fatalf("Expected no state without a file, got %v, %v", state, err)
+	}
+
+	saved := savedState{
+		Version:    stateVersion,
+		Network:    "calibration",
+		LastScrape: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Block:      1234,
+		Wallets: []WalletInfo{{
+			Address:    common.HexToAddress("0x01"),
+			Name:       "sp",
+			Type:       "provider",
+			ProviderID: 7,
+			FILBalance: big.NewInt(5),
+			PaymentsAccounts: []PaymentsAccount{
+				{Token: "usdfc", Decimals: 18, PaymentsInfo: &PaymentsInfo{Funds: big.NewInt(100), FundedUntilEpoch: big.NewInt(9999)}},
+				{Token: "fil", Decimals: 18}, // Lookup failed
+			},
+			Products: []ProviderProduct{{Type: 0, IsActive: true, Capabilities: map[string]string{"location": "EU"}}},
+		}},
+		PingResults: map[uint64][]PingResult{7: {{ProductType: 0, Success: true, Duration: 150 * time.Millisecond}}},
+	}
+	if err := writeState(path, saved); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := readState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wallet := state.Wallets[0]
+	if state.Block != 1234 || !state.LastScrape.Equal(saved.LastScrape) || wallet.FILBalance.Int64() != 5 || wallet.Region() != "EU" {
+		t.Errorf("Unexpected state %+v", state)
+	}
+	if wallet.PaymentsAccounts[0].Funds.Int64() != 100 || wallet.PaymentsAccounts[1].PaymentsInfo != nil {
+		t.Errorf("Unexpected Payments accounts %+v", wallet.PaymentsAccounts)
+	}
+	if pings := state.PingResults[7]; len(pings) != 1 || pings[0].Duration != 150*time.Millisecond {
+		t.Errorf("Unexpected ping results %+v", state.PingResults)
+	}
+
+	// No temporary files are left behind
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("Expected only the state file, got %d entries", len(entries))
+	}
+}
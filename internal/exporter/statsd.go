@@ -0,0 +1,108 @@
+package exporter
+
+import (
+	"context"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// statsdMaxPacket keeps StatsD datagrams within a typical MTU
+const statsdMaxPacket = 1432
+
+// statsdSink sends metrics to a StatsD server over UDP. Gauges are sent as gauges; counters as
+// the increase since the previous push, as StatsD counters are deltas.
+type statsdSink struct {
+	address string
+	prefix  string // Path prefix, e.g. "wallets.prod"
+	tags    bool   // Send labels as DogStatsD tags instead of path components
+
+	sent map[string]float64 // Counter values of the previous push by series (only touched by the pusher)
+}
+
+func newStatsdSink(address, prefix string, tags bool) *statsdSink {
+	return &statsdSink{address: address, prefix: prefix, tags: tags, sent: make(map[string]float64)}
+}
+
+func (s *statsdSink) Name() string { return "statsd" }
+
+// Push sends one line per sample, packed into as few datagrams as fit
+func (s *statsdSink) Push(ctx context.Context, families []*dto.MetricFamily, now time.Time) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", s.address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var packet []byte
+	flush := func() error {
+		if len(packet) == 0 {
+			return nil
+		}
+		_, err := conn.Write(packet)
+		packet = packet[:0]
+		return err
+	}
+	for _, sample := range pushSamples(families) {
+		line := s.line(sample)
+		if line == "" {
+			continue
+		}
+		if len(packet) > 0 && len(packet)+1+len(line) > statsdMaxPacket {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		if len(packet) > 0 {
+			packet = append(packet, '\n')
+		}
+		packet = append(packet, line...)
+	}
+	return flush()
+}
+
+// line formats a sample, empty for a counter that did not increase or a value that is not
+// finite, like the runway of a wallet that is not spending
+func (s *statsdSink) line(sample pushSample) string {
+	if math.IsInf(sample.value, 0) || math.IsNaN(sample.value) {
+		return ""
+	}
+
+	name, tags := metricPath(s.prefix, sample), ""
+	if s.tags {
+		name = sample.name
+		if s.prefix != "" {
+			name = strings.Trim(s.prefix, ".") + "." + name
+		}
+		var pairs []string
+		for _, label := range sample.labels {
+			if label.GetValue() != "" {
+				pairs = append(pairs, label.GetName()+":"+strings.NewReplacer(",", "_", "|", "_", "#", "_").Replace(label.GetValue()))
+			}
+		}
+		if len(pairs) > 0 {
+			tags = "|#" + strings.Join(pairs, ",")
+		}
+	}
+
+	if !sample.counter {
+		gauge := name + ":" + strconv.FormatFloat(sample.value, 'f', -1, 64) + "|g" + tags
+		if sample.value < 0 {
+			// A signed gauge value changes the gauge instead of setting it, so zero it first
+			gauge = name + ":0|g" + tags + "\n" + gauge
+		}
+		return gauge
+	}
+	key := name + tags
+	delta := sample.value - s.sent[key]
+	s.sent[key] = sample.value
+	if delta <= 0 {
+		return ""
+	}
+	return name + ":" + strconv.FormatFloat(delta, 'f', -1, 64) + "|c" + tags
+}
@@ -0,0 +1,44 @@
+package exporter
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ScrapeStatus summarizes the recent scrapes for the status API
+type ScrapeStatus struct {
+	LastScrape   time.Time
+	LastSuccess  time.Time // Zero until a scrape found wallets or ran without errors
+	LastDuration time.Duration
+	LastErrors   uint64 // Errors counted during the last scrape
+	TotalErrors  uint64 // Errors counted since startup
+	Restored     bool   // The wallets were restored from STATE_FILE and no scrape has completed yet
+}
+
+// errorCounter is the scrape error counter. It keeps its own count so the status API can
+// report errors without reading back the Prometheus metric.
+type errorCounter struct {
+	prometheus.Counter
+	count atomic.Uint64
+}
+
+func (c *errorCounter) Inc() {
+	c.Counter.Inc()
+	c.count.Add(1)
+}
+
+// GetScrapeStatus returns the outcome of the last scrape and the errors seen since startup
+func (e *WalletExporter) GetScrapeStatus() ScrapeStatus {
+	e.walletsMux.RLock()
+	defer e.walletsMux.RUnlock()
+	return ScrapeStatus{
+		LastScrape:   e.lastScrape,
+		LastSuccess:  e.lastSuccess,
+		LastDuration: e.lastScrapeDuration,
+		LastErrors:   e.lastScrapeErrors,
+		TotalErrors:  e.scrapeErrors.count.Load(),
+		Restored:     e.restored,
+	}
+}
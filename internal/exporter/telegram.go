@@ -0,0 +1,119 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// telegramAPI is the base URL of the Telegram Bot API
+const telegramAPI = "https://api.telegram.org"
+
+// telegramNotifier sends alerts as messages of a Telegram bot to one chat, and optionally
+// critical alerts to another
+type telegramNotifier struct {
+	baseURL        string
+	token          string
+	chatID         string
+	criticalChatID string // Empty = chatID
+	client         *http.Client
+}
+
+func newTelegramNotifier(token, chatID, criticalChatID string) *telegramNotifier {
+	return &telegramNotifier{baseURL: telegramAPI, token: token, chatID: chatID, criticalChatID: criticalChatID, client: &http.Client{}}
+}
+
+func (t *telegramNotifier) Name() string { return "telegram" }
+
+// Notify sends the alerts as one message
+func (t *telegramNotifier) Notify(ctx context.Context, alerts []Alert) error {
+	if len(alerts) == 0 {
+		return nil
+	}
+	chatID := t.chatID
+	if alerts[0].Severity == SeverityCritical && t.criticalChatID != "" {
+		chatID = t.criticalChatID
+	}
+	return t.send(ctx, chatID, formatTelegramAlerts(alerts))
+}
+
+func (t *telegramNotifier) send(ctx context.Context, chatID, text string) error {
+	body, err := json.Marshal(map[string]any{
+		"chat_id":                  chatID,
+		"text":                     text,
+		"parse_mode":               "HTML",
+		"disable_web_page_preview": true,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.baseURL+"/bot"+t.token+"/sendMessage", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		// The request URL contains the bot token, keep it out of the logs
+		return fmt.Errorf("telegram request failed: %w", stripURLError(err))
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("telegram returned status %d", resp.StatusCode)
+	}
+	if !result.OK {
+		return fmt.Errorf("telegram rejected the message: %s", result.Description)
+	}
+	return nil
+}
+
+// formatTelegramAlerts renders alerts of one rule, severity and state as a Telegram HTML
+// message
+func formatTelegramAlerts(alerts []Alert) string {
+	first := alerts[0]
+	state := "FIRING"
+	if !first.Firing {
+		state = "RESOLVED"
+	}
+
+	var b strings.Builder
+	if len(alerts) == 1 {
+		fmt.Fprintf(&b, "<b>%s</b> [%s] %s\n", state, first.Severity, html.EscapeString(first.Rule))
+	} else {
+		fmt.Fprintf(&b, "<b>%s</b> [%s] %s: %d wallets\n", state, first.Severity, html.EscapeString(first.Rule), len(alerts))
+	}
+	for _, alert := range alerts {
+		fmt.Fprintf(&b, "%s\n\n", html.EscapeString(alert.Summary))
+		wallet := alert.Wallet
+		fmt.Fprintf(&b, "<b>%s</b> (%s)\n", html.EscapeString(wallet.Name), html.EscapeString(wallet.Type))
+		fmt.Fprintf(&b, "<code>%s</code>\n", wallet.Address.Hex())
+		fmt.Fprintf(&b, "Balance: %s FIL, %s USDFC\n", FormatUnits(wallet.FILBalance, 18), FormatUnits(wallet.USDFCBalance, 18))
+		if alert.URL != "" {
+			fmt.Fprintf(&b, "<a href=\"%s\">Wallet details</a>\n", html.EscapeString(alert.URL))
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// stripURLError drops the request URL from an HTTP client error
+func stripURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
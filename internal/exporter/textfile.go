@@ -0,0 +1,71 @@
+package exporter
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// textfileSink writes the metrics to a .prom file for the node_exporter textfile collector,
+// for hosts where the exporter may not open a port of its own
+type textfileSink struct {
+	path string
+}
+
+func (t *textfileSink) Name() string { return "textfile" }
+
+// Push replaces the file with the metrics in the text exposition format
+func (t *textfileSink) Push(_ context.Context, families []*dto.MetricFamily, _ time.Time) error {
+	return writeTextfile(t.path, families)
+}
+
+// WriteTextfile writes the current metrics to path, like the TEXTFILE_PATH sink does after
+// every scrape
+func (e *WalletExporter) WriteTextfile(path string) error {
+	families, err := e.registry.Gather()
+	if err != nil {
+		return fmt.Errorf("failed to gather metrics: %w", err)
+	}
+	return writeTextfile(path, families)
+}
+
+// writeTextfile writes families to a temporary file that is renamed over path, so the
+// textfile collector never reads a partial file. The temporary name does not end in .prom,
+// which the collector ignores.
+func writeTextfile(path string, families []*dto.MetricFamily) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write textfile: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	for _, family := range families {
+		if _, err := expfmt.MetricFamilyToText(w, family); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to write textfile: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write textfile: %w", err)
+	}
+	// CreateTemp uses 0600, but node_exporter usually runs as another user
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write textfile: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write textfile: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write textfile: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package exporter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTextfileSink(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "wallet-exporter.prom")
+	sink := &textfileSink{path: path}
+
+	for _, errors := range []float64{3, 5} {
+		if err := sink.Push(context.Background(), pushTestFamilies(t, errors), time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(data)
+	for _, want := range []string{
+		"# TYPE test_scrape_errors_total counter\ntest_scrape_errors_total 5\n",
+		`test_wallet_mempool_transactions{address="0x01",name="Client A",network="calibration",type="client"} 2`,
+		"+Inf",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected textfile to contain %q, got\n%s", want, text)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o644 {
+		t.Errorf("Expected mode 0644, got %v", info.Mode().Perm())
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected only the textfile to be left, got %d files", len(entries))
+	}
+}
@@ -0,0 +1,106 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"wallet-exporter/internal/contracts"
+)
+
+func (e *WalletExporter) registerTransferMetrics() {
+	e.transfersCounter = newCounterVec(e.config.MetricsPrefix, "wallet_usdfc_transfers_total")
+	e.transferredAmountCounter = newCounterVec(e.config.MetricsPrefix, "wallet_usdfc_transferred_amount_total")
+
+	e.registry.MustRegister(e.transfersCounter)
+	e.registry.MustRegister(e.transferredAmountCounter)
+}
+
+// collectTransfers counts USDFC Transfer events into and out of the given wallets in the
+// blocks mined since the previous call. Like Payments events, history is not backfilled.
+func (e *WalletExporter) collectTransfers(ctx context.Context, wallets []WalletInfo) {
+	head, err := e.headBlock(ctx)
+	if err != nil {
+		e.logger.Warn("Failed to get head block for USDFC transfers", "error", err)
+		e.scrapeErrors.Inc()
+		return
+	}
+
+	byAddress := make(map[common.Address]WalletInfo, len(wallets))
+	addresses := make([]common.Address, 0, len(wallets))
+	for _, wallet := range wallets {
+		byAddress[wallet.Address] = wallet
+		addresses = append(addresses, wallet.Address)
+
+		// Initialize series so the first transfer shows up as an increase
+		for _, direction := range []string{"in", "out"} {
+			labels := e.transferLabels(wallet, direction)
+			e.transfersCounter.With(labels)
+			e.transferredAmountCounter.With(labels)
+		}
+	}
+
+	if len(addresses) == 0 {
+		return
+	}
+
+	usdfcAddr := common.HexToAddress(e.config.USDFCTokenAddress)
+	filterer, err := contracts.NewERC20Filterer(usdfcAddr, e.client)
+	if err != nil {
+		e.logger.Warn("Failed to create USDFC filterer", "error", err)
+		e.scrapeErrors.Inc()
+		return
+	}
+
+	err = filterNewBlocks(ctx, &e.transfersNextBlock, head, func(opts *bind.FilterOpts) error {
+		if err := e.filterTransfers(filterer, opts, "in", nil, addresses, byAddress); err != nil {
+			return fmt.Errorf("failed to filter inbound transfers: %w", err)
+		}
+		if err := e.filterTransfers(filterer, opts, "out", addresses, nil, byAddress); err != nil {
+			return fmt.Errorf("failed to filter outbound transfers: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		e.logger.Warn("Failed to collect USDFC transfers", "error", err)
+		e.scrapeErrors.Inc()
+	}
+}
+
+// filterTransfers counts the transfers matching from/to, attributing them to the receiving
+// wallet for direction "in" and to the sending wallet for direction "out"
+func (e *WalletExporter) filterTransfers(filterer *contracts.ERC20Filterer, opts *bind.FilterOpts, direction string, from, to []common.Address, wallets map[common.Address]WalletInfo) error {
+	it, err := filterer.FilterTransfer(opts, from, to)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	// USDFC is always the first Payments token
+	decimals := e.paymentsTokens[0].Decimals
+
+	for it.Next() {
+		address := it.Event.To
+		if direction == "out" {
+			address = it.Event.From
+		}
+
+		labels := e.transferLabels(wallets[address], direction)
+		e.transfersCounter.With(labels).Inc()
+		e.transferredAmountCounter.With(labels).Add(tokenAmount(it.Event.Value, decimals))
+	}
+	return it.Error()
+}
+
+func (e *WalletExporter) transferLabels(wallet WalletInfo, direction string) prometheus.Labels {
+	return prometheus.Labels{
+		"address":   wallet.Address.Hex(),
+		"network":   e.config.Network,
+		"name":      wallet.Name,
+		"type":      wallet.Type,
+		"direction": direction,
+	}
+}
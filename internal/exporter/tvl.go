@@ -0,0 +1,93 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"wallet-exporter/internal/contracts"
+)
+
+// ContractBalance is a token balance held by one of the protocol contracts
+type ContractBalance struct {
+	Contract string
+	Address  common.Address
+	Token    string // "fil" for the native balance, otherwise the Payments token symbol
+	Balance  *big.Int
+	Decimals int
+}
+
+func (e *WalletExporter) registerTVLMetrics() {
+	e.contractBalanceGauge = newGaugeVec(e.config.MetricsPrefix, "contract_balance")
+
+	e.registry.MustRegister(e.contractBalanceGauge)
+}
+
+// tvlContracts are the protocol contracts whose holdings make up the TVL. The registry is
+// included because provider registration fees accumulate there.
+func (e *WalletExporter) tvlContracts() []contractBinding {
+	return []contractBinding{
+		{Name: "Payments", Address: common.HexToAddress(e.config.PaymentsAddress)},
+		{Name: "WarmStorageService", Address: e.warmStorageAddress},
+		{Name: "ServiceProviderRegistry", Address: e.registryAddress},
+	}
+}
+
+// fetchContractBalances reads the native balance and every Payments token balance of the protocol contracts
+func (e *WalletExporter) fetchContractBalances(ctx context.Context) ([]ContractBalance, error) {
+	var balances []ContractBalance
+
+	for _, c := range e.tvlContracts() {
+		filBalance, err := e.client.BalanceAt(ctx, c.Address, snapshotBlock(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get FIL balance of %s: %w", c.Name, err)
+		}
+		balances = append(balances, ContractBalance{
+			Contract: c.Name,
+			Address:  c.Address,
+			Token:    "fil",
+			Balance:  filBalance,
+			Decimals: nativeTokenDecimals,
+		})
+
+		for _, token := range e.paymentsTokens {
+			// The native account is already covered by the FIL balance
+			if token.Address == (common.Address{}) {
+				continue
+			}
+
+			erc20, err := contracts.NewERC20(token.Address, e.client)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create %s contract: %w", token.Symbol, err)
+			}
+			tokenBalance, err := erc20.BalanceOf(callOpts(ctx), c.Address)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get %s balance of %s: %w", token.Symbol, c.Name, err)
+			}
+			balances = append(balances, ContractBalance{
+				Contract: c.Name,
+				Address:  c.Address,
+				Token:    token.Symbol,
+				Balance:  tokenBalance,
+				Decimals: token.Decimals,
+			})
+		}
+	}
+
+	return balances, nil
+}
+
+func (e *WalletExporter) updateTVLMetrics(balances []ContractBalance) {
+	e.contractBalanceGauge.Reset()
+
+	for _, b := range balances {
+		e.contractBalanceGauge.With(prometheus.Labels{
+			"contract": b.Contract,
+			"address":  b.Address.Hex(),
+			"token":    b.Token,
+		}).Set(tokenAmount(b.Balance, b.Decimals))
+	}
+}
@@ -0,0 +1,32 @@
+package exporter
+
+import (
+	"math/big"
+	"strings"
+)
+
+// FormatUnits renders a raw token amount with the given number of decimals as an exact
+// decimal string (e.g. 1500000000000000000 with 18 decimals becomes "1.5")
+func FormatUnits(amount *big.Int, decimals int) string {
+	if amount == nil {
+		return "0"
+	}
+
+	negative := amount.Sign() < 0
+	digits := new(big.Int).Abs(amount).String()
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+
+	whole := digits[:len(digits)-decimals]
+	fraction := strings.TrimRight(digits[len(digits)-decimals:], "0")
+
+	result := whole
+	if fraction != "" {
+		result += "." + fraction
+	}
+	if negative {
+		result = "-" + result
+	}
+	return result
+}
@@ -0,0 +1,28 @@
+package exporter
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestFormatUnits(t *testing.T) {
+	tests := []struct {
+		amount   string
+		decimals int
+		expected string
+	}{
+		{"0", 18, "0"},
+		{"1500000000000000000", 18, "1.5"},
+		{"1", 18, "0.000000000000000001"},
+		{"123000000000000000000", 18, "123"},
+		{"-250000000000000000", 18, "-0.25"},
+		{"42", 0, "42"},
+	}
+
+	for _, tt := range tests {
+		amount, _ := new(big.Int).SetString(tt.amount, 10)
+		if got := FormatUnits(amount, tt.decimals); got != tt.expected {
+			t.Errorf("FormatUnits(%s, %d) = %s, want %s", tt.amount, tt.decimals, got, tt.expected)
+		}
+	}
+}
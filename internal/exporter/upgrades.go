@@ -0,0 +1,129 @@
+package exporter
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Kinds of contract changes, used as the "kind" label of the change counter
+const (
+	contractAddressChange        = "address"
+	contractImplementationChange = "implementation"
+)
+
+// contractState is where a protocol contract lives and, for EIP-1967 proxies, which
+// implementation it currently delegates to (zero if it is not a proxy)
+type contractState struct {
+	Address        common.Address
+	Implementation common.Address
+}
+
+func (e *WalletExporter) registerUpgradeMetrics() {
+	e.contractImplementationGauge = newGaugeVec(e.config.MetricsPrefix, "contract_implementation_info")
+	e.contractChangesCounter = newCounterVec(e.config.MetricsPrefix, "contract_changes_total")
+	e.contractStaleGauge = newGaugeVec(e.config.MetricsPrefix, "contract_binding_stale")
+
+	e.registry.MustRegister(e.contractImplementationGauge)
+	e.registry.MustRegister(e.contractChangesCounter)
+	e.registry.MustRegister(e.contractStaleGauge)
+
+	ctx, cancel := context.WithTimeout(context.Background(), bindingCheckTimeout)
+	defer cancel()
+	e.contractStates = e.readContractStates(ctx)
+	e.updateUpgradeMetrics()
+}
+
+// readContractStates re-resolves the view contract and registry through WarmStorage and
+// reads the proxy implementation of every bound contract. Contracts that fail to resolve
+// are left out and keep their previous state.
+func (e *WalletExporter) readContractStates(ctx context.Context) map[string]contractState {
+	states := make(map[string]contractState)
+
+	for _, b := range e.bindings() {
+		address := b.Address
+		switch b.Name {
+		case "WarmStorageServiceStateView":
+			resolved, err := e.warmStorageContract.ViewContractAddress(callOpts(ctx))
+			if err != nil {
+				e.logger.Warn("Failed to re-resolve view contract address", "error", err)
+				continue
+			}
+			address = resolved
+		case "ServiceProviderRegistry":
+			resolved, err := e.warmStorageContract.ServiceProviderRegistry(callOpts(ctx))
+			if err != nil {
+				e.logger.Warn("Failed to re-resolve registry address", "error", err)
+				continue
+			}
+			address = resolved
+		}
+
+		implementation, _ := e.implementationAddress(ctx, address)
+		states[b.Name] = contractState{Address: address, Implementation: implementation}
+	}
+
+	return states
+}
+
+// checkContractUpgrades compares the protocol contracts with the previous scrape, counts and
+// logs every address or implementation change and re-runs the ABI drift check when one is found.
+// Bindings keep using the addresses resolved at startup, so an address change needs a restart.
+func (e *WalletExporter) checkContractUpgrades(ctx context.Context) {
+	changed := false
+	for name, current := range e.readContractStates(ctx) {
+		previous, known := e.contractStates[name]
+		e.contractStates[name] = current
+		if !known {
+			continue
+		}
+
+		if current.Address != previous.Address {
+			changed = true
+			e.contractChangesCounter.WithLabelValues(name, contractAddressChange).Inc()
+			e.logger.Warn("Contract address changed, restart the exporter to bind the new contract",
+				"contract", name, "previous", previous.Address.Hex(), "current", current.Address.Hex())
+		}
+		if current.Implementation != previous.Implementation {
+			changed = true
+			e.contractChangesCounter.WithLabelValues(name, contractImplementationChange).Inc()
+			e.logger.Warn("Contract implementation upgraded",
+				"contract", name, "address", current.Address.Hex(),
+				"previous", previous.Implementation.Hex(), "current", current.Implementation.Hex())
+		}
+	}
+
+	if changed {
+		e.checkABIDrift(ctx, e.bindings())
+	}
+	e.updateUpgradeMetrics()
+}
+
+func (e *WalletExporter) updateUpgradeMetrics() {
+	e.contractImplementationGauge.Reset()
+	e.contractStaleGauge.Reset()
+
+	bound := make(map[string]common.Address)
+	for _, b := range e.bindings() {
+		bound[b.Name] = b.Address
+	}
+
+	for name, state := range e.contractStates {
+		implementation := ""
+		if state.Implementation != (common.Address{}) {
+			implementation = state.Implementation.Hex()
+		}
+		e.contractImplementationGauge.With(prometheus.Labels{
+			"contract":       name,
+			"address":        state.Address.Hex(),
+			"implementation": implementation,
+		}).Set(1)
+
+		stale := 0.0
+		if state.Address != bound[name] {
+			stale = 1.0
+		}
+		e.contractStaleGauge.WithLabelValues(name).Set(stale)
+	}
+}
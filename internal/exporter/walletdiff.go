@@ -0,0 +1,154 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Snapshot is every wallet at one block, as written by the snapshot command. It has the
+// layout of STATE_FILE, so a copy of the state file can be diffed as well.
+type Snapshot struct {
+	Version    int          `json:"version"`
+	Network    string       `json:"network"`
+	LastScrape time.Time    `json:"last_scrape"`
+	Block      uint64       `json:"block"`
+	Wallets    []WalletInfo `json:"wallets"`
+}
+
+// Snapshot returns the wallets of the last scrape
+func (e *WalletExporter) Snapshot() Snapshot {
+	e.walletsMux.RLock()
+	defer e.walletsMux.RUnlock()
+	return Snapshot{
+		Version:    stateVersion,
+		Network:    e.config.Network,
+		LastScrape: e.lastScrape,
+		Block:      e.lastScrapeBlock,
+		Wallets:    e.wallets,
+	}
+}
+
+// ReadSnapshot reads a snapshot or state file
+func ReadSnapshot(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("invalid snapshot %s: %w", path, err)
+	}
+	if snapshot.Version != stateVersion {
+		return nil, fmt.Errorf("snapshot %s has version %d, expected %d", path, snapshot.Version, stateVersion)
+	}
+	return &snapshot, nil
+}
+
+// BalanceChange is the change of one balance of a wallet between two snapshots
+type BalanceChange struct {
+	Address  common.Address `json:"address"`
+	Name     string         `json:"name"`
+	Type     string         `json:"type"`
+	Asset    string         `json:"asset"` // fil, usdfc, or payments_<token> for Payments funds
+	Decimals int            `json:"decimals"`
+	Before   *big.Int       `json:"before"` // nil if the wallet or balance is missing from the first snapshot
+	After    *big.Int       `json:"after"`  // nil if the wallet or balance is missing from the second snapshot
+	Change   *big.Int       `json:"change"`
+}
+
+// walletBalance is a balance of a wallet as compared by DiffSnapshots
+type walletBalance struct {
+	asset    string
+	decimals int
+	amount   *big.Int
+}
+
+// balances lists the balances of a wallet that are compared, in display order
+func (w WalletInfo) balances() []walletBalance {
+	balances := []walletBalance{
+		{"fil", nativeTokenDecimals, w.FILBalance},
+		{"usdfc", nativeTokenDecimals, w.USDFCBalance},
+	}
+	for _, account := range w.PaymentsAccounts {
+		if account.PaymentsInfo != nil {
+			balances = append(balances, walletBalance{"payments_" + account.Token, account.Decimals, account.Funds})
+		}
+	}
+	return balances
+}
+
+// DiffSnapshots returns the balance changes of every wallet from a to b, in the wallet order of
+// b followed by wallets only in a. Unchanged balances are left out unless all is set.
+func DiffSnapshots(a, b *Snapshot, all bool) []BalanceChange {
+	before := make(map[common.Address]WalletInfo, len(a.Wallets))
+	for _, wallet := range a.Wallets {
+		before[wallet.Address] = wallet
+	}
+
+	var changes []BalanceChange
+	seen := make(map[common.Address]bool, len(b.Wallets))
+	for _, wallet := range b.Wallets {
+		seen[wallet.Address] = true
+		changes = append(changes, diffWallet(before[wallet.Address], wallet, all)...)
+	}
+	for _, wallet := range a.Wallets {
+		if !seen[wallet.Address] {
+			changes = append(changes, diffWallet(wallet, WalletInfo{}, all)...)
+		}
+	}
+	return changes
+}
+
+// diffWallet compares the balances of one wallet; a zero WalletInfo is a wallet missing from
+// that snapshot
+func diffWallet(a, b WalletInfo, all bool) []BalanceChange {
+	wallet := b
+	var beforeBalances, afterBalances []walletBalance
+	if a.Address != (common.Address{}) {
+		beforeBalances = a.balances()
+	}
+	if b.Address != (common.Address{}) {
+		afterBalances = b.balances()
+	} else {
+		wallet = a
+	}
+
+	var changes []BalanceChange
+	add := func(asset string, decimals int, before, after *big.Int) {
+		change := new(big.Int)
+		if after != nil {
+			change.Set(after)
+		}
+		if before != nil {
+			change.Sub(change, before)
+		}
+		if change.Sign() == 0 && (before == nil) == (after == nil) && !all {
+			return
+		}
+		changes = append(changes, BalanceChange{
+			Address: wallet.Address, Name: wallet.Name, Type: wallet.Type,
+			Asset: asset, Decimals: decimals, Before: before, After: after, Change: change,
+		})
+	}
+
+	beforeAmounts := make(map[string]*big.Int, len(beforeBalances))
+	for _, balance := range beforeBalances {
+		beforeAmounts[balance.asset] = balance.amount
+	}
+	afterAssets := make(map[string]bool, len(afterBalances))
+	for _, balance := range afterBalances {
+		afterAssets[balance.asset] = true
+		add(balance.asset, balance.decimals, beforeAmounts[balance.asset], balance.amount)
+	}
+	for _, balance := range beforeBalances {
+		if !afterAssets[balance.asset] {
+			add(balance.asset, balance.decimals, balance.amount, nil)
+		}
+	}
+	return changes
+}
@@ -0,0 +1,71 @@
+package exporter
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	client := common.HexToAddress("0x01")
+	removed := common.HexToAddress("0x02")
+	added := common.HexToAddress("0x03")
+	usdfc := func(funds int64) []PaymentsAccount {
+		return []PaymentsAccount{{Token: "usdfc", Decimals: 18, PaymentsInfo: &PaymentsInfo{Funds: big.NewInt(funds)}}}
+	}
+
+	a := &Snapshot{Wallets: []WalletInfo{
+		{Address: client, Name: "client", FILBalance: big.NewInt(10), USDFCBalance: big.NewInt(5), PaymentsAccounts: usdfc(100)},
+		{Address: removed, Name: "old", FILBalance: big.NewInt(1), USDFCBalance: big.NewInt(0)},
+	}}
+	b := &Snapshot{Wallets: []WalletInfo{
+		{Address: client, Name: "client", FILBalance: big.NewInt(7), USDFCBalance: big.NewInt(5), PaymentsAccounts: usdfc(40)},
+		{Address: added, Name: "new", FILBalance: big.NewInt(0), USDFCBalance: big.NewInt(2)},
+	}}
+
+	type change struct {
+		address common.Address
+		asset   string
+		change  int64
+	}
+	var got []change
+	for _, c := range DiffSnapshots(a, b, false) {
+		got = append(got, change{c.Address, c.Asset, c.Change.Int64()})
+	}
+	want := []change{
+		{client, "fil", -3},
+		{client, "payments_usdfc", -60},
+		{added, "fil", 0}, // New wallets list every balance, even zero ones
+		{added, "usdfc", 2},
+		{removed, "fil", -1},
+		{removed, "usdfc", 0},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Change %d: expected %v, got %v", i, want[i], got[i])
+		}
+	}
+
+	if changes := DiffSnapshots(a, b, true); len(changes) != len(want)+1 {
+		t.Errorf("Expected the unchanged USDFC balance with all, got %d changes", len(changes))
+	}
+}
+
+func TestReadSnapshotOfStateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := writeState(path, savedState{Version: stateVersion, Network: "mainnet", Block: 42}); err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := ReadSnapshot(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.Network != "mainnet" || snapshot.Block != 42 {
+		t.Errorf("Unexpected snapshot %+v", snapshot)
+	}
+}
@@ -0,0 +1,104 @@
+// Package filaddr converts between Filecoin addresses and the 0x addresses the FEVM uses for them.
+//
+// An f410 (delegated) address wraps a 20-byte Ethereum address in the Ethereum Address
+// Manager namespace; both forms name the same account. ID addresses (f0) appear on the EVM
+// side as masked 0xff000000... addresses.
+package filaddr
+
+import (
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// Address protocols
+const (
+	ProtocolID        = 0
+	ProtocolActor     = 2
+	ProtocolDelegated = 4
+
+	// eamNamespace is the actor ID of the Ethereum Address Manager
+	eamNamespace = 10
+
+	checksumLength = 4
+)
+
+// encoding is the lowercase, unpadded base32 alphabet of Filecoin addresses
+var encoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// Protocol returns the protocol of a Filecoin address on mainnet (f) or a testnet (t)
+func Protocol(s string) (int, bool) {
+	if len(s) < 3 || (s[0] != 'f' && s[0] != 't') || s[1] < '0' || s[1] > '4' {
+		return 0, false
+	}
+	return int(s[1] - '0'), true
+}
+
+// IsDelegated reports whether s looks like an f410 address on mainnet (f) or a testnet (t)
+func IsDelegated(s string) bool {
+	return strings.HasPrefix(s, "f410f") || strings.HasPrefix(s, "t410f")
+}
+
+// ToEth returns the 0x form of an f410 address after checking its checksum
+func ToEth(s string) (string, error) {
+	if !IsDelegated(s) {
+		return "", fmt.Errorf("%q is not an f410 address", s)
+	}
+
+	payload, err := encoding.DecodeString(s[len("f410f"):])
+	if err != nil {
+		return "", fmt.Errorf("invalid f410 address %q: %w", s, err)
+	}
+	if len(payload) != 20+checksumLength {
+		return "", fmt.Errorf("invalid f410 address %q: payload is %d bytes", s, len(payload))
+	}
+
+	var address [20]byte
+	copy(address[:], payload)
+	if string(payload[20:]) != string(delegatedChecksum(address)) {
+		return "", fmt.Errorf("invalid f410 address %q: checksum mismatch", s)
+	}
+	return "0x" + hex.EncodeToString(address[:]), nil
+}
+
+// FromEth returns the Filecoin form of a 0x address: f0 for masked ID addresses, f410 otherwise.
+// Testnets (any network but mainnet) use the t prefix.
+func FromEth(address [20]byte, network string) string {
+	prefix := "t"
+	if network == "mainnet" {
+		prefix = "f"
+	}
+
+	if id, ok := maskedID(address); ok {
+		return prefix + strconv.Itoa(ProtocolID) + strconv.FormatUint(id, 10)
+	}
+	payload := append(address[:], delegatedChecksum(address)...)
+	return prefix + "410f" + encoding.EncodeToString(payload)
+}
+
+// maskedID returns the actor ID of a 0xff-prefixed ID address
+func maskedID(address [20]byte) (uint64, bool) {
+	if address[0] != 0xff {
+		return 0, false
+	}
+	for _, b := range address[1:12] {
+		if b != 0 {
+			return 0, false
+		}
+	}
+	return binary.BigEndian.Uint64(address[12:]), true
+}
+
+// delegatedChecksum is the blake2b-32 hash of the address bytes: protocol, namespace
+// (unsigned LEB128) and subaddress
+func delegatedChecksum(address [20]byte) []byte {
+	hash, _ := blake2b.New(checksumLength, nil)
+	hash.Write([]byte{ProtocolDelegated, eamNamespace})
+	hash.Write(address[:])
+	return hash.Sum(nil)
+}
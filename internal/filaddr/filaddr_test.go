@@ -0,0 +1,46 @@
+package filaddr
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestToEth(t *testing.T) {
+	eth, err := ToEth("f410fkkld55ioe7qg24wvt7fu6pbknb56ht7pt4zamxa")
+	if err != nil {
+		t.Fatalf("ToEth failed: %v", err)
+	}
+	if eth != "0x52963ef50e27e06d72d59fcb4f3c2a687be3cfef" {
+		t.Errorf("Expected 0x52963ef50e27e06d72d59fcb4f3c2a687be3cfef, got %s", eth)
+	}
+}
+
+func TestToEthRejectsBadChecksum(t *testing.T) {
+	if _, err := ToEth("f410fkkld55ioe7qg24wvt7fu6pbknb56ht7pt4zamya"); err == nil {
+		t.Error("Expected a checksum error")
+	}
+	if _, err := ToEth("f1abjxfbp274xpdqcpuaykwkfb43omjotacm2p3za"); err == nil {
+		t.Error("Expected an error for a non-delegated address")
+	}
+}
+
+func TestFromEth(t *testing.T) {
+	var address [20]byte
+	hex.Decode(address[:], []byte("52963ef50e27e06d72d59fcb4f3c2a687be3cfef"))
+
+	if got := FromEth(address, "mainnet"); got != "f410fkkld55ioe7qg24wvt7fu6pbknb56ht7pt4zamxa" {
+		t.Errorf("Unexpected mainnet address %s", got)
+	}
+	if got := FromEth(address, "calibration"); got != "t410fkkld55ioe7qg24wvt7fu6pbknb56ht7pt4zamxa" {
+		t.Errorf("Unexpected calibration address %s", got)
+	}
+}
+
+func TestFromEthMaskedID(t *testing.T) {
+	var address [20]byte
+	hex.Decode(address[:], []byte("ff00000000000000000000000000000000000405"))
+
+	if got := FromEth(address, "mainnet"); got != "f01029" {
+		t.Errorf("Expected f01029, got %s", got)
+	}
+}
@@ -0,0 +1,209 @@
+// Package history keeps the wallets of every scrape in an embedded BoltDB database, so balance
+// history outlives the retention of Prometheus.
+//
+// Records are stored per address, keyed by scrape time and wallet type, which keeps the history
+// of one wallet in a single contiguous range.
+package history
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	bolt "go.etcd.io/bbolt"
+)
+
+// pruneInterval is how often records older than the retention are deleted
+const pruneInterval = time.Hour
+
+// walletsBucket holds one nested bucket of records per address
+var walletsBucket = []byte("wallets")
+
+// Wallet is the state of one wallet in one scrape
+type Wallet struct {
+	Time       time.Time      `json:"time"`
+	Block      uint64         `json:"block"`
+	Address    common.Address `json:"address"`
+	Name       string         `json:"name"`
+	Type       string         `json:"type"`
+	ProviderID uint64         `json:"provider_id,omitempty"`
+	FIL        *big.Int       `json:"fil"`   // Raw balance (nil if the lookup failed)
+	USDFC      *big.Int       `json:"usdfc"` // Raw balance (nil if the lookup failed)
+	Payments   []Payments     `json:"payments,omitempty"`
+	Pings      []Ping         `json:"pings,omitempty"`
+}
+
+// Payments is a Payments contract account of a wallet, in raw token units
+type Payments struct {
+	Token            string   `json:"token"`
+	Decimals         int      `json:"decimals"`
+	Funds            *big.Int `json:"funds"`
+	Available        *big.Int `json:"available"`
+	Locked           *big.Int `json:"locked"`
+	FundedUntilEpoch *big.Int `json:"funded_until_epoch"`
+	LockupRate       *big.Int `json:"lockup_rate"`
+}
+
+// Ping is the result of pinging one product of a provider
+type Ping struct {
+	ProductType     string  `json:"product_type"`
+	ServiceURL      string  `json:"service_url"`
+	Success         bool    `json:"success"`
+	StatusCode      int     `json:"status_code,omitempty"`
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+// Store records wallets in a BoltDB file and deletes them once they are older than the
+// retention
+type Store struct {
+	db        *bolt.DB
+	retention time.Duration // 0 = keep forever
+
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+// Open opens or creates the database at path
+func Open(path string, retention time.Duration) (*Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history database %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(walletsBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize history database: %w", err)
+	}
+	return &Store{db: db, retention: retention}, nil
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Record stores the wallets of one scrape taken at time now
+func (s *Store) Record(now time.Time, wallets []Wallet) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		root := tx.Bucket(walletsBucket)
+		for _, wallet := range wallets {
+			wallet.Time = now
+			value, err := json.Marshal(wallet)
+			if err != nil {
+				return err
+			}
+			b, err := root.CreateBucketIfNotExists(wallet.Address.Bytes())
+			if err != nil {
+				return err
+			}
+			if err := b.Put(recordKey(now, wallet.Type), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record history: %w", err)
+	}
+
+	s.mu.Lock()
+	due := s.retention > 0 && now.Sub(s.lastPrune) >= pruneInterval
+	if due {
+		s.lastPrune = now
+	}
+	s.mu.Unlock()
+	if due {
+		return s.prune(now.Add(-s.retention))
+	}
+	return nil
+}
+
+// Wallet returns the records of an address taken in [from, to], oldest first
+func (s *Store) Wallet(address common.Address, from, to time.Time) ([]Wallet, error) {
+	var records []Wallet
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(walletsBucket).Bucket(address.Bytes())
+		if b == nil {
+			return nil
+		}
+		end := timeKey(to.Add(time.Nanosecond))
+		c := b.Cursor()
+		for k, v := c.Seek(timeKey(from)); k != nil && bytes.Compare(k, end) < 0; k, v = c.Next() {
+			var record Wallet
+			if err := json.Unmarshal(v, &record); err != nil {
+				return fmt.Errorf("corrupt history record: %w", err)
+			}
+			records = append(records, record)
+		}
+		return nil
+	})
+	return records, err
+}
+
+// prune deletes the records taken before cutoff, and the addresses left without records
+func (s *Store) prune(cutoff time.Time) error {
+	end := timeKey(cutoff)
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		root := tx.Bucket(walletsBucket)
+		var empty [][]byte
+		err := root.ForEachBucket(func(address []byte) error {
+			b := root.Bucket(address)
+			var expired [][]byte
+			c := b.Cursor()
+			for k, _ := c.First(); k != nil && bytes.Compare(k, end) < 0; k, _ = c.Next() {
+				expired = append(expired, bytes.Clone(k))
+			}
+			for _, k := range expired {
+				if err := b.Delete(k); err != nil {
+					return err
+				}
+			}
+			if k, _ := b.Cursor().First(); k == nil {
+				empty = append(empty, bytes.Clone(address))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, address := range empty {
+			if err := root.DeleteBucket(address); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to prune history: %w", err)
+	}
+	return nil
+}
+
+// timeKey encodes a time so that keys sort chronologically. Times outside of what UnixNano
+// represents are clamped, so open ranges can use the zero time.
+func timeKey(t time.Time) []byte {
+	var n uint64
+	switch {
+	case t.Before(time.Unix(0, 0)):
+	case t.Year() >= 2262:
+		n = math.MaxInt64
+	default:
+		n = uint64(t.UnixNano())
+	}
+	return binary.BigEndian.AppendUint64(nil, n)
+}
+
+// recordKey identifies the record of a wallet type at a time; an address monitored under
+// several types has one record per type
+func recordKey(t time.Time, walletType string) []byte {
+	return append(timeKey(t), walletType...)
+}
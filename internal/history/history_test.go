@@ -0,0 +1,65 @@
+package history
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestStore(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "history.db"), 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	client := common.HexToAddress("0x01")
+	provider := common.HexToAddress("0x02")
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		wallets := []Wallet{
+			{Address: client, Name: "client", Type: "client", FIL: big.NewInt(int64(100 - i)), Payments: []Payments{{Token: "usdfc", Funds: big.NewInt(5)}}},
+			{Address: provider, Name: "sp", Type: "provider", ProviderID: 7, FIL: big.NewInt(1), Pings: []Ping{{ProductType: "pdp", Success: true}}},
+		}
+		if err := store.Record(start.Add(time.Duration(i)*time.Hour), wallets); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records, err := store.Wallet(client, start.Add(time.Hour), start.Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0].FIL.Int64() != 99 || !records[1].Time.Equal(start.Add(2*time.Hour)) {
+		t.Fatalf("Unexpected records %+v", records)
+	}
+	if records[0].Payments[0].Funds.Int64() != 5 {
+		t.Errorf("Expected the Payments account to be kept, got %+v", records[0].Payments)
+	}
+
+	records, err = store.Wallet(provider, time.Time{}, start.Add(24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[0].ProviderID != 7 || len(records[0].Pings) != 1 {
+		t.Fatalf("Unexpected provider records %+v", records)
+	}
+
+	if records, _ := store.Wallet(common.HexToAddress("0x03"), time.Time{}, start.Add(24*time.Hour)); len(records) != 0 {
+		t.Errorf("Expected no records of an unknown address, got %+v", records)
+	}
+
+	// Records older than the retention are deleted
+	if err := store.Record(start.Add(26*time.Hour+30*time.Minute), []Wallet{{Address: provider, Type: "provider"}}); err != nil {
+		t.Fatal(err)
+	}
+	if records, _ := store.Wallet(client, time.Time{}, start.Add(48*time.Hour)); len(records) != 0 {
+		t.Errorf("Expected the client history to be pruned, got %d records", len(records))
+	}
+	if records, _ := store.Wallet(provider, time.Time{}, start.Add(48*time.Hour)); len(records) != 1 {
+		t.Errorf("Expected only the latest provider record, got %d records", len(records))
+	}
+}